@@ -0,0 +1,467 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"hmcalister/gravity_simulation/persist"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The help for the analyze command, printed with -h
+const ANALYZEHELP = `
+Gravity Simulation - analyze
+Usage:
+	./gravity_simulation analyze [flags] trajectory
+
+	Reads a trajectory file (as written by --trajectoryOut, in either format) and prints a report of the run:
+	- the number of frames and the time they cover, the number of bodies and their total mass, and how much
+	  the total momentum and energy changed between the first and last frames
+	- the energy drift, the furthest the total energy strayed from where it started over the whole run
+	  Merges lose energy (they are inelastic), so in runs with merges not all of the drift is error
+	- the merger tree of the most massive merged bodies, which bodies they absorbed and when
+	  The trajectory only has the bodies in each frame, so a merge is inferred wherever a body disappears and a
+	  nearby body gains about its mass. Bodies that disappear without one (e.g. culled or deleted) are counted as removed
+	- the mass function, the number of bodies in the last frame in each of a range of masses (spaced logarithmically)
+	- the eccentricities of the orbits of the bodies around the most massive body in the last frame, found from their
+	  positions and velocities as if each was alone with it, and their mean over the bound bodies
+
+Flags:
+	--G : The gravitational constant the trajectory was simulated with, used for the energy and eccentricities
+		Defaults to 100. The potential energy ignores any softening
+	--every : Find the energy every this many frames, as the potential energy is slow to find for many bodies
+		Defaults to 1, every frame. Mergers are found from every frame regardless
+	--massBins : The number of ranges of mass in the mass function
+		Defaults to 10
+	--plotsOut : If set, also write the data behind the report as csv files into this directory, for plotting:
+		energy.csv (the energy of each frame it was found for), mergers.csv (every inferred merge),
+		massfunction.csv (the mass function) and eccentricities.csv (the orbit of each body)`
+
+// The most merged bodies whose merger trees are printed, the rest are only in mergers.csv
+const MERGERTREES = 10
+
+// The statistics of the first and last frames of a trajectory
+type frameStats struct {
+	time      float64
+	bodies    int
+	mass      float64
+	momentum  simulation.Vec2
+	kinetic   float64
+	potential float64
+	// The angular momentum about the barycenter
+	angularMomentum float64
+}
+
+// Work out the statistics of a frame
+func statsOf(time float64, bodies []*simulation.Body, G float64) frameStats {
+	s := frameStats{
+		time:            time,
+		mass:            simulation.TotalMass(bodies),
+		momentum:        simulation.Momentum(bodies),
+		kinetic:         simulation.KineticEnergy(bodies),
+		potential:       simulation.PotentialEnergy(bodies, G),
+		angularMomentum: simulation.BarycentricAngularMomentum(bodies),
+	}
+	for _, b := range bodies {
+		if b != nil {
+			s.bodies++
+		}
+	}
+	return s
+}
+
+// A merge inferred from a trajectory, where a body disappeared between two frames and a nearby body gained its mass
+type merger struct {
+	time     float64
+	absorbed int
+	into     int
+	// The mass of the absorbed body at the frame before it disappeared
+	mass float64
+}
+
+// Infer the merges between two consecutive frames, returning them and the ids of the bodies that disappeared without merging
+// Each body that disappeared is matched with the nearest body that gained at least half its mass (that hasn't already
+// been matched with enough other bodies to account for what it gained), as the merged body is at their center of mass
+func inferMergers(time float64, prev, cur []*simulation.Body) ([]merger, []int) {
+	var gone []int
+	for id, b := range prev {
+		if b != nil && (id >= len(cur) || cur[id] == nil) {
+			gone = append(gone, id)
+		}
+	}
+	if len(gone) == 0 {
+		return nil, nil
+	}
+	// The mass each body gained that hasn't been matched to a body that disappeared yet
+	gained := map[int]float64{}
+	for id, b := range cur {
+		if b != nil && id < len(prev) && prev[id] != nil && b.Mass > prev[id].Mass {
+			gained[id] = b.Mass - prev[id].Mass
+		}
+	}
+	// The most massive bodies are matched first, as they account for the most of what was gained
+	slices.SortStableFunc(gone, func(a, b int) int { return cmp.Compare(prev[b].Mass, prev[a].Mass) })
+
+	var mergers []merger
+	var removed []int
+	for _, id := range gone {
+		b := prev[id]
+		into := -1
+		for candidate, mass := range gained {
+			if mass < b.Mass/2 {
+				continue
+			}
+			if into < 0 || simulation.DistSquared(b, prev[candidate]) < simulation.DistSquared(b, prev[into]) ||
+				(simulation.DistSquared(b, prev[candidate]) == simulation.DistSquared(b, prev[into]) && candidate < into) {
+				into = candidate
+			}
+		}
+		if into < 0 {
+			removed = append(removed, id)
+			continue
+		}
+		gained[into] -= b.Mass
+		mergers = append(mergers, merger{time: time, absorbed: id, into: into, mass: b.Mass})
+	}
+	slices.SortFunc(mergers, func(a, b merger) int { return a.absorbed - b.absorbed })
+	slices.Sort(removed)
+	return mergers, removed
+}
+
+// The energy of a frame, and how far it has drifted from the energy of the first frame as a fraction of it
+type energySample struct {
+	time      float64
+	bodies    int
+	kinetic   float64
+	potential float64
+	drift     float64
+}
+
+// A range of masses in the mass function, from low up to (and for the last range, including) high
+type massBin struct {
+	low, high float64
+	bodies    int
+}
+
+// Count the bodies in each of n ranges of mass, spaced logarithmically between the smallest and largest masses
+// Bodies without mass have no place on a logarithmic scale, so are left out. If every body has the same mass there is one range
+func massFunction(bodies []*simulation.Body, n int) []massBin {
+	smallest, largest := math.Inf(1), 0.0
+	for _, b := range bodies {
+		if b != nil && b.Mass > 0 {
+			smallest, largest = math.Min(smallest, b.Mass), math.Max(largest, b.Mass)
+		}
+	}
+	if largest == 0 {
+		return nil
+	}
+	if smallest == largest {
+		n = 1
+	}
+	bins := make([]massBin, n)
+	ratio := largest / smallest
+	for i := range bins {
+		bins[i].low = smallest * math.Pow(ratio, float64(i)/float64(n))
+		bins[i].high = smallest * math.Pow(ratio, float64(i+1)/float64(n))
+	}
+	bins[n-1].high = largest
+	for _, b := range bodies {
+		if b == nil || b.Mass <= 0 {
+			continue
+		}
+		i := 0
+		if ratio > 1 {
+			i = int(float64(n) * math.Log(b.Mass/smallest) / math.Log(ratio))
+		}
+		bins[min(max(i, 0), n-1)].bodies++
+	}
+	return bins
+}
+
+// The orbit of a body around the primary in the last frame
+type eccentricity struct {
+	id           int
+	mass         float64
+	distance     float64
+	eccentricity float64
+	bound        bool
+}
+
+// The analyze command, which prints a report of a trajectory file
+func analyzeCommand(args []string) {
+	var helpFlag bool
+	var G float64
+	var every, massBins int
+	var plotsOut string
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	fs.Float64Var(&G, "G", 100, "The gravitational constant the trajectory was simulated with")
+	fs.IntVar(&every, "every", 1, "Find the energy every this many frames")
+	fs.IntVar(&massBins, "massBins", 10, "The number of ranges of mass in the mass function")
+	fs.StringVar(&plotsOut, "plotsOut", "", "A directory to write the data behind the report into as csv files")
+	fs.BoolVar(&helpFlag, "h", false, "Display help on this command, then quit")
+	logFlags(fs)
+	fs.Parse(args)
+	setupLogging()
+
+	if helpFlag {
+		fmt.Println(ANALYZEHELP + LOGGINGHELP)
+		os.Exit(0)
+	}
+	if fs.NArg() != 1 {
+		fatal("ANALYZE NEEDS A TRAJECTORY FILE, SEE analyze -h")
+	}
+	if every < 1 || massBins < 1 {
+		fatal("--every AND --massBins MUST BE AT LEAST 1")
+	}
+	path := fs.Arg(0)
+
+	// Only the first, previous and last frames are kept, so trajectories of any length can be analyzed
+	frames := 0
+	var first, last []*simulation.Body
+	var firstTime, lastTime float64
+	var energies []energySample
+	var mergers []merger
+	var removed int
+	// The mass of each body at the last frame it was in, for the bodies that absorbed others
+	lastMass := map[int]float64{}
+	err := persist.ReadTrajectory(path, func(time float64, bodies []*simulation.Body) error {
+		if frames == 0 {
+			first, firstTime = bodies, time
+		} else {
+			merged, gone := inferMergers(time, last, bodies)
+			mergers = append(mergers, merged...)
+			removed += len(gone)
+			for _, id := range gone {
+				lastMass[id] = last[id].Mass
+			}
+		}
+		if frames%every == 0 {
+			s := statsOf(time, bodies, G)
+			e := energySample{time: time, bodies: s.bodies, kinetic: s.kinetic, potential: s.potential}
+			if len(energies) > 0 {
+				start := energies[0].kinetic + energies[0].potential
+				e.drift = (e.kinetic + e.potential - start) / math.Abs(start)
+			}
+			energies = append(energies, e)
+		}
+		last, lastTime = bodies, time
+		frames++
+		return nil
+	})
+	if err != nil {
+		fatal("COULD NOT READ TRAJECTORY", "err", err)
+	}
+	if frames == 0 {
+		fatal("THE TRAJECTORY HAS NO FRAMES", "path", path)
+	}
+	for id, b := range last {
+		if b != nil {
+			lastMass[id] = b.Mass
+		}
+	}
+
+	start := statsOf(firstTime, first, G)
+	end := statsOf(lastTime, last, G)
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Fprintln(tableWriter, "TRAJECTORY\t", path)
+	fmt.Fprintln(tableWriter, "FRAMES\t", frames)
+	fmt.Fprintln(tableWriter, "\tFIRST FRAME\tLAST FRAME\tCHANGE")
+	fmt.Fprintf(tableWriter, "TIME\t%.4g\t%.4g\t%.4g\n", start.time, end.time, end.time-start.time)
+	fmt.Fprintf(tableWriter, "BODIES\t%v\t%v\t%v\n", start.bodies, end.bodies, end.bodies-start.bodies)
+	fmt.Fprintf(tableWriter, "TOTAL MASS\t%.6g\t%.6g\t%.3g\n", start.mass, end.mass, end.mass-start.mass)
+	fmt.Fprintf(tableWriter, "MOMENTUM\t(%.6g, %.6g)\t(%.6g, %.6g)\t%.3g\n", start.momentum.X, start.momentum.Y, end.momentum.X, end.momentum.Y,
+		math.Hypot(end.momentum.X-start.momentum.X, end.momentum.Y-start.momentum.Y))
+	fmt.Fprintf(tableWriter, "ANGULAR MOMENTUM\t%.6g\t%.6g\t%.3g (%.3g%%)\n", start.angularMomentum, end.angularMomentum,
+		end.angularMomentum-start.angularMomentum, 100*angularMomentumDrift(start.angularMomentum, end.angularMomentum))
+	fmt.Fprintf(tableWriter, "KINETIC ENERGY\t%.6g\t%.6g\t%.3g\n", start.kinetic, end.kinetic, end.kinetic-start.kinetic)
+	fmt.Fprintf(tableWriter, "POTENTIAL ENERGY\t%.6g\t%.6g\t%.3g\n", start.potential, end.potential, end.potential-start.potential)
+	startEnergy, endEnergy := start.kinetic+start.potential, end.kinetic+end.potential
+	fmt.Fprintf(tableWriter, "TOTAL ENERGY\t%.6g\t%.6g\t%.3g (%.3g%%)\n", startEnergy, endEnergy, endEnergy-startEnergy,
+		100*(endEnergy-startEnergy)/math.Abs(startEnergy))
+	tableWriter.Flush()
+
+	// The energy drift
+	worst := energies[0]
+	for _, e := range energies {
+		if math.Abs(e.drift) > math.Abs(worst.drift) {
+			worst = e
+		}
+	}
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Fprintf(tableWriter, "ENERGY DRIFT\t%.3g%% at time %.4g (from %v frames)\n", 100*worst.drift, worst.time, len(energies))
+	fmt.Fprintf(tableWriter, "MERGERS\t%v\n", len(mergers))
+	fmt.Fprintf(tableWriter, "REMOVED WITHOUT MERGING\t%v\n", removed)
+	tableWriter.Flush()
+
+	printMergerTrees(mergers, lastMass, last)
+	bins := massFunction(last, massBins)
+	printMassFunction(bins)
+	eccentricities := lastEccentricities(last, G)
+	printEccentricities(eccentricities, last)
+
+	if plotsOut != "" {
+		writePlotData(plotsOut, energies, mergers, bins, eccentricities)
+	}
+}
+
+// Print the merger trees of the bodies that absorbed the most mass, most massive first
+// A tree is each body its root absorbed in order, each followed by the bodies it had absorbed itself, and so on
+func printMergerTrees(mergers []merger, lastMass map[int]float64, last []*simulation.Body) {
+	if len(mergers) == 0 {
+		return
+	}
+	absorbedBy := map[int][]merger{}
+	wasAbsorbed := map[int]bool{}
+	for _, m := range mergers {
+		absorbedBy[m.into] = append(absorbedBy[m.into], m)
+		wasAbsorbed[m.absorbed] = true
+	}
+	var roots []int
+	for id := range absorbedBy {
+		if !wasAbsorbed[id] {
+			roots = append(roots, id)
+		}
+	}
+	slices.SortFunc(roots, func(a, b int) int {
+		if c := cmp.Compare(lastMass[b], lastMass[a]); c != 0 {
+			return c
+		}
+		return a - b
+	})
+
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Println("MERGER TREES")
+	var printTree func(id, depth int)
+	printTree = func(id, depth int) {
+		for _, m := range absorbedBy[id] {
+			fmt.Printf("%vabsorbed %v (mass %.4g) at time %.4g\n", strings.Repeat("    ", depth), m.absorbed, m.mass, m.time)
+			printTree(m.absorbed, depth+1)
+		}
+	}
+	for _, id := range roots[:min(len(roots), MERGERTREES)] {
+		state := "in the last frame"
+		if id >= len(last) || last[id] == nil {
+			state = "removed before the last frame"
+		}
+		fmt.Printf("BODY %v (mass %.4g, %v)\n", id, lastMass[id], state)
+		printTree(id, 1)
+	}
+	if len(roots) > MERGERTREES {
+		fmt.Printf("... AND %v MORE MERGED BODIES\n", len(roots)-MERGERTREES)
+	}
+}
+
+// Print the mass function as a table, with a bar for the number of bodies in each range of mass
+func printMassFunction(bins []massBin) {
+	if len(bins) == 0 {
+		return
+	}
+	most := 0
+	for _, bin := range bins {
+		most = max(most, bin.bodies)
+	}
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Println("MASS FUNCTION (LAST FRAME)")
+	fmt.Fprintln(tableWriter, "MASS FROM\tTO\tBODIES\t")
+	for _, bin := range bins {
+		fmt.Fprintf(tableWriter, "%.4g\t%.4g\t%v\t%v\n", bin.low, bin.high, bin.bodies, strings.Repeat("#", (40*bin.bodies+most-1)/most))
+	}
+	tableWriter.Flush()
+}
+
+// The orbit of each body around the primary in the last frame, in order of id
+func lastEccentricities(last []*simulation.Body, G float64) []eccentricity {
+	primary := simulation.Primary(last)
+	if primary < 0 {
+		return nil
+	}
+	var eccentricities []eccentricity
+	for id, b := range last {
+		if b == nil || id == primary {
+			continue
+		}
+		e, bound := simulation.OsculatingEccentricity(b, last[primary], G)
+		distance, _ := simulation.Radial(b, last[primary])
+		eccentricities = append(eccentricities, eccentricity{id: id, mass: b.Mass, distance: distance, eccentricity: e, bound: bound})
+	}
+	return eccentricities
+}
+
+// Print the mean eccentricity of the bound orbits around the primary, and how many bodies are unbound from it
+func printEccentricities(eccentricities []eccentricity, last []*simulation.Body) {
+	if len(eccentricities) == 0 {
+		return
+	}
+	bound := 0
+	sum := 0.0
+	for _, e := range eccentricities {
+		if e.bound {
+			bound++
+			sum += e.eccentricity
+		}
+	}
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Fprintf(tableWriter, "ORBITS AROUND BODY\t%v (LAST FRAME)\n", simulation.Primary(last))
+	fmt.Fprintf(tableWriter, "BOUND\t%v\n", bound)
+	fmt.Fprintf(tableWriter, "UNBOUND\t%v\n", len(eccentricities)-bound)
+	if bound > 0 {
+		fmt.Fprintf(tableWriter, "MEAN ECCENTRICITY\t%.4g\n", sum/float64(bound))
+	}
+	tableWriter.Flush()
+}
+
+// Write the data behind the report into dir as csv files, each with a header like a csv trajectory
+func writePlotData(dir string, energies []energySample, mergers []merger, bins []massBin, eccentricities []eccentricity) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fatal("COULD NOT CREATE PLOTS DIRECTORY", "dir", dir, "err", err)
+	}
+	writePlotFile(dir, "energy.csv", "#time, bodies, kinetic, potential, energy, drift", func(w io.Writer) {
+		for _, e := range energies {
+			fmt.Fprintf(w, "%v,%v,%v,%v,%v,%v\n", e.time, e.bodies, e.kinetic, e.potential, e.kinetic+e.potential, e.drift)
+		}
+	})
+	writePlotFile(dir, "mergers.csv", "#time, absorbed, into, mass", func(w io.Writer) {
+		for _, m := range mergers {
+			fmt.Fprintf(w, "%v,%v,%v,%v\n", m.time, m.absorbed, m.into, m.mass)
+		}
+	})
+	writePlotFile(dir, "massfunction.csv", "#low, high, bodies", func(w io.Writer) {
+		for _, bin := range bins {
+			fmt.Fprintf(w, "%v,%v,%v\n", bin.low, bin.high, bin.bodies)
+		}
+	})
+	writePlotFile(dir, "eccentricities.csv", "#id, mass, distance, eccentricity, bound", func(w io.Writer) {
+		for _, e := range eccentricities {
+			fmt.Fprintf(w, "%v,%v,%v,%v,%v\n", e.id, e.mass, e.distance, e.eccentricity, e.bound)
+		}
+	})
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Println("PLOT DATA WRITTEN TO", dir)
+}
+
+// Write one csv file of plot data, with the header and then the rows written by rows
+func writePlotFile(dir, name, header string, rows func(w io.Writer)) {
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		fatal("COULD NOT WRITE PLOT DATA", "path", path, "err", err)
+	}
+	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, header)
+	rows(w)
+	err = w.Flush()
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fatal("COULD NOT WRITE PLOT DATA", "path", path, "err", err)
+	}
+}
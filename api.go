@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// With --apiAddr, the simulation can be driven over HTTP while it runs, with or without a window, e.g.
+//
+//	curl localhost:8080/state
+//	curl -X POST localhost:8080/pause
+//	curl -X POST -d '{"timescale": 0.1}' localhost:8080/params
+//	curl -X POST -d '{"x": 100, "y": 0, "yVel": 3, "mass": 5}' localhost:8080/bodies
+//	curl -X DELETE localhost:8080/bodies/3
+//	curl -X POST -d '{"path": "run.pb"}' localhost:8080/save
+//
+// Requests are handled with physicsLock held, like the inputs of the window, so they happen between steps
+// Changes to the bodies can be undone in the window like any other edit
+
+// Set by the --apiAddr flag
+var apiAddr string
+
+// The largest request body read, in bytes, far more than any request needs
+const APIMAXBODY = 1 << 20
+
+// The directory that POST /save writes named saves to, so clients can't overwrite any other file (e.g. the source)
+const APISAVEDIR = "saves"
+
+// The extensions of the formats POST /save can write, optionally gzipped
+var apiSaveExtensions = []string{".csv", ".json", ".pb"}
+
+// The state of the simulation, as returned by GET /state
+type apiState struct {
+	Time   float64   `json:"time"`
+	Steps  int       `json:"steps"`
+	Paused bool      `json:"paused"`
+	Params apiParams `json:"params"`
+	Bodies []apiBody `json:"bodies"`
+}
+
+// The parameters of the simulation, as returned in the state and set by POST /params
+// Only the parameters given are changed
+type apiParams struct {
+	G          *float64 `json:"G,omitempty"`
+	Timescale  *float64 `json:"timescale,omitempty"`
+	Softening  *float64 `json:"softening,omitempty"`
+	Collisions *string  `json:"collisions,omitempty"`
+}
+
+// A body, as returned in the state and added by POST /bodies
+// A body added without a radius gets the radius of its mass, and without a mass a mass of 1
+type apiBody struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name,omitempty"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	XVel   float64 `json:"xVel"`
+	YVel   float64 `json:"yVel"`
+	Mass   float64 `json:"mass"`
+	Radius float64 `json:"radius"`
+	Fixed  bool    `json:"fixed,omitempty"`
+}
+
+// An error from a request, with the HTTP status to respond with
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+// Create an error responded to with the given status
+func newAPIError(status int, format string, args ...any) error {
+	return &apiError{status, fmt.Sprintf(format, args...)}
+}
+
+// Start serving the API on --apiAddr in the background
+// Like the metrics, failing to serve the API is not fatal - the simulation simply runs without it
+func setupAPI() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/state", apiHandler(http.MethodGet, apiGetState))
+	mux.HandleFunc("/pause", apiHandler(http.MethodPost, func(r *http.Request) (any, error) { return apiSetPaused(true) }))
+	mux.HandleFunc("/resume", apiHandler(http.MethodPost, func(r *http.Request) (any, error) { return apiSetPaused(false) }))
+	mux.HandleFunc("/params", apiHandler(http.MethodPost, apiSetParams))
+	mux.HandleFunc("/bodies", apiHandler(http.MethodPost, apiAddBody))
+	mux.HandleFunc("/bodies/", apiHandler(http.MethodDelete, apiRemoveBody))
+	mux.HandleFunc("/save", apiHandler(http.MethodPost, apiSave))
+	go func() {
+		if err := http.ListenAndServe(apiAddr, mux); err != nil {
+			slog.Warn("COULD NOT SERVE API", "addr", apiAddr, "err", err)
+		}
+	}()
+	slog.Info("SERVING API", "url", "http://"+apiAddr)
+}
+
+// Handle requests with the given method by running f with physicsLock held, responding with what it returns as JSON
+func apiHandler(method string, f func(r *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var response any
+		var err error
+		r.Body = http.MaxBytesReader(w, r.Body, APIMAXBODY)
+		if r.Method != method {
+			err = newAPIError(http.StatusMethodNotAllowed, "%v must be requested with %v", r.URL.Path, method)
+		} else {
+			physicsLock.Lock()
+			if shuttingDown() {
+				err = newAPIError(http.StatusServiceUnavailable, "the simulation is shutting down")
+			} else {
+				response, err = f(r)
+			}
+			physicsLock.Unlock()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			status := http.StatusInternalServerError
+			var apiErr *apiError
+			if errors.As(err, &apiErr) {
+				status = apiErr.status
+			}
+			slog.Debug("API REQUEST FAILED", "method", r.Method, "path", r.URL.Path, "err", err)
+			w.WriteHeader(status)
+			response = map[string]string{"error": err.Error()}
+		}
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// Decode the JSON body of a request into v
+func decodeAPIRequest(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return newAPIError(http.StatusBadRequest, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+// The current state of the simulation, with every body
+func apiGetState(r *http.Request) (any, error) {
+	state := apiState{
+		Time:   sim.Time,
+		Steps:  sim.Steps,
+		Paused: paused,
+		Params: currentAPIParams(),
+		Bodies: []apiBody{},
+	}
+	for id, b := range sim.Bodies() {
+		if b == nil {
+			continue
+		}
+		state.Bodies = append(state.Bodies, apiBody{ID: id, Name: b.Name, X: b.Pos.X, Y: b.Pos.Y, XVel: b.Vel.X, YVel: b.Vel.Y,
+			Mass: b.Mass, Radius: b.Radius, Fixed: b.Fixed})
+	}
+	return state, nil
+}
+
+// The current parameters of the simulation
+// They are copied, so can be sent after physicsLock is released
+func currentAPIParams() apiParams {
+	G, timescale, softening := sim.G, sim.Timescale, sim.Softening
+	collisions := simulation.CollisionModeNames[sim.CollisionMode]
+	return apiParams{G: &G, Timescale: &timescale, Softening: &softening, Collisions: &collisions}
+}
+
+// Pause or resume the simulation
+func apiSetPaused(value bool) (any, error) {
+	paused = value
+	slog.Info("API SET PAUSED", "paused", paused)
+	return map[string]bool{"paused": paused}, nil
+}
+
+// Change any of the parameters given, checking them all before changing any, responding with the new parameters
+func apiSetParams(r *http.Request) (any, error) {
+	var params apiParams
+	if err := decodeAPIRequest(r, &params); err != nil {
+		return nil, err
+	}
+	for name, value := range map[string]*float64{"G": params.G, "timescale": params.Timescale} {
+		if value != nil && *value <= 0 {
+			return nil, newAPIError(http.StatusBadRequest, "%v must be positive", name)
+		}
+	}
+	if params.Softening != nil && *params.Softening < 0 {
+		return nil, newAPIError(http.StatusBadRequest, "softening must not be negative")
+	}
+	mode := sim.CollisionMode
+	if params.Collisions != nil {
+		var err error
+		if mode, err = simulation.ParseCollisionMode(*params.Collisions); err != nil {
+			return nil, newAPIError(http.StatusBadRequest, "%v", err)
+		}
+	}
+
+	if params.G != nil {
+		sim.G = *params.G
+	}
+	if params.Timescale != nil {
+		sim.Timescale = *params.Timescale
+	}
+	if params.Softening != nil {
+		sim.Softening = *params.Softening
+	}
+	sim.CollisionMode = mode
+	slog.Info("API SET PARAMETERS", "G", sim.G, "timescale", sim.Timescale, "softening", sim.Softening,
+		"collisions", simulation.CollisionModeNames[sim.CollisionMode])
+	return currentAPIParams(), nil
+}
+
+// Add a body, responding with its id
+func apiAddBody(r *http.Request) (any, error) {
+	body := apiBody{Mass: 1}
+	if err := decodeAPIRequest(r, &body); err != nil {
+		return nil, err
+	}
+	if body.Mass <= 0 || body.Radius < 0 {
+		return nil, newAPIError(http.StatusBadRequest, "the mass must be positive and the radius not negative")
+	}
+	if body.Radius == 0 {
+		body.Radius = simulation.MassToRadius(body.Mass)
+	}
+	recordUndo()
+	id := sim.AddBody(&simulation.Body{
+		Pos:    simulation.Vec2{X: body.X, Y: body.Y},
+		Vel:    simulation.Vec2{X: body.XVel, Y: body.YVel},
+		Mass:   body.Mass,
+		Radius: body.Radius,
+		Color:  simulation.RandomColor(sim.Rand),
+		Name:   body.Name,
+		Fixed:  body.Fixed,
+	})
+	slog.Info("API ADDED BODY", "id", id)
+	return map[string]int{"id": id}, nil
+}
+
+// Remove the body with the id at the end of the path, e.g. /bodies/3
+func apiRemoveBody(r *http.Request) (any, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/bodies/"))
+	if err != nil || id < 0 || id >= len(sim.Bodies()) || sim.Bodies()[id] == nil {
+		return nil, newAPIError(http.StatusNotFound, "there is no body %q", strings.TrimPrefix(r.URL.Path, "/bodies/"))
+	}
+	recordUndo()
+	sim.RemoveBody(id)
+	slog.Info("API REMOVED BODY", "id", id)
+	return map[string]int{"removed": id}, nil
+}
+
+// Save the simulation, to the path given in the request (in the format of its extension) or as with O if none is given
+func apiSave(r *http.Request) (any, error) {
+	var request struct {
+		Path string `json:"path"`
+	}
+	if r.ContentLength != 0 {
+		if err := decodeAPIRequest(r, &request); err != nil {
+			return nil, err
+		}
+	}
+	save := saveState
+	path := request.Path
+	if path != "" {
+		// The API has no authentication, so clients can only write save files, within APISAVEDIR, and can't overwrite
+		// anything else the simulation can
+		if !filepath.IsLocal(path) {
+			return nil, newAPIError(http.StatusBadRequest, "the path %q must be relative and within the %v directory", path, APISAVEDIR)
+		}
+		if !slices.Contains(apiSaveExtensions, filepath.Ext(strings.TrimSuffix(path, ".gz"))) {
+			return nil, newAPIError(http.StatusBadRequest, "the path %q must end in one of %v (optionally followed by .gz)", path, strings.Join(apiSaveExtensions, ", "))
+		}
+		path = filepath.Join(APISAVEDIR, path)
+		save = func() error {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			return saveStateToPath(path)
+		}
+	}
+	if err := save(); err != nil {
+		return nil, err
+	}
+	slog.Info("API SAVED", "path", path)
+	return map[string]bool{"saved": true}, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// After every step, the next close approaches between the most massive bodies are predicted (see simulation.CloseApproaches),
+// so it is known where to look before they happen. The soonest are shown in the HUD, and each can be logged once as it is
+// first predicted. The predictions treat each pair as if they were the only two bodies, so are only estimates
+
+// The most close approaches shown in the HUD
+const APPROACHHUDLINES = 3
+
+var (
+	// Set by the approach flags, see simulationFlags
+	approachDistance float64
+	approachSteps    int
+	approachBodies   int
+	logApproaches    bool
+	// The close approaches predicted after the last step, soonest first
+	approaches []simulation.Approach
+	// The simulated time each pair of bodies logged was predicted to approach at, so each approach is only logged once,
+	// even if it drops in and out of the predictions as it gets closer. Pairs are forgotten once the time has passed
+	loggedApproaches = map[[2]int]float64{}
+)
+
+// Predict the close approaches after every step, starting from the current state
+func trackApproaches() {
+	predictApproaches(sim)
+	sim.OnStep(predictApproaches)
+}
+
+// Predict the close approaches of the simulation within the next --approachSteps steps, logging any new ones if asked to
+func predictApproaches(s *simulation.Simulation) {
+	approaches = simulation.CloseApproaches(s.Bodies(), s.G, approachBodies, approachDistance, float64(approachSteps)*s.Timescale)
+	if !logApproaches {
+		return
+	}
+	for pair, time := range loggedApproaches {
+		if time < s.Time {
+			delete(loggedApproaches, pair)
+		}
+	}
+	for _, a := range approaches {
+		pair := [2]int{a.A, a.B}
+		if _, logged := loggedApproaches[pair]; !logged {
+			slog.Info("CLOSE APPROACH PREDICTED", "a", a.A, "b", a.B, "distance", a.Distance, "steps", approachStepsUntil(a, s.Timescale), "time", s.Time)
+		}
+		loggedApproaches[pair] = s.Time + a.Time
+	}
+}
+
+// The number of steps until an approach at the given timescale, 0 if the simulation isn't moving
+func approachStepsUntil(a simulation.Approach, timescale float64) int {
+	if timescale <= 0 {
+		return 0
+	}
+	return int(math.Ceil(a.Time / timescale))
+}
+
+// The lines of the HUD showing the soonest close approaches
+func approachStatus() []string {
+	var lines []string
+	for i, a := range approaches {
+		if i == APPROACHHUDLINES {
+			lines = append(lines, fmt.Sprintf("AND %v MORE APPROACHES", len(approaches)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("APPROACH: %v & %v WITHIN %.3g IN ~%v STEPS", a.A, a.B, a.Distance, approachStepsUntil(a, sim.Timescale)))
+	}
+	return lines
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// With --benchmark, the starting bodies are run at each of a list of timescales instead of being simulated as usual,
+// and a table of how long each run took against how far its energy strayed is printed, to help pick a timescale that
+// is fast enough and accurate enough. Each run is a copy of the starting bodies (see Simulation.Clone) run for the same
+// simulated time, --simTime or BENCHMARKSIMTIME. The simulation has one integrator, the explicit Euler method of
+// Body.Update, so the table compares timescales for it
+
+// How much time each benchmark run simulates if --simTime isn't given
+const BENCHMARKSIMTIME = 100
+
+// How many times during each benchmark run its energy is checked, for the largest energy error
+// The energy takes as long to find as a step, so isn't found every step, and isn't counted in the runtime
+const BENCHMARKSAMPLES = 100
+
+// Set by the --benchmark flag, a comma separated list of the timescales to benchmark
+var benchmarkString string
+
+// The result of running the starting bodies at one timescale
+type benchmarkRun struct {
+	timescale float64
+	steps     int
+	runtime   time.Duration
+	// The relative energy error at the end of the run, and the largest seen during it
+	finalError float64
+	maxError   float64
+	merges     int
+	bodies     int
+}
+
+// Parse a comma separated list of positive timescales
+func parseTimescales(s string) ([]float64, error) {
+	var timescales []float64
+	for _, field := range strings.Split(s, ",") {
+		timescale, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, err
+		}
+		if timescale <= 0 || math.IsInf(timescale, 0) || math.IsNaN(timescale) {
+			return nil, fmt.Errorf("timescale %v must be positive", field)
+		}
+		timescales = append(timescales, timescale)
+	}
+	return timescales, nil
+}
+
+// Run the starting bodies at each timescale of --benchmark and print the table of runtime against energy error
+// An interrupt (or --duration) stops the benchmark early, and the runs finished so far are still printed
+func runBenchmark() {
+	timescales, err := parseTimescales(benchmarkString)
+	if err != nil {
+		fatal("INVALID --benchmark, MUST BE A COMMA SEPARATED LIST OF TIMESCALES", "err", err)
+	}
+	length := float64(BENCHMARKSIMTIME)
+	if maxSimTime > 0 {
+		length = maxSimTime
+	}
+	startEnergy := totalEnergy()
+	catchInterrupts()
+
+	var runs []benchmarkRun
+	for _, timescale := range timescales {
+		slog.Info("BENCHMARKING", "timescale", timescale, "simTime", length)
+		run, finished := benchmark(timescale, length, startEnergy)
+		if !finished {
+			slog.Warn("BENCHMARK INTERRUPTED", "timescale", timescale)
+			break
+		}
+		runs = append(runs, run)
+	}
+
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Fprintf(tableWriter, "BENCHMARK\t%v BODIES, EULER INTEGRATOR, %v SIMULATED TIME PER RUN\n", countBodies(sim.Bodies()), length)
+	fmt.Fprintln(tableWriter, "TIMESCALE\tSTEPS\tRUNTIME\tSTEPS/S\tFINAL ENERGY ERROR\tMAX ENERGY ERROR\tMERGES\tBODIES")
+	for _, r := range runs {
+		fmt.Fprintf(tableWriter, "%v\t%v\t%v\t%.0f\t%.3g%%\t%.3g%%\t%v\t%v\n", r.timescale, r.steps, r.runtime.Round(time.Millisecond),
+			float64(r.steps)/r.runtime.Seconds(), 100*r.finalError, 100*r.maxError, r.merges, r.bodies)
+	}
+	tableWriter.Flush()
+	for _, r := range runs {
+		if r.merges > 0 {
+			fmt.Println("Merges lose energy, so not all of the energy error is from the integrator. Use --collisions=pass to see only its error")
+			break
+		}
+	}
+}
+
+// Run a copy of the starting bodies at the timescale for the given simulated time, returning how it went and whether it finished
+// The energy error is relative to the energy of the starting bodies
+func benchmark(timescale, length, startEnergy float64) (benchmarkRun, bool) {
+	c := sim.Clone()
+	c.Timescale = timescale
+	run := benchmarkRun{timescale: timescale}
+	start := c.Time
+	for sample := 1; sample <= BENCHMARKSAMPLES; sample++ {
+		until := start + length*float64(sample)/BENCHMARKSAMPLES
+		began := time.Now()
+		for c.Time < until {
+			if shuttingDown() {
+				return run, false
+			}
+			c.Step()
+			run.steps++
+		}
+		run.runtime += time.Since(began)
+		energy := simulation.KineticEnergy(c.Bodies()) + simulation.PotentialEnergy(c.Bodies(), c.G)
+		run.finalError = math.Abs(energy-startEnergy) / math.Abs(startEnergy)
+		run.maxError = math.Max(run.maxError, run.finalError)
+	}
+	run.merges = c.Merges - sim.Merges
+	run.bodies = countBodies(c.Bodies())
+	return run, true
+}
+
+// The number of bodies that haven't been removed
+func countBodies(bodies []*simulation.Body) int {
+	count := 0
+	for _, b := range bodies {
+		if b != nil {
+			count++
+		}
+	}
+	return count
+}
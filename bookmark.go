@@ -0,0 +1,57 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"log/slog"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"hmcalister/gravity_simulation/render"
+)
+
+// Camera bookmarks store the view (position, zoom and rotation) on the number keys 1 to 9,
+// so it is quick to flip between interesting regions of a large simulation
+// Ctrl and a number stores the current view, and Alt and the number recalls it
+// Bookmarks were first recalled with the number alone, but those keys became the speed presets (see speedpreset.go)
+
+// The stored views, indexed by number key (0 is unused), nil where nothing is stored
+var bookmarks [10]*render.Camera
+
+// Handle a press of the number keys with Ctrl or Alt, returning false if it wasn't one
+// The number keys are used by position (their scancodes), so are not rebound in the keymap
+// Without Ctrl or Alt, the number keys are speed presets instead
+func handleBookmarkKey(t *sdl.KeyboardEvent) bool {
+	if t.Keysym.Scancode < sdl.SCANCODE_1 || t.Keysym.Scancode > sdl.SCANCODE_9 || t.Repeat == 1 {
+		return false
+	}
+	n := int(t.Keysym.Scancode-sdl.SCANCODE_1) + 1
+	switch {
+	case t.Keysym.Mod&sdl.KMOD_CTRL != 0:
+		storeBookmark(n)
+	case t.Keysym.Mod&sdl.KMOD_ALT != 0:
+		recallBookmark(n)
+	default:
+		return false
+	}
+	return true
+}
+
+// Store the current view in bookmark n
+func storeBookmark(n int) {
+	stored := camera
+	bookmarks[n] = &stored
+	slog.Info("STORED VIEW IN BOOKMARK", "bookmark", n)
+}
+
+// Move the view to bookmark n, which stops following any body
+func recallBookmark(n int) {
+	b := bookmarks[n]
+	if b == nil {
+		slog.Info("NO VIEW STORED IN BOOKMARK, USE CTRL AND THE NUMBER TO STORE ONE", "bookmark", n)
+		return
+	}
+	camera = *b
+	followedBody = -1
+	canvas.Fill(backgroundColor)
+}
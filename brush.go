@@ -0,0 +1,166 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"image/color"
+	"log/slog"
+	"math"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Spawn brushes add bodies where the user clicks on empty space, cycled through with B
+// Spawned bodies are given the velocity of a circular orbit around the most massive body at the clicked point,
+// so they join the system rather than falling straight into it
+
+const (
+	// The number of bodies, and the size in the simulation, of clusters, rings and streams
+	BRUSHBODIES = 24
+	BRUSHRADIUS = 60
+	// The mass of the bodies making up a cluster, ring or stream, and of the body at the center of a ring
+	BRUSHBODYMASS = 0.5
+	BRUSHRINGMASS = 50
+	// The mass and radius of black holes, spawned with H
+	BLACKHOLEMASS   = 5000
+	BLACKHOLERADIUS = 2
+)
+
+// A way of spawning bodies at a point in the simulation
+type brush struct {
+	name  string
+	spawn func(x, y float64)
+}
+
+var brushes = []brush{
+	{"NONE", nil},
+	{"BODY", spawnBody},
+	{"CLUSTER", spawnCluster},
+	{"RING", spawnRing},
+	{"STREAM", spawnStream},
+}
+
+// The index of the current brush, the first brush (none) means clicking empty space deselects instead
+var currentBrush = 0
+
+// Switch to the next brush
+func cycleBrush() {
+	currentBrush = (currentBrush + 1) % len(brushes)
+	slog.Info("BRUSH", "brush", brushes[currentBrush].name)
+}
+
+// Spawn bodies with the current brush at a position on the screen, returning false if there is no brush
+func paintBrush(x, y int32) bool {
+	b := brushes[currentBrush]
+	if b.spawn == nil {
+		return false
+	}
+	worldX, worldY := screenToWorld(x, y)
+	recordUndo()
+	b.spawn(worldX, worldY)
+	return true
+}
+
+// Show the current brush in the bottom left corner of the window
+func drawBrush() {
+	if brushes[currentBrush].spawn == nil {
+		return
+	}
+	render.Panel(frame, render.PANELMARGIN, int(screenHeight)-render.PANELMARGIN-render.TextLineHeight-2*render.PANELPADDING, []string{
+		"BRUSH: " + brushes[currentBrush].name + " (CLICK TO SPAWN, B TO CHANGE)",
+	})
+}
+
+// The velocity of a circular orbit at a point around the most massive body in the simulation
+// If there are no bodies (or the point is on top of the most massive one) the velocity is zero
+func orbitVelocity(x, y float64) (float64, float64) {
+	primary := simulation.Primary(sim.Bodies())
+	if primary < 0 {
+		return 0, 0
+	}
+	center := sim.Bodies()[primary]
+	dx, dy := x-center.Pos.X, y-center.Pos.Y
+	dist := math.Hypot(dx, dy)
+	if dist < center.Radius {
+		return center.Vel.X, center.Vel.Y
+	}
+	speed := math.Sqrt(sim.G * center.Mass / dist)
+	return center.Vel.X - speed*dy/dist, center.Vel.Y + speed*dx/dist
+}
+
+// Add a small body with a random color
+func spawnSmallBody(x, y, xVel, yVel, mass float64) {
+	sim.AddBody(&simulation.Body{
+		Pos:    simulation.Vec2{X: x, Y: y},
+		Vel:    simulation.Vec2{X: xVel, Y: yVel},
+		Mass:   mass,
+		Radius: simulation.MassToRadius(mass),
+		Color:  simulation.RandomColor(sim.Rand),
+	})
+}
+
+// A single random body, as generated at startup
+func spawnBody(x, y float64) {
+	b := simulation.NewRandomBody(sim.Rand, randomOptions())
+	b.Pos.X, b.Pos.Y = x, y
+	b.Vel.X, b.Vel.Y = orbitVelocity(x, y)
+	sim.AddBody(b)
+}
+
+// A loose cluster of small bodies spread over a disk, moving together with a small random spread of velocities
+func spawnCluster(x, y float64) {
+	xVel, yVel := orbitVelocity(x, y)
+	// The spread of velocities is a small fraction of the cluster's own orbital speed, so it holds together for a while
+	spread := 0.05 * math.Sqrt(sim.G*BRUSHBODIES*BRUSHBODYMASS/BRUSHRADIUS)
+	for i := 0; i < BRUSHBODIES; i++ {
+		// The square root spreads the bodies evenly over the disk
+		r := BRUSHRADIUS * math.Sqrt(sim.Rand.Float64())
+		angle := sim.Rand.Float64() * 2 * math.Pi
+		spawnSmallBody(x+r*math.Cos(angle), y+r*math.Sin(angle),
+			xVel+spread*(sim.Rand.Float64()-0.5), yVel+spread*(sim.Rand.Float64()-0.5), BRUSHBODYMASS)
+	}
+}
+
+// A heavy body with a ring of small bodies in circular orbits around it
+func spawnRing(x, y float64) {
+	xVel, yVel := orbitVelocity(x, y)
+	spawnSmallBody(x, y, xVel, yVel, BRUSHRINGMASS)
+	speed := math.Sqrt(sim.G * BRUSHRINGMASS / BRUSHRADIUS)
+	for i := 0; i < BRUSHBODIES; i++ {
+		angle := 2 * math.Pi * float64(i) / BRUSHBODIES
+		spawnSmallBody(x+BRUSHRADIUS*math.Cos(angle), y+BRUSHRADIUS*math.Sin(angle),
+			xVel-speed*math.Sin(angle), yVel+speed*math.Cos(angle), BRUSHBODYMASS)
+	}
+}
+
+// A line of small bodies strung out along the direction of the orbit at the clicked point, like the debris trailing a comet
+func spawnStream(x, y float64) {
+	xVel, yVel := orbitVelocity(x, y)
+	// Without anything to orbit, the stream lies horizontally
+	dirX, dirY := 1.0, 0.0
+	if speed := math.Hypot(xVel, yVel); speed > 0 {
+		dirX, dirY = xVel/speed, yVel/speed
+	}
+	for i := 0; i < BRUSHBODIES; i++ {
+		offset := BRUSHRADIUS * (float64(i)/BRUSHBODIES - 0.5) * 2
+		sx, sy := x+offset*dirX, y+offset*dirY
+		vx, vy := orbitVelocity(sx, sy)
+		spawnSmallBody(sx, sy, vx, vy, BRUSHBODYMASS)
+	}
+}
+
+// Spawn a black hole (a very heavy, very small body) at rest at a position on the screen, e.g. under the mouse
+// Dropped into a running system it tears it apart
+func spawnBlackHole(x, y int32) {
+	worldX, worldY := screenToWorld(x, y)
+	recordUndo()
+	i := sim.AddBody(&simulation.Body{
+		Pos:    simulation.Vec2{X: worldX, Y: worldY},
+		Mass:   BLACKHOLEMASS,
+		Radius: BLACKHOLERADIUS,
+		Color:  color.RGBA{160, 60, 255, 255},
+		Name:   "Black hole",
+	})
+	slog.Info("SPAWNED BLACK HOLE", "id", i, "x", worldX, "y", worldY)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log/slog"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// With --chaos, a shadow copy of the simulation is run alongside it, started a tiny distance away, and how fast the two
+// diverge is logged as a DIVERGENCE message every --chaosEvery steps, e.g.
+//
+//	level=INFO msg=DIVERGENCE time=250 divergence=3.1e-05 lyapunov=0.032
+//
+// where lyapunov is the estimate of the largest Lyapunov exponent so far (see simulation.Chaos)
+// The shadow is stepped along with the simulation, so each step takes twice as long
+
+var (
+	// Set by the chaos flags, see simulationFlags
+	chaosPerturbation float64
+	chaosEvery        int
+	// The measurement of the chaos of the simulation, nil if it isn't being measured
+	chaos *simulation.Chaos
+)
+
+// Start measuring the chaos of the simulation from its current state
+func setupChaos() {
+	if chaosEvery <= 0 {
+		fatal("--chaosEvery MUST BE POSITIVE")
+	}
+	chaos = simulation.NewChaos(sim, chaosPerturbation)
+	if chaos == nil {
+		slog.Warn("NO FREE BODIES TO MEASURE THE CHAOS OF")
+		return
+	}
+	slog.Info("MEASURING CHAOS", "perturbation", chaosPerturbation)
+	sim.OnStep(func(s *simulation.Simulation) {
+		chaos.Step(s)
+		if s.Steps%chaosEvery == 0 {
+			slog.Info("DIVERGENCE", "time", s.Time, "divergence", chaos.Divergence(), "lyapunov", chaos.Lyapunov())
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"log/slog"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// A checkpoint is an in-memory copy of the entire simulation state: the bodies, the parameters, the time, the number
+// of steps and merges, and the state of the random number generator
+// Bodies are stored by value so later updates to the simulation cannot alter a checkpoint
+type checkpoint struct {
+	bodies []simulation.Body
+	alive  []bool
+	params simulation.Params
+	time   float64
+	steps  int
+	merges int
+	// The state of the random number generator, if it can be saved (see simulation.RandomSource)
+	randomState uint64
+}
+
+// The stack of stored checkpoints, most recent last
+// At most numCheckpoints are kept, the oldest checkpoint is discarded to make room for new ones
+var checkpoints []checkpoint
+
+// Copy the current state of the simulation into a checkpoint
+func takeCheckpoint() checkpoint {
+	c := checkpoint{
+		bodies: make([]simulation.Body, len(sim.Bodies())),
+		alive:  make([]bool, len(sim.Bodies())),
+		params: sim.Params,
+		time:   sim.Time,
+		steps:  sim.Steps,
+		merges: sim.Merges,
+	}
+	if sim.RandomSource != nil {
+		c.randomState = sim.RandomSource.State
+	}
+	for i, b := range sim.Bodies() {
+		if b != nil {
+			c.bodies[i] = *b
+			c.alive[i] = true
+		}
+	}
+	return c
+}
+
+// Replace the state of the simulation with the state in the checkpoint
+// Each restore gets freshly allocated bodies, so the checkpoint itself is never modified
+func (c checkpoint) restore() {
+	bodies := make([]*simulation.Body, len(c.bodies))
+	for i := range c.bodies {
+		if c.alive[i] {
+			b := c.bodies[i]
+			bodies[i] = &b
+		}
+	}
+	sim.SetBodies(bodies)
+	sim.Params = c.params
+	sim.Time = c.time
+	sim.Steps = c.steps
+	sim.Merges = c.merges
+	if sim.RandomSource != nil {
+		sim.RandomSource.State = c.randomState
+	}
+}
+
+// Store the current state of the simulation as a new checkpoint
+func storeCheckpoint() {
+	if numCheckpoints <= 0 {
+		slog.Info("CHECKPOINTS ARE DISABLED")
+		return
+	}
+
+	if len(checkpoints) >= numCheckpoints {
+		checkpoints = checkpoints[len(checkpoints)-numCheckpoints+1:]
+	}
+	checkpoints = append(checkpoints, takeCheckpoint())
+	slog.Info("STORED CHECKPOINT", "checkpoint", len(checkpoints), "max", numCheckpoints)
+}
+
+// Restore the most recent checkpoint
+// The checkpoint is kept so it can be restored again (e.g. to try several experiments from the same point)
+func restoreCheckpoint() {
+	if len(checkpoints) == 0 {
+		slog.Info("NO CHECKPOINT TO RESTORE")
+		return
+	}
+	finishEdit()
+	checkpoints[len(checkpoints)-1].restore()
+	// What happens from here is a new experiment rather than the steps already written to the output files again
+	furthestStep = sim.Steps
+	slog.Info("RESTORED CHECKPOINT", "checkpoint", len(checkpoints), "max", numCheckpoints)
+}
+
+// Discard the most recent checkpoint, making the one before it the next to be restored
+func discardCheckpoint() {
+	if len(checkpoints) == 0 {
+		slog.Info("NO CHECKPOINT TO DISCARD")
+		return
+	}
+	checkpoints = checkpoints[:len(checkpoints)-1]
+	slog.Info("DISCARDED CHECKPOINT", "remaining", len(checkpoints))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Restoring a checkpoint brings back the whole state of the simulation, not only the bodies
+func TestCheckpointRestoresEverything(t *testing.T) {
+	sim = simulation.New(simulation.Params{G: 1, Timescale: 0.01, Softening: 2, CollisionMode: simulation.COLLISIONMERGE}, 1)
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{X: -50}, Mass: 100, Radius: 10})
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{X: 50}, Mass: 100, Radius: 10})
+	sim.Step()
+	saved := takeCheckpoint()
+	want := sim.Clone()
+
+	sim.G, sim.Softening, sim.Timescale, sim.CollisionMode = 5, 0, 1, simulation.COLLISIONPASS
+	for i := 0; i < 10; i++ {
+		sim.Step()
+	}
+	sim.Merges++
+	sim.Rand.Float64()
+	sim.AddBody(&simulation.Body{Mass: 1})
+	saved.restore()
+
+	if sim.Params != want.Params {
+		t.Errorf("restored parameters %+v, want %+v", sim.Params, want.Params)
+	}
+	if sim.Time != want.Time || sim.Steps != want.Steps || sim.Merges != want.Merges {
+		t.Errorf("restored time %v, steps %v and merges %v, want %v, %v and %v", sim.Time, sim.Steps, sim.Merges, want.Time, want.Steps, want.Merges)
+	}
+	if sim.RandomSource.State != want.RandomSource.State {
+		t.Errorf("restored random state %v, want %v", sim.RandomSource.State, want.RandomSource.State)
+	}
+	if len(sim.Bodies()) != 2 || sim.Bodies()[0].Pos != want.Bodies()[0].Pos {
+		t.Errorf("restored bodies %v, want %v", sim.Bodies(), want.Bodies())
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// The program is split into commands, chosen by the first argument, each with its own flags, e.g.
+//
+//	./gravity_simulation simulate --numBodies=100 --snapshotEvery=1000
+//
+// Without a command (i.e. with only flags) DEFAULTCOMMAND is used, which is run unless built without the window
+
+// A command of the program
+type command struct {
+	name        string
+	description string
+	run         func(args []string)
+}
+
+// Print the commands of the program
+func printCommands(commands []command) {
+	fmt.Println(`
+Gravity Simulation
+Usage:
+	./gravity_simulation [command] [flags]
+	Use "./gravity_simulation [command] -h" for help on a command and its flags
+
+Commands:`)
+	for _, c := range commands {
+		fmt.Fprintf(tableWriter, "\t%v\t%v\n", c.name, c.description)
+	}
+	tableWriter.Flush()
+}
+
+func main() {
+	setupLogging()
+	commands := []command{
+		{"run", "Run the simulation in a window, with interactive controls (the default)", runCommand},
+		{"simulate", "Run the simulation without a window, as fast as possible", simulateCommand},
+		{"convert", "Convert a save file between the csv, protobuf and REBOUND formats", convertCommand},
+		{"analyze", "Print a report of a trajectory file: energy drift, merger trees, mass function and eccentricities", analyzeCommand},
+		{"compare", "Print how far the bodies of two trajectory files diverge, e.g. run with different timescales", compareCommand},
+		{"spectate", "Watch a simulation served with --spectateAddr in a window, with your own view of it", spectateCommand},
+		{"worker", "Experimental: find the gravity of parts of a simulation run elsewhere with --workers", workerCommand},
+		{"sweep", "Run the simulate command for each value of a parameter, with a summary row of each run", sweepCommand},
+		{"ensemble", "Run the same configuration with many seeds and print statistics of the outcomes", ensembleCommand},
+		{"render", "Replay a trajectory file offline into a video (encoded by ffmpeg) or a numbered sequence of PNG frames", renderCommand},
+	}
+
+	name := DEFAULTCOMMAND
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		printCommands(commands)
+		return
+	}
+	for _, c := range commands {
+		if c.name == name {
+			c.run(args)
+			return
+		}
+	}
+	slog.Error("UNKNOWN COMMAND", "command", name)
+	printCommands(commands)
+	os.Exit(1)
+}
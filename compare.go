@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	"hmcalister/gravity_simulation/persist"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The help for the compare command, printed with -h
+const COMPAREHELP = `
+Gravity Simulation - compare
+Usage:
+	./gravity_simulation compare [flags] trajectoryA trajectoryB
+
+	Reads two trajectory files (as written by --trajectoryOut, in either format) of the same starting state, e.g. run with
+	different timescales or settings, and prints how far apart each body is in the two runs: the mean, largest and final
+	distance between its positions, the largest difference in its velocity, and the time it first strayed further than
+	--threshold. Frames are compared wherever both trajectories have a frame at the same time, so a run with half the
+	timescale can be compared against one with the full timescale. Bodies are matched by id, and the number of compared
+	frames with a body in only one of the runs (e.g. after it merged in one run but not the other) is counted as missing
+
+Flags:
+	--threshold : The distance between the positions of a body in the two runs that counts as having diverged
+		Defaults to 1
+	--timeTolerance : How close the times of two frames must be to be compared, allowing for rounding in the simulated time
+		Defaults to 1e-6`
+
+// How far one body strayed between two trajectories, over the frames compared so far
+type bodyDivergence struct {
+	frames int
+	// Frames where the body was in only one of the trajectories
+	missing int
+	// The sum, largest and latest distance between the positions, and the largest difference between the velocities
+	sum         float64
+	max         float64
+	final       float64
+	maxVelocity float64
+	// The time the distance first exceeded the threshold, and whether it has
+	exceededAt float64
+	exceeded   bool
+}
+
+// A frame of a trajectory
+type trajectoryFrame struct {
+	time   float64
+	bodies []*simulation.Body
+}
+
+// Returned while reading a trajectory to stop reading it early
+var errStopReading = errors.New("stopped reading")
+
+// Read a trajectory in the background, sending its frames over the returned channel until they run out or done is closed
+// The channel is closed once reading stops, after which the error reading the trajectory (if any) is sent on the error channel
+func streamTrajectory(path string, done <-chan struct{}) (<-chan trajectoryFrame, <-chan error) {
+	frames := make(chan trajectoryFrame)
+	errs := make(chan error, 1)
+	go func() {
+		err := persist.ReadTrajectory(path, func(time float64, bodies []*simulation.Body) error {
+			select {
+			case frames <- trajectoryFrame{time, bodies}:
+				return nil
+			case <-done:
+				return errStopReading
+			}
+		})
+		close(frames)
+		if errors.Is(err, errStopReading) {
+			err = nil
+		}
+		errs <- err
+	}()
+	return frames, errs
+}
+
+// The compare command, which prints how far two trajectories diverge
+func compareCommand(args []string) {
+	var helpFlag bool
+	var threshold, timeTolerance float64
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	fs.Float64Var(&threshold, "threshold", 1, "The distance between the positions of a body in the two runs that counts as having diverged")
+	fs.Float64Var(&timeTolerance, "timeTolerance", 1e-6, "How close the times of two frames must be to be compared")
+	fs.BoolVar(&helpFlag, "h", false, "Display help on this command, then quit")
+	logFlags(fs)
+	fs.Parse(args)
+	setupLogging()
+
+	if helpFlag {
+		fmt.Println(COMPAREHELP + LOGGINGHELP)
+		os.Exit(0)
+	}
+	if fs.NArg() != 2 {
+		fatal("COMPARE NEEDS TWO TRAJECTORY FILES, SEE compare -h")
+	}
+	pathA, pathB := fs.Arg(0), fs.Arg(1)
+
+	// The second trajectory is read alongside the first, so trajectories of any length can be compared
+	done := make(chan struct{})
+	framesB, errsB := streamTrajectory(pathB, done)
+	var b trajectoryFrame
+	moreB := true
+
+	var divergences []*bodyDivergence
+	frames := 0
+	var firstTime, lastTime float64
+	err := persist.ReadTrajectory(pathA, func(time float64, bodiesA []*simulation.Body) error {
+		// Skip the frames of the second trajectory from before this frame, stopping once it runs out
+		for moreB && (b.bodies == nil || b.time < time-timeTolerance) {
+			b, moreB = <-framesB
+		}
+		if !moreB {
+			return errStopReading
+		}
+		if math.Abs(b.time-time) > timeTolerance {
+			return nil
+		}
+
+		if frames == 0 {
+			firstTime = time
+		}
+		lastTime = time
+		frames++
+		for id := 0; id < len(bodiesA) || id < len(b.bodies); id++ {
+			var bodyA, bodyB *simulation.Body
+			if id < len(bodiesA) {
+				bodyA = bodiesA[id]
+			}
+			if id < len(b.bodies) {
+				bodyB = b.bodies[id]
+			}
+			if bodyA == nil && bodyB == nil {
+				continue
+			}
+			for len(divergences) <= id {
+				divergences = append(divergences, nil)
+			}
+			d := divergences[id]
+			if d == nil {
+				d = &bodyDivergence{}
+				divergences[id] = d
+			}
+			if bodyA == nil || bodyB == nil {
+				d.missing++
+				continue
+			}
+			distance := bodyA.Pos.Dist(bodyB.Pos)
+			d.frames++
+			d.sum += distance
+			d.max = math.Max(d.max, distance)
+			d.final = distance
+			d.maxVelocity = math.Max(d.maxVelocity, bodyA.Vel.Dist(bodyB.Vel))
+			if distance > threshold && !d.exceeded {
+				d.exceeded, d.exceededAt = true, time
+			}
+		}
+		return nil
+	})
+	close(done)
+	errB := <-errsB
+	if errors.Is(err, errStopReading) {
+		err = nil
+	}
+	if err != nil {
+		fatal("COULD NOT READ TRAJECTORY", "path", pathA, "err", err)
+	}
+	if errB != nil {
+		fatal("COULD NOT READ TRAJECTORY", "path", pathB, "err", errB)
+	}
+	if frames == 0 {
+		fatal("THE TRAJECTORIES HAVE NO FRAMES AT THE SAME TIME", "a", pathA, "b", pathB)
+	}
+
+	printComparison(pathA, pathB, frames, firstTime, lastTime, threshold, divergences)
+}
+
+// Print the divergence of every body, and the first time any body diverged, with some formatting like the analyze command
+func printComparison(pathA, pathB string, frames int, firstTime, lastTime, threshold float64, divergences []*bodyDivergence) {
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Fprintln(tableWriter, "TRAJECTORIES\t", pathA, "AND", pathB)
+	fmt.Fprintf(tableWriter, "COMPARED FRAMES\t %v (time %.4g to %.4g)\n", frames, firstTime, lastTime)
+	fmt.Fprintf(tableWriter, "THRESHOLD\t %.4g\n", threshold)
+	tableWriter.Flush()
+
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Fprintln(tableWriter, "BODY\tFRAMES\tMISSING\tMEAN\tMAX\tFINAL\tMAX VELOCITY\tEXCEEDED AT\t")
+	diverged := -1
+	for id, d := range divergences {
+		if d == nil {
+			continue
+		}
+		mean := 0.0
+		if d.frames > 0 {
+			mean = d.sum / float64(d.frames)
+		}
+		exceeded := "NEVER"
+		if d.exceeded {
+			exceeded = fmt.Sprintf("%.4g", d.exceededAt)
+			if diverged < 0 || d.exceededAt < divergences[diverged].exceededAt {
+				diverged = id
+			}
+		}
+		fmt.Fprintf(tableWriter, "BODY %v\t%v\t%v\t%.4g\t%.4g\t%.4g\t%.4g\t%v\t\n", id, d.frames, d.missing, mean, d.max, d.final, d.maxVelocity, exceeded)
+	}
+	tableWriter.Flush()
+
+	fmt.Println("--------------------------------------------------------------------------------")
+	if diverged < 0 {
+		fmt.Println("NO BODY DIVERGED FURTHER THAN THE THRESHOLD")
+	} else {
+		fmt.Printf("BODY %v DIVERGED FIRST, AT TIME %.4g\n", diverged, divergences[diverged].exceededAt)
+	}
+}
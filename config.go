@@ -0,0 +1,215 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// The config file sets defaults for any of the flags of the run and simulate commands, so the keys of the file are exactly the flag names, e.g.
+//
+//	numBodies = 20
+//	timescale = 0.5
+//	G = 50
+//	width = 1600
+//	height = 900
+//	background = [10, 10, 30]
+//
+// The config file can also rebind the controls in a [keys] table, see keymap.go
+//
+// Any flag can also be set by an environment variable named GRAVITYSIM_ followed by the flag name in capitals, e.g.
+//
+//	GRAVITYSIM_NUMBODIES=20 GRAVITYSIM_G=50 ./gravity_simulation
+//
+// Each flag is taken from the first of these that sets it, in order of precedence:
+// the command line, then the environment, then the config file, then the default of the flag
+const CONFIGFILENAME = "config.toml"
+
+// The prefix of the environment variables setting flags
+const ENVPREFIX = "GRAVITYSIM_"
+
+// The path to the default config file, used if --config is not given
+// Usually ~/.config/gravity-sim/config.toml
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gravity-sim", CONFIGFILENAME)
+}
+
+// The name of the environment variable setting the flag with the given name, e.g. GRAVITYSIM_NUMBODIES for numBodies
+func envName(flagName string) string {
+	return ENVPREFIX + strings.ToUpper(flagName)
+}
+
+// Apply the environment variables to every flag of fs that is not in setFlags, adding the flags they set to setFlags
+func loadEnvironment(fs *flag.FlagSet, setFlags map[string]bool) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || setFlags[f.Name] {
+			return
+		}
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("%v: invalid value for %v: %w", envName(f.Name), f.Name, setErr)
+			return
+		}
+		slog.Debug("FLAG SET FROM ENVIRONMENT", "flag", f.Name, "variable", envName(f.Name))
+		setFlags[f.Name] = true
+	})
+	return err
+}
+
+// Fill in every flag of fs that was not set on the command line, first from the environment and then from the config file at path
+// If path is empty the config file can be given by GRAVITYSIM_CONFIG, otherwise the default config file is used if it exists.
+// A missing default config file is not an error
+func loadConfig(fs *flag.FlagSet, path string) error {
+	// Find all the flags set on the command line, as these override everything else
+	setFlags := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	// The environment comes next, and can give the path to the config file as well
+	if err := loadEnvironment(fs, setFlags); err != nil {
+		return err
+	}
+	if path == "" {
+		if f := fs.Lookup("config"); f != nil {
+			path = f.Value.String()
+		}
+	}
+
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+		if _, err := os.Stat(path); err != nil {
+			return nil
+		}
+	}
+
+	var config map[string]any
+	if _, err := toml.DecodeFile(path, &config); err != nil {
+		return err
+	}
+	slog.Info("LOADING CONFIG", "path", path)
+
+	for key, value := range config {
+		if key == "keys" {
+			keys, ok := value.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%v: keys must be a table of action = key name", path)
+			}
+			if err := loadKeymap(keys); err != nil {
+				return fmt.Errorf("%v: %w", path, err)
+			}
+			continue
+		}
+		if fs.Lookup(key) == nil || key == "config" {
+			slog.Warn("UNKNOWN CONFIG KEY", "key", key)
+			continue
+		}
+		if setFlags[key] {
+			continue
+		}
+		if err := fs.Set(key, configValueString(value)); err != nil {
+			return fmt.Errorf("%v: invalid value for %v: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+// Convert a value from the config file to the string form the matching flag expects
+// Arrays become comma separated lists, e.g. the background color [10, 10, 30] becomes "10,10,30"
+func configValueString(value any) string {
+	if list, ok := value.([]any); ok {
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// A flag.Value for int32 variables, such as the window size, which the flag package does not support directly
+type int32Value struct {
+	p *int32
+}
+
+func (v int32Value) String() string {
+	if v.p == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*v.p))
+}
+
+func (v int32Value) Set(s string) error {
+	i, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return err
+	}
+	*v.p = int32(i)
+	return nil
+}
+
+// A flag.Value for a range of floats given as "min,max", such as the range of random masses
+type rangeValue struct {
+	min *float64
+	max *float64
+}
+
+func (v rangeValue) String() string {
+	if v.min == nil || v.max == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v,%v", *v.min, *v.max)
+}
+
+func (v rangeValue) Set(s string) error {
+	minString, maxString, found := strings.Cut(s, ",")
+	if !found {
+		return fmt.Errorf("range %q must be given as min,max", s)
+	}
+	min, err := strconv.ParseFloat(strings.TrimSpace(minString), 64)
+	if err != nil {
+		return err
+	}
+	max, err := strconv.ParseFloat(strings.TrimSpace(maxString), 64)
+	if err != nil {
+		return err
+	}
+	if min > max {
+		return fmt.Errorf("range %q has min greater than max", s)
+	}
+	*v.min, *v.max = min, max
+	return nil
+}
+
+// Parse a color given as "red,green,blue"
+func parseColor(s string) (color.RGBA, error) {
+	channels := strings.Split(s, ",")
+	if len(channels) != 3 {
+		return color.RGBA{}, fmt.Errorf("color %q must be three channels red,green,blue", s)
+	}
+	var rgb [3]uint8
+	for i, c := range channels {
+		v, err := strconv.ParseUint(strings.TrimSpace(c), 10, 8)
+		if err != nil {
+			return color.RGBA{}, fmt.Errorf("color channel %q must be between 0 and 255", c)
+		}
+		rgb[i] = uint8(v)
+	}
+	return color.RGBA{rgb[0], rgb[1], rgb[2], 255}, nil
+}
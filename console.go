@@ -0,0 +1,251 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"hmcalister/gravity_simulation/persist"
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The console is a line of commands typed into the window, opened and closed with ` (the key below Escape)
+// The commands are
+//
+//	spawn x y [xVel yVel mass]  : add a body, with the parameters in the same order as a save file
+//	remove id                   : remove a body
+//	select id                   : select a body, showing it in the inspector
+//	follow [id]                 : keep the view centered on a body, or stop following without an id
+//	set name value              : set a parameter, one of G, timescale, zoom, softening, minMass, collisions or paused
+//	save path                   : save the simulation, as protobuf, a REBOUND snapshot or JSON if the path ends in .pb, .rebound or .json (then .gz to compress)
+//	hide category, show category: hide or show a category of bodies (named, unnamed, fixed or free)
+//	help                        : list the commands
+
+const (
+	// The label of the console's text input
+	CONSOLELABEL = "CONSOLE"
+	// The number of lines of output kept in the console
+	CONSOLELINES = 12
+)
+
+// The recent commands and their output, oldest first
+var consoleLog []string
+
+// Whether the console is open
+func consoleOpen() bool {
+	return activeInput != nil && activeInput.label == CONSOLELABEL
+}
+
+// Open the console, ready to type a command
+func openConsole() {
+	startTextInput(CONSOLELABEL, "", func(command string) {
+		runConsoleCommand(command)
+		// The console stays open for the next command
+		openConsole()
+	})
+}
+
+// Add a line of output to the console, which is also printed so it isn't lost once it scrolls away
+func consolePrint(format string, args ...any) {
+	line := fmt.Sprintf(format, args...)
+	slog.Info("CONSOLE", "output", line)
+	consoleLog = append(consoleLog, line)
+	if len(consoleLog) > CONSOLELINES {
+		consoleLog = consoleLog[len(consoleLog)-CONSOLELINES:]
+	}
+}
+
+// Draw the console at the top center of the window
+func drawConsole() {
+	if !consoleOpen() {
+		return
+	}
+	lines := append([]string{}, consoleLog...)
+	lines = append(lines, "> "+activeInput.text+"_")
+	width, _ := render.PanelSize(lines)
+	render.Panel(frame, int(screenWidth)/2-width/2, render.PANELMARGIN, lines)
+}
+
+// Run a command typed into the console
+func runConsoleCommand(command string) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return
+	}
+	consolePrint("> %v", command)
+	name, args := fields[0], fields[1:]
+	var err error
+	switch name {
+	case "spawn":
+		err = consoleSpawn(args)
+	case "remove":
+		err = consoleRemove(args)
+	case "select":
+		err = consoleSelect(args)
+	case "follow":
+		err = consoleFollow(args)
+	case "set":
+		err = consoleSet(args)
+	case "save":
+		err = consoleSave(args)
+	case "hide", "show":
+		err = consoleHide(name, args)
+	case "help":
+		consolePrint("COMMANDS: spawn x y [xVel yVel mass], remove id, select id, follow [id], set name value, save path, hide category, show category")
+	default:
+		err = fmt.Errorf("unknown command %q, try help", name)
+	}
+	if err != nil {
+		consolePrint("ERROR: %v", err)
+	}
+}
+
+// Parse the id of an existing body
+func consoleBodyID(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a body id", arg)
+	}
+	if id < 0 || id >= len(sim.Bodies()) || sim.Bodies()[id] == nil {
+		return 0, fmt.Errorf("there is no body %v", id)
+	}
+	return id, nil
+}
+
+func consoleSpawn(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("spawn needs at least a position, spawn x y [xVel yVel mass]")
+	}
+	// Any missing velocity or mass is filled in, so "spawn 10 20" gives a stationary body of mass 1
+	defaults := []string{"0", "0", "0", "0", "1"}
+	params := append([]string{}, args...)
+	for len(params) < len(defaults) {
+		params = append(params, defaults[len(params)])
+	}
+	b, err := persist.NewBodyFromStrings(params, sim.Rand)
+	if err != nil {
+		return err
+	}
+	recordUndo()
+	consolePrint("SPAWNED BODY %v", sim.AddBody(b))
+	return nil
+}
+
+func consoleRemove(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("remove needs a body id, remove id")
+	}
+	id, err := consoleBodyID(args[0])
+	if err != nil {
+		return err
+	}
+	recordUndo()
+	sim.RemoveBody(id)
+	consolePrint("REMOVED BODY %v", id)
+	return nil
+}
+
+func consoleSelect(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("select needs a body id, select id")
+	}
+	id, err := consoleBodyID(args[0])
+	if err != nil {
+		return err
+	}
+	selectedBody = id
+	return nil
+}
+
+func consoleFollow(args []string) error {
+	if len(args) == 0 {
+		followedBody = -1
+		consolePrint("STOPPED FOLLOWING")
+		return nil
+	}
+	id, err := consoleBodyID(args[0])
+	if err != nil {
+		return err
+	}
+	followedBody = id
+	consolePrint("FOLLOWING BODY %v", id)
+	return nil
+}
+
+func consoleSet(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("set needs a name and a value, set name value")
+	}
+	name, text := args[0], args[1]
+	switch name {
+	case "collisions":
+		mode, err := simulation.ParseCollisionMode(text)
+		if err != nil {
+			return err
+		}
+		sim.CollisionMode = mode
+	case "paused":
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("paused must be true or false")
+		}
+		paused = value
+	case "G", "timescale", "zoom", "softening", "minMass":
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("%v must be a number", name)
+		}
+		if value < 0 || (value == 0 && name != "softening" && name != "minMass") {
+			return fmt.Errorf("%v must be positive", name)
+		}
+		switch name {
+		case "G":
+			sim.G = value
+		case "timescale":
+			sim.Timescale = value
+		case "zoom":
+			camera.Zoom = value
+			canvas.Fill(backgroundColor)
+		case "softening":
+			sim.Softening = value
+		case "minMass":
+			minVisibleMass = value
+			canvas.Fill(backgroundColor)
+		}
+	default:
+		return fmt.Errorf("unknown parameter %q, must be one of G, timescale, zoom, softening, minMass, collisions or paused", name)
+	}
+	consolePrint("SET %v TO %v", name, text)
+	return nil
+}
+
+func consoleHide(name string, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%v needs a category, %v category", name, name)
+	}
+	if err := setCategoryHidden(args[0], name == "hide"); err != nil {
+		return err
+	}
+	if name == "hide" {
+		consolePrint("HIDING %v BODIES", strings.ToUpper(args[0]))
+	} else {
+		consolePrint("SHOWING %v BODIES", strings.ToUpper(args[0]))
+	}
+	return nil
+}
+
+func consoleSave(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("save needs a path, save path")
+	}
+	path := args[0]
+	if err := saveStateToPath(path); err != nil {
+		return err
+	}
+	consolePrint("SAVED TO %v", path)
+	return nil
+}
@@ -0,0 +1,126 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"hmcalister/gravity_simulation/render"
+)
+
+// The control panel holds sliders for the global parameters of the simulation, so they can be adjusted with the mouse
+// rather than by memorising hotkeys. It is toggled with Tab and drawn in the top right corner of the window
+
+const (
+	// The width of the slider bars, in pixels
+	SLIDERWIDTH = 220
+	// The height of a slider's bar, and the space left for it below the slider's label
+	SLIDERBARHEIGHT = 4
+	SLIDERHEIGHT    = 14
+)
+
+// A slider for a parameter between min and max
+// Sliders for parameters that are usually scaled up or down by multiplying (e.g. the timescale) are logarithmic
+type slider struct {
+	label       string
+	min         float64
+	max         float64
+	logarithmic bool
+	get         func() float64
+	set         func(value float64)
+}
+
+// The position of the slider's knob for its current value, between 0 and 1
+func (s slider) fraction() float64 {
+	value := math.Max(s.min, math.Min(s.max, s.get()))
+	if s.logarithmic {
+		return math.Log(value/s.min) / math.Log(s.max/s.min)
+	}
+	return (value - s.min) / (s.max - s.min)
+}
+
+// Set the parameter from a position of the knob between 0 and 1
+func (s slider) setFraction(f float64) {
+	f = math.Max(0, math.Min(1, f))
+	if s.logarithmic {
+		s.set(s.min * math.Pow(s.max/s.min, f))
+	} else {
+		s.set(s.min + f*(s.max-s.min))
+	}
+}
+
+var sliders = []slider{
+	{"G", 1, 1000, true, func() float64 { return sim.G }, func(v float64) { sim.G = v }},
+	{"TIMESCALE", 0.001, 10, true, func() float64 { return sim.Timescale }, func(v float64) { sim.Timescale = v }},
+	{"SOFTENING", 0, 20, false, func() float64 { return sim.Softening }, func(v float64) { sim.Softening = v }},
+	// A slower decay gives longer trails
+	{"TRAIL DECAY", 1, 50, false, func() float64 { return float64(pixelDecayRate) }, func(v float64) { pixelDecayRate = uint8(math.Round(v)) }},
+	{"MOVESCALE", 1, 200, true, func() float64 { return movescale }, func(v float64) { movescale = v }},
+}
+
+var (
+	// Whether the control panel is shown
+	showControlPanel bool
+	// The index of the slider being dragged, or -1 if none is
+	activeSlider = -1
+	// Where the control panel was last drawn, so clicks on it can be found
+	controlPanelRect image.Rectangle
+	sliderTrackColor = color.RGBA{100, 100, 100, 255}
+)
+
+// Draw the control panel in the top right corner of the window
+func drawControlPanel() {
+	if !showControlPanel {
+		controlPanelRect = image.Rectangle{}
+		return
+	}
+	width := SLIDERWIDTH + 2*render.PANELPADDING
+	height := len(sliders)*(render.TextLineHeight+SLIDERHEIGHT) + 2*render.PANELPADDING
+	x := int(screenWidth) - width - render.PANELMARGIN
+	y := render.PANELMARGIN
+	controlPanelRect = image.Rect(x, y, x+width, y+height)
+	draw.Draw(frame, controlPanelRect, image.NewUniform(render.PanelColor), image.Point{}, draw.Over)
+
+	for i, s := range sliders {
+		top := y + render.PANELPADDING + i*(render.TextLineHeight+SLIDERHEIGHT)
+		render.Text(frame, x+render.PANELPADDING, top, fmt.Sprintf("%-12v%.4g", s.label, s.get()), render.TextColor)
+		barY := top + render.TextLineHeight + (SLIDERHEIGHT-SLIDERBARHEIGHT)/2
+		bar := image.Rect(x+render.PANELPADDING, barY, x+render.PANELPADDING+SLIDERWIDTH, barY+SLIDERBARHEIGHT)
+		draw.Draw(frame, bar, image.NewUniform(sliderTrackColor), image.Point{}, draw.Src)
+		knobX := bar.Min.X + int(s.fraction()*SLIDERWIDTH)
+		knob := image.Rect(knobX-3, barY-4, knobX+3, barY+SLIDERBARHEIGHT+4)
+		draw.Draw(frame, knob, image.NewUniform(render.HighlightColor), image.Point{}, draw.Src)
+	}
+}
+
+// Handle a click on the control panel, starting to drag the clicked slider
+// Returns false if the click was not on the panel
+func clickControlPanel(x, y int32) bool {
+	if !image.Pt(int(x), int(y)).In(controlPanelRect) {
+		return false
+	}
+	row := (int(y) - controlPanelRect.Min.Y - render.PANELPADDING) / (render.TextLineHeight + SLIDERHEIGHT)
+	if row >= 0 && row < len(sliders) {
+		activeSlider = row
+		dragSlider(x)
+	}
+	return true
+}
+
+// Move the slider being dragged to follow the mouse
+func dragSlider(x int32) {
+	if activeSlider < 0 {
+		return
+	}
+	left := controlPanelRect.Min.X + render.PANELPADDING
+	sliders[activeSlider].setFraction(float64(int(x)-left) / SLIDERWIDTH)
+}
+
+// Stop dragging a slider when the mouse is released
+func releaseSlider() {
+	activeSlider = -1
+}
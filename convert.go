@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"hmcalister/gravity_simulation/persist"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The help for the convert command, printed with -h
+const CONVERTHELP = `
+Gravity Simulation - convert
+Usage:
+	./gravity_simulation convert [flags] input output
+
+	Converts the save file input into the save file output, e.g. "convert save.csv save.pb"
+	The format of each file is chosen by its extension: .pb for protobuf (binary), .rebound for a REBOUND snapshot,
+	.json for JSON and anything else for csv. Any of these can be compressed with gzip by adding .gz, e.g. save.csv.gz
+	An input of - reads a csv save from stdin
+
+	A trajectory (as written by --trajectoryOut, in either format) is converted into another trajectory instead,
+	e.g. "convert --trajectoryFormat=columnar run.csv run.col" to shrink a csv trajectory, or "convert --every=10 run.csv short.csv"
+	to keep only every tenth frame. The last frame is always kept, so the trajectory still ends where the run did
+
+Flags:
+	--G : The gravitational constant of the simulation, used to convert the units of REBOUND snapshots
+		Defaults to 100. Protobuf saves include their own G, which is used instead
+	--trajectoryFormat : The format to write a converted trajectory in, either csv or columnar
+		Defaults to csv
+	--every : Only keep every this many frames of a trajectory, to downsample long runs
+		Defaults to 1 (every frame)`
+
+// The convert command, which converts a save file or trajectory from one format to another
+func convertCommand(args []string) {
+	var helpFlag bool
+	var format string
+	var every int
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	fs.Float64Var(&sim.G, "G", 100, "The gravitational constant, used to convert the units of REBOUND snapshots")
+	fs.StringVar(&format, "trajectoryFormat", "csv", "The format to write a converted trajectory in, either csv or columnar")
+	fs.IntVar(&every, "every", 1, "Only keep every this many frames of a trajectory")
+	fs.BoolVar(&helpFlag, "h", false, "Display help on this command, then quit")
+	logFlags(fs)
+	fs.Parse(args)
+	setupLogging()
+
+	if helpFlag {
+		fmt.Println(CONVERTHELP + LOGGINGHELP)
+		os.Exit(0)
+	}
+	if fs.NArg() != 2 {
+		fatal("CONVERT NEEDS AN INPUT AND AN OUTPUT FILE, SEE convert -h")
+	}
+	input, output := fs.Arg(0), fs.Arg(1)
+	if every <= 0 {
+		fatal("--every MUST BE POSITIVE")
+	}
+
+	if input != "-" && persist.IsTrajectory(input) {
+		convertTrajectory(input, output, format, every)
+		return
+	}
+	if every != 1 {
+		fatal("--every ONLY APPLIES TO TRAJECTORIES", "input", input)
+	}
+
+	if err := loadStateFile(input); err != nil {
+		fatal("COULD NOT LOAD INPUT", "err", err)
+	}
+
+	if err := saveStateToPath(output); err != nil {
+		fatal("COULD NOT WRITE OUTPUT", "path", output, "err", err)
+	}
+	slog.Info("CONVERTED", "input", input, "output", output)
+}
+
+// Convert the trajectory input into a trajectory in the given format, keeping only every so many frames and the last
+// The output is replaced rather than appended to, as csv trajectories otherwise are
+func convertTrajectory(input, output, format string, every int) {
+	if format != "csv" && format != "columnar" {
+		fatal("UNKNOWN TRAJECTORY FORMAT, MUST BE csv OR columnar", "format", format)
+	}
+	if same, err := sameFile(input, output); err == nil && same {
+		fatal("THE OUTPUT WOULD OVERWRITE THE INPUT", "path", output)
+	}
+	if err := os.Remove(output); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fatal("COULD NOT REPLACE OUTPUT", "path", output, "err", err)
+	}
+	out, err := persist.OpenTrajectory(output, format)
+	if err != nil {
+		fatal("COULD NOT WRITE OUTPUT", "path", output, "err", err)
+	}
+
+	// The last frame read, if it wasn't kept, so it can be written at the end
+	var last *trajectoryFrame
+	read, written := 0, 0
+	err = persist.ReadTrajectory(input, func(time float64, bodies []*simulation.Body) error {
+		read++
+		if (read-1)%every != 0 {
+			last = &trajectoryFrame{time, bodies}
+			return nil
+		}
+		last = nil
+		if err := out.Record(time, bodies); err != nil {
+			return fmt.Errorf("writing %v: %w", output, err)
+		}
+		written++
+		return nil
+	})
+	if err != nil {
+		out.Close()
+		fatal("COULD NOT CONVERT TRAJECTORY", "path", input, "err", err)
+	}
+	if last != nil {
+		if err := out.Record(last.time, last.bodies); err != nil {
+			out.Close()
+			fatal("COULD NOT WRITE OUTPUT", "path", output, "err", err)
+		}
+		written++
+	}
+	if err := out.Close(); err != nil {
+		fatal("COULD NOT WRITE OUTPUT", "path", output, "err", err)
+	}
+	slog.Info("CONVERTED TRAJECTORY", "input", input, "output", output, "format", format, "framesRead", read, "framesWritten", written)
+}
+
+// Whether two paths are the same file, e.g. through different relative paths
+func sameFile(a, b string) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(infoA, infoB), nil
+}
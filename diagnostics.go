@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log/slog"
+
+	"hmcalister/gravity_simulation/persist"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+var (
+	// Set by the diagnostics flags
+	diagnosticsPath  string
+	diagnosticsEvery int
+	// The file the totals of each step are written to, nil if they aren't being written
+	diagnostics *persist.DiagnosticsCSV
+)
+
+// Start writing the totals (energy, momentum and angular momentum) every --diagnosticsEvery steps, including the start
+// Like the trajectory, failing to open the diagnostics file is not fatal - the simulation simply runs without it
+func openDiagnostics() {
+	var err error
+	diagnostics, err = persist.NewDiagnosticsCSV(diagnosticsPath)
+	if err != nil {
+		slog.Warn("COULD NOT OPEN DIAGNOSTICS FILE", "path", diagnosticsPath, "err", err)
+		diagnostics = nil
+		return
+	}
+	diagnostics.Record(sim.Steps, sim.Time, sim.Bodies(), sim.G)
+	sim.OnStep(func(s *simulation.Simulation) {
+		if s.Steps%diagnosticsEvery == 0 && !replayingStep(s) {
+			diagnostics.Record(s.Steps, s.Time, s.Bodies(), s.G)
+		}
+	})
+}
+
+// Finish writing the diagnostics file, if one is being written
+// Like the snapshots, the last step is written even if it isn't one of every --diagnosticsEvery, so the file ends with the run
+func closeDiagnostics() {
+	if diagnostics == nil {
+		return
+	}
+	if sim.Steps%diagnosticsEvery != 0 {
+		diagnostics.Record(sim.Steps, sim.Time, sim.Bodies(), sim.G)
+	}
+	if err := diagnostics.Close(); err != nil {
+		slog.Warn("COULD NOT WRITE DIAGNOSTICS FILE", "path", diagnosticsPath, "err", err)
+	}
+	diagnostics = nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/rpc"
+	"os"
+	"strings"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Experimental: with --workers, the gravity between the bodies is found by worker processes (started with the worker
+// command, on this machine or others) rather than by this one, for simulations with very many bodies
+// Each step the bodies are split into one domain per worker (see simulation.Decompose), each worker is sent its domain
+// with its ghosts over net/rpc, and the accelerations they send back are put together before the bodies are moved
+// Everything else (moving the bodies, collisions, saving...) still happens here, so only the gravity is shared out
+// A worker that fails is given up on, and its domain found here from then on, so the run carries on more slowly
+
+// The help for the worker command, printed with -h
+const WORKERHELP = `
+Gravity Simulation - worker
+Usage:
+	./gravity_simulation worker [flags]
+
+	Experimental: serves the gravity of parts of a simulation run elsewhere with --workers, e.g.
+	./gravity_simulation worker --addr=:7400 on each machine, then
+	./gravity_simulation simulate --numBodies=20000 --workers=machine1:7400,machine2:7400
+	Runs until interrupted (e.g. with Ctrl+C)
+
+Flags:
+	--addr : The address to serve on
+		Defaults to :7400`
+
+var (
+	// Set by the --workers flag, a comma separated list of the addresses of the workers
+	workersString string
+	// Set by the --ghostDistance flag
+	ghostDistance float64
+)
+
+// What a worker is sent each step: a domain, and the parameters to find its gravity with
+type ForceRequest struct {
+	Domain simulation.Domain
+	Params simulation.Params
+}
+
+// The accelerations of the owned bodies of a domain, as sent back by a worker
+type ForceReply struct {
+	Accelerations []simulation.Vec2
+}
+
+// The service a worker serves over net/rpc
+type ForceWorker struct{}
+
+// Find the accelerations of the owned bodies of a domain
+func (ForceWorker) Accelerations(request ForceRequest, reply *ForceReply) error {
+	reply.Accelerations = request.Domain.Accelerations(request.Params)
+	return nil
+}
+
+// The gravity between the bodies, found in domains by the workers
+type distributedGravity struct {
+	addrs []string
+	// The connection to each worker, nil once it has failed
+	clients []*rpc.Client
+}
+
+// Connect to the workers in --workers and find the gravity with them from the next step
+// A worker that can't be connected to is fatal, as the run would be far slower than expected without it
+func setupWorkers() {
+	if ghostDistance < 0 {
+		fatal("--ghostDistance MUST NOT BE NEGATIVE")
+	}
+	gravity := &distributedGravity{}
+	for _, addr := range strings.Split(workersString, ",") {
+		addr = strings.TrimSpace(addr)
+		client, err := rpc.Dial("tcp", addr)
+		if err != nil {
+			fatal("COULD NOT CONNECT TO WORKER", "addr", addr, "err", err)
+		}
+		gravity.addrs = append(gravity.addrs, addr)
+		gravity.clients = append(gravity.clients, client)
+	}
+	sim.SetGravity(gravity)
+	slog.Info("FINDING GRAVITY WITH WORKERS", "workers", len(gravity.clients), "ghostDistance", ghostDistance)
+}
+
+func (g *distributedGravity) Accelerations(bodies []*simulation.Body) []simulation.Vec2 {
+	p := sim.Params
+	domains := simulation.Decompose(bodies, len(g.clients), ghostDistance)
+	// Every domain is sent before any reply is waited for, so the workers all work at once
+	calls := make([]*rpc.Call, len(domains))
+	for i, d := range domains {
+		if g.clients[i] != nil {
+			calls[i] = g.clients[i].Go("ForceWorker.Accelerations", ForceRequest{d, p}, &ForceReply{}, nil)
+		}
+	}
+
+	acc := make([]simulation.Vec2, len(bodies))
+	for i, d := range domains {
+		var domainAcc []simulation.Vec2
+		if calls[i] != nil {
+			<-calls[i].Done
+			if calls[i].Error == nil {
+				domainAcc = calls[i].Reply.(*ForceReply).Accelerations
+			} else {
+				slog.Warn("WORKER FAILED, FINDING ITS DOMAIN HERE FROM NOW ON", "addr", g.addrs[i], "err", calls[i].Error)
+				g.clients[i].Close()
+				g.clients[i] = nil
+			}
+		}
+		if len(domainAcc) != len(d.Owned) {
+			domainAcc = d.Accelerations(p)
+		}
+		for j, id := range d.Owned {
+			acc[id] = domainAcc[j]
+		}
+	}
+	return acc
+}
+
+// The worker command, which serves the gravity of domains of simulations run with --workers
+func workerCommand(args []string) {
+	var helpFlag bool
+	var addr string
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	fs.StringVar(&addr, "addr", ":7400", "The address to serve on")
+	fs.BoolVar(&helpFlag, "h", false, "Display help on this command, then quit")
+	logFlags(fs)
+	fs.Parse(args)
+	setupLogging()
+
+	if helpFlag {
+		fmt.Println(WORKERHELP + LOGGINGHELP)
+		os.Exit(0)
+	}
+	server := rpc.NewServer()
+	if err := server.Register(ForceWorker{}); err != nil {
+		fatal("COULD NOT START WORKER", "err", err)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		fatal("COULD NOT START WORKER", "addr", addr, "err", err)
+	}
+	slog.Info("SERVING AS A WORKER, INTERRUPT TO QUIT", "addr", listener.Addr().String())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			fatal("COULD NOT ACCEPT CONNECTION", "err", err)
+		}
+		slog.Info("COORDINATOR CONNECTED", "addr", conn.RemoteAddr().String())
+		go func() {
+			server.ServeConn(conn)
+			slog.Info("COORDINATOR DISCONNECTED", "addr", conn.RemoteAddr().String())
+		}()
+	}
+}
@@ -0,0 +1,70 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+
+	"hmcalister/gravity_simulation/render"
+)
+
+// While paused, the selected body can be dragged to a new position, or shift-dragged to set its velocity
+// The velocity is shown as a line from the body to the mouse, which is this many times longer than the velocity
+const VELOCITYDRAGSCALE = 100
+
+var (
+	// Whether the selected body is being dragged, and whether the drag is setting its velocity rather than position
+	dragging         bool
+	draggingVelocity bool
+	// The offset from the center of the body to where it was grabbed, so it doesn't jump to be centered on the mouse
+	dragOffsetX float64
+	dragOffsetY float64
+)
+
+// Start dragging the selected body from a position on the screen, if the simulation is paused
+func startDrag(x, y int32) {
+	b := selected()
+	if b == nil || !paused {
+		return
+	}
+	recordUndo()
+	dragging = true
+	draggingVelocity = sdl.GetModState()&sdl.KMOD_SHIFT != 0
+	worldX, worldY := screenToWorld(x, y)
+	dragOffsetX, dragOffsetY = b.Pos.X-worldX, b.Pos.Y-worldY
+	updateDrag(x, y)
+}
+
+// Move (or set the velocity of) the body being dragged as the mouse moves
+func updateDrag(x, y int32) {
+	b := selected()
+	if !dragging || b == nil {
+		dragging = false
+		return
+	}
+	worldX, worldY := screenToWorld(x, y)
+	if draggingVelocity {
+		b.Vel.X = (worldX - b.Pos.X) / VELOCITYDRAGSCALE
+		b.Vel.Y = (worldY - b.Pos.Y) / VELOCITYDRAGSCALE
+	} else {
+		b.Pos.X = worldX + dragOffsetX
+		b.Pos.Y = worldY + dragOffsetY
+		canvas.Fill(backgroundColor)
+	}
+}
+
+// Stop dragging when the mouse is released
+func stopDrag() {
+	dragging = false
+}
+
+// Draw the velocity of the body while its velocity is being dragged
+func drawDrag() {
+	b := selected()
+	if !dragging || !draggingVelocity || b == nil {
+		return
+	}
+	x, y := worldToScreen(b.Pos.X, b.Pos.Y)
+	endX, endY := worldToScreen(b.Pos.X+b.Vel.X*VELOCITYDRAGSCALE, b.Pos.Y+b.Vel.Y*VELOCITYDRAGSCALE)
+	render.Line(frame, x, y, endX, endY, render.HighlightColor)
+}
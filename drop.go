@@ -0,0 +1,83 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"hmcalister/gravity_simulation/persist"
+)
+
+// The buttons of the prompt shown when a file is dropped onto the window
+const (
+	DROPREPLACE = iota
+	DROPMERGE
+	DROPCANCEL
+)
+
+// Load a save file dropped onto the window into the running simulation
+// The user is asked whether to replace the current bodies with those in the file, or merge them in alongside the current bodies
+// Any problem with the file is reported without stopping the simulation
+func loadDroppedFile(path string) {
+	slog.Info("LOADING DROPPED FILE", "path", path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		slog.Error("COULD NOT READ FILE", "path", path, "err", err)
+		return
+	}
+	name, data, err := persist.Decompress(path, data)
+	if err != nil {
+		slog.Error("COULD NOT READ FILE", "path", path, "err", err)
+		return
+	}
+
+	choice, err := sdl.ShowMessageBox(&sdl.MessageBoxData{
+		Flags:   sdl.MESSAGEBOX_INFORMATION,
+		Title:   "Load " + filepath.Base(path),
+		Message: "Replace the current simulation with this file, or merge its bodies into the current simulation?",
+		Buttons: []sdl.MessageBoxButtonData{
+			{Flags: sdl.MESSAGEBOX_BUTTON_RETURNKEY_DEFAULT, ButtonID: DROPREPLACE, Text: "Replace"},
+			{ButtonID: DROPMERGE, Text: "Merge"},
+			{Flags: sdl.MESSAGEBOX_BUTTON_ESCAPEKEY_DEFAULT, ButtonID: DROPCANCEL, Text: "Cancel"},
+		},
+	})
+	if err != nil || choice == DROPCANCEL || choice < 0 {
+		slog.Info("CANCELLED LOADING DROPPED FILE")
+		return
+	}
+
+	// When replacing with a protobuf save the settings are restored too, as when loading it at startup, so undoing it
+	// restores the whole state before
+	if choice == DROPREPLACE && strings.HasSuffix(name, ".pb") {
+		recordReplaceUndo()
+		if err := decodeState(data); err != nil {
+			slog.Error("COULD NOT LOAD FILE", "path", path, "err", err)
+			return
+		}
+		canvas.Fill(backgroundColor)
+		return
+	}
+
+	recordUndo()
+	bodies, err := persist.ParseStateFile(name, data, sim.G, sim.Rand)
+	if err != nil {
+		slog.Error("COULD NOT LOAD FILE", "path", path, "err", err)
+		return
+	}
+	if choice == DROPREPLACE {
+		sim.SetBodies(bodies)
+	} else {
+		for _, b := range bodies {
+			if b != nil {
+				sim.AddBody(b)
+			}
+		}
+	}
+	canvas.Fill(backgroundColor)
+	slog.Info("LOADED BODIES", "bodies", len(bodies))
+}
@@ -0,0 +1,95 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// A parameter of the selected body that can be edited in the editor panel
+type editField struct {
+	label string
+	get   func(b *simulation.Body) float64
+	set   func(b *simulation.Body, value float64)
+}
+
+// Set a color channel, clamping the value between 0 and 255
+func colorChannel(value float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(value))))
+}
+
+// The fields of the editor panel, in the order they are shown
+var editFields = []editField{
+	{"MASS", func(b *simulation.Body) float64 { return b.Mass }, func(b *simulation.Body, v float64) {
+		// The radius follows the mass, as when bodies merge
+		b.Mass = v
+		b.Radius = simulation.MassToRadius(v)
+	}},
+	{"X VELOCITY", func(b *simulation.Body) float64 { return b.Vel.X }, func(b *simulation.Body, v float64) { b.Vel.X = v }},
+	{"Y VELOCITY", func(b *simulation.Body) float64 { return b.Vel.Y }, func(b *simulation.Body, v float64) { b.Vel.Y = v }},
+	{"RED", func(b *simulation.Body) float64 { return float64(b.Color.R) }, func(b *simulation.Body, v float64) { b.Color.R = colorChannel(v) }},
+	{"GREEN", func(b *simulation.Body) float64 { return float64(b.Color.G) }, func(b *simulation.Body, v float64) { b.Color.G = colorChannel(v) }},
+	{"BLUE", func(b *simulation.Body) float64 { return float64(b.Color.B) }, func(b *simulation.Body, v float64) { b.Color.B = colorChannel(v) }},
+}
+
+// Where the editor panel was last drawn, so clicks on it can be found
+var editorRect image.Rectangle
+
+// The text shown for a field, including the text being typed if the field is being edited
+func editFieldLine(b *simulation.Body, field editField) string {
+	if activeInput != nil && activeInput.label == field.label {
+		return fmt.Sprintf("> %-12v%v_", field.label, activeInput.text)
+	}
+	return fmt.Sprintf("  %-12v%v", field.label, strconv.FormatFloat(field.get(b), 'g', 6, 64))
+}
+
+// Draw the editor panel for the selected body with its top left corner at x, y
+func drawEditor(x, y int) {
+	b := selected()
+	if b == nil {
+		editorRect = image.Rectangle{}
+		return
+	}
+	lines := []string{"EDIT (CLICK A FIELD, ENTER TO APPLY)"}
+	for _, field := range editFields {
+		lines = append(lines, editFieldLine(b, field))
+	}
+	editorRect = render.Panel(frame, x, y, lines)
+}
+
+// Handle a click on the editor panel, starting to edit the clicked field
+// Returns false if the click was not on the panel
+func clickEditor(x, y int32) bool {
+	if !image.Pt(int(x), int(y)).In(editorRect) {
+		return false
+	}
+	// The first line of the panel is the title, the fields follow
+	row := (int(y)-editorRect.Min.Y-render.PANELPADDING)/render.TextLineHeight - 1
+	if row < 0 || row >= len(editFields) {
+		return true
+	}
+	field := editFields[row]
+	index := selectedBody
+	initial := strconv.FormatFloat(field.get(sim.Bodies()[index]), 'g', -1, 64)
+	startTextInput(field.label, initial, func(text string) {
+		value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			slog.Warn("NOT A NUMBER", "field", field.label, "value", text)
+			return
+		}
+		// The body may have changed since editing started, e.g. by merging or being replaced in the next step
+		if index < len(sim.Bodies()) && sim.Bodies()[index] != nil {
+			recordUndo()
+			field.set(sim.Bodies()[index], value)
+		}
+	})
+	return true
+}
@@ -0,0 +1,85 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The energy plot is a small scrolling plot of the kinetic, potential and total energy, drawn above the HUD
+// while turned on with ', so drift in the total energy (from the integrator, or lost in merges) can be watched as it happens
+// The energy is found once per step rather than per frame, so the plot stands still while paused
+
+const (
+	// The number of steps shown, one per pixel across the plot
+	ENERGYPLOTSAMPLES = 240
+	// The height of the plot in pixels, not including its legend
+	ENERGYPLOTHEIGHT = 100
+)
+
+var (
+	// Whether the energy plot is shown, toggled with '
+	showEnergyPlot bool
+	// The kinetic, potential and total energy of the last ENERGYPLOTSAMPLES steps, oldest first
+	energySamples [3][]float64
+	// The step the last sample was taken at, so each step is only sampled once
+	energySampleStep = -1
+	// The names and colors of the plotted energies, in the order of energySamples
+	energyNames  = [3]string{"KINETIC", "POTENTIAL", "TOTAL"}
+	energyColors = []color.RGBA{{255, 140, 80, 255}, {90, 160, 255, 255}, {255, 255, 255, 255}}
+)
+
+// Show or hide the energy plot, starting it afresh when shown
+func toggleEnergyPlot() {
+	showEnergyPlot = !showEnergyPlot
+	energySamples = [3][]float64{}
+	energySampleStep = -1
+}
+
+// Sample the energies of the current step, if it hasn't been sampled already
+func sampleEnergy() {
+	if sim.Steps == energySampleStep {
+		return
+	}
+	energySampleStep = sim.Steps
+	kinetic := simulation.KineticEnergy(sim.Bodies())
+	potential := simulation.PotentialEnergy(sim.Bodies(), sim.G)
+	for i, v := range []float64{kinetic, potential, kinetic + potential} {
+		energySamples[i] = append(energySamples[i], v)
+		if len(energySamples[i]) > ENERGYPLOTSAMPLES {
+			energySamples[i] = energySamples[i][1:]
+		}
+	}
+}
+
+// Draw the energy plot in the bottom right corner, above the HUD, with the latest energies as its legend
+func drawEnergyPlot() {
+	if !showEnergyPlot {
+		return
+	}
+	sampleEnergy()
+
+	width := ENERGYPLOTSAMPLES + 2*render.PANELPADDING
+	height := (len(energyNames)+1)*render.TextLineHeight + ENERGYPLOTHEIGHT + 3*render.PANELPADDING
+	_, hudHeight := render.PanelSize(hudLines())
+	x := int(screenWidth) - width - render.PANELMARGIN
+	y := int(screenHeight) - hudHeight - height - 2*render.PANELMARGIN
+	render.Box(frame, image.Rect(x, y, x+width, y+height))
+
+	textX, textY := x+render.PANELPADDING, y+render.PANELPADDING
+	render.Text(frame, textX, textY, fmt.Sprintf("ENERGY (LAST %v STEPS)", ENERGYPLOTSAMPLES), render.TextColor)
+	for i, name := range energyNames {
+		value := 0.0
+		if n := len(energySamples[i]); n > 0 {
+			value = energySamples[i][n-1]
+		}
+		render.Text(frame, textX, textY+(i+1)*render.TextLineHeight, fmt.Sprintf("%v: %.6g", name, value), energyColors[i])
+	}
+	plotY := textY + (len(energyNames)+1)*render.TextLineHeight + render.PANELPADDING
+	render.Plot(frame, image.Rect(textX, plotY, textX+ENERGYPLOTSAMPLES, plotY+ENERGYPLOTHEIGHT), energySamples[:], energyColors)
+}
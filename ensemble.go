@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// The help for the ensemble command, printed with -h
+const ENSEMBLEHELP = `
+Gravity Simulation - ensemble
+Usage:
+	./gravity_simulation ensemble [flags] -- [simulate flags]
+
+	Runs the same configuration many times with different random seeds, and prints statistics of how the runs ended,
+	e.g. how likely 20 bodies are to all merge into one within a simulated time of 5000:
+	./gravity_simulation ensemble --runs=100 -- --numBodies=20 --simTime=5000
+	The flags after -- are given to every run, which must include a run limit (--steps, --simTime or --duration)
+	and mustn't include --seed, as each run gets its own: --seed, --seed+1, --seed+2...
+
+	As with the sweep command, each run has its own directory in --out (e.g. ensemble/seed=1234) which it is run in,
+	and a row of each run's summary is written to summary.csv in --out. Then the mean, standard deviation, smallest,
+	median and largest of the mergers, bodies left, largest mass, energy drift and wall time over the finished runs are
+	printed and written to statistics.csv, along with the fraction of runs that ended with at most --outcomeBodies bodies
+	left, the chance of that outcome, with its standard error (written as the mean and stddev of its row in statistics.csv)
+	An interrupt (Ctrl+C) stops every run as usual, and the statistics are of the runs that finished
+
+Flags:
+	--runs : The number of runs
+		Defaults to 10
+	--seed : The seed of the first run, each run after has the next seed
+		Defaults to the current time
+	--outcomeBodies : The chance of a run ending with at most this many bodies left is estimated
+		Defaults to 1, all of the bodies merging into one
+	--out : The directory to put the directory of each run in
+		Defaults to ensemble
+	--parallel : How many runs to run at once
+		Defaults to the number of CPUs`
+
+// A statistic of the runs of an ensemble, found from each run's summary
+type ensembleStatistic struct {
+	name  string
+	value func(s *runSummary) float64
+}
+
+// The statistics of an ensemble
+var ensembleStatistics = []ensembleStatistic{
+	{"mergers", func(s *runSummary) float64 { return float64(s.Mergers) }},
+	{"bodies", func(s *runSummary) float64 { return float64(s.Bodies) }},
+	{"largestMass", func(s *runSummary) float64 {
+		if s.Largest == nil {
+			return 0
+		}
+		return s.Largest.Mass
+	}},
+	{"energyDrift", func(s *runSummary) float64 { return s.EnergyDrift }},
+	{"wallSeconds", func(s *runSummary) float64 { return s.WallSeconds }},
+}
+
+// The ensemble command, which runs the simulate command with many seeds and prints statistics of the results
+func ensembleCommand(args []string) {
+	var helpFlag bool
+	var out string
+	var runCount, parallel, outcomeBodies int
+	var seed int64
+	fs := flag.NewFlagSet("ensemble", flag.ExitOnError)
+	fs.IntVar(&runCount, "runs", 10, "The number of runs")
+	fs.Int64Var(&seed, "seed", 0, "The seed of the first run, each run after has the next seed.\nIf 0, the current time is used")
+	fs.IntVar(&outcomeBodies, "outcomeBodies", 1, "Estimate the chance of a run ending with at most this many bodies left")
+	fs.StringVar(&out, "out", "ensemble", "The directory to put the directory of each run in")
+	fs.IntVar(&parallel, "parallel", runtime.NumCPU(), "How many runs to run at once")
+	fs.BoolVar(&helpFlag, "h", false, "Display help on this command, then quit")
+	logFlags(fs)
+	fs.Parse(args)
+	setupLogging()
+
+	if helpFlag {
+		fmt.Println(ENSEMBLEHELP + LOGGINGHELP)
+		os.Exit(0)
+	}
+	if runCount < 1 || parallel < 1 {
+		fatal("--runs AND --parallel MUST BE AT LEAST 1")
+	}
+	if seed == 0 {
+		seed = time.Now().UnixMicro()
+	}
+	// As with sweeps, this is before the flags of the runs are read, so their --duration isn't a deadline for the whole ensemble
+	catchInterrupts()
+	runArgs := sweepRunArgs("seed", fs.Args(), false)
+
+	runs := make([]sweepRun, runCount)
+	for i := range runs {
+		value := strconv.FormatInt(seed+int64(i), 10)
+		runs[i] = sweepRun{value: value, dir: filepath.Join(out, "seed="+value)}
+		if err := os.MkdirAll(runs[i].dir, 0755); err != nil {
+			fatal("COULD NOT CREATE RUN DIRECTORY", "dir", runs[i].dir, "err", err)
+		}
+	}
+	slog.Info("RUNNING ENSEMBLE", "runs", runCount, "firstSeed", seed, "out", out, "parallel", parallel)
+	runSweeps("seed", runs, runArgs, parallel)
+	failed := writeSweepSummary("seed", out, runs)
+	writeEnsembleStatistics(out, runs, outcomeBodies)
+	if failed > 0 {
+		fatal("SOME RUNS FAILED", "failed", failed, "runs", len(runs))
+	}
+}
+
+// Print the statistics of the finished runs and write them to statistics.csv in out
+func writeEnsembleStatistics(out string, runs []sweepRun, outcomeBodies int) {
+	var finished []*runSummary
+	for _, r := range runs {
+		if r.err == nil && r.summary != nil {
+			finished = append(finished, r.summary)
+		}
+	}
+	if len(finished) == 0 {
+		slog.Warn("NO RUNS FINISHED, SO THERE ARE NO STATISTICS")
+		return
+	}
+
+	path := filepath.Join(out, "statistics.csv")
+	file, err := os.Create(path)
+	if err != nil {
+		fatal("COULD NOT WRITE STATISTICS", "path", path, "err", err)
+	}
+	fmt.Fprintln(file, "#statistic, runs, mean, stddev, min, median, max")
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Fprintf(tableWriter, "STATISTICS OF %v RUNS\tMEAN\tSTDDEV\tMIN\tMEDIAN\tMAX\n", len(finished))
+	for _, statistic := range ensembleStatistics {
+		values := make([]float64, len(finished))
+		for i, s := range finished {
+			values[i] = statistic.value(s)
+		}
+		mean, stddev := meanAndStddev(values)
+		slices.Sort(values)
+		median := (values[(len(values)-1)/2] + values[len(values)/2]) / 2
+		fmt.Fprintf(file, "%v,%v,%v,%v,%v,%v,%v\n", statistic.name, len(values), mean, stddev, values[0], median, values[len(values)-1])
+		fmt.Fprintf(tableWriter, "%v\t%.4g\t%.4g\t%.4g\t%.4g\t%.4g\n", statistic.name, mean, stddev, values[0], median, values[len(values)-1])
+	}
+
+	// The chance of the outcome is estimated by the fraction of runs with it, with the standard error of a binomial proportion
+	outcomes := 0
+	for _, s := range finished {
+		if s.Bodies <= outcomeBodies {
+			outcomes++
+		}
+	}
+	chance := float64(outcomes) / float64(len(finished))
+	standardError := math.Sqrt(chance * (1 - chance) / float64(len(finished)))
+	fmt.Fprintf(file, "atMost%vBodies,%v,%v,%v,,,\n", outcomeBodies, len(finished), chance, standardError)
+	tableWriter.Flush()
+	fmt.Printf("CHANCE OF AT MOST %v BODIES LEFT: %.3g ± %.2g (%v OF %v RUNS)\n", outcomeBodies, chance, standardError, outcomes, len(finished))
+	if err := file.Close(); err != nil {
+		fatal("COULD NOT WRITE STATISTICS", "path", path, "err", err)
+	}
+	fmt.Println("STATISTICS WRITTEN TO", path)
+}
+
+// The mean and the (sample) standard deviation of the values, with a standard deviation of 0 for a single value
+func meanAndStddev(values []float64) (float64, float64) {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	squares := 0.0
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(values)-1))
+}
@@ -0,0 +1,19 @@
+//go:build sdl && !headless
+
+package main
+
+import "hmcalister/gravity_simulation/render"
+
+// Draw a ring around every visible body escaping the system, if they are being marked
+func drawEscaping() {
+	if !markUnbound {
+		return
+	}
+	for id, b := range sim.Bodies() {
+		if b == nil || !isEscaping(id) || !visible(b) {
+			continue
+		}
+		x, y := worldToScreen(b.Pos.X, b.Pos.Y)
+		render.Ring(frame, x, y, b.Radius/camera.Zoom+3, render.EscapingColor)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log/slog"
+
+	"hmcalister/gravity_simulation/persist"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+var (
+	// Set by the --eventsOut flag
+	eventsPath string
+	// The file every collision is written to, nil if they aren't being written
+	eventLog *persist.EventLog
+)
+
+// Start writing every collision to the event log
+// Like the trajectory, failing to open the event log is not fatal - the simulation simply runs without it
+func openEventLog() {
+	var err error
+	eventLog, err = persist.NewEventLog(eventsPath)
+	if err != nil {
+		slog.Warn("COULD NOT OPEN EVENTS FILE", "path", eventsPath, "err", err)
+		eventLog = nil
+		return
+	}
+	sim.OnCollision(func(s *simulation.Simulation, c simulation.Collision) {
+		if !replayingStep(s) {
+			eventLog.RecordCollision(s, c)
+		}
+	})
+}
+
+// Finish writing the event log, if one is being written
+func closeEventLog() {
+	if eventLog == nil {
+		return
+	}
+	if err := eventLog.Close(); err != nil {
+		slog.Warn("COULD NOT WRITE EVENTS FILE", "path", eventsPath, "err", err)
+	}
+	eventLog = nil
+}
@@ -0,0 +1,73 @@
+// The customforce example adds forces other than gravity to a simulation:
+// a spring pulling every body back to the origin, declared as a ForceProvider type,
+// and a steady wind written as a plain function with ForceFunc
+//
+//	go run ./examples/customforce
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+const (
+	TIMESCALE = 0.01
+	STEPS     = 2000
+)
+
+func main() {
+	if err := run(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err)
+		os.Exit(1)
+	}
+}
+
+// A spring from the origin to every body, so the bodies oscillate about the origin
+type Spring struct {
+	// The acceleration of a body is -Stiffness times its position
+	Stiffness float64
+}
+
+func (s Spring) Accelerations(bodies []*simulation.Body) []simulation.Vec2 {
+	acc := make([]simulation.Vec2, len(bodies))
+	for i, b := range bodies {
+		if b != nil {
+			acc[i] = b.Pos.Scale(-s.Stiffness)
+		}
+	}
+	return acc
+}
+
+// Run the simulation with the two forces, printing where the bodies end up
+func run(w io.Writer) error {
+	// With no gravity, each body only feels the custom forces, and the bodies pass through each other at the origin
+	sim := simulation.New(simulation.Params{G: 0, Timescale: TIMESCALE, CollisionMode: simulation.COLLISIONPASS}, 1)
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{X: 100}, Mass: 1, Radius: 1, Name: "light"})
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{Y: -100}, Mass: 10, Radius: 3, Name: "heavy"})
+
+	sim.AddForce(Spring{Stiffness: 1})
+	// The wind pushes every body along x, whatever its mass
+	sim.AddForce(simulation.ForceFunc(func(bodies []*simulation.Body) []simulation.Vec2 {
+		acc := make([]simulation.Vec2, len(bodies))
+		for i := range acc {
+			acc[i].X = 5
+		}
+		return acc
+	}))
+
+	for i := 0; i < STEPS; i++ {
+		sim.Step()
+	}
+	for _, b := range sim.Bodies() {
+		if math.IsNaN(b.Pos.X) || math.IsNaN(b.Pos.Y) {
+			return fmt.Errorf("body %v has an invalid position", b.Name)
+		}
+		// The wind moves the center of the oscillation to x = 5 / Stiffness
+		fmt.Fprintf(w, "%v at (%.2f, %.2f) at time %.2f\n", b.Name, b.Pos.X, b.Pos.Y, sim.Time)
+	}
+	return nil
+}
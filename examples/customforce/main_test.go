@@ -0,0 +1,13 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+// Running the example keeps it working as the library changes
+func TestRun(t *testing.T) {
+	if err := run(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,65 @@
+// The orbit example integrates a single planet around a fixed star with no window, and compares the orbit
+// it finds against the circular orbit it was started on
+//
+//	go run ./examples/orbit
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+const (
+	G           = 100
+	STARMASS    = 1000
+	ORBITRADIUS = 200
+	TIMESCALE   = 0.01
+	// How many orbits to integrate for
+	ORBITS = 3
+)
+
+func main() {
+	if err := run(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err)
+		os.Exit(1)
+	}
+}
+
+// Integrate the orbit, printing each period and how far the radius strayed
+func run(w io.Writer) error {
+	sim := simulation.New(simulation.Params{G: G, Timescale: TIMESCALE}, 1)
+	sim.AddBody(&simulation.Body{Mass: STARMASS, Radius: simulation.MassToRadius(STARMASS), Name: "star", Fixed: true})
+	// The speed of a circular orbit, where gravity provides exactly the centripetal acceleration
+	speed := math.Sqrt(G * STARMASS / ORBITRADIUS)
+	planet := sim.AddBody(&simulation.Body{Pos: simulation.Vec2{X: ORBITRADIUS}, Vel: simulation.Vec2{Y: speed}, Mass: 1, Radius: 1, Name: "planet"})
+
+	expected := 2 * math.Pi * ORBITRADIUS / speed
+	fmt.Fprintf(w, "expected period %.3f\n", expected)
+
+	// An orbit is complete each time the planet crosses the positive x axis going up
+	minRadius, maxRadius := math.Inf(1), 0.0
+	lastCrossing, orbits := 0.0, 0
+	sim.OnStep(func(s *simulation.Simulation) {
+		p := s.Bodies()[planet]
+		r := p.Pos.Norm()
+		minRadius, maxRadius = math.Min(minRadius, r), math.Max(maxRadius, r)
+		if p.Pos.X > 0 && p.Pos.Y >= 0 && p.Pos.Y < p.Vel.Y*s.Timescale {
+			fmt.Fprintf(w, "orbit %v: period %.3f\n", orbits+1, s.Time-lastCrossing)
+			lastCrossing = s.Time
+			orbits++
+		}
+	})
+
+	for orbits < ORBITS {
+		if sim.Time > 2*ORBITS*expected {
+			return fmt.Errorf("the planet did not complete %v orbits in %.3f", ORBITS, sim.Time)
+		}
+		sim.Step()
+	}
+	fmt.Fprintf(w, "radius stayed between %.3f and %.3f\n", minRadius, maxRadius)
+	return nil
+}
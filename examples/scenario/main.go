@@ -0,0 +1,64 @@
+// The scenario example builds a starting configuration in code rather than by hand in a save file:
+// a pair of stars orbiting each other, circled by a ring of small bodies.
+// It writes the configuration as a csv save, so it can be loaded straight into the program, e.g.
+//
+//	go run ./examples/scenario | ./gravity_simulation --saveFile=-
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+const (
+	G          = 100
+	STARMASS   = 500
+	STARS      = 60
+	RINGSIZE   = 40
+	RINGRADIUS = 300
+)
+
+func main() {
+	if err := run(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err)
+		os.Exit(1)
+	}
+}
+
+// Build the binary and its ring, then save it to w
+func run(w io.Writer) error {
+	sim := simulation.New(simulation.Params{G: G, Timescale: 0.25}, 1)
+
+	// Two equal stars on a circular orbit about their center of mass
+	// Each is pulled by the other from 2*STARS away, which must provide the centripetal acceleration at radius STARS
+	starSpeed := math.Sqrt(G * STARMASS / (4 * STARS))
+	for i, side := range []float64{1, -1} {
+		sim.AddBody(&simulation.Body{
+			Pos:    simulation.Vec2{X: side * STARS},
+			Vel:    simulation.Vec2{Y: side * starSpeed},
+			Mass:   STARMASS,
+			Radius: simulation.MassToRadius(STARMASS),
+			Color:  color.RGBA{255, 220, 120, 255},
+			Name:   fmt.Sprintf("star %v", i+1),
+		})
+	}
+
+	// The ring orbits far enough out that the pair pulls like a single mass at the center
+	ringSpeed := math.Sqrt(G * 2 * STARMASS / RINGRADIUS)
+	for i := 0; i < RINGSIZE; i++ {
+		angle := 2 * math.Pi * float64(i) / RINGSIZE
+		sim.AddBody(&simulation.Body{
+			Pos:    simulation.Vec2{X: RINGRADIUS * math.Cos(angle), Y: RINGRADIUS * math.Sin(angle)},
+			Vel:    simulation.Vec2{X: -ringSpeed * math.Sin(angle), Y: ringSpeed * math.Cos(angle)},
+			Mass:   1,
+			Radius: 1,
+			Color:  simulation.RandomColor(sim.Rand),
+		})
+	}
+	return sim.Save(w)
+}
@@ -0,0 +1,80 @@
+// The trajectory example records the trajectory of a random simulation to a file, as --trajectoryOut does,
+// then reads it back to check how well the energy was conserved, as the analyze command does
+// Close encounters between the random bodies make the energy drift much more than in a calm system, so the timescale is small
+//
+//	go run ./examples/trajectory
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"hmcalister/gravity_simulation/persist"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+const (
+	G         = 100
+	NUMBODIES = 20
+	STEPS     = 500
+)
+
+func main() {
+	dir, err := os.MkdirTemp("", "trajectory")
+	if err == nil {
+		defer os.RemoveAll(dir)
+		err = run(os.Stdout, filepath.Join(dir, "trajectory.csv"))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err)
+		os.Exit(1)
+	}
+}
+
+// Record STEPS steps of a random simulation to the trajectory file at path, then read it back
+func run(w io.Writer, path string) error {
+	sim := simulation.New(simulation.Params{G: G, Timescale: 0.01, CollisionMode: simulation.COLLISIONPASS}, 1)
+	for i := 0; i < NUMBODIES; i++ {
+		sim.AddBody(simulation.NewRandomBody(sim.Rand, simulation.RandomOptions{MassMin: 1, MassMax: 11, VelocityRange: 1, SpawnRadius: 800}))
+	}
+
+	trajectory, err := persist.OpenTrajectory(path, "csv")
+	if err != nil {
+		return err
+	}
+	if err := trajectory.Record(sim.Time, sim.Bodies()); err != nil {
+		trajectory.Close()
+		return err
+	}
+	// The first error writing the trajectory is kept, and returned again by Close
+	sim.OnStep(func(s *simulation.Simulation) { trajectory.Record(s.Time, s.Bodies()) })
+	for i := 0; i < STEPS; i++ {
+		sim.Step()
+	}
+	if err := trajectory.Close(); err != nil {
+		return err
+	}
+
+	frames := 0
+	var first, last float64
+	err = persist.ReadTrajectory(path, func(time float64, bodies []*simulation.Body) error {
+		energy := simulation.KineticEnergy(bodies) + simulation.PotentialEnergy(bodies, G)
+		if frames == 0 {
+			first = energy
+		}
+		last = energy
+		frames++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if frames != STEPS+1 {
+		return fmt.Errorf("read %v frames back, but recorded %v", frames, STEPS+1)
+	}
+	fmt.Fprintf(w, "read %v frames, the energy drifted from %.6g to %.6g (%.3g%%)\n", frames, first, last, 100*(last-first)/math.Abs(first))
+	return nil
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// Running the example keeps it working as the library changes
+func TestRun(t *testing.T) {
+	if err := run(io.Discard, filepath.Join(t.TempDir(), "trajectory.csv")); err != nil {
+		t.Fatal(err)
+	}
+}
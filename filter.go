@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Visibility filters hide bodies from view without changing the physics, e.g. to see the few massive bodies through a cloud of debris
+// Hidden bodies are not drawn and can't be clicked, but still pull on and collide with the other bodies
+
+var (
+	// Bodies lighter than this are hidden, changed with [ and ] or --minVisibleMass
+	minVisibleMass float64
+	// The categories of bodies that are hidden, set with --hide or the console
+	hiddenCategories = map[string]bool{}
+	hiddenString     string
+)
+
+// The categories bodies can be hidden by, and whether a body is in each
+var bodyCategories = map[string]func(b *simulation.Body) bool{
+	"named":   func(b *simulation.Body) bool { return b.Name != "" },
+	"unnamed": func(b *simulation.Body) bool { return b.Name == "" },
+	"fixed":   func(b *simulation.Body) bool { return b.Fixed },
+	"free":    func(b *simulation.Body) bool { return !b.Fixed },
+}
+
+// Whether a body passes the visibility filters
+func visible(b *simulation.Body) bool {
+	if b.Mass < minVisibleMass {
+		return false
+	}
+	for category := range hiddenCategories {
+		if bodyCategories[category](b) {
+			return false
+		}
+	}
+	return true
+}
+
+// Hide or show a category of bodies
+func setCategoryHidden(category string, hidden bool) error {
+	if _, ok := bodyCategories[category]; !ok {
+		return fmt.Errorf("unknown category %q, must be one of %v", category, strings.Join(categoryNames(), ", "))
+	}
+	if hidden {
+		hiddenCategories[category] = true
+	} else {
+		delete(hiddenCategories, category)
+	}
+	canvas.Fill(backgroundColor)
+	return nil
+}
+
+// Parse a comma separated list of categories to hide, e.g. from --hide
+func parseHiddenCategories(s string) error {
+	for _, category := range strings.Split(s, ",") {
+		if category = strings.TrimSpace(category); category == "" {
+			continue
+		}
+		if err := setCategoryHidden(category, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The names of the categories, in order
+func categoryNames() []string {
+	var names []string
+	for name := range bodyCategories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Raise the mass below which bodies are hidden, starting from a mass of 1 and doubling each time
+func raiseMassFilter() {
+	if minVisibleMass <= 0 {
+		minVisibleMass = 1
+	} else {
+		minVisibleMass *= 2
+	}
+	canvas.Fill(backgroundColor)
+	slog.Info("HIDING LIGHT BODIES", "minVisibleMass", minVisibleMass)
+}
+
+// Lower the mass below which bodies are hidden, halving it each time until no bodies are hidden
+func lowerMassFilter() {
+	minVisibleMass /= 2
+	if minVisibleMass < 1 {
+		minVisibleMass = 0
+		slog.Info("SHOWING BODIES OF ALL MASSES")
+	} else {
+		slog.Info("HIDING LIGHT BODIES", "minVisibleMass", minVisibleMass)
+	}
+	canvas.Fill(backgroundColor)
+}
+
+// The lines describing the active filters, shown in the HUD
+func filterStatus() []string {
+	var lines []string
+	if minVisibleMass > 0 {
+		lines = append(lines, fmt.Sprintf("HIDING MASS < %v", minVisibleMass))
+	}
+	var hidden []string
+	for _, name := range categoryNames() {
+		if hiddenCategories[name] {
+			hidden = append(hidden, strings.ToUpper(name))
+		}
+	}
+	if len(hidden) > 0 {
+		lines = append(lines, "HIDING: "+strings.Join(hidden, ", "))
+	}
+	return lines
+}
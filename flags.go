@@ -0,0 +1,278 @@
+package main
+
+import (
+	"flag"
+	"time"
+)
+
+// Define the flags setting up a simulation, which the run and simulate commands share
+func simulationFlags(fs *flag.FlagSet, helpFlag, listFlag *bool) {
+	fs.StringVar(&configPath, "config", "", "The path to a TOML config file setting defaults for any of these flags.\nIf not specified, "+defaultConfigPath()+" is used if it exists")
+	fs.Var(int32Value{&screenWidth}, "width", "The width of the window in pixels")
+	fs.Var(int32Value{&screenHeight}, "height", "The height of the window in pixels")
+	fs.Float64Var(&sim.G, "G", 100, "The gravitational constant")
+	fs.Float64Var(&sim.Timescale, "timescale", 0.25, "The initial timescale of the simulation")
+	fs.Float64Var(&sim.Softening, "softening", 0, "The softening length, which limits the force between bodies that get very close")
+	fs.BoolVar(&headlessFlag, "headless", false, "Run the simulation as fast as possible with no window, as the simulate command does")
+	fs.IntVar(&maxSteps, "steps", 0, "Stop after this many steps, saving and writing the final snapshot as when interrupted.\nIf 0, run until interrupted")
+	fs.Float64Var(&maxSimTime, "simTime", 0, "Stop once this much time has been simulated (the sum of the timescale over every step).\nIf 0, run until interrupted")
+	fs.BoolVar(&printSummaryFlag, "summary", true, "Print a summary of the run when it ends (mergers, bodies remaining, masses, energy and momentum drift...)")
+	fs.StringVar(&summaryPath, "summaryOut", "", "The path to write the summary of the run to as JSON when it ends.\nIf not specified, the summary is only printed")
+	fs.DurationVar(&progressEvery, "progressEvery", 10*time.Second, "How often runs without a window log their progress (steps, speed, time left, bodies, energy drift and virial ratio), 0 to never")
+	fs.DurationVar(&maxDuration, "duration", 0, "Stop after running for this long, e.g. 10m.\nIf 0, run until interrupted")
+	fs.StringVar(&rendererName, "renderer", DEFAULTRENDERER, "What the run command shows the simulation on, either window or null (nothing, e.g. for tests without a display)")
+	fs.BoolVar(&realtime, "realtime", false, "Keep the run command stepping at its full rate however slow drawing is, skipping frames instead of slowing down")
+	fs.StringVar(&collisionsName, "collisions", "merge", "What happens when bodies touch, one of merge, bounce or pass")
+	fs.Float64Var(&dragCoefficient, "drag", 0, "The strength of a drag force slowing every body, proportional to its velocity")
+	fs.Float64Var(&centralMass, "centralMass", 0, "The mass of a fixed point mass at the origin that pulls on every body, without being a body itself")
+	fs.Float64Var(&minVisibleMass, "minVisibleMass", 0, "Hide bodies lighter than this mass from view, without changing the physics")
+	fs.StringVar(&hiddenString, "hide", "", "A comma separated list of categories of bodies to hide from view (named, unnamed, fixed or free)")
+	fs.StringVar(&backgroundString, "background", "0,0,0", "The background color as red,green,blue")
+	fs.StringVar(&saveFilePath, "saveFile", "", "The path to the save file to use.\nIf not specified, use other flags to determine simulation behavior")
+	fs.StringVar(&saveFormat, "saveFormat", "csv", "The format to save the simulation in, one of csv (save.csv), protobuf (save.pb), rebound (save.rebound) or json (save.json)")
+	fs.BoolVar(&saveOnExit, "saveOnExit", true, "Save the state of the simulation when quitting, so work isn't lost on an accidental close")
+	fs.IntVar(&streamEvery, "streamEvery", 0, "Stream a snapshot of the simulation to stdout every this many steps, 0 to disable.\nWhile streaming, all other output goes to stderr")
+	fs.IntVar(&snapshotEvery, "snapshotEvery", 0, "Write a numbered snapshot file into --snapshotDir every this many steps, 0 to disable")
+	fs.StringVar(&snapshotDir, "snapshotDir", "snapshots", "The directory to write snapshot files into")
+	fs.IntVar(&numBodies, "numBodies", 5, "The number of bodies to add to this simulation")
+	fs.Var(rangeValue{&randomMassMin, &randomMassMax}, "massRange", "The range of masses of randomly generated bodies, as min,max")
+	fs.Float64Var(&randomVelocityRange, "velRange", 1, "The width of the range of each velocity component of randomly generated bodies, centered on zero")
+	fs.Float64Var(&randomSpawnRadius, "spawnRadius", 0, "Randomly generated bodies are spawned in a disk of this radius about the origin.\nIf 0, they are spawned over the starting view of the screen")
+	fs.Int64Var(&seed, "seed", 0, "The seed for the random number generator, so a random simulation can be repeated.\nIf 0, the current time is used")
+	fs.IntVar(&numCheckpoints, "numCheckpoints", 10, "The maximum number of in-memory checkpoints to keep")
+	fs.StringVar(&trajectoryPath, "trajectoryOut", "", "The path to a csv file to append the trajectory of every body to at each step.\nIf not specified, no trajectory is recorded")
+	fs.StringVar(&trajectoryFormat, "trajectoryFormat", "csv", "The format of the trajectory file, either csv or columnar")
+	fs.StringVar(&framesDir, "framesOut", "", "Without a window, the directory to write a numbered PNG frame of the bodies into every --framesEvery steps, e.g. to make a video.\nIf not specified, no frames are written")
+	fs.IntVar(&framesEvery, "framesEvery", 1, "Only write a frame to --framesOut every this many steps")
+	fs.Float64Var(&frameView.X, "frameX", 0, "The x coordinate in the simulation at the center of the frames written to --framesOut")
+	fs.Float64Var(&frameView.Y, "frameY", 0, "The y coordinate in the simulation at the center of the frames written to --framesOut")
+	fs.Float64Var(&frameView.Zoom, "frameZoom", 1, "The zoomscale of the frames written to --framesOut, the distance in the simulation across each pixel")
+	fs.BoolVar(&frameTrails, "frameTrails", false, "Leave fading trails behind the bodies in the frames written to --framesOut, as with X in the window")
+	fs.BoolVar(&markUnbound, "markUnbound", true, "Mark the bodies escaping the system with a ring in the window")
+	fs.BoolVar(&logUnbound, "logUnbound", false, "Log each body as it starts escaping the system")
+	fs.Float64Var(&cullUnboundDistance, "cullUnbound", 0, "Remove bodies escaping the system once this far from the rest of it, 0 to never remove them")
+	fs.Float64Var(&approachDistance, "approachDistance", 50, "Predict the close approaches between the most massive bodies passing within this distance of each other, shown in the HUD, 0 to not predict them")
+	fs.IntVar(&approachSteps, "approachSteps", 1000, "Only predict the close approaches within this many steps")
+	fs.IntVar(&approachBodies, "approachBodies", 20, "Only predict the close approaches between this many of the most massive bodies")
+	fs.BoolVar(&logApproaches, "logApproaches", false, "Log each close approach as it is first predicted")
+	fs.BoolVar(&watchdogEnabled, "watchdog", true, "Pause the simulation when a body's position, velocity or mass goes NaN or infinite, logging the bodies")
+	fs.StringVar(&watchdogPath, "watchdogOut", "watchdog.csv", "The path to a csv file to write the last steps of every body to when the watchdog finds a NaN or infinite body.\nIf empty, they are not written")
+	fs.Float64Var(&chaosPerturbation, "chaos", 0, "Measure how chaotic the simulation is, by running a shadow copy with one body moved this far and logging how fast they diverge.\nIf 0, chaos is not measured")
+	fs.IntVar(&chaosEvery, "chaosEvery", 100, "Log the divergence of the shadow copy every this many steps")
+	fs.StringVar(&diagnosticsPath, "diagnosticsOut", "", "The path to a csv file to write the total energy, momentum and angular momentum to at each step.\nIf not specified, they are not written")
+	fs.IntVar(&diagnosticsEvery, "diagnosticsEvery", 1, "Only write the totals to --diagnosticsOut every this many steps")
+	fs.StringVar(&phasePath, "phaseOut", "", "The path to a csv file to write phase space samples of every body to (x, xVel, y, yVel, and distance and radial speed from the most massive body).\nIf not specified, they are not written")
+	fs.IntVar(&phaseEvery, "phaseEvery", 1, "Only write phase space samples to --phaseOut every this many steps")
+	fs.StringVar(&eventsPath, "eventsOut", "", "The path to a JSON lines file to write every merge and bounce to, with the ids, masses and velocities of the bodies.\nIf not specified, no events are written")
+	fs.DurationVar(&metricsLogEvery, "metricsLogEvery", 0, "Log the metrics of the simulation (step time, bodies, collisions, energy) this often, e.g. 10s, 0 to disable")
+	fs.StringVar(&metricsPath, "metricsOut", "", "The path to a csv file to write the metrics of every step to.\nIf not specified, no metrics are written")
+	fs.StringVar(&metricsAddr, "metricsAddr", "", "The address to serve the metrics to Prometheus on, at /metrics, e.g. localhost:9100.\nIf not specified, the metrics are not served")
+	fs.StringVar(&apiAddr, "apiAddr", "", "The address to serve the HTTP control API on, e.g. localhost:8080.\nIf not specified, the API is not served")
+	fs.StringVar(&grpcAddr, "grpcAddr", "", "The address to serve the gRPC SimulationService on, e.g. localhost:50051.\nIf not specified, the service is not served")
+	fs.StringVar(&spectateAddr, "spectateAddr", "", "The address to listen for spectators on, who watch the simulation with the spectate command, e.g. :7000.\nIf not specified, no spectators can connect")
+	fs.StringVar(&workersString, "workers", "", "Experimental: a comma separated list of the addresses of worker processes (see the worker command) to find the gravity with, for very many bodies.\nIf not specified, the gravity is found here")
+	fs.Float64Var(&ghostDistance, "ghostDistance", 0, "With --workers, bodies further than this from a worker's domain pull on it as one body per domain, so less is sent.\nIf 0, every body is sent to every worker and the gravity is exact")
+	fs.StringVar(&benchmarkString, "benchmark", "", "A comma separated list of timescales to run the starting bodies at for --simTime (by default 100), instead of simulating them,\nthen print a table of the runtime against the energy error of each and quit")
+	fs.IntVar(&verifySteps, "verify", 0, "Run the starting bodies twice for this many steps instead of simulating them, comparing checksums of the two after every step,\nthen quit, exiting with 1 if they ever differ. If 0, the simulation runs as usual")
+	fs.StringVar(&verifyThreadsString, "verifyThreads", "", "With --verify, the numbers of threads to give the two runs, e.g. 1,8.\nIf not specified, both runs have every thread")
+	fs.StringVar(&horizonsPaths, "horizons", "", "A comma separated list of JPL Horizons vector table files (in CSV format) to load bodies from")
+	fs.Float64Var(&horizonsScale, "horizonsScale", 100, "The number of pixels per AU when loading Horizons files")
+	fs.Float64Var(&horizonsMass, "horizonsSolarMass", 1000, "The simulation mass of one solar mass when loading Horizons files")
+	fs.StringVar(&scriptPath, "script", "", "The path to a Starlark script that generates bodies and schedules events")
+	fs.StringVar(&scenarioName, "scenario", "", "The name of an embedded scenario to load, see --listScenarios")
+	fs.BoolVar(listFlag, "listScenarios", false, "List the embedded scenarios, then quit")
+	fs.BoolVar(helpFlag, "h", false, "Display help on this program, then quit")
+}
+
+// The help on the flags defined by simulationFlags
+func simulationFlagsHelp() string {
+	return `Flags:
+	When running this program, some flags can be specified to change starting configurations
+	--config : The path to a TOML config file setting defaults for any of the flags below, keyed by flag name
+		Defaults to ` + defaultConfigPath() + ` if it exists. Flags on the command line override the config file
+	Any flag can also be set by an environment variable of its name in capitals after GRAVITYSIM_, e.g. GRAVITYSIM_NUMBODIES=20
+		The command line overrides the environment, which overrides the config file
+	--width, --height : The size of the window in pixels
+		Defaults to 1200x800
+	--G : The gravitational constant
+		Defaults to 100
+	--timescale : The initial timescale of the simulation
+		Defaults to 0.25
+	--softening : The softening length, which limits the force between bodies that get very close
+		Defaults to 0 (no softening)
+	--headless : Run the simulation as fast as possible with no window (and no render loop), as the simulate command does
+		Defaults to false. Use with --steps for batch runs, e.g. --headless --steps=1000000 --trajectoryOut=run.csv
+	--steps : Stop after this many steps, then save and write the final snapshot as when interrupted
+		Defaults to 0 (run until interrupted, or the window is closed)
+	--simTime : Stop once this much time has been simulated (the sum of the timescale over every step), as with --steps
+		Defaults to 0 (no limit)
+	--duration : Stop after running for this long on the wall clock, e.g. 10m or 1h30m, as with --steps
+		Defaults to 0 (no limit)
+	--summary : Print a summary of the run when it ends: the steps and time taken, the number of mergers, the bodies remaining,
+		the distribution of their masses, the drift in energy and momentum and the largest body
+		Defaults to true, use --summary=false to disable
+	--summaryOut : The path to write the summary of the run to as JSON when it ends, e.g. to collect the results of batch runs
+		Note if this flag is not set, the summary is only printed
+	--progressEvery : How often runs without a window (simulate, or --headless) log a line of progress: the steps done,
+		steps per second, the time left until the nearest run limit, the bodies remaining, the drift in energy and the virial ratio
+		Defaults to 10s, 0 to never log progress
+	--renderer : What the run command shows the simulation on, either window or null
+		Defaults to ` + DEFAULTRENDERER + `. The null renderer shows nothing, running the whole program without a window or display,
+		e.g. to test it in CI. Without a window nothing can unpause the simulation, so it starts running
+	--realtime : Keep the run command stepping at its full rate, one step every 16ms, however slow drawing is.
+		Steps held up by drawing are made up straight after, so frames are skipped instead of the simulation slowing down.
+		The HUD shows the simulated time per second against the full rate, and the fraction of steps not drawn.
+		If the steps themselves take too long the simulation still slows down, as no skipping can help
+		Defaults to false
+	--collisions : What happens when bodies touch, one of merge (into one body), bounce (elastically) or pass (through each other)
+		Defaults to merge. This can also be changed while running with N
+	--drag : The strength of a drag force slowing every body, i.e. each body accelerates by -drag times its velocity
+		Defaults to 0 (no drag)
+	--centralMass : The mass of a fixed point mass at the origin pulling on every body, e.g. to stand in for a galactic center
+		Defaults to 0 (no central mass). Unlike a fixed body it can't be collided with, and it is not drawn
+	--minVisibleMass : Hide bodies lighter than this mass from view, the physics is unchanged
+		Defaults to 0 (all bodies are shown). This can also be changed while running with [ and ]
+	--hide : A comma separated list of categories of bodies to hide from view, from named, unnamed, fixed and free
+		Defaults to none. Categories can also be hidden and shown while running with the console
+	--background : The background color as red,green,blue
+		Defaults to 0,0,0
+	--saveFile : The path to the csv file to load into the simulation
+		Note if this flag is not set, the simulation will be loaded with a random initial configuration
+		Files ending in .pb are loaded as protobuf (including the simulation settings),
+		files ending in .rebound as REBOUND snapshots, and anything else as csv
+		A save file of - reads a csv save from stdin
+	--saveFormat : The format to save the simulation in, one of csv (to save.csv), protobuf (to save.pb), rebound (to save.rebound)
+		or json (to save.json)
+		Only protobuf saves hold the settings and random number generator, so only they resume exactly
+		Defaults to csv
+	--saveOnExit : Save the state of the simulation (as with O) when the window is closed or the program is interrupted
+		Defaults to true, use --saveOnExit=false to disable
+	--scenario : The name of a scenario embedded in the program to load, e.g. "solar"
+		Note this flag is ignored if --saveFile is set
+	--listScenarios : List the names and descriptions of all embedded scenarios, then quit
+	--script : The path to a Starlark script to run at startup, which can generate bodies and schedule events
+		If no other starting configuration is given, the simulation starts empty and the script adds all bodies
+		If the protobuf save file given was made by the same script, the script's scheduled events are restored instead
+	--streamEvery : Stream a csv snapshot of the simulation to stdout every this many steps
+		Defaults to 0 (no streaming). While streaming, all other output goes to stderr so it does not corrupt the stream
+	--snapshotEvery : Write a numbered snapshot file (in the --saveFormat format) into --snapshotDir every this many steps
+		Defaults to 0 (no snapshots). Files are named by step, e.g. snapshots/step00000100.csv
+	--snapshotDir : The directory to write snapshot files into, created if it doesn't exist
+		Defaults to snapshots
+	--numBodies : An integer to specify the number of bodies to randomly seed when starting this simulation
+		Defaults to 5
+	--massRange : The range of masses of randomly generated bodies, as min,max
+		Defaults to 1,11
+	--velRange : The width of the range of each velocity component of randomly generated bodies, centered on zero
+		Defaults to 1, i.e. each component is between -0.5 and 0.5
+	--spawnRadius : Spawn randomly generated bodies in a disk of this radius about the origin
+		Defaults to 0, which spawns bodies over the starting view of the screen instead
+	--seed : The seed for the random number generator, so a random simulation can be repeated exactly
+		Defaults to 0, which uses the current time. The seed used is printed at startup and with P
+	--numCheckpoints : The maximum number of in-memory checkpoints to keep, the oldest is discarded when full
+		Defaults to 10
+	--trajectoryOut : The path to a csv file to append one row per body per step to (time, id, x, y, xVel, yVel, mass)
+		Note if this flag is not set, no trajectory is recorded
+	--trajectoryFormat : The format of the trajectory file, either csv or columnar (a chunked binary format for long runs)
+		Defaults to csv
+	--framesOut : Without a window (the simulate command or --headless), the directory to write a numbered PNG frame of the bodies
+		into, e.g. frames/frame00000000.png, drawn as in the window with the bodies' own colors, on servers without a display.
+		The frames are the size of the window, set with --width and --height. To make a video of a run instead, record it
+		with --trajectoryOut and replay it with e.g. render --out=video.mp4 (see render -h), which encodes it with ffmpeg
+		Note if this flag is not set, no frames are written
+	--framesEvery : Only write a frame to --framesOut every this many steps
+		Defaults to 1 (every step)
+	--frameX, --frameY : The position in the simulation at the center of the frames
+		Defaults to 0,0
+	--frameZoom : The zoomscale of the frames, the distance in the simulation across each pixel
+		Defaults to 1
+	--frameTrails : Leave fading trails behind the bodies in the frames, as with X in the window
+		Defaults to false
+	--markUnbound : Mark the bodies escaping the system with a red ring in the window. A body is escaping if it has enough energy
+		to get away from the rest of the bodies and is moving away from them. The number escaping is shown in the HUD
+		Defaults to true, use --markUnbound=false to disable
+	--logUnbound : Log each body as it starts escaping the system, with its energy and distance from the rest of the system
+		Defaults to false
+	--cullUnbound : Remove bodies escaping the system once they are this far from the center of mass of the rest of it,
+		so bodies that will never come back don't slow down the simulation. Each body removed is logged
+		Defaults to 0 (never removed)
+	--approachDistance : After every step, predict the next close approaches between the most massive bodies, those passing
+		within this distance (between their centers) of each other, so you know where to look. The soonest are shown in the HUD,
+		e.g. "APPROACH: 2 & 7 WITHIN 15 IN ~300 STEPS". Each pair is treated as if they were the only two bodies, so the
+		predictions are estimates, and get better as the approach gets closer
+		Defaults to 50, use --approachDistance=0 to not predict them
+	--approachSteps : Only predict the close approaches within this many steps at the current timescale
+		Defaults to 1000
+	--approachBodies : Only predict the close approaches between this many of the most massive bodies
+		Defaults to 20
+	--logApproaches : Log each close approach as it is first predicted, with the bodies, distance and steps until it
+		Defaults to false
+	--watchdog : After every step, check for bodies whose position, velocity or mass has gone NaN or infinite (e.g. from two
+		bodies at the same place with no softening). When one is found the simulation is paused, a warning is shown in the window,
+		and the bodies are logged with their state before the step. Without a window or an API to unpause it, the run is
+		stopped and saved, and exits with 1. Each body is only reported once, so unpausing carries on with it
+		Defaults to true, use --watchdog=false to disable
+	--watchdogOut : The path to a csv file to write the last 20 steps of every body to when the watchdog finds a body,
+		in the --trajectoryOut format, replacing the file if it exists
+		Defaults to watchdog.csv, use --watchdogOut= to not write them
+	--chaos : Measure how chaotic the simulation is, by running a shadow copy alongside it with one body moved this far, e.g. 1e-8
+		How fast the two diverge is logged as a DIVERGENCE message, with an estimate of the largest Lyapunov exponent
+		Defaults to 0 (not measured). The shadow is stepped along with the simulation, so each step takes twice as long
+	--chaosEvery : Log the divergence of the shadow copy every this many steps
+		Defaults to 100
+	--diagnosticsOut : The path to a csv file to write the totals over all bodies to at each step, for plotting how well they are
+		conserved: step, time, kinetic, potential and total energy, px, py (the momentum), and angular momentum about the origin
+		and about the barycenter (which doesn't change as the system moves, see the README)
+		Note if this flag is not set, the totals are not written. Finding the potential energy takes as long as a step
+	--diagnosticsEvery : Only write the totals to --diagnosticsOut every this many steps, to keep long runs fast and the file small
+		Defaults to 1 (every step)
+	--phaseOut : The path to a csv file to write phase space samples of every body to at each step, for plotting e.g. x against xVel,
+		or r against rVel: one row per body per step of time, id, x, xVel, y, yVel, r and rVel, where r is the distance
+		from the most massive body and rVel the speed away from it. Note if this flag is not set, no samples are written
+	--phaseEvery : Only write phase space samples to --phaseOut every this many steps
+		Defaults to 1 (every step)
+	--eventsOut : The path to a JSON lines file to write every merge and bounce to, one object per line with the step and time,
+		and the id, name, mass, position and velocity of both bodies (and for merges, the body they merged into)
+		Note if this flag is not set, no events are written. Finding bounces checks every pair of bodies, slowing the bounce mode
+	--metricsLogEvery : Log the metrics of the simulation (steps, collisions, time, step time, bodies and energy) this often, e.g. 10s
+		Defaults to 0 (not logged). Any metrics make each step slower, as the energy is found over every pair of bodies
+	--metricsOut : The path to a csv file to write the metrics of every step to, one row per step
+		Note if this flag is not set, no metrics are written
+	--metricsAddr : The address to serve the metrics to Prometheus on, at /metrics, e.g. localhost:9100
+		Note if this flag is not set, the metrics are not served
+	--apiAddr : The address to serve the HTTP control API on, e.g. localhost:8080, to drive the simulation from scripts while it runs:
+		GET /state, POST /pause, POST /resume, POST /params, POST /bodies, DELETE /bodies/id and POST /save (see the README)
+		Note if this flag is not set, the API is not served
+	--grpcAddr : The address to serve the gRPC SimulationService (see statepb/service.proto) on, e.g. localhost:50051, to step,
+		query and stream the state of the simulation from other languages, with the same State messages as protobuf saves
+		Note if this flag is not set, the service is not served
+	--spectateAddr : The address to listen for spectators on, e.g. :7000, who each watch the simulation in their own window with
+		the spectate command, panning and zooming their own view but unable to change the simulation, e.g. for a classroom
+		Note if this flag is not set, no spectators can connect
+	--workers : Experimental: a comma separated list of the addresses of worker processes, started with the worker command on this
+		or other machines, to share finding the gravity between very many bodies with. Each step the bodies are split into a
+		strip per worker, and each worker finds the gravity on its strip. Everything else still happens here
+		Note if this flag is not set, the gravity is found here
+	--ghostDistance : With --workers, each worker is sent the bodies within this distance of its strip one by one, and the rest
+		of each other strip as one body of their total mass at their center of mass, which is approximate but sends much less
+		Defaults to 0 (every body is sent to every worker, so the gravity is exact)
+	--benchmark : A comma separated list of timescales, e.g. 1,0.5,0.25,0.1, to run the starting bodies at instead of simulating
+		them, then print a table of the runtime of each against how far its total energy strayed, and quit. Each run is a copy
+		of the starting bodies run for the same simulated time, --simTime (by default 100), to help pick a timescale
+		Note if this flag is not set, the simulation runs as usual. Merges lose energy, so use --collisions=pass to see only
+		the error of the integrator. An interrupt or --duration stops the benchmark early, printing the runs finished so far
+	--verify : Run the starting bodies twice for this many steps instead of simulating them, comparing checksums of the exact
+		positions, velocities, masses and radii of the two runs after every step, then quit. If the runs ever differ, the first
+		step they differ at and the bodies that differ are logged and the program exits with 1, e.g. for checking in CI that
+		runs stay reproducible. With --workers the second run finds the gravity here, checking the workers against it
+		Defaults to 0 (the simulation runs as usual)
+	--verifyThreads : With --verify, the numbers of threads (GOMAXPROCS) to give the two runs, e.g. 1,8
+		Note if this flag is not set, both runs have every thread
+	--horizons : A comma separated list of JPL Horizons vector table files to load bodies from, one body per file
+		Each file must be a Vector Table with "CSV format" selected, the first state vector in each file is used
+		Note this flag is ignored if --saveFile or --scenario is set
+	--horizonsScale : The number of pixels per AU when loading Horizons files
+		Defaults to 100
+	--horizonsSolarMass : The simulation mass of one solar mass when loading Horizons files
+		Defaults to 1000` + LOGGINGHELP
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// In the run command, the physics runs in its own goroutine, stepping every FRAMETIME milliseconds (unless paused),
+// and hands each frame to the render loop as a simulation.Snapshot over a channel. The snapshots are copies, so can be
+// drawn while the next step is taken. Only the newest frame is kept, so slow drawing (trails, huge numbers of bodies)
+// drops frames rather than slowing the simulation, and slow steps just mean the last frame is drawn again
+//
+// Inputs, overlays and console commands still change the simulation directly, so the render loop holds physicsLock
+// while doing so, as the physics goroutine does for each step
+//
+// The render loop shows its frames with a render.Renderer, which is the window, or render.Null to run the whole loop
+// without a display (with --renderer=null). The inputs and overlays need the window, so are left out without one
+
+var (
+	// Held by the physics goroutine while stepping, and by the render loop while using the simulation
+	physicsLock sync.Mutex
+	// The newest frame from the physics goroutine, not yet taken by the render loop
+	frames = make(chan simulation.Snapshot, 1)
+	// The index of the body the view follows, or -1 if the view doesn't follow a body
+	followedBody = -1
+)
+
+// Step the simulation every FRAMETIME milliseconds until ctx is cancelled, publishing a frame after each step
+// A frame is published even while paused, so changes made by the inputs (e.g. dragging a body) are drawn
+// In realtime mode, the steps missed since the last tick are made up, and only the frame after the last is published
+func runPhysics(ctx context.Context) {
+	ticker := time.NewTicker(FRAMETIME * time.Millisecond)
+	defer ticker.Stop()
+	var pacer realtimePacer
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		physicsLock.Lock()
+		switch {
+		case paused:
+			pacer.stop()
+		case realtime:
+			// Pausing (e.g. by the watchdog) or reaching a run limit stops the steps being made up
+			for steps := pacer.due(time.Now()); steps > 0 && !paused && !shuttingDown(); steps-- {
+				timeStep()
+			}
+		default:
+			timeStep()
+		}
+		frame := sim.Snapshot()
+		physicsLock.Unlock()
+		publishFrame(frame)
+	}
+}
+
+// Hand a frame to the render loop, replacing any frame it hasn't taken yet
+// There is only one publisher, so once the old frame is taken there is always room for the new one
+func publishFrame(frame simulation.Snapshot) {
+	select {
+	case <-frames:
+	default:
+	}
+	frames <- frame
+}
+
+// The newest frame from the physics goroutine, or last if there hasn't been a new one since
+func takeFrame(last simulation.Snapshot) simulation.Snapshot {
+	select {
+	case frame := <-frames:
+		return frame
+	default:
+		return last
+	}
+}
+
+// Center the view on the followed body, as it is in the bodies being drawn
+// If the body merged into another body the view follows that body instead, and if it was removed the view stops following
+func followView(bodies []*simulation.Body) {
+	if followedBody < 0 {
+		return
+	}
+	if survivor := simulation.Survivor(bodies, followedBody); survivor != followedBody {
+		if survivor < 0 {
+			slog.Info("STOPPED FOLLOWING BODY", "id", followedBody)
+		} else {
+			slog.Info("FOLLOWING THE BODY IT MERGED INTO", "id", followedBody, "into", survivor)
+		}
+		followedBody = survivor
+		if survivor < 0 {
+			return
+		}
+	}
+	camera.X, camera.Y = bodies[followedBody].Pos.X, bodies[followedBody].Pos.Y
+}
+
+// Run the physics and show its frames with r, until asked to shut down
+// handleInputs is run at the start of each frame and drawOverlays at the end, both with physicsLock held
+// drawOverlays draws over the canvas, returning the finished frame to show
+// Without a window both are nil: nothing handles inputs, and the canvas is shown as it is
+func renderLoop(r render.Renderer, handleInputs func(), drawOverlays func() *render.Canvas) error {
+	// Once asked to shut down, the physics goroutine finishes its step and stops before the final save
+	physicsDone := make(chan struct{})
+	go func() {
+		runPhysics(shutdownContext)
+		close(physicsDone)
+	}()
+	defer func() { <-physicsDone }()
+	frame := sim.Snapshot()
+
+	// Game loop, until the window is closed or the program is interrupted
+	for {
+		// At start of each frame, handle any inputs, stopping if asked to shut down
+		frame = takeFrame(frame)
+		physicsLock.Lock()
+		if handleInputs != nil {
+			handleInputs()
+		}
+		followView(frame.Bodies)
+		physicsLock.Unlock()
+		if shuttingDown() {
+			return nil
+		}
+
+		// Before drawing bodies on top, do something (set black or decay) to the background
+		if pixeldecay {
+			if !paused {
+				canvas.Decay(pixelDecayRate)
+			}
+		} else {
+			canvas.Fill(backgroundColor)
+		}
+
+		// Then, draw the bodies on top, from the frame so the physics can carry on meanwhile
+		if realtime {
+			drawnSpeed.drew(frame, time.Now())
+		}
+		for _, b := range frame.Bodies {
+			drawBody(b)
+		}
+
+		// Draw any overlays over the bodies, then actually show the frame and carry on
+		// The overlays show the simulation as it is now (e.g. the selected body), so need the lock
+		shown := canvas
+		if drawOverlays != nil {
+			physicsLock.Lock()
+			shown = drawOverlays()
+			physicsLock.Unlock()
+		}
+		if err := r.Present(shown); err != nil {
+			return err
+		}
+
+		time.Sleep(FRAMETIME * time.Millisecond)
+	}
+}
+
+// Run the render loop with a renderer that shows nothing, so the whole program can run with no window or display
+// Nothing can unpause the simulation without a window, so it starts running
+func runNullRenderer() error {
+	slog.Info("RUNNING WITH THE NULL RENDERER, INTERRUPT TO QUIT")
+	paused = false
+	r := &render.Null{}
+	defer r.Close()
+	if err := renderLoop(r, nil, nil); err != nil {
+		return err
+	}
+	slog.Info("RENDERED FRAMES", "frames", r.Frames)
+	return nil
+}
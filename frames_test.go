@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The render loop runs the physics and presents its frames with the null renderer, with no window, until shut down
+func TestRenderLoopNull(t *testing.T) {
+	sim = simulation.New(simulation.Params{G: 1, Timescale: 0.01, CollisionMode: simulation.COLLISIONPASS}, 1)
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{X: -50}, Mass: 100, Radius: 10})
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{X: 50}, Mass: 100, Radius: 10})
+	canvas = render.NewCanvas(screenWidth, screenHeight)
+	paused = false
+
+	// Shut down after the time of about 20 frames, as an interrupt or --duration would
+	var cancel context.CancelFunc
+	shutdownContext, cancel = context.WithTimeout(context.Background(), 20*FRAMETIME*time.Millisecond)
+	t.Cleanup(func() {
+		cancel()
+		shutdownContext = context.Background()
+	})
+
+	r := &render.Null{}
+	if err := renderLoop(r, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if r.Frames < 2 {
+		t.Errorf("presented %v frames, expected several", r.Frames)
+	}
+	if sim.Steps < 2 {
+		t.Errorf("the simulation took %v steps, expected several", sim.Steps)
+	}
+	// The bodies start at rest, so have moved towards each other if the simulation stepped
+	if b := sim.Bodies()[0]; b.Pos.X <= -50 || b.Vel.X <= 0 {
+		t.Errorf("body %+v hasn't been pulled towards the other body", b)
+	}
+}
@@ -1,5 +1,19 @@
 module hmcalister/gravity_simulation
 
-go 1.19
+go 1.21
 
-require github.com/veandco/go-sdl2 v0.4.28
+require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/veandco/go-sdl2 v0.4.28
+	go.starlark.net v0.0.0-20231121155337-90ade8b19d09
+	golang.org/x/image v0.18.0
+	google.golang.org/grpc v1.64.1
+	google.golang.org/protobuf v1.33.0
+)
+
+require (
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+)
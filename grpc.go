@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"hmcalister/gravity_simulation/simulation"
+	"hmcalister/gravity_simulation/statepb"
+)
+
+// With --grpcAddr, the simulation is served as the gRPC SimulationService (see statepb/service.proto), so it can be
+// stepped, queried and streamed from any language with gRPC, using the same State messages as protobuf save files
+// Like the HTTP API, requests are handled with physicsLock held, so they happen between steps
+
+var (
+	// Set by the --grpcAddr flag
+	grpcAddr string
+	// The clients streaming the state, guarded by physicsLock
+	grpcSubscribers = map[*grpcSubscriber]bool{}
+)
+
+// A client streaming the state every few steps
+type grpcSubscriber struct {
+	every int
+	// The newest snapshot not yet sent, only ever holding one
+	snapshots chan *statepb.Snapshot
+}
+
+// The implementation of statepb.SimulationServiceServer
+type grpcServer struct {
+	statepb.UnimplementedSimulationServiceServer
+}
+
+// Start serving the gRPC service on --grpcAddr in the background
+// Like the metrics, failing to serve the service is not fatal - the simulation simply runs without it
+func setupGRPC() {
+	listener, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		slog.Warn("COULD NOT SERVE GRPC", "addr", grpcAddr, "err", err)
+		return
+	}
+	server := grpc.NewServer()
+	statepb.RegisterSimulationServiceServer(server, grpcServer{})
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			slog.Warn("COULD NOT SERVE GRPC", "addr", grpcAddr, "err", err)
+		}
+	}()
+	sim.OnStep(publishToSubscribers)
+	slog.Info("SERVING GRPC", "addr", listener.Addr().String())
+}
+
+// The current state of the simulation, as sent by the service
+func grpcSnapshot() *statepb.Snapshot {
+	return &statepb.Snapshot{Steps: int64(sim.Steps), State: stateToProto()}
+}
+
+// Send the state to every subscriber due one this step, replacing any snapshot they haven't been sent yet
+// Only called after a step, with physicsLock held
+func publishToSubscribers(s *simulation.Simulation) {
+	var snapshot *statepb.Snapshot
+	for subscriber := range grpcSubscribers {
+		if s.Steps%subscriber.every != 0 {
+			continue
+		}
+		if snapshot == nil {
+			snapshot = grpcSnapshot()
+		}
+		select {
+		case <-subscriber.snapshots:
+		default:
+		}
+		subscriber.snapshots <- snapshot
+	}
+}
+
+// Run f with physicsLock held, unless the simulation is shutting down
+func withPhysicsLock(f func()) error {
+	physicsLock.Lock()
+	defer physicsLock.Unlock()
+	if shuttingDown() {
+		return status.Error(codes.Unavailable, "the simulation is shutting down")
+	}
+	f()
+	return nil
+}
+
+func (grpcServer) GetState(ctx context.Context, request *statepb.GetStateRequest) (*statepb.Snapshot, error) {
+	var snapshot *statepb.Snapshot
+	err := withPhysicsLock(func() { snapshot = grpcSnapshot() })
+	return snapshot, err
+}
+
+// Steps are taken all at once with physicsLock held, so the window freezes while taking many
+func (grpcServer) Step(ctx context.Context, request *statepb.StepRequest) (*statepb.Snapshot, error) {
+	if request.Steps == 0 {
+		return nil, status.Error(codes.InvalidArgument, "steps must be at least 1")
+	}
+	var snapshot *statepb.Snapshot
+	err := withPhysicsLock(func() {
+		for i := uint32(0); i < request.Steps; i++ {
+			timeStep()
+		}
+		snapshot = grpcSnapshot()
+	})
+	return snapshot, err
+}
+
+func (grpcServer) SetPaused(ctx context.Context, request *statepb.SetPausedRequest) (*statepb.Settings, error) {
+	var settings *statepb.Settings
+	err := withPhysicsLock(func() {
+		paused = request.Paused
+		slog.Info("GRPC SET PAUSED", "paused", paused)
+		settings = stateToProto().Settings
+	})
+	return settings, err
+}
+
+// Stream the state until the client stops listening or the simulation shuts down
+func (grpcServer) Subscribe(request *statepb.SubscribeRequest, stream statepb.SimulationService_SubscribeServer) error {
+	if request.Every == 0 {
+		return status.Error(codes.InvalidArgument, "every must be at least 1")
+	}
+	subscriber := &grpcSubscriber{every: int(request.Every), snapshots: make(chan *statepb.Snapshot, 1)}
+	if err := withPhysicsLock(func() { grpcSubscribers[subscriber] = true }); err != nil {
+		return err
+	}
+	defer func() {
+		physicsLock.Lock()
+		delete(grpcSubscribers, subscriber)
+		physicsLock.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-shutdownContext.Done():
+			return status.Error(codes.Unavailable, "the simulation is shutting down")
+		case snapshot := <-subscriber.snapshots:
+			if err := stream.Send(snapshot); err != nil {
+				return err
+			}
+		}
+	}
+}
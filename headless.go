@@ -0,0 +1,60 @@
+//go:build !sdl || headless
+
+package main
+
+// The window (and with it SDL and cgo) is only built with the sdl tag, so by default the program builds on any machine,
+// e.g. servers, or for using the library for analysis, without SDL installed. The headless tag leaves the window out
+// even with the sdl tag. This build runs simulate by default, see simulate.go. The run command is still there,
+// but only with the null renderer
+
+// The command run when no other command is given
+const DEFAULTCOMMAND = "simulate"
+
+// There is no window in this build, so the run command can only use the null renderer
+const DEFAULTRENDERER = "null"
+
+// The help for the run command, printed with -h
+func runHelp() string {
+	return `
+Gravity Simulation - run
+Usage:
+	./gravity_simulation run [flags]
+
+	This program was built without the window (without -tags sdl), so the run command runs the render loop
+	with the null renderer, which shows nothing, until it is interrupted (e.g. with Ctrl+C). This runs the program
+	just as it runs in a window, e.g. to test it where there is no display. To run as fast as possible use --headless
+	(or the simulate command), and to get the window build with "go build -tags sdl ."
+
+` + simulationFlagsHelp()
+}
+
+// There is no window in this build, so the run command runs the render loop with the null renderer
+func runCommand(args []string) {
+	setupSimulation("run", args, runHelp())
+	if headlessFlag {
+		runHeadless()
+		return
+	}
+
+	catchInterrupts()
+	if err := runNullRenderer(); err != nil {
+		fatal("RENDERING FAILED", "err", err)
+	}
+	if err := shutdown(); err != nil {
+		fatal("COULD NOT SAVE BEFORE QUITTING", "err", err)
+	}
+	exitIfWatchdogStopped()
+}
+
+// There is no window to set up
+func setupDisplay() {}
+
+// There are no controls without a window, so the [keys] table of the config file is ignored
+func loadKeymap(keys map[string]any) error {
+	return nil
+}
+
+// There is no window in this build to spectate in
+func spectateCommand(args []string) {
+	fatal("THE SPECTATE COMMAND NEEDS THE WINDOW, BUILD WITH go build -tags sdl .")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log/slog"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// A short history of the states before each step is kept, so the simulation can be stepped backward a frame at a time
+// e.g. to look at a close encounter again. This is separate from the checkpoints, which are only stored on request
+// Stepping forward again takes the same steps again, which the trajectory, diagnostics and other output files already
+// have, so they are only written the first time each step is taken (see replayingStep)
+
+// The number of steps that can be stepped backward
+const STEPHISTORY = 100
+
+var (
+	// The states before each of the most recent steps, most recent last
+	stepHistory []checkpoint
+	// The most steps the simulation has taken, so steps taken again after stepping backward can be told apart
+	furthestStep int
+)
+
+// Whether the step just taken (e.g. while in an OnStep callback) was taken before, and is being taken again after
+// stepping backward. The output files already have such steps, so they are not written again
+func replayingStep(s *simulation.Simulation) bool {
+	return s.Steps <= furthestStep
+}
+
+// Record the state before a step
+func recordStep() {
+	if len(stepHistory) >= STEPHISTORY {
+		stepHistory = stepHistory[len(stepHistory)-STEPHISTORY+1:]
+	}
+	stepHistory = append(stepHistory, takeCheckpoint())
+}
+
+// Go back to the state before the most recent step
+func stepBackward() {
+	if len(stepHistory) == 0 {
+		slog.Info("NO EARLIER STEP TO GO BACK TO")
+		return
+	}
+	finishEdit()
+	stepHistory[len(stepHistory)-1].restore()
+	stepHistory = stepHistory[:len(stepHistory)-1]
+	canvas.Fill(backgroundColor)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Stepping backward restores the step and merge counts, and the steps taken again are known to be replays
+func TestStepBackward(t *testing.T) {
+	sim = simulation.New(simulation.Params{G: 1, Timescale: 1, CollisionMode: simulation.COLLISIONMERGE}, 1)
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{X: -3}, Vel: simulation.Vec2{X: 1}, Mass: 10, Radius: 3})
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{X: 3}, Vel: simulation.Vec2{X: -1}, Mass: 10, Radius: 3})
+	canvas = render.NewCanvas(10, 10)
+	stepHistory, furthestStep = nil, 0
+	var replayed []bool
+	sim.OnStep(func(s *simulation.Simulation) { replayed = append(replayed, replayingStep(s)) })
+
+	for i := 0; i < 3; i++ {
+		timeStep()
+	}
+	if sim.Merges != 1 {
+		t.Fatalf("%v merges, want the bodies to have merged", sim.Merges)
+	}
+	for i := 0; i < 2; i++ {
+		stepBackward()
+	}
+	if sim.Steps != 1 || sim.Merges != 0 || sim.Time != 1 {
+		t.Errorf("stepped back to step %v at %v with %v merges, want step 1 at 1 with none", sim.Steps, sim.Time, sim.Merges)
+	}
+
+	for i := 0; i < 3; i++ {
+		timeStep()
+	}
+	want := []bool{false, false, false, true, true, false}
+	for i := range want {
+		if replayed[i] != want[i] {
+			t.Errorf("steps were replays %v, want %v", replayed, want)
+			break
+		}
+	}
+}
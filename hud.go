@@ -0,0 +1,61 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The HUD is a small panel of status lines in the bottom right corner of the window, always shown
+
+// The lines of the HUD
+func hudLines() []string {
+	lines := []string{
+		fmt.Sprintf("TIME: %.2f", sim.Time),
+		fmt.Sprintf("G: %.2f", sim.G),
+		"COLLISIONS: " + strings.ToUpper(simulation.CollisionModeNames[sim.CollisionMode]),
+		angularMomentumStatus(),
+		virialStatus(),
+	}
+	lines = append(lines, filterStatus()...)
+	lines = append(lines, realtimeStatus()...)
+	if chaos != nil {
+		lines = append(lines, fmt.Sprintf("LYAPUNOV: %.4f", chaos.Lyapunov()))
+	}
+	lines = append(lines, approachStatus()...)
+	if numEscaping > 0 {
+		lines = append(lines, fmt.Sprintf("ESCAPING: %v", numEscaping))
+	}
+	if kickTool {
+		lines = append(lines, "KICK TOOL: ON")
+	}
+	if predictionMode != PREDICTIONOFF {
+		lines = append(lines, "PREDICTION: "+strings.ToUpper(predictionModeNames[predictionMode]))
+	}
+	return lines
+}
+
+// Draw the HUD
+func drawHUD() {
+	lines := hudLines()
+	width, height := render.PanelSize(lines)
+	render.Panel(frame, int(screenWidth)-width-render.PANELMARGIN, int(screenHeight)-height-render.PANELMARGIN, lines)
+}
+
+// Switch to the next collision mode
+func cycleCollisionMode() {
+	sim.CollisionMode = (sim.CollisionMode + 1) % len(simulation.CollisionModeNames)
+	slog.Info("COLLISIONS", "mode", simulation.CollisionModeNames[sim.CollisionMode])
+}
+
+// The line of the HUD showing the angular momentum about the barycenter, and how far it has drifted since the start of the run
+// Merges lose the angular momentum of the two bodies around each other, so it drops with each merge
+func angularMomentumStatus() string {
+	l := simulation.BarycentricAngularMomentum(sim.Bodies())
+	return fmt.Sprintf("ANGULAR MOMENTUM: %.4g (DRIFT %.2g%%)", l, 100*angularMomentumDrift(summaryStart.angularMomentum, l))
+}
@@ -0,0 +1,127 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image"
+	"log/slog"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The index of the body selected by clicking on it, or -1 if no body is selected
+// The parameters of the selected body are shown live in the inspector panel
+var selectedBody = -1
+
+// Select the body under a position on the screen, clicking empty space clears the selection
+func selectBodyAtScreen(x, y int32) {
+	selectedBody = bodyAtScreen(x, y)
+}
+
+// The selected body, or nil if there is none (or it has since merged into another body or been removed)
+// If the selected body merged into another body, that body is selected instead
+func selected() *simulation.Body {
+	if selectedBody < 0 {
+		return nil
+	}
+	selectedBody = simulation.Survivor(sim.Bodies(), selectedBody)
+	if selectedBody < 0 {
+		return nil
+	}
+	return sim.Bodies()[selectedBody]
+}
+
+// Draw the inspector panel showing the live parameters of the selected body, and highlight the body itself
+// The editor panel for the body is drawn below the inspector
+func drawInspector() {
+	b := selected()
+	if b == nil {
+		selectedBody = -1
+		editorRect = image.Rectangle{}
+		return
+	}
+
+	x, y := worldToScreen(b.Pos.X, b.Pos.Y)
+	render.Ring(frame, x, y, b.Radius/camera.Zoom+3, render.HighlightColor)
+
+	title := fmt.Sprintf("BODY %v", selectedBody)
+	if b.Name != "" {
+		title += " - " + b.Name
+	}
+	if b.Fixed {
+		title += " (FIXED)"
+	}
+	acc := simulation.Acceleration(b, sim.Bodies(), sim.Params)
+	barycenter, barycenterVel := simulation.Barycenter(sim.Bodies())
+	lines := []string{
+		title,
+		fmt.Sprintf("POSITION      %.2f, %.2f", b.Pos.X, b.Pos.Y),
+		fmt.Sprintf("VELOCITY      %.3f, %.3f (%.3f)", b.Vel.X, b.Vel.Y, b.Vel.Norm()),
+		fmt.Sprintf("ACCELERATION  %.4f, %.4f (%.4f)", acc.X, acc.Y, acc.Norm()),
+		fmt.Sprintf("MASS          %.2f", b.Mass),
+		fmt.Sprintf("RADIUS        %.2f", b.Radius),
+		fmt.Sprintf("ANG. MOMENTUM %.4g", simulation.AngularMomentumAbout(b, barycenter, barycenterVel)),
+	}
+	rect := render.Panel(frame, render.PANELMARGIN, render.PANELMARGIN, append(lines, orbitLines()...))
+	drawEditor(render.PANELMARGIN, rect.Max.Y+render.PANELMARGIN)
+}
+
+// The lines of the inspector describing the orbit of the selected body around the most massive body
+// The most massive body itself has no orbit to describe
+func orbitLines() []string {
+	if selectedBody == orbits.Primary() {
+		return []string{"ORBIT         PRIMARY"}
+	}
+	o, ok := orbits.Orbit(selectedBody)
+	if !ok || !o.Measured() {
+		return []string{fmt.Sprintf("ORBIT         NOT YET MEASURED (AROUND BODY %v)", orbits.Primary())}
+	}
+	return []string{
+		fmt.Sprintf("ORBIT         AROUND BODY %v, %v SEEN", orbits.Primary(), o.Orbits),
+		fmt.Sprintf("PERIOD        %.3f", o.Period),
+		fmt.Sprintf("ECCENTRICITY  %.4f", o.Eccentricity),
+		fmt.Sprintf("APSIDES       %.2f, %.2f", o.Periapsis, o.Apoapsis),
+	}
+}
+
+// Copy the selected body to the clipboard as a row of a csv save file, so its exact state can be pasted into a save file or bug report
+func copySelectedBody() {
+	b := selected()
+	if b == nil {
+		slog.Info("NO BODY SELECTED TO COPY")
+		return
+	}
+	var row strings.Builder
+	w := csv.NewWriter(&row)
+	w.Write(simulation.SaveRecord(b))
+	w.Flush()
+	if err := sdl.SetClipboardText(row.String()); err != nil {
+		slog.Warn("COULD NOT COPY BODY TO CLIPBOARD", "err", err)
+		return
+	}
+	slog.Info("COPIED BODY TO CLIPBOARD", "id", selectedBody)
+}
+
+// Toggle the selected body between moving freely and fixed in place
+// Freezing a body stops it dead, so it doesn't carry its old velocity when unfrozen
+func freezeSelectedBody() {
+	b := selected()
+	if b == nil {
+		slog.Info("NO BODY SELECTED TO FREEZE")
+		return
+	}
+	recordUndo()
+	b.Fixed = !b.Fixed
+	if b.Fixed {
+		b.Vel.X, b.Vel.Y = 0, 0
+		slog.Info("FROZE BODY", "id", selectedBody)
+	} else {
+		slog.Info("UNFROZE BODY", "id", selectedBody)
+	}
+}
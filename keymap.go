@@ -0,0 +1,95 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// The key bound to each action, so the controls can be rebound (e.g. for AZERTY keyboards)
+// Keys are rebound in the [keys] table of the config file, by action name and SDL key name, e.g.
+//
+//	[keys]
+//	moveUp = "Z"
+//	moveLeft = "Q"
+//	zoomOut = "A"
+//
+// The key names are those SDL uses, e.g. "Space", "Left" or "Keypad +"
+var keymap = map[string]sdl.Scancode{
+	"pause":             sdl.SCANCODE_SPACE,
+	"trails":            sdl.SCANCODE_X,
+	"step":              sdl.SCANCODE_C,
+	"stepBack":          sdl.SCANCODE_BACKSPACE,
+	"zoomOut":           sdl.SCANCODE_Q,
+	"zoomIn":            sdl.SCANCODE_E,
+	"moveUp":            sdl.SCANCODE_W,
+	"moveDown":          sdl.SCANCODE_S,
+	"moveLeft":          sdl.SCANCODE_A,
+	"moveRight":         sdl.SCANCODE_D,
+	"rotateLeft":        sdl.SCANCODE_COMMA,
+	"rotateRight":       sdl.SCANCODE_PERIOD,
+	"resetRotation":     sdl.SCANCODE_SLASH,
+	"moveFaster":        sdl.SCANCODE_UP,
+	"moveSlower":        sdl.SCANCODE_DOWN,
+	"slowDown":          sdl.SCANCODE_LEFT,
+	"speedUp":           sdl.SCANCODE_RIGHT,
+	"weakenGravity":     sdl.SCANCODE_PAGEDOWN,
+	"strengthenGravity": sdl.SCANCODE_PAGEUP,
+	"setTimescale":      sdl.SCANCODE_T,
+	"setZoom":           sdl.SCANCODE_I,
+	"print":             sdl.SCANCODE_P,
+	"save":              sdl.SCANCODE_O,
+	"storeCheckpoint":   sdl.SCANCODE_K,
+	"restoreCheckpoint": sdl.SCANCODE_L,
+	"discardCheckpoint": sdl.SCANCODE_J,
+	"controlPanel":      sdl.SCANCODE_TAB,
+	"brush":             sdl.SCANCODE_B,
+	"collisionMode":     sdl.SCANCODE_N,
+	"console":           sdl.SCANCODE_GRAVE,
+	"undo":              sdl.SCANCODE_Z,
+	"redo":              sdl.SCANCODE_Y,
+	"deleteSelection":   sdl.SCANCODE_DELETE,
+	"mergeSelection":    sdl.SCANCODE_M,
+	"recolorSelection":  sdl.SCANCODE_R,
+	"kickSelection":     sdl.SCANCODE_V,
+	"copyBody":          sdl.SCANCODE_C,
+	"prediction":        sdl.SCANCODE_U,
+	"rerandomize":       sdl.SCANCODE_R,
+	"freeze":            sdl.SCANCODE_F,
+	"kickTool":          sdl.SCANCODE_G,
+	"blackHole":         sdl.SCANCODE_H,
+	"measure":           sdl.SCANCODE_SEMICOLON,
+	"raiseMassFilter":   sdl.SCANCODE_RIGHTBRACKET,
+	"lowerMassFilter":   sdl.SCANCODE_LEFTBRACKET,
+	"energyPlot":        sdl.SCANCODE_APOSTROPHE,
+}
+
+// Rebind keys from the [keys] table of the config file, mapping action names to key names
+// Unknown actions are warned about and skipped, but a key name SDL doesn't recognise is an error
+func loadKeymap(keys map[string]any) error {
+	for action, value := range keys {
+		if _, ok := keymap[action]; !ok {
+			slog.Warn("UNKNOWN KEY BINDING ACTION", "action", action)
+			continue
+		}
+		name, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("key for %v must be a key name, e.g. \"W\"", action)
+		}
+		scancode := sdl.GetScancodeFromName(name)
+		if scancode == sdl.SCANCODE_UNKNOWN {
+			return fmt.Errorf("unknown key %q for %v", name, action)
+		}
+		keymap[action] = scancode
+	}
+	return nil
+}
+
+// The name of the key bound to an action, in capitals to match the rest of the overlays
+func keyName(action string) string {
+	return strings.ToUpper(sdl.GetScancodeName(keymap[action]))
+}
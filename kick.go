@@ -0,0 +1,73 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The kick tool stirs the simulation by hand: while it is on, dragging the mouse pushes the bodies under it
+// in the direction of the drag, as if painting velocity onto them
+
+// The radius of the area pushed by the kick tool, in pixels
+const KICKRADIUS = 40
+
+var (
+	// Whether the kick tool is on, toggled with G
+	kickTool bool
+	// Whether the mouse is being dragged with the kick tool
+	kicking bool
+	// The last position of the mouse in the simulation while kicking
+	lastKickX float64
+	lastKickY float64
+)
+
+// Start kicking from a position on the screen, returning false if the kick tool is off
+func startKick(x, y int32) bool {
+	if !kickTool {
+		return false
+	}
+	recordUndo()
+	kicking = true
+	lastKickX, lastKickY = screenToWorld(x, y)
+	return true
+}
+
+// Push the bodies under the mouse by how far it moved since the last update
+// The velocity added matches setting a velocity by shift-dragging, so dragging a body's length over it adds the same velocity
+func updateKick(x, y int32) {
+	if !kicking {
+		return
+	}
+	worldX, worldY := screenToWorld(x, y)
+	dx, dy := worldX-lastKickX, worldY-lastKickY
+	lastKickX, lastKickY = worldX, worldY
+
+	radius := KICKRADIUS * camera.Zoom
+	for _, b := range sim.Bodies() {
+		if b == nil || b.Fixed {
+			continue
+		}
+		if b.Pos.Dist(simulation.Vec2{X: worldX, Y: worldY}) <= radius {
+			b.Vel.X += dx / VELOCITYDRAGSCALE
+			b.Vel.Y += dy / VELOCITYDRAGSCALE
+		}
+	}
+}
+
+// Stop kicking when the mouse is released
+func stopKick() {
+	kicking = false
+}
+
+// Show the area the kick tool pushes around the mouse
+func drawKick() {
+	if !kickTool {
+		return
+	}
+	x, y, _ := sdl.GetMouseState()
+	render.Ring(frame, x, y, KICKRADIUS, render.HighlightColor)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"time"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// A run can be limited to a number of steps (--steps), an amount of simulated time (--simTime) or a length of time
+// on the wall clock (--duration), for unattended batch jobs and benchmarks. Reaching any limit shuts the run down
+// as an interrupt does, saving the final state. The limits count from the start of the run, not of a loaded save
+
+var (
+	// Set by the run limit flags, 0 for no limit
+	maxSteps    int
+	maxSimTime  float64
+	maxDuration time.Duration
+	// The steps and simulated time at the start of the run, which the limits count from
+	startSteps int
+	startTime  float64
+)
+
+// Stop the run once it reaches --steps or --simTime, checked after every step
+// --duration is a deadline on the shutdown context instead, see catchInterrupts
+func setupRunLimits() {
+	startSteps, startTime = sim.Steps, sim.Time
+	if maxSteps > 0 {
+		sim.OnStep(func(s *simulation.Simulation) {
+			if s.Steps-startSteps >= maxSteps {
+				reachedLimit("steps")
+			}
+		})
+	}
+	if maxSimTime > 0 {
+		sim.OnStep(func(s *simulation.Simulation) {
+			if s.Time-startTime >= maxSimTime {
+				reachedLimit("simTime")
+			}
+		})
+	}
+}
+
+// How far through the run is, from 0 to 1, by whichever limit is closest to being reached after running for elapsed
+// Returns false if there are no limits, so the run only ends when interrupted
+func runFraction(elapsed time.Duration) (float64, bool) {
+	fraction, limited := 0.0, false
+	if maxSteps > 0 {
+		fraction, limited = math.Max(fraction, float64(sim.Steps-startSteps)/float64(maxSteps)), true
+	}
+	if maxSimTime > 0 {
+		fraction, limited = math.Max(fraction, (sim.Time-startTime)/maxSimTime), true
+	}
+	if maxDuration > 0 {
+		fraction, limited = math.Max(fraction, elapsed.Seconds()/maxDuration.Seconds()), true
+	}
+	return math.Min(fraction, 1), limited
+}
+
+// Shut the run down, having reached the named limit
+func reachedLimit(limit string) {
+	if !shuttingDown() {
+		slog.Info("REACHED RUN LIMIT", "limit", limit, "time", sim.Time, "steps", sim.Steps)
+	}
+	requestShutdown()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// Status messages, warnings and errors are logged with log/slog to stderr, so they have levels and can be read by other programs
+// Messages keep to short ALLCAPS descriptions of what happened, with the details as attributes, e.g.
+//
+//	level=INFO msg="LOADING FROM FILE" path=save.csv
+//
+// Output the user asked for, such as help, printed bodies and reports, is still printed to stdout
+
+var (
+	// Set by the logging flags, see logFlags
+	verboseLogging bool
+	quietLogging   bool
+	logFormat      = "text"
+)
+
+// The help on the flags defined by logFlags, added to the help of every command
+const LOGGINGHELP = `
+	--verbose : Also log debugging messages, such as every collision and save
+		Defaults to false
+	--quiet : Only log warnings and errors
+		Defaults to false
+	--logFormat : The format of the log written to stderr, either text or json (one object per line)
+		Defaults to text`
+
+// Define the flags controlling the log, which every command has
+func logFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&verboseLogging, "verbose", false, "Also log debugging messages, such as every collision and save")
+	fs.BoolVar(&quietLogging, "quiet", false, "Only log warnings and errors")
+	fs.StringVar(&logFormat, "logFormat", "text", "The format of the log, either text or json (one object per line)")
+}
+
+// Set up the log from the logging flags
+func setupLogging() {
+	level := slog.LevelInfo
+	if verboseLogging {
+		level = slog.LevelDebug
+	}
+	if quietLogging {
+		level = slog.LevelWarn
+	}
+	options := &slog.HandlerOptions{Level: level}
+
+	switch logFormat {
+	case "text":
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, options)))
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, options)))
+	default:
+		fmt.Fprintln(os.Stderr, "ERROR: Unknown --logFormat", logFormat, "- must be text or json")
+		os.Exit(1)
+	}
+}
+
+// Log an error, then quit with a nonzero exit code
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
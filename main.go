@@ -1,408 +1,380 @@
 package main
 
 import (
-	"encoding/csv"
 	"flag"
 	"fmt"
-	"math/rand"
+	"image/color"
+	"log/slog"
 	"os"
 	"strings"
 	"text/tabwriter"
 	"time"
-	"unsafe"
 
-	"github.com/veandco/go-sdl2/sdl"
+	"hmcalister/gravity_simulation/persist"
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
 )
 
 const (
-	SCREENWIDTH    = 1200
-	SCREENHEIGHT   = 800
-	FRAMETIME      = 16
-	G              = 100
+	FRAMETIME = 16
+	// The default rate pixels decay at when trails are on, see pixelDecayRate
 	PIXELDECAYRATE = 2
 )
 
+// The state of the running program, shared by the window, the API, the gRPC service and the headless commands
+// The physics (the bodies, parameters, time and random numbers) all lives in sim, a simulation.Simulation, which other programs
+// can embed without any of this. The rest (the view, the canvas, whether paused, and the flags) belongs to this program, of
+// which there is only ever one, so it is kept in package variables, guarded by physicsLock while the physics goroutine runs
 var (
-	// The array of pixels to give to the SDL renderer
-	pixels []byte = make([]byte, SCREENWIDTH*SCREENHEIGHT*4)
-	// The color black which is used multiple times for the background
-	sdlColorBlack sdl.Color = sdl.Color{0, 0, 0, 255}
+	// The size of the window, set from the command line or config file
+	screenWidth  int32 = 1200
+	screenHeight int32 = 800
+	// The pixels the simulation is drawn into, shown in the window with the overlays on top
+	canvas *render.Canvas
+	// The color used for the background, black unless configured otherwise
+	backgroundColor color.RGBA
 	// Some variables for command line flags
-	saveFilePath string
-	numBodies    int
-	// List of bodies to store current frame and next frame
-	// This allows for consistent simulations (not changing bodies mid frame)
-	// We keep both so the garbage collector does not kill old arrays every frame
-	currentBodies []*Body
-	nextBodies    []*Body
+	configPath       string
+	backgroundString string
+	saveFilePath     string
+	numBodies        int
+	numCheckpoints   int
+	trajectoryPath   string
+	trajectoryFormat string
+	saveFormat       string
+	scenarioName     string
+	streamEvery      int
+	saveOnExit       bool
+	snapshotEvery    int
+	snapshotDir      string
+	horizonsPaths    string
+	scriptPath       string
+	horizonsScale    float64
+	horizonsMass     float64
+	// Limits on the parameters of randomly generated bodies
+	randomMassMin       float64 = 1
+	randomMassMax       float64 = 11
+	randomVelocityRange float64
+	randomSpawnRadius   float64
+	// The simulation itself: the bodies, the parameters they are updated with (G, timescale, softening and collisions)
+	// and the random number generator. The parameters are set from the command line or config file
+	sim = simulation.New(simulation.Params{CollisionMode: simulation.COLLISIONMERGE}, 0)
 	// Variables to do with the simulation behavior
-	paused        bool    = true
-	pixeldecay    bool    = false
-	timescale     float64 = 0.25
-	zoomscale     float64 = 1
-	movescale     float64 = 25
-	currentXCoord float64 = 0
-	currentYCoord float64 = 0
+	paused     bool    = true
+	pixeldecay bool    = false
+	movescale  float64 = 25
+	// The view of the simulation in the window, sized to the window in setupDisplay
+	camera = render.Camera{Zoom: 1}
+	// How quickly trails fade, a lower rate gives longer trails
+	pixelDecayRate uint8 = PIXELDECAYRATE
+	collisionsName string
+	// What the run command shows the simulation on, see --renderer
+	rendererName string
+	// Set by --headless, to run without a window as fast as possible
+	headlessFlag bool
+	// Extra forces acting on the bodies, both off unless set from the command line or config file
+	dragCoefficient float64
+	centralMass     float64
+	// The seed the random number generator was last seeded with, so random simulations can be repeated
+	seed int64
 	// Finally, a writer to print these variables nicely
 	tableWriter *tabwriter.Writer = tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
 )
 
-// At start of program, process command line flags and allocate some memory for bodies
-func init() {
+// Parse the flags of the run or simulate command, then set up the simulation they describe and allocate some memory for bodies
+// If the help flag is given, help is printed then the program quits
+func setupSimulation(name string, args []string, help string) {
 	var helpFlag bool
-	flag.StringVar(&saveFilePath, "saveFile", "", "The path to the save file to use.\nIf not specified, use other flags to determine simulation behavior")
-	flag.IntVar(&numBodies, "numBodies", 5, "The number of bodies to add to this simulation")
-	flag.BoolVar(&helpFlag, "h", false, "Display help on this program, then quit")
-	flag.Parse()
+	var listFlag bool
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	simulationFlags(fs, &helpFlag, &listFlag)
+	logFlags(fs)
+	fs.Parse(args)
+	setupLogging()
 
 	// If the user has selected the help flag, print the help message then quit
 	if helpFlag {
-		fmt.Println(`
-Gravity Simulation
-Usage:
-	Run from source using "go run ."
-	Build from source using "go build ."
-	Run from executable using "./gravity_simulation"
-
-Flags:
-	When running this program, some flags can be specified to change starting configurations
-	--saveFile : The path to the csv file to load into the simulation
-		Note if this flag is not set, the simulation will be loaded with a random initial configuration
-	--numBodies : An integer to specify the number of bodies to randomly seed when starting this simulation
-		Defaults to 5
-
-Controls:
-	While the simulation is running you can use the keyboard to control parts of the application. The controls are:
-
-	W : Move view window up
-	A : Move view window left
-	S : Move view window down
-	D : Move view window right
-	Q : Zoom out
-	E : Zoom in
-	
-	ArrowKeyDown : Decrease the rate of view window movement
-	ArrowKeyUp : Increase the rate of view window movement
-	ArrowKeyLeft : Decrease the speed of the simulation
-	ArrowKeyRight : Increase the speed of the simulation
-
-	Spacebar : Toggle pause/resume
-	X : Toggle particle trails
-	C : Advance a single timestep (without unpausing)
-	P : Print the current state of the simulation (all bodies + settings)
-	O : Save the currect state of the simulation`)
+		fmt.Println(help)
 		os.Exit(0)
 	}
 
+	// When streaming, snapshots go to the real stdout and all other output is moved to stderr so it doesn't corrupt the stream
+	if streamEvery > 0 {
+		openStream()
+	}
+
+	// Fill in anything not given on the command line from the config file
+	if err := loadConfig(fs, configPath); err != nil {
+		fatal("COULD NOT LOAD CONFIG FILE", "err", err)
+	}
+	// The config file can set the logging flags too
+	setupLogging()
+	var err error
+	backgroundColor, err = parseColor(backgroundString)
+	if err != nil {
+		fatal("INVALID BACKGROUND COLOR", "err", err)
+	}
+	if rendererName != "null" && rendererName != DEFAULTRENDERER {
+		fatal("UNKNOWN RENDERER, MUST BE window (BUILT WITH -tags sdl) OR null", "renderer", rendererName)
+	}
+	sim.CollisionMode, err = simulation.ParseCollisionMode(collisionsName)
+	if err != nil {
+		fatal("INVALID COLLISION MODE", "err", err)
+	}
+	if dragCoefficient != 0 {
+		sim.AddForce(simulation.Drag{Coefficient: dragCoefficient})
+	}
+	if centralMass != 0 {
+		// The potential is made at each step, so it follows changes to G and the softening length like the gravity between bodies
+		sim.AddForce(simulation.ForceFunc(func(bodies []*simulation.Body) []simulation.Vec2 {
+			return simulation.CentralPotential{Mass: centralMass, G: sim.G, Softening: sim.Softening}.Accelerations(bodies)
+		}))
+	}
+	if workersString != "" {
+		setupWorkers()
+	}
+	// Now the window size is known we can allocate the pixels
+	if screenWidth <= 0 || screenHeight <= 0 {
+		fatal("--width AND --height MUST BOTH BE POSITIVE")
+	}
+	canvas = render.NewCanvas(screenWidth, screenHeight)
+	if err := parseHiddenCategories(hiddenString); err != nil {
+		fatal("INVALID --hide", "err", err)
+	}
+	setupDisplay()
+
+	// If the user wants to see the scenarios, list them then quit
+	if listFlag {
+		listScenarios()
+		os.Exit(0)
+	}
+
+	// Seed the random number generator with the current time to get new simulations with each run, unless given a seed
+	// If a protobuf save is loaded, the saved state of the generator replaces this
+	if seed == 0 {
+		seed = time.Now().UnixMicro()
+	}
+	sim.Rand.Seed(seed)
+	slog.Info("USING SEED", "seed", seed)
+	setupProvenance(name, fs)
+
 	// If we were given a file to read from, try it
 	if saveFilePath != "" {
-		fmt.Println("LOADING FROM FILE ", saveFilePath)
-		data, err := os.ReadFile(saveFilePath)
+		slog.Info("LOADING FROM FILE", "path", saveFilePath)
+		if err := loadStateFile(saveFilePath); err != nil {
+			fatal("COULD NOT LOAD SAVE FILE", "err", err)
+		}
+	} else if scenarioName != "" { // If we were given one of the embedded scenarios, load that
+		slog.Info("LOADING SCENARIO", "scenario", scenarioName)
+		data, err := readScenario(scenarioName)
 		if err != nil {
-			fmt.Println("ERROR: Could not read file ", saveFilePath)
-			panic(err)
+			fatal("NO SUCH SCENARIO, USE --listScenarios TO SEE ALL SCENARIOS", "scenario", scenarioName)
 		}
-
-		// Save files are in csv format, so we can use the encoding/csv to read it out
-		r := csv.NewReader(strings.NewReader(string(data)))
-		// Comment lines start with #
-		// e.g. the first line which details the csv format
-		r.Comment = '#'
-		records, err := r.ReadAll()
+		bodies, err := persist.ParseSaveData("scenarios/"+scenarioName+".csv", data, sim.Rand)
 		if err != nil {
-			fmt.Println("ERROR: CSV file not correctly formatted")
-			panic(err)
+			fatal("COULD NOT LOAD SCENARIO", "err", err)
 		}
-
-		// Now we have read all the bodies in the saved file we can allocate exactly this much memory!
-		currentBodies = make([]*Body, len(records))
-		nextBodies = make([]*Body, len(records))
-		for i, b := range records {
-			currentBodies[i] = NewBodyFromStrings(b)
+		sim.SetBodies(bodies)
+	} else if horizonsPaths != "" { // If we were given real ephemerides, convert those to bodies
+		slog.Info("LOADING FROM HORIZONS FILES", "paths", horizonsPaths)
+		bodies, err := persist.LoadHorizonsFiles(strings.Split(horizonsPaths, ","), horizonsScale, horizonsMass, sim.G, sim.Rand)
+		if err != nil {
+			fatal("COULD NOT LOAD HORIZONS FILES", "err", err)
 		}
+		sim.SetBodies(bodies)
+	} else if scriptPath != "" { // If we were given a script, it will generate the bodies itself
+		slog.Info("NO LOAD FILE, BODIES WILL BE CREATED BY SCRIPT")
 	} else { // If we did not get a save file we will instead create a set of random bodies
-		fmt.Println("NO LOAD FILE")
-		fmt.Println("USING NUMBODIES = ", numBodies)
-		// Seed the creation  with the current time to get new simulations with each run
-		rand.Seed(time.Now().UnixMicro())
+		slog.Info("NO LOAD FILE, USING RANDOM BODIES", "numBodies", numBodies)
 		// We also know exactly how many bodies we expect so we can allocate this memory
-		currentBodies = make([]*Body, numBodies)
-		nextBodies = make([]*Body, numBodies)
+		sim.SetBodies(make([]*simulation.Body, numBodies))
 		for i := 0; i < numBodies; i++ {
-			currentBodies[i] = NewRandomBody()
+			sim.Bodies()[i] = simulation.NewRandomBody(sim.Rand, randomOptions())
 		}
 	}
 
-	// Finally, we can save this starting config to a file so the user can run it again if need be
-	saveState()
-}
-
-// Save the state of the simulation to a file
-func saveState() {
-	f, err := os.OpenFile("save.csv", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
-	if err != nil {
-		// However, if we cannot create the file as expected it isn't the end of the world
-		// We just return, not panic
-		fmt.Println("Cannot create save.csv to save state!")
-		return
-	}
-	fmt.Fprintln(f, "#x, y, xVel, yVel, mass, radius, red, green, blue")
-	for _, b := range currentBodies {
-		if b != nil {
-			fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v\n", b.x, b.y, b.xVel, b.yVel, b.mass, b.radius, b.color.R, b.color.G, b.color.B)
+	// Run the script now the starting bodies are known, so it can add to them
+	// If the save was made by the same script, it is resumed instead, restoring the events it had scheduled
+	if scriptPath != "" && savedScript != nil && savedScript.path == scriptPath {
+		slog.Info("RESUMING SCRIPT", "path", scriptPath)
+		if err := resumeScript(savedScript); err != nil {
+			fatal("SCRIPT FAILED", "err", err)
 		}
-	}
-	fmt.Fprintf(f, "\n")
-}
-
-// print all of the bodies that are not nil from the currentBodies array
-// some extra formatting is added (a line of hyphens, etc)
-func printBodies() {
-	fmt.Println("--------------------------------------------------------------------------------")
-	fmt.Fprintf(tableWriter, "Body Index\tx\ty\txVel\tyVel\tmass\tradius\tcolor\n")
-	for i, b := range currentBodies {
-		if b == nil {
-			continue
+	} else if scriptPath != "" {
+		slog.Info("RUNNING SCRIPT", "path", scriptPath)
+		if err := runScript(scriptPath); err != nil {
+			fatal("SCRIPT FAILED", "err", err)
 		}
-		fmt.Fprintf(tableWriter, "BODY %v\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%v\t\n",
-			i,
-			b.x,
-			b.y,
-			b.xVel,
-			b.yVel,
-			b.mass,
-			b.radius,
-			b.color,
-		)
+	} else if savedScript != nil && len(savedScript.pending) > 0 {
+		slog.Warn("THE SAVE HAS SCRIPTED EVENTS STILL TO RUN, USE --script TO RESTORE THEM", "events", len(savedScript.pending), "script", savedScript.path)
 	}
-	tableWriter.Flush()
-}
 
-// print the configuration variables with some formatting
-func printConfiguration() {
-	fmt.Println("--------------------------------------------------------------------------------")
-	fmt.Fprintln(tableWriter, "PAUSED\t", paused)
-	fmt.Fprintf(tableWriter, "TIMESCALE\t%.2f\n", timescale)
-	fmt.Fprintf(tableWriter, "ZOOMSCALE\t%.2f\n", zoomscale)
-	fmt.Fprintf(tableWriter, "MOVESCALE\t%.2f\n", movescale)
-	fmt.Fprintf(tableWriter, "SCREEN CENTER\t (%.2f, %.2f)\n", currentXCoord, currentYCoord)
-	fmt.Fprintf(tableWriter, "SCREEN LIMITS\t X: %v - %v,  Y: %v - %v\n",
-		int32(currentXCoord-zoomscale*SCREENWIDTH),
-		int32(currentXCoord+zoomscale*SCREENWIDTH),
-		int32(currentYCoord-zoomscale*SCREENHEIGHT),
-		int32(currentYCoord+zoomscale*SCREENHEIGHT))
-	tableWriter.Flush()
-}
-
-// set all pixels in the array to a specific color
-func setAllPixels(color sdl.Color) {
-	for y := 0; y < SCREENHEIGHT; y++ {
-		for x := 0; x < SCREENWIDTH; x++ {
-			setPixel(int32(x), int32(y), color)
-		}
+	// If asked to, benchmark the starting bodies at several timescales instead of running them
+	if benchmarkString != "" {
+		runBenchmark()
+		os.Exit(0)
 	}
-}
-
-// set a specific pixel to a color
-func setPixel(x, y int32, c sdl.Color) {
-	// This is the index into the pixels array
-	// Which is a flattened array of rgb values
-	// Hence the extra factor of screenwidth for y
-	// and multiplying by the four color channels
-	index := (y*SCREENWIDTH + x) * 4
-
-	// The conditional here is just to avoid drawing off the screen
-	if index < int32(len(pixels)-4) && index >= 0 {
-		pixels[index] = c.R
-		pixels[index+1] = c.G
-		pixels[index+2] = c.B
+	// Or check that running them is reproducible
+	if verifySteps > 0 {
+		runVerify()
+		os.Exit(0)
 	}
-}
 
-// Decay a pixel by subtracting a small value from each RGB channel
-// When the color channel is below the decay rate (i.e. the next subtraction would be negative)
-// instead we set the color channel to zero. A zero value in the color channel will remain at zero
-func decayPixel(x, y int32) {
-	index := (y*SCREENWIDTH + x) * 4
-	if index < int32(len(pixels)-4) && index >= 0 {
-		var i int32
-		for i = 0; i < 3; i++ {
-			if pixels[index+i] < PIXELDECAYRATE {
-				pixels[index+i] = 0
-				continue
-			}
-			pixels[index+i] = pixels[index+i] - PIXELDECAYRATE
-		}
+	// Collisions are only logged when debugging messages are, as finding bouncing collisions slows down each step
+	if verboseLogging {
+		sim.OnCollision(func(s *simulation.Simulation, c simulation.Collision) {
+			slog.Debug("COLLISION", "a", c.A, "b", c.B, "merged", c.Merged, "time", s.Time)
+		})
 	}
-}
 
-// Handle all the inputs for the application
-// This includes quit events (alt+F4, ...) and keyboard events
-// SDL also supports other events such as mouse inputs but these are not used
-func handleInputs() {
-	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-		switch t := event.(type) {
-		case *sdl.QuitEvent:
-			os.Exit(0)
-		case *sdl.KeyboardEvent:
-			// Ignore released keys
-			if t.State == sdl.RELEASED {
-				continue
-			}
+	// Scheduled script events run first after each step, so everything else sees their effects
+	sim.OnStep(func(*simulation.Simulation) { runScriptEvents() })
 
-			// If spacebar pressed, pause the simulation
-			if t.Keysym.Scancode == sdl.SCANCODE_SPACE && t.Repeat != 1 {
-				paused = !paused
-			}
+	// Then the watchdog, so nothing else sees bodies that have gone NaN or infinite without them being reported
+	if watchdogEnabled {
+		setupWatchdog()
+	}
 
-			// X makes pixels decay
-			if t.Keysym.Scancode == sdl.SCANCODE_X && t.Repeat != 1 {
-				pixeldecay = !pixeldecay
-			}
+	// Stop once the simulation reaches --steps or --simTime
+	setupRunLimits()
 
-			// Pressing c steps one frame
-			if t.Keysym.Scancode == sdl.SCANCODE_C {
-				timeStep()
-			}
+	// Measure the orbits of the bodies around the most massive body, and find the bodies escaping altogether
+	trackOrbits()
+	trackUnbound()
 
-			// Pressing Q/E zooms
-			if t.Keysym.Scancode == sdl.SCANCODE_Q {
-				zoomscale *= 1.2
-				setAllPixels(sdlColorBlack)
-			}
-			if t.Keysym.Scancode == sdl.SCANCODE_E {
-				zoomscale /= 1.2
-				setAllPixels(sdlColorBlack)
-			}
+	// And predict the close approaches between the most massive bodies
+	if approachDistance > 0 {
+		if approachSteps <= 0 || approachBodies <= 0 {
+			fatal("--approachSteps AND --approachBodies MUST BE POSITIVE")
+		}
+		trackApproaches()
+	}
 
-			// Pressing W moves the view up and so on...
-			if t.Keysym.Scancode == sdl.SCANCODE_W {
-				currentYCoord -= movescale * zoomscale
-				setAllPixels(sdlColorBlack)
-			}
-			if t.Keysym.Scancode == sdl.SCANCODE_S {
-				currentYCoord += movescale * zoomscale
-				setAllPixels(sdlColorBlack)
-			}
-			if t.Keysym.Scancode == sdl.SCANCODE_A {
-				currentXCoord -= movescale * zoomscale
-				setAllPixels(sdlColorBlack)
-			}
-			if t.Keysym.Scancode == sdl.SCANCODE_D {
-				currentXCoord += movescale * zoomscale
-				setAllPixels(sdlColorBlack)
-			}
+	// If requested, measure how chaotic the simulation is from here on
+	if chaosPerturbation != 0 {
+		setupChaos()
+	}
 
-			// Pressing up and down scales how quickly we move through space
-			if t.Keysym.Scancode == sdl.SCANCODE_UP {
-				movescale += 1
-			}
-			if t.Keysym.Scancode == sdl.SCANCODE_DOWN {
-				if movescale > 0 {
-					movescale -= 1
-				}
+	// If requested, start recording the trajectory, including the starting config
+	if trajectoryPath != "" {
+		openTrajectory(trajectoryPath, trajectoryFormat)
+		writeTrajectory()
+		sim.OnStep(func(s *simulation.Simulation) {
+			if !replayingStep(s) {
+				writeTrajectory()
 			}
+		})
+	}
 
-			// Pressing left slows down the simulation
-			if t.Keysym.Scancode == sdl.SCANCODE_LEFT {
-				timescale /= 1.1
-			}
-			// Pressing right speeds up the simulation
-			if t.Keysym.Scancode == sdl.SCANCODE_RIGHT {
-				timescale *= 1.1
-			}
+	// The metrics are emitted by the simulation itself, after every step
+	setupMetrics()
 
-			// P prints out all bodies
-			if t.Keysym.Scancode == sdl.SCANCODE_P {
-				fmt.Printf("\n\n\n")
-				printBodies()
-				printConfiguration()
-			}
+	// If requested, let the simulation be driven over HTTP or gRPC, and watched by spectators
+	if apiAddr != "" {
+		setupAPI()
+	}
+	if grpcAddr != "" {
+		setupGRPC()
+	}
+	if spectateAddr != "" {
+		setupSpectators()
+	}
 
-			// O saves the current state of the simulation to a file
-			if t.Keysym.Scancode == sdl.SCANCODE_O {
-				fmt.Println("SAVING TO FILE")
-				saveState()
-			}
+	// If requested, write the totals over all bodies, starting with the starting config
+	if diagnosticsPath != "" {
+		if diagnosticsEvery <= 0 {
+			fatal("--diagnosticsEvery MUST BE POSITIVE")
 		}
+		openDiagnostics()
 	}
-}
-
-// Perform a single timestep across the bodies.
-func timeStep() {
-	for i, body := range currentBodies {
-		nextBodies[i] = body.Update()
-	}
-	// To avoid memory being allocated and collected each frame
-	// Simply swap the next (now calculated) array and current array
-	temp := currentBodies
-	currentBodies = nextBodies
-	nextBodies = temp
-}
 
-func main() {
-	// Start the main method by initializing the SDL framework
-	if err := sdl.Init(sdl.INIT_VIDEO); err != nil {
-		fmt.Println("Failed to initialize SDL:", err)
-		return
+	// And the phase space samples of every body
+	if phasePath != "" {
+		if phaseEvery <= 0 {
+			fatal("--phaseEvery MUST BE POSITIVE")
+		}
+		openPhase()
 	}
-	defer sdl.Quit()
 
-	window, err := sdl.CreateWindow("Gravity Simulation", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		SCREENWIDTH, SCREENHEIGHT, sdl.WINDOW_SHOWN)
-	if err != nil {
-		fmt.Println("Failed to create window:", err)
-		return
+	// If requested, write every collision from now on
+	if eventsPath != "" {
+		openEventLog()
 	}
-	defer window.Destroy()
 
-	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
-	if err != nil {
-		fmt.Println("Failed to create renderer:", err)
-		return
+	// The stream starts with the starting config too
+	if streamEvery > 0 {
+		streamSnapshot()
+		sim.OnStep(func(s *simulation.Simulation) {
+			if s.Steps%streamEvery == 0 && !replayingStep(s) {
+				streamSnapshot()
+			}
+		})
 	}
-	defer renderer.Destroy()
 
-	tex, err := renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STREAMING, SCREENWIDTH, SCREENHEIGHT)
-	if err != nil {
-		fmt.Println("Failed to create texture:", err)
-		return
+	// As do the snapshot files
+	if snapshotEvery > 0 {
+		openSnapshotDir()
+		writeSnapshot()
+		sim.OnStep(func(s *simulation.Simulation) {
+			if s.Steps%snapshotEvery == 0 && !replayingStep(s) {
+				writeSnapshot()
+			}
+		})
 	}
-	defer tex.Destroy()
 
-	// Game loop
-	for {
-		// At start of each frame, handle any inputs
-		handleInputs()
+	// The summary at the end of the run compares against the state now
+	startSummary()
+	logVirialRatio()
 
-		// If we are not paused, the bodies can be updated
-		if !paused {
-			timeStep()
-		}
-
-		// Before drawing bodies on top, do something (set black or decay) to the background
-		for y := 0; y < SCREENHEIGHT; y++ {
-			for x := 0; x < SCREENWIDTH; x++ {
-				if pixeldecay {
-					if !paused {
-						decayPixel(int32(x), int32(y))
-					}
-				} else {
-					setPixel(int32(x), int32(y), sdlColorBlack)
-				}
-			}
-		}
+	// Finally, we can save this starting config to a file so the user can run it again if need be
+	// Not being able to isn't the end of the world, so the simulation runs anyway
+	if err := saveState(); err != nil {
+		slog.Warn("COULD NOT SAVE THE STARTING STATE", "err", err)
+	}
+}
 
-		// Then, draw the bodies on top
-		for _, bodies := range currentBodies {
-			bodies.Draw()
+// print all of the bodies in the simulation that are not nil
+// some extra formatting is added (a line of hyphens, etc)
+func printBodies() {
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Fprintf(tableWriter, "Body Index\tname\tx\ty\txVel\tyVel\tmass\tradius\tcolor\n")
+	for i, b := range sim.Bodies() {
+		if b == nil {
+			continue
 		}
+		fmt.Fprintf(tableWriter, "BODY %v\t%v\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%v\t\n",
+			i,
+			b.Name,
+			b.Pos.X,
+			b.Pos.Y,
+			b.Vel.X,
+			b.Vel.Y,
+			b.Mass,
+			b.Radius,
+			b.Color,
+		)
+	}
+	tableWriter.Flush()
+}
 
-		// Actually draw the pixel array to the window and carry on
-		tex.Update(nil, unsafe.Pointer(&pixels[0]), SCREENWIDTH*4)
-		renderer.Copy(tex, nil, nil)
-		renderer.Present()
+// Perform a single timestep across the bodies.
+// Everything that happens after each step (scripts, trajectories...) is attached to the simulation with OnStep in setupSimulation
+func timeStep() {
+	finishEdit()
+	recordStep()
+	sim.Step()
+	furthestStep = max(furthestStep, sim.Steps)
+}
 
-		sdl.Delay(FRAMETIME)
+// The limits on randomly generated bodies, set by the --massRange, --velRange and --spawnRadius flags
+// Without a spawn radius, bodies are spawned over the starting view of the screen
+func randomOptions() simulation.RandomOptions {
+	return simulation.RandomOptions{
+		MassMin:       randomMassMin,
+		MassMax:       randomMassMax,
+		VelocityRange: randomVelocityRange,
+		SpawnRadius:   randomSpawnRadius,
+		Width:         float64(screenWidth),
+		Height:        float64(screenHeight),
 	}
 }
@@ -0,0 +1,123 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The measure tool shows the distance and relative velocity between two points clicked in the simulation,
+// and an estimate of the period of an orbit between them. Clicking a body measures from the body, following it as it moves
+
+// An end of a measurement, either a body or a fixed point in the simulation
+type measurePoint struct {
+	// The index of the body, or -1 for a fixed point
+	body int
+	x, y float64
+}
+
+var (
+	// Whether the measure tool is on, toggled with ;
+	measureTool bool
+	// The ends of the current measurement, the second is added by the next click
+	measurePoints []measurePoint
+)
+
+// Turn the measure tool on or off, clearing any measurement
+func toggleMeasureTool() {
+	measureTool = !measureTool
+	measurePoints = nil
+}
+
+// Add an end of a measurement at a position on the screen, returning false if the measure tool is off
+// Clicking after a measurement is complete starts a new one
+func clickMeasure(x, y int32) bool {
+	if !measureTool {
+		return false
+	}
+	if len(measurePoints) == 2 {
+		measurePoints = nil
+	}
+	worldX, worldY := screenToWorld(x, y)
+	measurePoints = append(measurePoints, measurePoint{bodyAtScreen(x, y), worldX, worldY})
+	return true
+}
+
+// The current body at the end of a measurement, or nil for a fixed point (or a body that no longer exists)
+func (p measurePoint) current() *simulation.Body {
+	if p.body < 0 || p.body >= len(sim.Bodies()) {
+		return nil
+	}
+	return sim.Bodies()[p.body]
+}
+
+// The position and velocity of the end of a measurement, fixed points are at rest
+func (p measurePoint) state() (x, y, xVel, yVel float64) {
+	if b := p.current(); b != nil {
+		return b.Pos.X, b.Pos.Y, b.Vel.X, b.Vel.Y
+	}
+	return p.x, p.y, 0, 0
+}
+
+// Estimate the period of an orbit between the ends of a measurement
+// Between two bodies this is the period of their two body orbit, found from their separation and relative velocity,
+// and from a body to a fixed point it is the period of a circular orbit around the body at that distance
+// The second result is false if the bodies are not bound to each other, or there is no body to orbit
+func measurePeriod(a, b measurePoint, dist, speed float64) (float64, bool) {
+	bodyA, bodyB := a.current(), b.current()
+	var mu float64
+	switch {
+	case bodyA != nil && bodyB != nil:
+		mu = sim.G * (bodyA.Mass + bodyB.Mass)
+		// The semi-major axis, from the vis-viva equation
+		inverseAxis := 2/dist - speed*speed/mu
+		if inverseAxis <= 0 {
+			return 0, false
+		}
+		return 2 * math.Pi * math.Sqrt(math.Pow(1/inverseAxis, 3)/mu), true
+	case bodyA != nil:
+		mu = sim.G * bodyA.Mass
+	case bodyB != nil:
+		mu = sim.G * bodyB.Mass
+	default:
+		return 0, false
+	}
+	if mu <= 0 {
+		return 0, false
+	}
+	return 2 * math.Pi * math.Sqrt(math.Pow(dist, 3)/mu), true
+}
+
+// Draw the current measurement as a line between its ends, labeled with the measured values
+func drawMeasure() {
+	if !measureTool || len(measurePoints) == 0 {
+		return
+	}
+	x0, y0, xVel0, yVel0 := measurePoints[0].state()
+	screenX0, screenY0 := worldToScreen(x0, y0)
+	render.Ring(frame, screenX0, screenY0, 4, render.HighlightColor)
+	if len(measurePoints) < 2 {
+		return
+	}
+	x1, y1, xVel1, yVel1 := measurePoints[1].state()
+	screenX1, screenY1 := worldToScreen(x1, y1)
+	render.Ring(frame, screenX1, screenY1, 4, render.HighlightColor)
+	render.Line(frame, screenX0, screenY0, screenX1, screenY1, render.HighlightColor)
+
+	dist := math.Hypot(x1-x0, y1-y0)
+	speed := math.Hypot(xVel1-xVel0, yVel1-yVel0)
+	lines := []string{
+		fmt.Sprintf("DISTANCE           %.2f", dist),
+		fmt.Sprintf("RELATIVE VELOCITY  %.3f", speed),
+	}
+	if period, ok := measurePeriod(measurePoints[0], measurePoints[1], dist, speed); ok {
+		lines = append(lines, fmt.Sprintf("ORBITAL PERIOD     %.2f", period))
+	} else if measurePoints[0].current() != nil && measurePoints[1].current() != nil {
+		lines = append(lines, "ORBITAL PERIOD     UNBOUND")
+	}
+	render.Panel(frame, int(screenX0+screenX1)/2+render.PANELMARGIN, int(screenY0+screenY1)/2+render.PANELMARGIN, lines)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"hmcalister/gravity_simulation/metrics"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The namespace of the metrics served to Prometheus, e.g. gravitysim_steps_total
+const METRICSNAMESPACE = "gravitysim"
+
+var (
+	// Set by the metrics flags, see simulationFlags
+	metricsLogEvery time.Duration
+	metricsPath     string
+	metricsAddr     string
+	// The csv file the metrics are written to, nil if they aren't being written
+	metricsFile *os.File
+	metricsCSV  *metrics.CSV
+)
+
+// Send the metrics of the simulation to every sink asked for by the flags, if any
+// Like the trajectory, failing to open the metrics file or serve the metrics is not fatal
+func setupMetrics() {
+	var sinks metrics.Multi
+	if metricsLogEvery > 0 {
+		sinks = append(sinks, metrics.NewLog(metricsLogEvery))
+	}
+	if metricsPath != "" {
+		f, err := os.Create(metricsPath)
+		if err != nil {
+			slog.Warn("COULD NOT OPEN METRICS FILE", "path", metricsPath, "err", err)
+		} else {
+			metricsFile, metricsCSV = f, metrics.NewCSV(f)
+			sinks = append(sinks, metricsCSV)
+		}
+	}
+	if metricsAddr != "" {
+		prometheus := metrics.NewPrometheus(METRICSNAMESPACE)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", prometheus)
+		go func() {
+			if err := http.ListenAndServe(metricsAddr, mux); err != nil {
+				slog.Warn("COULD NOT SERVE METRICS", "addr", metricsAddr, "err", err)
+			}
+		}()
+		slog.Info("SERVING METRICS", "url", "http://"+metricsAddr+"/metrics")
+		sinks = append(sinks, prometheus)
+	}
+
+	var m simulation.Metrics
+	switch len(sinks) {
+	case 0:
+		return
+	case 1:
+		m = sinks[0]
+	default:
+		m = sinks
+	}
+	sim.SetMetrics(m)
+}
+
+// Finish writing the metrics file, if the metrics are being written
+func closeMetrics() {
+	if metricsCSV == nil {
+		return
+	}
+	if err := metricsCSV.Close(); err != nil {
+		slog.Warn("COULD NOT WRITE METRICS FILE", "path", metricsPath, "err", err)
+	}
+	metricsFile.Close()
+	metricsCSV, metricsFile = nil, nil
+}
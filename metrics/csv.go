@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// Writes the metrics to a csv file, one row per step with a column per metric
+// The columns are the metrics emitted in the first step, named in a header row
+// Counters are written as their running totals
+type CSV struct {
+	values
+	writer  *csv.Writer
+	columns []string
+	err     error
+}
+
+// Write the metrics as csv to w
+func NewCSV(w io.Writer) *CSV {
+	return &CSV{writer: csv.NewWriter(w)}
+}
+
+func (c *CSV) Counter(name string, delta float64) { c.add(name, delta, true) }
+
+func (c *CSV) Gauge(name string, value float64) { c.add(name, value, false) }
+
+// Write the row of the step, and the header first if this is the first step
+// Writes are buffered, so a row may not reach w until a later step
+func (c *CSV) Flush() {
+	if c.err != nil {
+		return
+	}
+	if c.columns == nil {
+		c.each(func(name string, value float64, counter bool) {
+			c.columns = append(c.columns, name)
+		})
+		c.err = c.writer.Write(c.columns)
+	}
+	row := make([]string, len(c.columns))
+	c.mu.Lock()
+	for i, name := range c.columns {
+		row[i] = strconv.FormatFloat(c.current[name], 'g', -1, 64)
+	}
+	c.mu.Unlock()
+	if c.err == nil {
+		c.err = c.writer.Write(row)
+	}
+}
+
+// Write out any buffered rows, returning the first error writing any row
+func (c *CSV) Close() error {
+	c.writer.Flush()
+	if c.err != nil {
+		return c.err
+	}
+	return c.writer.Error()
+}
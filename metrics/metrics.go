@@ -0,0 +1,97 @@
+// Package metrics has sinks for the metrics a simulation emits after each step (see simulation.Metrics):
+// logging them with log/slog, serving them to Prometheus, or writing them to a csv file, one row per step
+// Several sinks can be used at once with Multi
+package metrics
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Sends every metric to each of the sinks in turn
+type Multi []simulation.Metrics
+
+func (m Multi) Counter(name string, delta float64) {
+	for _, sink := range m {
+		sink.Counter(name, delta)
+	}
+}
+
+func (m Multi) Gauge(name string, value float64) {
+	for _, sink := range m {
+		sink.Gauge(name, value)
+	}
+}
+
+func (m Multi) Flush() {
+	for _, sink := range m {
+		sink.Flush()
+	}
+}
+
+// The current values of the counters and gauges, kept in the order they were first emitted
+// The values are locked, so they can be read (e.g. by a Prometheus scrape) while the simulation is stepping
+type values struct {
+	mu      sync.Mutex
+	names   []string
+	counter map[string]bool
+	current map[string]float64
+}
+
+func (v *values) add(name string, delta float64, counter bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.current == nil {
+		v.counter = map[string]bool{}
+		v.current = map[string]float64{}
+	}
+	if _, ok := v.current[name]; !ok {
+		v.names = append(v.names, name)
+		v.counter[name] = counter
+	}
+	if counter {
+		v.current[name] += delta
+	} else {
+		v.current[name] = delta
+	}
+}
+
+// Call f with each metric in order, with the values locked
+func (v *values) each(f func(name string, value float64, counter bool)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, name := range v.names {
+		f(name, v.current[name], v.counter[name])
+	}
+}
+
+// Logs the metrics with log/slog at most once every interval, rather than after every step
+type Log struct {
+	values
+	every time.Duration
+	last  time.Time
+}
+
+// Log the metrics at most once every interval
+func NewLog(every time.Duration) *Log {
+	return &Log{every: every, last: time.Now()}
+}
+
+func (l *Log) Counter(name string, delta float64) { l.add(name, delta, true) }
+
+func (l *Log) Gauge(name string, value float64) { l.add(name, value, false) }
+
+func (l *Log) Flush() {
+	if time.Since(l.last) < l.every {
+		return
+	}
+	l.last = time.Now()
+	var attrs []any
+	l.each(func(name string, value float64, counter bool) {
+		attrs = append(attrs, name, value)
+	})
+	slog.Info("METRICS", attrs...)
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Two touching bodies, which merge in the first step
+func mergingSimulation() *simulation.Simulation {
+	s := simulation.New(simulation.Params{G: 1, Timescale: 0.1, CollisionMode: simulation.COLLISIONMERGE}, 1)
+	s.AddBody(&simulation.Body{Pos: simulation.Vec2{X: 0}, Mass: 1, Radius: 2})
+	s.AddBody(&simulation.Body{Pos: simulation.Vec2{X: 3}, Mass: 1, Radius: 2})
+	return s
+}
+
+func TestCSV(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewCSV(&out)
+	s := mergingSimulation()
+	s.SetMetrics(sink)
+	s.Step()
+	s.Step()
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %v lines, want a header and two rows:\n%v", len(lines), out.String())
+	}
+	if lines[0] != "steps,collisions,time,step_seconds,bodies,energy" {
+		t.Errorf("header %q", lines[0])
+	}
+	// The counters are running totals, so the collision in the first step is still counted in the second row
+	for i, prefix := range []string{"1,1,0.1,", "2,1,0.2,"} {
+		if !strings.HasPrefix(lines[i+1], prefix) {
+			t.Errorf("row %v is %q, want it to start %q", i+1, lines[i+1], prefix)
+		}
+	}
+}
+
+func TestPrometheus(t *testing.T) {
+	sink := NewPrometheus("gravitysim")
+	s := mergingSimulation()
+	s.SetMetrics(Multi{sink, NewLog(0)})
+	s.Step()
+
+	response := httptest.NewRecorder()
+	sink.ServeHTTP(response, httptest.NewRequest("GET", "/metrics", nil))
+	body := response.Body.String()
+	for _, want := range []string{
+		"# TYPE gravitysim_steps_total counter\ngravitysim_steps_total 1\n",
+		"# TYPE gravitysim_collisions_total counter\ngravitysim_collisions_total 1\n",
+		"# TYPE gravitysim_bodies gauge\ngravitysim_bodies 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in\n%v", want, body)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Serves the latest metrics to Prometheus, in its text exposition format
+// Metric names are prefixed with the namespace, and counters are suffixed with _total, e.g. gravitysim_steps_total
+type Prometheus struct {
+	values
+	namespace string
+}
+
+// Serve the metrics under the given namespace, which may be empty
+// The Prometheus is an http.Handler, so it can be served on any path, e.g. http.Handle("/metrics", p)
+func NewPrometheus(namespace string) *Prometheus {
+	return &Prometheus{namespace: namespace}
+}
+
+func (p *Prometheus) Counter(name string, delta float64) { p.add(name, delta, true) }
+
+func (p *Prometheus) Gauge(name string, value float64) { p.add(name, value, false) }
+
+// Scrapes always see the latest values, so there is nothing to do at the end of a step
+func (p *Prometheus) Flush() {}
+
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	p.each(func(name string, value float64, counter bool) {
+		if p.namespace != "" {
+			name = p.namespace + "_" + name
+		}
+		kind := "gauge"
+		if counter {
+			name += "_total"
+			kind = "counter"
+		}
+		fmt.Fprintf(w, "# TYPE %v %v\n%v %v\n", name, kind, name, value)
+	})
+}
@@ -0,0 +1,185 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log/slog"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Dragging across empty space draws a selection rectangle, selecting every body whose center is inside it
+// The selected bodies can then be removed, merged, recoloured or kicked all at once, e.g. to clean up debris
+
+var (
+	// The indices of the bodies in the selection
+	multiSelection []int
+	// Whether a selection rectangle is being dragged, and its corners on the screen
+	selectingBand bool
+	bandStart     image.Point
+	bandEnd       image.Point
+	bandColor     = color.RGBA{120, 180, 255, 255}
+)
+
+// Start dragging a selection rectangle from a position on the screen
+func startBand(x, y int32) {
+	selectingBand = true
+	bandStart = image.Pt(int(x), int(y))
+	bandEnd = bandStart
+}
+
+// Move the corner of the selection rectangle to follow the mouse
+func updateBand(x, y int32) {
+	if selectingBand {
+		bandEnd = image.Pt(int(x), int(y))
+	}
+}
+
+// Finish the selection rectangle, selecting the bodies inside it
+func endBand() {
+	if !selectingBand {
+		return
+	}
+	selectingBand = false
+	// The rectangle is on the screen, which may be rotated, so the bodies are checked by where they are on the screen
+	rect := image.Rectangle{bandStart, bandEnd}.Canon()
+	multiSelection = nil
+	for i, b := range sim.Bodies() {
+		if b == nil {
+			continue
+		}
+		x, y := worldToScreen(b.Pos.X, b.Pos.Y)
+		if image.Pt(int(x), int(y)).In(rect.Inset(-1)) {
+			multiSelection = append(multiSelection, i)
+		}
+	}
+}
+
+// Clear the selection
+func clearMultiSelection() {
+	multiSelection = nil
+}
+
+// The bodies in the selection that still exist
+func multiSelected() []*simulation.Body {
+	var bodies []*simulation.Body
+	for _, i := range multiSelection {
+		if i < len(sim.Bodies()) && sim.Bodies()[i] != nil {
+			bodies = append(bodies, sim.Bodies()[i])
+		}
+	}
+	return bodies
+}
+
+// Handle a key press for the bulk operations on the selection, returning false if it wasn't one
+func handleMultiSelectionKey(t *sdl.KeyboardEvent) bool {
+	// Shifted keys are left for other actions, e.g. Shift+R which regenerates the simulation
+	if len(multiSelected()) == 0 || t.Repeat == 1 || t.Keysym.Mod&sdl.KMOD_SHIFT != 0 {
+		return false
+	}
+	switch t.Keysym.Scancode {
+	case keymap["deleteSelection"]:
+		deleteMultiSelection()
+	case keymap["mergeSelection"]:
+		mergeMultiSelection()
+	case keymap["recolorSelection"]:
+		recolorMultiSelection()
+	case keymap["kickSelection"]:
+		x, y, _ := sdl.GetMouseState()
+		kickMultiSelection(x, y)
+	default:
+		return false
+	}
+	return true
+}
+
+// Remove every selected body
+func deleteMultiSelection() {
+	recordUndo()
+	for _, i := range multiSelection {
+		sim.RemoveBody(i)
+	}
+	slog.Info("REMOVED BODIES", "bodies", len(multiSelection))
+	multiSelection = nil
+}
+
+// Merge the selected bodies into one, as if they had all collided
+// Like a collision, the merged body keeps the place (and name, color and density) of a fixed body if any are fixed, or
+// else the most massive body, and mass and momentum are conserved
+func mergeMultiSelection() {
+	bodies := multiSelected()
+	if len(bodies) < 2 {
+		return
+	}
+	recordUndo()
+	kept := sim.MergeBodies(multiSelection)
+	slog.Info("MERGED BODIES", "bodies", len(bodies), "into", kept)
+	multiSelection = []int{kept}
+}
+
+// Give every selected body the same new random color
+func recolorMultiSelection() {
+	recordUndo()
+	c := simulation.RandomColor(sim.Rand)
+	for _, b := range multiSelected() {
+		b.Color = c
+	}
+}
+
+// Kick every selected body towards a position on the screen
+// The kick is scaled like dragging a velocity, i.e. by the distance from the selection's center of mass
+func kickMultiSelection(x, y int32) {
+	recordUndo()
+	var centerX, centerY, mass float64
+	bodies := multiSelected()
+	for _, b := range bodies {
+		centerX += b.Pos.X * b.Mass
+		centerY += b.Pos.Y * b.Mass
+		mass += b.Mass
+	}
+	worldX, worldY := screenToWorld(x, y)
+	kickX := (worldX - centerX/mass) / VELOCITYDRAGSCALE
+	kickY := (worldY - centerY/mass) / VELOCITYDRAGSCALE
+	for _, b := range bodies {
+		b.Vel.X += kickX
+		b.Vel.Y += kickY
+	}
+	slog.Info("KICKED BODIES", "bodies", len(bodies), "xVel", kickX, "yVel", kickY)
+}
+
+// Draw the selection rectangle while dragging, and highlight the selected bodies with a panel of the bulk operations
+func drawMultiSelection() {
+	if selectingBand {
+		rect := image.Rectangle{bandStart, bandEnd}.Canon()
+		render.Line(frame, int32(rect.Min.X), int32(rect.Min.Y), int32(rect.Max.X), int32(rect.Min.Y), bandColor)
+		render.Line(frame, int32(rect.Max.X), int32(rect.Min.Y), int32(rect.Max.X), int32(rect.Max.Y), bandColor)
+		render.Line(frame, int32(rect.Max.X), int32(rect.Max.Y), int32(rect.Min.X), int32(rect.Max.Y), bandColor)
+		render.Line(frame, int32(rect.Min.X), int32(rect.Max.Y), int32(rect.Min.X), int32(rect.Min.Y), bandColor)
+	}
+
+	bodies := multiSelected()
+	if len(bodies) == 0 {
+		return
+	}
+	var mass float64
+	for _, b := range bodies {
+		x, y := worldToScreen(b.Pos.X, b.Pos.Y)
+		render.Ring(frame, x, y, b.Radius/camera.Zoom+3, bandColor)
+		mass += b.Mass
+	}
+	lines := []string{
+		fmt.Sprintf("%v BODIES SELECTED, TOTAL MASS %.2f", len(bodies), mass),
+		keyName("deleteSelection") + " : REMOVE",
+		keyName("mergeSelection") + " : MERGE INTO ONE",
+		keyName("recolorSelection") + " : RECOLOR",
+		keyName("kickSelection") + " : KICK TOWARDS THE MOUSE",
+	}
+	_, height := render.PanelSize(lines)
+	render.Panel(frame, render.PANELMARGIN, int(screenHeight)/2-height/2, lines)
+}
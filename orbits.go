@@ -0,0 +1,23 @@
+package main
+
+import (
+	"log/slog"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The orbits of the bodies around the most massive body are measured after every step,
+// shown in the inspector for the selected body and logged (as debugging messages) each time a body completes an orbit
+var orbits = simulation.NewOrbitTracker()
+
+// Measure the orbits after every step, starting from the current state
+func trackOrbits() {
+	orbits.Update(sim.Bodies(), sim.Time)
+	sim.OnStep(func(s *simulation.Simulation) {
+		for _, id := range orbits.Update(s.Bodies(), s.Time) {
+			o, _ := orbits.Orbit(id)
+			slog.Debug("ORBIT", "id", id, "primary", orbits.Primary(), "orbits", o.Orbits, "period", o.Period,
+				"eccentricity", o.Eccentricity, "periapsis", o.Periapsis, "apoapsis", o.Apoapsis, "time", s.Time)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"image"
+
+	"hmcalister/gravity_simulation/render"
+)
+
+// Overlays (panels of text, highlights...) are drawn over a copy of the canvas each frame,
+// so they never leave trails behind when pixel decay is on
+
+var (
+	// The pixels shown in the window, i.e. the simulation with the overlays drawn on top
+	frameCanvas *render.Canvas
+	// The frame as an image, so the overlays can draw into it
+	frame *image.RGBA
+)
+
+// Allocate the frame, once the window size is known
+// The pixel format of the texture (ABGR8888) is RGBA in memory, so the frame pixels can be used as an image directly
+func allocateFrame() {
+	frameCanvas = render.NewCanvas(screenWidth, screenHeight)
+	frame = frameCanvas.Image()
+}
+
+// Copy the simulation into the frame and draw the overlays on top
+func drawFrame() {
+	copy(frameCanvas.Pixels, canvas.Pixels)
+	drawPrediction()
+	drawEscaping()
+	drawInspector()
+	drawDrag()
+	drawControlPanel()
+	drawBrush()
+	drawKick()
+	drawMeasure()
+	drawMultiSelection()
+	drawHUD()
+	drawWatchdog()
+	drawEnergyPlot()
+	drawTooltip()
+	drawPrompt()
+	drawConsole()
+}
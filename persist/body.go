@@ -0,0 +1,228 @@
+// Package persist reads and writes the states of simulations in the supported file formats:
+// csv save files, protobuf, REBOUND snapshots, JPL Horizons vector tables and trajectory exports
+package persist
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// An error in the parameters given to create a body
+// Field is the index of the offending parameter, or -1 if the problem is with the parameters as a whole
+// This lets loaders point the user at the exact place in a file that needs fixing
+type FieldError struct {
+	Field int
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Parse a single parameter as a float, with a readable error pointing at the bad field
+// NaN and infinite values are rejected too, as one such body would spread through the whole simulation
+func parseField(param string, field int, name string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(param), 64)
+	if err != nil {
+		return 0, &FieldError{field, fmt.Errorf("cannot convert %q to a number for %v", param, name)}
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, &FieldError{field, fmt.Errorf("%v must be a finite number, not %q", name, param)}
+	}
+	return value, nil
+}
+
+// Check that the radius of a body (in the given field) is not negative, which has no physical meaning
+func checkNotNegative(value float64, field int, name string) error {
+	if value < 0 {
+		return &FieldError{field, fmt.Errorf("%v must not be negative, not %v", name, value)}
+	}
+	return nil
+}
+
+// Check that the mass of a body (in the given field) is greater than zero
+// Merging takes the mass-weighted average of the bodies, which is NaN for two bodies without any mass
+func checkPositive(value float64, field int, name string) error {
+	if value <= 0 {
+		return &FieldError{field, fmt.Errorf("%v must be greater than zero, not %v", name, value)}
+	}
+	return nil
+}
+
+// Check that a color channel of a body (in the given field) fits in 0 to 255, rather than letting it wrap around
+func checkColor(value float64, field int, name string) error {
+	if value < 0 || value > 255 {
+		return &FieldError{field, fmt.Errorf("%v must be between 0 and 255, not %v", name, value)}
+	}
+	return nil
+}
+
+// Create a body from a set of strings that map to the body parameters.
+// If only some strings are supplied, parameters can be randomly generated.
+// Notice all strings are parsed to floats, so the strings MUST be float-y
+//
+// If 5 or more strings are supplied, the first five strings are mapped to
+// - x, y, xVel, yVel, mass
+// The remaining parameters are randomly generated with rng (except radius which is calculated using simulation.MassToRadius)
+//
+// # If 9 (or more) strings are supplied then all parameters are  set from these strings
+//
+// If a string is not a finite number, the mass isn't positive, the radius is negative, a color is outside 0 to 255,
+// or fewer than 5 strings are supplied, a *FieldError is returned
+func NewBodyFromStrings(bodyParams []string, rng *rand.Rand) (*simulation.Body, error) {
+	// If we don't even have five params we can't do anything!
+	if len(bodyParams) < 5 {
+		return nil, &FieldError{-1, fmt.Errorf("found %v fields but need at least five (x, y, xVel, yVel, mass) to create a body", len(bodyParams))}
+	}
+
+	// Start by converting all params to floats
+	// This could be redone in future if none numeric fields are needed
+	// Notice that even if color channels are present it will be okay to
+	// Temporarily make these floats
+	names := []string{"x", "y", "xVel", "yVel", "mass", "radius", "red", "green", "blue"}
+	var floatParams []float64
+	for i := 0; i < len(bodyParams) && i < len(names); i++ {
+		convertedParam, err := parseField(bodyParams[i], i, names[i])
+		if err != nil {
+			return nil, err
+		}
+		floatParams = append(floatParams, convertedParam)
+	}
+	if err := checkPositive(floatParams[4], 4, "mass"); err != nil {
+		return nil, err
+	}
+	if len(floatParams) > 5 {
+		if err := checkNotNegative(floatParams[5], 5, "radius"); err != nil {
+			return nil, err
+		}
+	}
+	for i := 6; i < len(floatParams); i++ {
+		if err := checkColor(floatParams[i], i, names[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	// If given more than nine params we have the five basic params
+	// x,y,xVel, yVel, mass
+	// AND the additional four params
+	// radius, red, green, blue
+	if len(floatParams) >= 9 {
+		var name string
+		if len(bodyParams) >= 10 {
+			name = strings.TrimSpace(bodyParams[9])
+		}
+		return &simulation.Body{
+			Pos:    simulation.Vec2{X: floatParams[0], Y: floatParams[1]},
+			Vel:    simulation.Vec2{X: floatParams[2], Y: floatParams[3]},
+			Mass:   floatParams[4],
+			Radius: floatParams[5],
+			Color:  color.RGBA{uint8(floatParams[6]), uint8(floatParams[7]), uint8(floatParams[8]), 255},
+			Name:   name,
+		}, nil
+	}
+
+	// If given five options, this is in form of
+	// x,y,xVel, yVel, mass
+	// Other properties can be inferred (radius) or randomized
+	return &simulation.Body{
+		Pos:    simulation.Vec2{X: floatParams[0], Y: floatParams[1]},
+		Vel:    simulation.Vec2{X: floatParams[2], Y: floatParams[3]},
+		Mass:   floatParams[4],
+		Radius: simulation.MassToRadius(floatParams[4]),
+		Color:  simulation.RandomColor(rng),
+	}, nil
+}
+
+// Create a body from a csv record whose columns are given by name (e.g. from the header of a save file)
+// The columns x, y, xVel, yVel and mass are required. The radius, color (red, green, blue) and name columns are optional,
+// if missing the radius is calculated using simulation.MassToRadius, the color is randomly generated with rng and the body is unnamed
+// Any other columns are ignored
+//
+// If a field is not a finite number, the mass isn't positive, the radius is negative, a color is outside 0 to 255,
+// or a required field is missing, a *FieldError is returned
+func NewBodyFromColumns(record []string, columns map[string]int, rng *rand.Rand) (*simulation.Body, error) {
+	// Find the value of a column, returning false if the column (or the field in this record) is missing
+	field := func(name string) (float64, bool, error) {
+		i, ok := columns[name]
+		if !ok || i >= len(record) || record[i] == "" {
+			return 0, false, nil
+		}
+		value, err := parseField(record[i], i, name)
+		return value, err == nil, err
+	}
+
+	var required [5]float64
+	for i, name := range []string{"x", "y", "xVel", "yVel", "mass"} {
+		value, ok, err := field(name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			if _, inHeader := columns[name]; !inHeader {
+				return nil, &FieldError{-1, fmt.Errorf("the header has no %v column, need at least x, y, xVel, yVel, mass to create a body", name)}
+			}
+			return nil, &FieldError{-1, fmt.Errorf("missing a value for %v", name)}
+		}
+		required[i] = value
+	}
+	if err := checkPositive(required[4], columns["mass"], "mass"); err != nil {
+		return nil, err
+	}
+
+	b := &simulation.Body{
+		Pos:    simulation.Vec2{X: required[0], Y: required[1]},
+		Vel:    simulation.Vec2{X: required[2], Y: required[3]},
+		Mass:   required[4],
+		Radius: simulation.MassToRadius(required[4]),
+		Color:  simulation.RandomColor(rng),
+	}
+	radius, hasRadius, err := field("radius")
+	if err != nil {
+		return nil, err
+	}
+	if hasRadius {
+		if err := checkNotNegative(radius, columns["radius"], "radius"); err != nil {
+			return nil, err
+		}
+		b.Radius = radius
+	}
+	var rgb [3]float64
+	hasColor := true
+	for i, name := range []string{"red", "green", "blue"} {
+		value, ok, err := field(name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if err := checkColor(value, columns[name], name); err != nil {
+				return nil, err
+			}
+		}
+		rgb[i] = value
+		hasColor = hasColor && ok
+	}
+	if hasColor {
+		b.Color = color.RGBA{uint8(rgb[0]), uint8(rgb[1]), uint8(rgb[2]), 255}
+	}
+	if i, ok := columns["name"]; ok && i < len(record) {
+		b.Name = strings.TrimSpace(record[i])
+	}
+	if i, ok := columns["fixed"]; ok && i < len(record) && strings.TrimSpace(record[i]) != "" {
+		fixed, err := strconv.ParseBool(strings.TrimSpace(record[i]))
+		if err != nil {
+			return nil, &FieldError{i, fmt.Errorf("cannot convert %q to true or false for fixed", record[i])}
+		}
+		b.Fixed = fixed
+	}
+	return b, nil
+}
@@ -0,0 +1,309 @@
+package persist
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The columnar trajectory format is a simple chunked binary format for long runs,
+// where a csv trajectory would be many gigabytes and slow to parse.
+// All values are little endian and eight bytes wide, so each column can be read directly into an array
+// (e.g. numpy.frombuffer) without any parsing.
+//
+// The layout of the file is:
+//   - The magic string "GRAVCOL2"
+//   - The comments (e.g. the provenance of the run): their length in bytes (uint64), then the comments as text,
+//     one "#comment" line each, as in csv trajectories
+//   - A sequence of chunks, each holding up to COLUMNARCHUNKROWS rows stored column by column:
+//     time (float64), id (int64), x, y, xVel, yVel, mass (all float64)
+//   - The index: the number of chunks (uint64), then for each chunk its
+//     offset (int64), number of rows (uint64), first time (float64) and last time (float64)
+//   - The footer: the offset of the index (int64) followed by the magic string again
+//
+// The index allows a reader to jump straight to the chunks covering a time range of interest.
+// Files from before the comments were added start (and end) with "GRAVCOL1" and go straight to the chunks, and can still be read
+const (
+	COLUMNARMAGIC     = "GRAVCOL2"
+	COLUMNARMAGICV1   = "GRAVCOL1"
+	COLUMNARCHUNKROWS = 1 << 16
+	// The number of columns in each chunk, and therefore the number of bytes per row
+	COLUMNARCOLUMNS = 7
+)
+
+// The index entry describing a single chunk of a columnar trajectory file
+type ColumnarChunkInfo struct {
+	Offset    int64
+	Rows      uint64
+	StartTime float64
+	EndTime   float64
+}
+
+// A chunk of rows, stored as columns
+type ColumnarChunk struct {
+	Time []float64
+	ID   []int64
+	X    []float64
+	Y    []float64
+	XVel []float64
+	YVel []float64
+	Mass []float64
+}
+
+// Append a single row to the chunk
+func (c *ColumnarChunk) append(time float64, id int, b *simulation.Body) {
+	c.Time = append(c.Time, time)
+	c.ID = append(c.ID, int64(id))
+	c.X = append(c.X, b.Pos.X)
+	c.Y = append(c.Y, b.Pos.Y)
+	c.XVel = append(c.XVel, b.Vel.X)
+	c.YVel = append(c.YVel, b.Vel.Y)
+	c.Mass = append(c.Mass, b.Mass)
+}
+
+// Empty the chunk, keeping the memory allocated for the next chunk
+func (c *ColumnarChunk) reset() {
+	c.Time = c.Time[:0]
+	c.ID = c.ID[:0]
+	c.X = c.X[:0]
+	c.Y = c.Y[:0]
+	c.XVel = c.XVel[:0]
+	c.YVel = c.YVel[:0]
+	c.Mass = c.Mass[:0]
+}
+
+// Records the trajectory in the columnar format
+// Rows are collected into a chunk in memory, and each full chunk is written out in one go
+type ColumnarTrajectory struct {
+	file   *os.File
+	writer *bufio.Writer
+	// The number of bytes written so far, i.e. the offset of the next chunk
+	offset int64
+	chunk  ColumnarChunk
+	index  []ColumnarChunkInfo
+	// The first error writing the file, after which nothing more is written
+	err error
+}
+
+// Create a new columnar trajectory file
+// Any comments are written to the comment block at the start of the file, so it says where it came from like a csv trajectory
+// Unlike the csv trajectory the file cannot be appended to, as the index is at the end of the file
+func NewColumnarTrajectory(path string, comments ...string) (*ColumnarTrajectory, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	var text strings.Builder
+	for _, c := range comments {
+		fmt.Fprintf(&text, "#%v\n", c)
+	}
+	t := &ColumnarTrajectory{file: f, writer: bufio.NewWriter(f)}
+	t.write([]byte(COLUMNARMAGIC))
+	t.write(uint64(text.Len()))
+	t.write([]byte(text.String()))
+	t.offset = int64(len(COLUMNARMAGIC) + 8 + text.Len())
+	return t, nil
+}
+
+// Write data in little endian, unless writing has already failed, keeping the first error
+func (t *ColumnarTrajectory) write(data any) {
+	if t.err == nil {
+		t.err = binary.Write(t.writer, binary.LittleEndian, data)
+	}
+}
+
+// Add one row per body in the frame, writing out the chunk when it is full
+// Returns the first error writing the file, so a failed disk isn't noticed only at the end of a long run
+func (t *ColumnarTrajectory) Record(time float64, bodies []*simulation.Body) error {
+	for i, b := range bodies {
+		if b == nil {
+			continue
+		}
+		t.chunk.append(time, i, b)
+		if len(t.chunk.Time) >= COLUMNARCHUNKROWS {
+			t.flushChunk()
+		}
+	}
+	return t.err
+}
+
+// Write the current chunk to the file and add it to the index
+func (t *ColumnarTrajectory) flushChunk() {
+	rows := len(t.chunk.Time)
+	if rows == 0 {
+		return
+	}
+	t.index = append(t.index, ColumnarChunkInfo{
+		Offset:    t.offset,
+		Rows:      uint64(rows),
+		StartTime: t.chunk.Time[0],
+		EndTime:   t.chunk.Time[rows-1],
+	})
+	for _, column := range []any{t.chunk.Time, t.chunk.ID, t.chunk.X, t.chunk.Y, t.chunk.XVel, t.chunk.YVel, t.chunk.Mass} {
+		t.write(column)
+	}
+	t.offset += int64(rows * COLUMNARCOLUMNS * 8)
+	t.chunk.reset()
+}
+
+// Write the final chunk, the index and the footer, then close the file, returning the first error writing it
+// A columnar file that is not closed has no index and cannot be read
+func (t *ColumnarTrajectory) Close() error {
+	t.flushChunk()
+	t.write(uint64(len(t.index)))
+	t.write(t.index)
+	t.write(t.offset)
+	t.write([]byte(COLUMNARMAGIC))
+	if t.err == nil {
+		t.err = t.writer.Flush()
+	}
+	if err := t.file.Close(); t.err == nil {
+		t.err = err
+	}
+	return t.err
+}
+
+// Reads a columnar trajectory file chunk by chunk
+type ColumnarReader struct {
+	file *os.File
+	// The size of the file, which limits how many chunks and rows can be read from it
+	size int64
+	// The chunks of the file in order, as read from its index
+	Index []ColumnarChunkInfo
+	// The comments at the start of the file, without their #, e.g. the provenance of the run
+	Comments []string
+}
+
+// Open a columnar trajectory file and read its index
+func OpenColumnarTrajectory(path string) (*ColumnarReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &ColumnarReader{file: f}
+	if err := r.readIndex(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	if err := r.readComments(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return r, nil
+}
+
+// Whether a file starting (or ending) with magic is a columnar trajectory, of either version
+func isColumnarMagic(magic string) bool {
+	return magic == COLUMNARMAGIC || magic == COLUMNARMAGICV1
+}
+
+// Read the comment block at the start of the file, which files of the first version don't have
+func (r *ColumnarReader) readComments() error {
+	if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	magic := make([]byte, len(COLUMNARMAGIC))
+	if _, err := io.ReadFull(r.file, magic); err != nil {
+		return err
+	}
+	if string(magic) == COLUMNARMAGICV1 {
+		return nil
+	}
+	if string(magic) != COLUMNARMAGIC {
+		return errors.New("not a columnar trajectory")
+	}
+	var length uint64
+	if err := binary.Read(r.file, binary.LittleEndian, &length); err != nil {
+		return err
+	}
+	if length > uint64(r.size) {
+		return fmt.Errorf("comments of %v bytes don't fit in the file", length)
+	}
+	text := make([]byte, length)
+	if _, err := io.ReadFull(r.file, text); err != nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(string(text), "\n"), "\n") {
+		if line != "" {
+			r.Comments = append(r.Comments, strings.TrimPrefix(line, "#"))
+		}
+	}
+	return nil
+}
+
+// Read the footer and then the index it points to
+func (r *ColumnarReader) readIndex() error {
+	var footer struct {
+		IndexOffset int64
+		Magic       [len(COLUMNARMAGIC)]byte
+	}
+	end, err := r.file.Seek(-int64(binary.Size(footer)), io.SeekEnd)
+	if err != nil {
+		return errors.New("file too short to be a columnar trajectory")
+	}
+	r.size = end + int64(binary.Size(footer))
+	if err := binary.Read(r.file, binary.LittleEndian, &footer); err != nil {
+		return err
+	}
+	if !isColumnarMagic(string(footer.Magic[:])) {
+		return errors.New("not a columnar trajectory (was the run closed cleanly?)")
+	}
+
+	if footer.IndexOffset < 0 || footer.IndexOffset > end {
+		return fmt.Errorf("index offset %v is outside the file", footer.IndexOffset)
+	}
+	if _, err := r.file.Seek(footer.IndexOffset, io.SeekStart); err != nil {
+		return err
+	}
+	var numChunks uint64
+	if err := binary.Read(r.file, binary.LittleEndian, &numChunks); err != nil {
+		return err
+	}
+	// The index lies between the count and the footer, so can't hold more chunks than fit there
+	if room := end - footer.IndexOffset - 8; room < 0 || numChunks > uint64(room)/uint64(binary.Size(ColumnarChunkInfo{})) {
+		return fmt.Errorf("index of %v chunks doesn't fit in the file", numChunks)
+	}
+	r.Index = make([]ColumnarChunkInfo, numChunks)
+	return binary.Read(r.file, binary.LittleEndian, r.Index)
+}
+
+// Read the i'th chunk of the file
+func (r *ColumnarReader) ReadChunk(i int) (*ColumnarChunk, error) {
+	if i < 0 || i >= len(r.Index) {
+		return nil, fmt.Errorf("chunk %v out of range, the file has %v", i, len(r.Index))
+	}
+	info := r.Index[i]
+	if info.Offset < 0 || info.Offset > r.size || info.Rows > uint64(r.size-info.Offset)/(COLUMNARCOLUMNS*8) {
+		return nil, fmt.Errorf("chunk %v of %v rows at %v doesn't fit in the file", i, info.Rows, info.Offset)
+	}
+	if _, err := r.file.Seek(info.Offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(io.LimitReader(r.file, int64(info.Rows)*COLUMNARCOLUMNS*8))
+	c := &ColumnarChunk{
+		Time: make([]float64, info.Rows),
+		ID:   make([]int64, info.Rows),
+		X:    make([]float64, info.Rows),
+		Y:    make([]float64, info.Rows),
+		XVel: make([]float64, info.Rows),
+		YVel: make([]float64, info.Rows),
+		Mass: make([]float64, info.Rows),
+	}
+	for _, column := range []any{c.Time, c.ID, c.X, c.Y, c.XVel, c.YVel, c.Mass} {
+		if err := binary.Read(br, binary.LittleEndian, column); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Close the underlying file
+func (r *ColumnarReader) Close() {
+	r.file.Close()
+}
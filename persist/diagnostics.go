@@ -0,0 +1,46 @@
+package persist
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Records the totals over all bodies (energies, momentum and angular momentum about the origin and the barycenter) as csv, one row per recorded step,
+// for plotting how well a run conserves them
+// The potential energy is O(n^2) to find, like a step, so recording every step roughly doubles the time each takes
+type DiagnosticsCSV struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// Create a diagnostics file, replacing any file already at path, and write its header
+func NewDiagnosticsCSV(path string) (*DiagnosticsCSV, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &DiagnosticsCSV{file: f, writer: bufio.NewWriter(f)}
+	fmt.Fprintln(d.writer, "#step, time, kinetic, potential, energy, px, py, angularMomentum, barycentricAngularMomentum")
+	return d, nil
+}
+
+// Append the totals of the bodies at the given step and time, with the potential energy found using G
+func (d *DiagnosticsCSV) Record(step int, time float64, bodies []*simulation.Body, G float64) {
+	kinetic := simulation.KineticEnergy(bodies)
+	potential := simulation.PotentialEnergy(bodies, G)
+	momentum := simulation.Momentum(bodies)
+	fmt.Fprintf(d.writer, "%v,%v,%v,%v,%v,%v,%v,%v,%v\n", step, time, kinetic, potential, kinetic+potential,
+		momentum.X, momentum.Y, simulation.AngularMomentum(bodies), simulation.BarycentricAngularMomentum(bodies))
+}
+
+// Flush any buffered rows and close the file, returning any error writing them
+func (d *DiagnosticsCSV) Close() error {
+	err := d.writer.Flush()
+	if closeErr := d.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
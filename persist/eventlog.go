@@ -0,0 +1,92 @@
+package persist
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Records the collisions of a run as JSON lines, one object per collision, for analysing e.g. how the largest bodies grew
+// Each line is of the form
+//
+//	{"type":"merge","step":12,"time":3,"a":{"id":0,"mass":10,...},"b":{"id":4,"mass":2,...},"result":{"id":0,"mass":12,...}}
+//
+// where a and b are the bodies as they collided, and for merges result is the body they merged into at the end of the step
+type EventLog struct {
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	err     error
+}
+
+// One body taking part in an event, as written to the event log
+type EventBody struct {
+	ID   int     `json:"id"`
+	Name string  `json:"name,omitempty"`
+	Mass float64 `json:"mass"`
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	XVel float64 `json:"xVel"`
+	YVel float64 `json:"yVel"`
+}
+
+// One line of the event log
+type Event struct {
+	// Either merge or bounce
+	Type   string     `json:"type"`
+	Step   int        `json:"step"`
+	Time   float64    `json:"time"`
+	A      EventBody  `json:"a"`
+	B      EventBody  `json:"b"`
+	Result *EventBody `json:"result,omitempty"`
+}
+
+// Describe a body with the given id for the event log
+func NewEventBody(id int, b *simulation.Body) EventBody {
+	return EventBody{ID: id, Name: b.Name, Mass: b.Mass, X: b.Pos.X, Y: b.Pos.Y, XVel: b.Vel.X, YVel: b.Vel.Y}
+}
+
+// Create an event log, replacing any file already at path
+func NewEventLog(path string) (*EventLog, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	writer := bufio.NewWriter(f)
+	return &EventLog{file: f, writer: writer, encoder: json.NewEncoder(writer)}, nil
+}
+
+// Append a collision that happened in the last step of s
+func (l *EventLog) RecordCollision(s *simulation.Simulation, c simulation.Collision) {
+	e := Event{Type: "bounce", Step: s.Steps, Time: s.Time, A: NewEventBody(c.A, &c.BodyA), B: NewEventBody(c.B, &c.BodyB)}
+	if c.Merged {
+		e.Type = "merge"
+		if merged := s.Bodies()[c.A]; merged != nil {
+			result := NewEventBody(c.A, merged)
+			e.Result = &result
+		}
+	}
+	l.Record(e)
+}
+
+// Append an event
+// Writing stops at the first error (e.g. a body with a position of NaN, which JSON can't hold), which is returned by Close
+func (l *EventLog) Record(e Event) {
+	if l.err == nil {
+		l.err = l.encoder.Encode(e)
+	}
+}
+
+// Flush any buffered events and close the file, returning the first error writing them
+func (l *EventLog) Close() error {
+	err := l.writer.Flush()
+	if l.err != nil {
+		err = l.err
+	}
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
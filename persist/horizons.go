@@ -0,0 +1,169 @@
+package persist
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Importing real solar system state vectors from JPL Horizons (https://ssd.jpl.nasa.gov/horizons/)
+// Each file should be the output of a Horizons "Vector Table" request for a single body with "CSV format" selected.
+// The first state vector in the file is used, projected onto the reference plane (i.e. the Z components are dropped).
+//
+// To convert to simulation units we fix a length scale (pixels per AU) and a mass scale (simulation mass of the Sun).
+// The time scale is then whatever makes the real gravitational constant equal to the simulation G.
+const (
+	// Physical constants, in kilometres, kilograms and seconds
+	GREAL   = 6.674e-20
+	AUKM    = 1.495978707e8
+	SOLARKG = 1.98847e30
+	DAYSECS = 86400
+	// All imported bodies are drawn with this radius, as real radii are far smaller than a pixel at any useful scale
+	HORIZONSRADIUS = 2
+)
+
+var (
+	// Pattern to find the mass of the body in the Horizons header, with its unit, e.g. "Mass x10^24 (kg)= 5.97219",
+	// "Mass, 10^24 kg = ~1988500" or "Mass x 10^22 (g) = 189818722"
+	horizonsMassPattern = regexp.MustCompile(`Mass[^=\n]*?10\^(\d+)\s*\(?(kg|g)\b[^=\n]*=\s*~?([0-9.]+)`)
+	// Pattern to find the name of the body, e.g. "Target body name: Earth (399)"
+	horizonsNamePattern = regexp.MustCompile(`Target body name:\s*(.*?)\s*(\(|\{|$)`)
+	// Pattern to find the units of the state vectors, e.g. "Output units    : KM-S"
+	horizonsUnitsPattern = regexp.MustCompile(`Output units\s*:\s*(\S+)`)
+)
+
+// Load a set of Horizons vector table files as simulation bodies
+// pixelsPerAU and solarMass set the length and mass scales of the simulation, and G is the simulation's gravitational constant
+// The bodies are given random colors from rng
+func LoadHorizonsFiles(paths []string, pixelsPerAU float64, solarMass float64, G float64, rng *rand.Rand) ([]*simulation.Body, error) {
+	// Length, mass and time units of the simulation in kilometres, kilograms and seconds
+	lengthUnit := AUKM / pixelsPerAU
+	massUnit := SOLARKG / solarMass
+	timeUnit := math.Sqrt(G * math.Pow(lengthUnit, 3) / (GREAL * massUnit))
+	slog.Info("HORIZONS UNITS", "lengthKM", lengthUnit, "massKG", massUnit, "timeDays", timeUnit/DAYSECS)
+
+	bodies := make([]*simulation.Body, 0, len(paths))
+	for _, path := range paths {
+		b, err := loadHorizonsFile(path, lengthUnit, massUnit, timeUnit, rng)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", path, err)
+		}
+		bodies = append(bodies, b)
+	}
+	return bodies, nil
+}
+
+// Load the first state vector from a single Horizons file, converting it to simulation units
+func loadHorizonsFile(path string, lengthUnit, massUnit, timeUnit float64, rng *rand.Rand) (*simulation.Body, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Horizons reports in km and km/s unless configured otherwise
+	distanceKM := 1.0
+	durationSecs := 1.0
+	massKG := 0.0
+	massFound := false
+	var name string
+	// The indices of the columns we need, found from the column header line
+	columns := map[string]int{}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if m := horizonsMassPattern.FindStringSubmatch(line); m != nil && !massFound {
+			exponent, _ := strconv.Atoi(m[1])
+			mantissa, _ := strconv.ParseFloat(m[3], 64)
+			massKG = mantissa * math.Pow(10, float64(exponent))
+			if m[2] == "g" {
+				massKG /= 1000
+			}
+			massFound = true
+		}
+
+		if m := horizonsNamePattern.FindStringSubmatch(line); m != nil && name == "" {
+			name = m[1]
+		}
+
+		if m := horizonsUnitsPattern.FindStringSubmatch(line); m != nil {
+			switch m[1] {
+			case "KM-S":
+				distanceKM, durationSecs = 1, 1
+			case "KM-D":
+				distanceKM, durationSecs = 1, DAYSECS
+			case "AU-D":
+				distanceKM, durationSecs = AUKM, DAYSECS
+			default:
+				return nil, fmt.Errorf("unsupported output units %v", m[1])
+			}
+		}
+
+		// The column header line comes before the data, e.g.
+		// JDTDB, Calendar Date (TDB), X, Y, Z, VX, VY, VZ,
+		if strings.HasPrefix(strings.TrimSpace(line), "JDTDB") {
+			for i, name := range strings.Split(line, ",") {
+				columns[strings.TrimSpace(name)] = i
+			}
+		}
+
+		if strings.TrimSpace(line) != "$$SOE" {
+			continue
+		}
+
+		// The next line is the first state vector
+		if !scanner.Scan() {
+			break
+		}
+		for _, name := range []string{"X", "Y", "VX", "VY"} {
+			if _, ok := columns[name]; !ok {
+				return nil, errors.New("no X, Y, VX, VY columns found, was the vector table output in CSV format?")
+			}
+		}
+		fields := strings.Split(scanner.Text(), ",")
+		values := map[string]float64{}
+		for _, name := range []string{"X", "Y", "VX", "VY"} {
+			if columns[name] >= len(fields) {
+				return nil, fmt.Errorf("state vector is missing column %v", name)
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(fields[columns[name]]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert %v to float", fields[columns[name]])
+			}
+			values[name] = v
+		}
+
+		// Bodies without a mass (e.g. spacecraft) can't be simulated, as merging takes the mass-weighted average of the bodies
+		if !massFound {
+			return nil, errors.New("no mass found in the header, only bodies with a mass can be imported")
+		}
+		if massKG <= 0 {
+			return nil, fmt.Errorf("mass %v kg must be greater than zero", massKG)
+		}
+		velocityUnit := distanceKM / durationSecs
+		return &simulation.Body{
+			// Screen coordinates have y pointing down, so flip y to keep orbits counterclockwise
+			Pos:    simulation.Vec2{X: values["X"] * distanceKM / lengthUnit, Y: -values["Y"] * distanceKM / lengthUnit},
+			Vel:    simulation.Vec2{X: values["VX"] * velocityUnit * timeUnit / lengthUnit, Y: -values["VY"] * velocityUnit * timeUnit / lengthUnit},
+			Mass:   massKG / massUnit,
+			Radius: HORIZONSRADIUS,
+			Color:  simulation.RandomColor(rng),
+			Name:   name,
+		}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("no state vectors found (missing $$SOE marker)")
+}
@@ -0,0 +1,74 @@
+package persist
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The header and first state vector of a Horizons vector table for Jupiter, which gives its mass in grams
+const horizonsJupiter = `*******************************************************************************
+ Revised: April 12, 2021              Jupiter                           599
+
+ PHYSICAL DATA (revised 2024-Jun-30):
+  Mass x 10^22 (g)      = 189818722 +- 8817  Density (g/cm^3)  = 1.3262 +- .0003
+  Equat. radius (1 bar) = 71492+-4 km        Polar radius (km)     = 66854+-10
+  Vol. Mean Radius (km) = 69911+-6           Flattening            = 0.06487
+*******************************************************************************
+Target body name: Jupiter (599)                   {source: jup365_merged}
+Center body name: Sun (10)                        {source: DE441}
+Center-site name: BODY CENTER
+*******************************************************************************
+Output units    : KM-S
+Calendar mode   : Mixed Julian/Gregorian
+Output type     : GEOMETRIC cartesian states
+Output format   : 3 (position, velocity, LT, range, range-rate)
+Reference frame : Ecliptic of J2000.0
+*******************************************************************************
+            JDTDB,            Calendar Date (TDB),                      X,                      Y,                      Z,                     VX,                     VY,                     VZ,
+**************************************************************************************************************************************************************************
+$$SOE
+2460000.500000000, A.D. 2023-Feb-25 00:00:00.0000,  7.059612567882000E+08,  2.449373389513893E+08, -1.683566052226023E+07, -4.453213546218498E+00,  1.312086189853022E+01,  4.559023990196186E-02,
+$$EOE
+`
+
+func writeHorizonsFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "horizons.csv")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadHorizonsJupiter(t *testing.T) {
+	const pixelsPerAU, solarMass = 100, 1000
+	bodies, err := LoadHorizonsFiles([]string{writeHorizonsFile(t, horizonsJupiter)}, pixelsPerAU, solarMass, 1, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := bodies[0]
+	if b.Name != "Jupiter" {
+		t.Errorf("loaded name %q, want Jupiter", b.Name)
+	}
+	// 189818722e22 g is 1.898e27 kg, about a thousandth of the Sun
+	want := 1.89818722e27 / (SOLARKG / solarMass)
+	if math.Abs(b.Mass-want) > 1e-6*want {
+		t.Errorf("loaded mass %v, want %v", b.Mass, want)
+	}
+	if wantX := 7.059612567882e8 / (AUKM / pixelsPerAU); math.Abs(b.Pos.X-wantX) > 1e-9*wantX {
+		t.Errorf("loaded x %v, want %v", b.Pos.X, wantX)
+	}
+	if b.Pos.Y >= 0 || b.Vel.Y >= 0 {
+		t.Errorf("loaded %+v, want y flipped to screen coordinates", b)
+	}
+
+	// A body without a mass can't be simulated
+	massless := strings.Replace(horizonsJupiter, "  Mass x 10^22 (g)      = 189818722 +- 8817", "", 1)
+	if _, err := LoadHorizonsFiles([]string{writeHorizonsFile(t, massless)}, pixelsPerAU, solarMass, 1, rand.New(rand.NewSource(1))); err == nil {
+		t.Error("loaded a body without a mass")
+	}
+}
@@ -0,0 +1,97 @@
+package persist
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"log/slog"
+	"math/rand"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The JSON save format, for tools that read JSON more easily than csv, e.g. web pages:
+//
+//	{"version": 1, "bodies": [{"x": 0, "y": 0, "xVel": 0, "yVel": 0, "mass": 100, "radius": 10, "color": [255, 200, 0], "name": "sun"}]}
+//
+// Like csv saves, only x, y, xVel, yVel and mass are needed to load a body, the rest are made up if missing
+const JSONSAVEVERSION = 1
+
+// The contents of a JSON save file
+type JSONSave struct {
+	Version int `json:"version"`
+	// Where the save came from, if known
+	Provenance *Provenance `json:"provenance,omitempty"`
+	Bodies     []JSONBody  `json:"bodies"`
+}
+
+// A body in a JSON save file
+type JSONBody struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	XVel   float64 `json:"xVel"`
+	YVel   float64 `json:"yVel"`
+	Mass   float64 `json:"mass"`
+	Radius float64 `json:"radius,omitempty"`
+	// The red, green and blue of the body
+	Color *[3]uint8 `json:"color,omitempty"`
+	Name  string    `json:"name,omitempty"`
+	Fixed bool      `json:"fixed,omitempty"`
+}
+
+// Write the bodies as a JSON save file, skipping removed (nil) bodies, with where they came from if provenance isn't nil
+func WriteJSONSave(w io.Writer, bodies []*simulation.Body, provenance *Provenance) error {
+	save := JSONSave{Version: JSONSAVEVERSION, Provenance: provenance, Bodies: []JSONBody{}}
+	for _, b := range bodies {
+		if b == nil {
+			continue
+		}
+		save.Bodies = append(save.Bodies, JSONBody{
+			X: b.Pos.X, Y: b.Pos.Y, XVel: b.Vel.X, YVel: b.Vel.Y, Mass: b.Mass, Radius: b.Radius,
+			Color: &[3]uint8{b.Color.R, b.Color.G, b.Color.B}, Name: b.Name, Fixed: b.Fixed,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(save)
+}
+
+// Parse a JSON save file into a new array of bodies
+// Bodies without a radius get the radius of their mass, and without a color a random color from rng
+// Like csv save files, bodies without a positive mass or with a negative radius are reported and skipped, but a file that isn't valid JSON is an error
+func ParseJSONSave(name string, data []byte, rng *rand.Rand) ([]*simulation.Body, error) {
+	var save JSONSave
+	if err := json.Unmarshal(data, &save); err != nil {
+		return nil, fmt.Errorf("%v: %w", name, err)
+	}
+	if save.Version > JSONSAVEVERSION {
+		return nil, fmt.Errorf("%v: saved with format version %v, but this program only understands up to version %v", name, save.Version, JSONSAVEVERSION)
+	}
+
+	var bodies []*simulation.Body
+	for i, b := range save.Bodies {
+		if b.Mass <= 0 || b.Radius < 0 {
+			slog.Warn("SKIPPING BODY", "location", fmt.Sprintf("%v: body %v", name, i), "err", "the mass must be greater than zero and the radius not negative")
+			continue
+		}
+		body := &simulation.Body{
+			Pos:    simulation.Vec2{X: b.X, Y: b.Y},
+			Vel:    simulation.Vec2{X: b.XVel, Y: b.YVel},
+			Mass:   b.Mass,
+			Radius: b.Radius,
+			Name:   b.Name,
+			Fixed:  b.Fixed,
+		}
+		if body.Radius == 0 {
+			body.Radius = simulation.MassToRadius(body.Mass)
+		}
+		if b.Color != nil {
+			body.Color = color.RGBA{b.Color[0], b.Color[1], b.Color[2], 255}
+		} else {
+			body.Color = simulation.RandomColor(rng)
+		}
+		bodies = append(bodies, body)
+	}
+	return bodies, nil
+}
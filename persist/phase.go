@@ -0,0 +1,49 @@
+package persist
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Records samples of the phase space of every body as csv, one row per body per recorded step, for plotting
+// e.g. x against xVel to see an oscillation, or r against rVel to see an orbit as a closed loop
+// r and rVel are the distance from the most massive body (the primary) and the speed away from it, both 0 for the primary itself
+type PhaseCSV struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// Create a phase space file, replacing any file already at path, and write its header
+func NewPhaseCSV(path string) (*PhaseCSV, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &PhaseCSV{file: f, writer: bufio.NewWriter(f)}
+	fmt.Fprintln(p.writer, "#time, id, x, xVel, y, yVel, r, rVel")
+	return p, nil
+}
+
+// Append a sample of every body at the given time
+func (p *PhaseCSV) Record(time float64, bodies []*simulation.Body) {
+	primary := simulation.Primary(bodies)
+	for id, b := range bodies {
+		if b == nil {
+			continue
+		}
+		r, rVel := simulation.Radial(b, bodies[primary])
+		fmt.Fprintf(p.writer, "%v,%v,%v,%v,%v,%v,%v,%v\n", time, id, b.Pos.X, b.Vel.X, b.Pos.Y, b.Vel.Y, r, rVel)
+	}
+}
+
+// Flush any buffered rows and close the file, returning any error writing them
+func (p *PhaseCSV) Close() error {
+	err := p.writer.Flush()
+	if closeErr := p.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
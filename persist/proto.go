@@ -0,0 +1,80 @@
+package persist
+
+import (
+	"fmt"
+	"image/color"
+
+	"google.golang.org/protobuf/proto"
+
+	"hmcalister/gravity_simulation/simulation"
+	"hmcalister/gravity_simulation/statepb"
+)
+
+// Convert bodies to their protobuf form, using each body's index as its id
+// Removed (nil) bodies are skipped, leaving a gap in the ids
+func BodiesToProto(bodies []*simulation.Body) []*statepb.Body {
+	var out []*statepb.Body
+	for i, b := range bodies {
+		if b == nil {
+			continue
+		}
+		out = append(out, &statepb.Body{
+			Id:     int64(i),
+			X:      b.Pos.X,
+			Y:      b.Pos.Y,
+			XVel:   b.Vel.X,
+			YVel:   b.Vel.Y,
+			Mass:   b.Mass,
+			Radius: b.Radius,
+			Color:  &statepb.Color{Red: uint32(b.Color.R), Green: uint32(b.Color.G), Blue: uint32(b.Color.B)},
+			Name:   b.Name,
+			Fixed:  b.Fixed,
+		})
+	}
+	return out
+}
+
+// The largest body id read from a protobuf state
+// Gaps in the ids are kept, so the bodies take space for every id up to the largest, which is limited so a corrupt or
+// malicious file can't ask for more memory than there is
+const MAXPROTOID = 1 << 24
+
+// Convert the bodies of a protobuf state, ignoring the settings
+// Bodies are placed at their ids, so any gaps left by removed bodies are kept
+func BodiesFromProto(state *statepb.State) ([]*simulation.Body, error) {
+	byID := make(map[int64]*simulation.Body, len(state.Bodies))
+	numBodies := 0
+	for _, b := range state.Bodies {
+		if b.Id < 0 || b.Id > MAXPROTOID {
+			return nil, fmt.Errorf("body %q has an id %v outside 0 to %v", b.Name, b.Id, MAXPROTOID)
+		}
+		if byID[b.Id] != nil {
+			return nil, fmt.Errorf("two bodies have the same id %v", b.Id)
+		}
+		numBodies = max(numBodies, int(b.Id)+1)
+		byID[b.Id] = &simulation.Body{
+			Pos:    simulation.Vec2{X: b.X, Y: b.Y},
+			Vel:    simulation.Vec2{X: b.XVel, Y: b.YVel},
+			Mass:   b.Mass,
+			Radius: b.Radius,
+			Color:  color.RGBA{uint8(b.Color.GetRed()), uint8(b.Color.GetGreen()), uint8(b.Color.GetBlue()), 255},
+			Name:   b.Name,
+			Fixed:  b.Fixed,
+		}
+	}
+
+	bodies := make([]*simulation.Body, numBodies)
+	for id, b := range byID {
+		bodies[id] = b
+	}
+	return bodies, nil
+}
+
+// Decode only the bodies of a protobuf encoded state, ignoring the settings
+func DecodeBodies(data []byte) ([]*simulation.Body, error) {
+	var state statepb.State
+	if err := proto.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return BodiesFromProto(&state)
+}
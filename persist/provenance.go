@@ -0,0 +1,32 @@
+package persist
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Where a save or trajectory came from, so the run that made it can be reproduced
+// It is written as comment lines after the header of csv saves and trajectories, in the comment block of columnar
+// trajectories, and as a field of JSON saves
+// Protobuf saves and REBOUND snapshots have nowhere to put it, though protobuf saves keep the state of the random numbers
+type Provenance struct {
+	// The seed of the random numbers the run started with
+	Seed int64 `json:"seed"`
+	// The version of the program that made the file
+	Version string `json:"version"`
+	// The command to run the program with to run the run again
+	Command string `json:"command"`
+}
+
+// The provenance as comment lines without their #, one per field, or none for a nil provenance
+func (p *Provenance) Comments() []string {
+	if p == nil {
+		return nil
+	}
+	// A comment can't span lines, so any line breaks in the command are written as spaces
+	return []string{
+		fmt.Sprintf("seed %v", p.Seed),
+		"codeVersion " + p.Version,
+		"command " + strings.ReplaceAll(p.Command, "\n", " "),
+	}
+}
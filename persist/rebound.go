@@ -0,0 +1,107 @@
+package persist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Interoperability with REBOUND (https://rebound.readthedocs.io), so this simulator can be cross-checked against an established N-body code.
+// REBOUND's own plain text output omits masses, so we use a whitespace separated snapshot with one particle per line:
+//
+//	m x y z vx vy vz r
+//
+// preceded by comment lines giving the gravitational constant and the simulation time, e.g. "# G = 1" and "# t = 0".
+// Snapshots are written with G = 1 (REBOUND's default), by scaling the masses, so time units match exactly.
+// The z components are always zero on export, and are ignored on import.
+// A snapshot can be loaded into REBOUND with:
+//
+//	sim = rebound.Simulation()
+//	for line in open("save.rebound"):
+//	    if not line.startswith("#"):
+//	        m, x, y, z, vx, vy, vz, r = map(float, line.split())
+//	        sim.add(m=m, x=x, y=y, z=z, vx=vx, vy=vy, vz=vz, r=r)
+//
+// and written from REBOUND in the same format to be loaded here.
+
+// Write the bodies as a REBOUND snapshot at simulation time t, with masses scaled from the gravitational constant G
+func WriteReboundSnapshot(out io.Writer, bodies []*simulation.Body, t float64, G float64) {
+	w := bufio.NewWriter(out)
+	fmt.Fprintln(w, "# REBOUND snapshot: m x y z vx vy vz r")
+	fmt.Fprintln(w, "# G = 1")
+	fmt.Fprintln(w, "# t =", t)
+	for _, b := range bodies {
+		if b != nil {
+			fmt.Fprintf(w, "%v %v %v 0 %v %v 0 %v\n", b.Mass*G, b.Pos.X, b.Pos.Y, b.Vel.X, b.Vel.Y, b.Radius)
+		}
+	}
+	w.Flush()
+}
+
+// Parse a REBOUND snapshot into a new array of bodies, scaling masses to the gravitational constant G
+// Like csv save files, bad lines are reported and skipped, and bodies are given random colors from rng
+func ParseReboundSnapshot(name string, data []byte, G float64, rng *rand.Rand) ([]*simulation.Body, error) {
+	reboundG := 1.0
+	var bodies []*simulation.Body
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		// Comment lines may give the gravitational constant the masses are relative to
+		if strings.HasPrefix(text, "#") {
+			key, value, found := strings.Cut(strings.TrimPrefix(text, "#"), "=")
+			if found && strings.TrimSpace(key) == "G" {
+				g, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil || g <= 0 {
+					return nil, fmt.Errorf("%v:%v: invalid gravitational constant %q", name, line, strings.TrimSpace(value))
+				}
+				reboundG = g
+			}
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 7 {
+			slog.Warn("SKIPPING LINE", "location", fmt.Sprintf("%v:%v", name, line), "err", fmt.Sprintf("found %v fields but need at least seven (m x y z vx vy vz)", len(fields)))
+			continue
+		}
+		var values [8]float64
+		bad := -1
+		for i := 0; i < len(fields) && i < len(values); i++ {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				bad = i
+				break
+			}
+			values[i] = v
+		}
+		if bad >= 0 {
+			slog.Warn("SKIPPING LINE", "location", fmt.Sprintf("%v:%v", name, line), "err", fmt.Sprintf("cannot convert %q to a number", fields[bad]))
+			continue
+		}
+
+		mass := values[0] * reboundG / G
+		radius := simulation.MassToRadius(mass)
+		if len(fields) >= 8 && values[7] > 0 {
+			radius = values[7]
+		}
+		bodies = append(bodies, &simulation.Body{
+			Pos:    simulation.Vec2{X: values[1], Y: values[2]},
+			Vel:    simulation.Vec2{X: values[4], Y: values[5]},
+			Mass:   mass,
+			Radius: radius,
+			Color:  simulation.RandomColor(rng),
+		})
+	}
+	return bodies, scanner.Err()
+}
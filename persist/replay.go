@@ -0,0 +1,146 @@
+package persist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Read a trajectory file (in either format written by OpenTrajectory) frame by frame, calling f with the time and bodies of each frame
+// The bodies are indexed by their id, with nil for any id not in the frame, and have the radius of their mass
+// Each frame has its own bodies, so f may keep them
+// Reading stops at the first error, either in the file or returned by f
+func ReadTrajectory(path string, f func(time float64, bodies []*simulation.Body) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	magic := make([]byte, len(COLUMNARMAGIC))
+	n, _ := io.ReadFull(file, magic)
+	file.Close()
+
+	frames := &frameReader{f: f}
+	if isColumnarMagic(string(magic[:n])) {
+		err = readColumnarFrames(path, frames)
+	} else {
+		err = readCSVFrames(path, frames)
+	}
+	if err != nil {
+		return err
+	}
+	return frames.flush()
+}
+
+// Whether the file at path is a trajectory (in either format written by OpenTrajectory) rather than a save file, from how it starts
+func IsTrajectory(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	start := make([]byte, 64)
+	n, _ := io.ReadFull(file, start)
+	start = start[:n]
+	return isColumnarMagic(string(start[:min(n, len(COLUMNARMAGIC))])) || strings.HasPrefix(strings.TrimSpace(string(start)), "#time")
+}
+
+// Gathers the rows of a trajectory into frames, passing each frame on once all of its rows are read
+type frameReader struct {
+	f      func(time float64, bodies []*simulation.Body) error
+	time   float64
+	bodies []*simulation.Body
+}
+
+// Add a row to the current frame, first passing on the current frame if the row is the start of the next one
+func (r *frameReader) add(time float64, id int64, b *simulation.Body) error {
+	if r.bodies != nil && time != r.time {
+		if err := r.flush(); err != nil {
+			return err
+		}
+	}
+	r.time = time
+	for int64(len(r.bodies)) <= id {
+		r.bodies = append(r.bodies, nil)
+	}
+	b.Radius = simulation.MassToRadius(b.Mass)
+	r.bodies[id] = b
+	return nil
+}
+
+// Pass on the current frame, if there is one
+func (r *frameReader) flush() error {
+	if r.bodies == nil {
+		return nil
+	}
+	bodies := r.bodies
+	r.bodies = nil
+	return r.f(r.time, bodies)
+}
+
+// Read the rows of a csv trajectory (see CSVTrajectory)
+func readCSVFrames(path string, frames *frameReader) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, ",")
+		if len(fields) != COLUMNARCOLUMNS {
+			return fmt.Errorf("%v:%v: expected %v fields (time, id, x, y, xVel, yVel, mass) but found %v", path, line, COLUMNARCOLUMNS, len(fields))
+		}
+		var values [COLUMNARCOLUMNS]float64
+		for i, field := range fields {
+			if values[i], err = strconv.ParseFloat(strings.TrimSpace(field), 64); err != nil {
+				return fmt.Errorf("%v:%v: field %v is not a number: %w", path, line, i+1, err)
+			}
+		}
+		b := &simulation.Body{Pos: simulation.Vec2{X: values[2], Y: values[3]}, Vel: simulation.Vec2{X: values[4], Y: values[5]}, Mass: values[6]}
+		if values[1] < 0 || values[1] != float64(int64(values[1])) {
+			return fmt.Errorf("%v:%v: invalid body id %v", path, line, fields[1])
+		}
+		if err := frames.add(values[0], int64(values[1]), b); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Read the rows of a columnar trajectory, chunk by chunk
+func readColumnarFrames(path string, frames *frameReader) error {
+	r, err := OpenColumnarTrajectory(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for i := range r.Index {
+		c, err := r.ReadChunk(i)
+		if err != nil {
+			return fmt.Errorf("%v: chunk %v: %w", path, i, err)
+		}
+		for row := range c.Time {
+			b := &simulation.Body{Pos: simulation.Vec2{X: c.X[row], Y: c.Y[row]}, Vel: simulation.Vec2{X: c.XVel[row], Y: c.YVel[row]}, Mass: c.Mass[row]}
+			if c.ID[row] < 0 {
+				return fmt.Errorf("%v: chunk %v: invalid body id %v", path, i, c.ID[row])
+			}
+			if err := frames.add(c.Time[row], c.ID[row], b); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package persist
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Save files are versioned so that new fields can be added without breaking existing files.
+// Old files are upgraded on load by a chain of migrations, one per version, so only the latest format needs to be understood.
+//   - Version 0 files have no header, and are read by position (x, y, xVel, yVel, mass and optionally radius, red, green, blue, name)
+//   - Version 1 files have a header comment naming the columns
+//   - Version 2 files add the name column, and a version line before the header
+//   - Version 3 files add the fixed column
+//
+// The current version, and the header it is written with, are defined with the writer in the simulation package
+
+// The contents of a save file, in the format of the version it was written in
+type saveFile struct {
+	version int
+	// The index of each column by name
+	columns map[string]int
+	records [][]string
+	// The line and column of each field of each record, used to point at problems in the file
+	positions [][][2]int
+}
+
+// saveMigrations[i] upgrades a save file from version i to version i+1
+var saveMigrations = []func(*saveFile){
+	migrateSaveV0,
+	migrateSaveV1,
+	migrateSaveV2,
+}
+
+// Version 0 files are read by position, so give them the columns version 1 would have
+// As before, the radius and color are only used if all four are present
+func migrateSaveV0(f *saveFile) {
+	f.columns = map[string]int{}
+	for i, name := range []string{"x", "y", "xVel", "yVel", "mass", "radius", "red", "green", "blue", "name"} {
+		f.columns[name] = i
+	}
+	for i, record := range f.records {
+		if len(record) < 9 && len(record) > 5 {
+			f.records[i] = record[:5]
+		}
+	}
+}
+
+// Version 2 only adds the optional name column, which version 1 files simply don't have
+func migrateSaveV1(f *saveFile) {}
+
+// Version 3 only adds the optional fixed column, without which bodies are free to move as before
+func migrateSaveV2(f *saveFile) {}
+
+// Parse the contents of a save file into a new array of bodies, upgrading it from older versions if needed
+// Any random colors are generated with rng
+// name is used to give the location of any problems, e.g. "save.csv:3:12: cannot convert ..."
+// Rows that cannot be made into a body are logged as warnings (with log/slog) and skipped, so one typo doesn't lose a whole file,
+// but an error is returned if the file cannot be read as csv at all
+func ParseSaveData(name string, data []byte, rng *rand.Rand) ([]*simulation.Body, error) {
+	f, err := readSaveFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", name, err)
+	}
+	if f.version > simulation.SAVEVERSION {
+		return nil, fmt.Errorf("%v: saved with format version %v, but this program only understands up to version %v", name, f.version, simulation.SAVEVERSION)
+	}
+	for ; f.version < simulation.SAVEVERSION; f.version++ {
+		saveMigrations[f.version](f)
+	}
+
+	var bodies []*simulation.Body
+	for i, record := range f.records {
+		b, err := NewBodyFromColumns(record, f.columns, rng)
+		if err != nil {
+			slog.Warn("SKIPPING ROW", "location", strings.TrimSuffix(saveFileLocation(name, f.positions[i], err), ":"), "err", err)
+			continue
+		}
+		bodies = append(bodies, b)
+	}
+	return bodies, nil
+}
+
+// Read the version, header and records of a save file
+func readSaveFile(data []byte) (*saveFile, error) {
+	f := &saveFile{}
+	var err error
+	f.version, f.columns, err = parseSaveHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save files are in csv format, so we can use the encoding/csv to read it out
+	r := csv.NewReader(strings.NewReader(string(data)))
+	// Comment lines start with #
+	// e.g. the first line which details the csv format
+	r.Comment = '#'
+	// Rows may have differing numbers of fields (e.g. some with and some without colors)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// The csv package already gives the line and column, e.g. "record on line 3: ..."
+			return nil, fmt.Errorf("not a correctly formatted csv file: %w", err)
+		}
+
+		positions := make([][2]int, len(record))
+		for i := range record {
+			positions[i][0], positions[i][1] = r.FieldPos(i)
+		}
+		f.records = append(f.records, record)
+		f.positions = append(f.positions, positions)
+	}
+	return f, nil
+}
+
+// Format the location in the file of an error in a record, e.g. "save.csv:3:12:"
+func saveFileLocation(name string, positions [][2]int, err error) string {
+	position := positions[0]
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) && fieldErr.Field >= 0 && fieldErr.Field < len(positions) {
+		position = positions[fieldErr.Field]
+	}
+	return fmt.Sprintf("%v:%v:%v:", name, position[0], position[1])
+}
+
+// Find the version and column names from the comment lines at the top of a save file
+// The version is given by a "#version N" line, and the header is the first comment line which names both an x and a y column
+// Files without a version line are version 1 if they have a header, or version 0 (and nil columns) otherwise
+// Files with a version line but no header have the columns in the order they are written in, that of SAVEHEADER
+// A version line that isn't a non-negative whole number is an error, rather than silently reading the file as another version
+func parseSaveHeader(data []byte) (int, map[string]int, error) {
+	version := -1
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, simulation.SAVEVERSIONPREFIX) {
+			value := strings.TrimSpace(strings.TrimPrefix(line, simulation.SAVEVERSIONPREFIX))
+			v, err := strconv.Atoi(value)
+			if err != nil || v < 0 {
+				return 0, nil, fmt.Errorf("the version must be a whole number of at least zero, not %q", value)
+			}
+			if version < 0 {
+				version = v
+			}
+			continue
+		}
+
+		columns := headerColumns(line)
+		_, hasX := columns["x"]
+		_, hasY := columns["y"]
+		if hasX && hasY {
+			if version < 0 {
+				version = 1
+			}
+			return version, columns, nil
+		}
+	}
+	if version > 0 {
+		return version, headerColumns(simulation.SAVEHEADER), nil
+	}
+	return 0, nil, nil
+}
+
+// The index of each column named in a header line, e.g. "#x, y, xVel"
+func headerColumns(line string) map[string]int {
+	columns := map[string]int{}
+	for i, name := range strings.Split(strings.TrimPrefix(line, "#"), ",") {
+		columns[strings.TrimSpace(name)] = i
+	}
+	return columns
+}
+
+// Save files of any format can be compressed with gzip, shown by a .gz extension after the format's, e.g. save.csv.gz
+// Decompress the data of such a file, returning its name without the .gz so its format can be found
+// The name and data of any other file are returned as they are
+func Decompress(name string, data []byte) (string, []byte, error) {
+	if !strings.HasSuffix(name, ".gz") {
+		return name, data, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, fmt.Errorf("%v: %w", name, err)
+	}
+	defer r.Close()
+	data, err = io.ReadAll(r)
+	if err != nil {
+		return "", nil, fmt.Errorf("%v: %w", name, err)
+	}
+	return strings.TrimSuffix(name, ".gz"), data, nil
+}
+
+// Parse the bodies from a save file in any of the supported formats, chosen by the extension of name
+// Files ending in .pb are protobuf, .rebound are REBOUND snapshots, .json are JSON saves and anything else is csv,
+// any of which may be compressed with gzip (see Decompress)
+// Masses in REBOUND snapshots are scaled to the gravitational constant G, and any random colors are generated with rng
+// Note the settings in protobuf files are not returned, decode the whole statepb.State to load those too
+func ParseStateFile(name string, data []byte, G float64, rng *rand.Rand) ([]*simulation.Body, error) {
+	name, data, err := Decompress(name, data)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(name, ".pb") {
+		bodies, err := DecodeBodies(data)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", name, err)
+		}
+		return bodies, nil
+	}
+	if strings.HasSuffix(name, ".rebound") {
+		return ParseReboundSnapshot(name, data, G, rng)
+	}
+	if strings.HasSuffix(name, ".json") {
+		return ParseJSONSave(name, data, rng)
+	}
+	return ParseSaveData(name, data, rng)
+}
@@ -0,0 +1,156 @@
+package persist
+
+import (
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"hmcalister/gravity_simulation/simulation"
+	"hmcalister/gravity_simulation/statepb"
+)
+
+// The fuzz targets check that no save file, however malformed, can panic the loader or load a body that would
+// corrupt the simulation. Run them with e.g. go test ./persist -fuzz=FuzzParseSaveData, the seeds run with every go test
+
+// Check a loaded body is fit to simulate: every number finite, the mass positive and the radius not negative
+func checkBody(t *testing.T, b *simulation.Body) {
+	t.Helper()
+	if b == nil {
+		t.Fatal("loaded a nil body")
+	}
+	for _, v := range []float64{b.Pos.X, b.Pos.Y, b.Vel.X, b.Vel.Y, b.Mass, b.Radius} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			t.Fatalf("loaded a body with a non-finite value: %+v", b)
+		}
+	}
+	if b.Mass <= 0 || b.Radius < 0 {
+		t.Fatalf("loaded a body without a positive mass or with a negative radius: %+v", b)
+	}
+}
+
+func FuzzNewBodyFromStrings(f *testing.F) {
+	f.Add("1,2,3,4,5")
+	f.Add("1, 2, 3, 4, 5, 6, 7, 8, 9, planet")
+	f.Add("1,2,3,4")
+	f.Add("x,2,3,4,5")
+	f.Add("NaN,2,3,4,-5")
+	f.Add("1,2,3,4,5,6,300,-1,1e300")
+	f.Fuzz(func(t *testing.T, row string) {
+		b, err := NewBodyFromStrings(strings.Split(row, ","), rand.New(rand.NewSource(1)))
+		if err == nil {
+			checkBody(t, b)
+		}
+	})
+}
+
+func FuzzParseSaveData(f *testing.F) {
+	f.Add([]byte("#version 3\n#x, y, xVel, yVel, mass, radius, red, green, blue, name, fixed\n1, 2, 3, 4, 5, 6, 7, 8, 9, sun, true\n"))
+	f.Add([]byte("#x,y,xVel,yVel,mass\n1,2,3,4,5\n"))
+	f.Add([]byte("1,2,3,4,5\n1,2,3,4,5,6,7,8,9\n"))
+	f.Add([]byte("#version -1\n#x,y,xVel,yVel,mass\n1,2,3,4,5\n"))
+	f.Add([]byte("#version 99\n1,2,3,4,5\n"))
+	f.Add([]byte("#version 3\n1,2,3,4,5,6,7,8,9,sun,true\n"))
+	f.Add([]byte("#mass,y,x\n5,2\n"))
+	f.Add([]byte("1,2,\"3\n"))
+	f.Add([]byte(""))
+	// Nearly every input has rows to skip, and logging each one would swamp the output of the fuzzer
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		bodies, err := ParseSaveData("fuzz.csv", data, rand.New(rand.NewSource(1)))
+		if err != nil {
+			return
+		}
+		for _, b := range bodies {
+			checkBody(t, b)
+		}
+	})
+}
+
+// A versioned file without a header has its columns in the order they are written in
+func TestParseSaveDataWithoutHeader(t *testing.T) {
+	data := []byte("#version 3\n1, 2, 3, 4, 5, 6, 7, 8, 9, sun, true\n1, 2, 3, 4, 5\n")
+	bodies, err := ParseSaveData("test.csv", data, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("loaded %v bodies, want 2", len(bodies))
+	}
+	if b := bodies[0]; b.Radius != 6 || b.Name != "sun" || !b.Fixed {
+		t.Errorf("loaded %+v, want radius 6, named sun and fixed", b)
+	}
+	if b := bodies[1]; b.Mass != 5 || b.Radius != simulation.MassToRadius(5) {
+		t.Errorf("loaded %+v, want mass 5 with the radius of its mass", b)
+	}
+
+	// A version this program doesn't understand is still rejected
+	if _, err := ParseSaveData("test.csv", []byte("#version 99\n1, 2, 3, 4, 5\n"), rand.New(rand.NewSource(1))); err == nil {
+		t.Error("loaded a headerless file of a newer version")
+	}
+	// As is a version that isn't a whole number of at least zero, rather than reading the file as another version
+	for _, version := range []string{"-1", "two"} {
+		if _, err := ParseSaveData("test.csv", []byte("#version "+version+"\n1, 2, 3, 4, 5\n"), rand.New(rand.NewSource(1))); err == nil {
+			t.Errorf("loaded a file with version %v", version)
+		}
+	}
+}
+
+// Rows with a mass that can't merge, or a color that would wrap around, are skipped
+func TestParseSaveDataSkipsInvalidRows(t *testing.T) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	data := []byte("#version 3\n1, 2, 3, 4, 0, 6, 7, 8, 9\n1, 2, 3, 4, 5, 6, 256, 8, 9\n1, 2, 3, 4, 5, 6, 7, -1, 9\n1, 2, 3, 4, 5, 6, 255, 0, 9\n")
+	bodies, err := ParseSaveData("test.csv", data, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 1 || bodies[0].Color.R != 255 {
+		t.Fatalf("loaded %v bodies, want only the last with red 255", len(bodies))
+	}
+	if _, err := NewBodyFromStrings([]string{"1", "2", "3", "4", "5", "6", "7", "8", "300"}, rand.New(rand.NewSource(1))); err == nil {
+		t.Error("created a body with blue 300")
+	}
+}
+
+func FuzzParseJSONSave(f *testing.F) {
+	f.Add([]byte(`{"version": 1, "bodies": [{"x": 1, "y": 2, "xVel": 3, "yVel": 4, "mass": 5, "radius": 6, "color": [7, 8, 9], "name": "sun", "fixed": true}]}`))
+	f.Add([]byte(`{"bodies": [{"x": 1, "y": 2, "xVel": 3, "yVel": 4, "mass": 5}]}`))
+	f.Add([]byte(`{"version": 1, "bodies": [{"x": 1e400, "mass": -1}]}`))
+	f.Add([]byte(`{"version": 99, "bodies": []}`))
+	f.Add([]byte(`{"bodies": [{"color": [300, 0]}]}`))
+	f.Add([]byte(`[1, 2, 3]`))
+	f.Add([]byte(""))
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		bodies, err := ParseJSONSave("fuzz.json", data, rand.New(rand.NewSource(1)))
+		if err != nil {
+			return
+		}
+		for _, b := range bodies {
+			checkBody(t, b)
+		}
+	})
+}
+
+// Protobuf saves aren't checked for non-finite values, so only check no ids can panic the loader
+func FuzzDecodeBodies(f *testing.F) {
+	for _, ids := range [][]int64{{0, 1, 2}, {0, 5}, {1 << 50}, {-1}, {3, 3}} {
+		var state statepb.State
+		for _, id := range ids {
+			state.Bodies = append(state.Bodies, &statepb.Body{Id: id, Mass: 1, Radius: 1})
+		}
+		data, err := proto.Marshal(&state)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte(""))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		DecodeBodies(data)
+	})
+}
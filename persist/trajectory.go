@@ -0,0 +1,79 @@
+package persist
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// A TrajectoryRecorder is given every frame of the simulation and writes it to some output
+// The id of a body is its index in the bodies array, which is stable for the lifetime of the body
+// Record and Close return the first error writing the output, after which nothing more is written
+type TrajectoryRecorder interface {
+	Record(time float64, bodies []*simulation.Body) error
+	Close() error
+}
+
+// Open a trajectory file in the given format ("csv" or "columnar")
+// Any comments (e.g. from Provenance.Comments) are written after the header of csv trajectories, and in the comment block
+// at the start of columnar trajectories
+func OpenTrajectory(path string, format string, comments ...string) (TrajectoryRecorder, error) {
+	switch format {
+	case "csv":
+		return NewCSVTrajectory(path, comments...)
+	case "columnar":
+		return NewColumnarTrajectory(path, comments...)
+	default:
+		return nil, fmt.Errorf("unknown trajectory format %v, expected csv or columnar", format)
+	}
+}
+
+// Records the trajectory as csv, one row per body per step
+// Writes are buffered as a row is written for every body at every step
+type CSVTrajectory struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// Open a csv trajectory file for appending, writing the header if the file is new (or empty)
+// Any comments are written as comment lines, after the header or the rows already in the file, so each run appended says where it came from
+func NewCSVTrajectory(path string, comments ...string) (*CSVTrajectory, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	t := &CSVTrajectory{file: f, writer: bufio.NewWriter(f)}
+
+	if info, err := f.Stat(); err == nil && info.Size() == 0 {
+		fmt.Fprintln(t.writer, "#time, id, x, y, xVel, yVel, mass")
+	}
+	for _, c := range comments {
+		fmt.Fprintf(t.writer, "#%v\n", c)
+	}
+	return t, nil
+}
+
+// Append one row per body in the frame to the trajectory file
+// The writer keeps the first error writing the file, so it is returned here for every frame after it
+func (t *CSVTrajectory) Record(time float64, bodies []*simulation.Body) error {
+	for i, b := range bodies {
+		if b == nil {
+			continue
+		}
+		if _, err := fmt.Fprintf(t.writer, "%v,%v,%v,%v,%v,%v,%v\n", time, i, b.Pos.X, b.Pos.Y, b.Vel.X, b.Vel.Y, b.Mass); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush any buffered trajectory rows and close the file, returning any error writing them
+func (t *CSVTrajectory) Close() error {
+	err := t.writer.Flush()
+	if closeErr := t.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
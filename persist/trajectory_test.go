@@ -0,0 +1,125 @@
+package persist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Trajectories read back as the frames they were recorded with, in either format
+// The columnar trajectory records enough rows to fill more than one chunk, so frames are read across chunks
+func TestTrajectoryRoundTrip(t *testing.T) {
+	const frames = COLUMNARCHUNKROWS/2 + 10
+	for _, format := range []string{"csv", "columnar"} {
+		path := filepath.Join(t.TempDir(), "trajectory."+format)
+		out, err := OpenTrajectory(path, format, "seed 1729", "command ./gravity_simulation --seed=1729")
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies := []*simulation.Body{
+			{Pos: simulation.Vec2{X: 1, Y: -2}, Vel: simulation.Vec2{X: 0.5}, Mass: 3},
+			nil,
+			{Pos: simulation.Vec2{X: -7.25}, Vel: simulation.Vec2{Y: -1e-9}, Mass: 1e6},
+		}
+		for i := 0; i < frames; i++ {
+			bodies[0].Pos.X = float64(i)
+			if err := out.Record(float64(i)/10, bodies); err != nil {
+				t.Fatalf("%v: %v", format, err)
+			}
+		}
+		if err := out.Close(); err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+
+		read := 0
+		err = ReadTrajectory(path, func(time float64, got []*simulation.Body) error {
+			if time != float64(read)/10 {
+				t.Fatalf("%v: frame %v has time %v, want %v", format, read, time, float64(read)/10)
+			}
+			if len(got) != 3 || got[1] != nil || got[0] == nil || got[2] == nil {
+				t.Fatalf("%v: frame %v has bodies %v, want bodies 0 and 2", format, read, got)
+			}
+			if got[0].Pos != (simulation.Vec2{X: float64(read), Y: -2}) || got[0].Vel != bodies[0].Vel || got[0].Mass != 3 {
+				t.Fatalf("%v: frame %v has body 0 as %+v", format, read, got[0])
+			}
+			if got[2].Pos != bodies[2].Pos || got[2].Vel != bodies[2].Vel || got[2].Mass != bodies[2].Mass {
+				t.Fatalf("%v: frame %v has body 2 as %+v, want %+v", format, read, got[2], bodies[2])
+			}
+			read++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+		if read != frames {
+			t.Errorf("%v: read %v frames, want %v", format, read, frames)
+		}
+	}
+}
+
+// Columnar trajectories hold their comments (e.g. the provenance) like csv trajectories, and files from before the
+// comments were added can still be read
+func TestColumnarTrajectoryComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trajectory.columnar")
+	out, err := NewColumnarTrajectory(path, "seed 1729", "codeVersion abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Record(0, []*simulation.Body{{Mass: 1}})
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := OpenColumnarTrajectory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if !reflect.DeepEqual(r.Comments, []string{"seed 1729", "codeVersion abc"}) {
+		t.Errorf("read comments %q", r.Comments)
+	}
+	if c, err := r.ReadChunk(0); err != nil || len(c.Time) != 1 || c.Mass[0] != 1 {
+		t.Errorf("read chunk %+v, %v after the comments", c, err)
+	}
+
+	// A first version file without any chunks: the magic, an empty index and the footer pointing at it
+	var v1 bytes.Buffer
+	v1.WriteString(COLUMNARMAGICV1)
+	binary.Write(&v1, binary.LittleEndian, uint64(0))
+	binary.Write(&v1, binary.LittleEndian, int64(len(COLUMNARMAGICV1)))
+	v1.WriteString(COLUMNARMAGICV1)
+	path = filepath.Join(t.TempDir(), "v1.columnar")
+	if err := os.WriteFile(path, v1.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !IsTrajectory(path) {
+		t.Error("a first version columnar file isn't recognised as a trajectory")
+	}
+	if err := ReadTrajectory(path, func(float64, []*simulation.Body) error { return nil }); err != nil {
+		t.Errorf("reading a first version columnar file: %v", err)
+	}
+}
+
+// An error writing a columnar trajectory is kept, and returned by every later Record and by Close
+func TestColumnarTrajectoryWriteError(t *testing.T) {
+	out, err := NewColumnarTrajectory(filepath.Join(t.TempDir(), "trajectory.columnar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Closing the file underneath the trajectory makes writing the first full chunk fail
+	out.file.Close()
+	bodies := []*simulation.Body{{Mass: 1}}
+	for i := 0; i < COLUMNARCHUNKROWS*2; i++ {
+		out.Record(float64(i), bodies)
+	}
+	if err := out.Record(0, bodies); err == nil {
+		t.Error("recording after a failed write returned no error")
+	}
+	if err := out.Close(); err == nil {
+		t.Error("closing after a failed write returned no error")
+	}
+}
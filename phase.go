@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log/slog"
+
+	"hmcalister/gravity_simulation/persist"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+var (
+	// Set by the phase space flags
+	phasePath  string
+	phaseEvery int
+	// The file the phase space samples are written to, nil if they aren't being written
+	phase *persist.PhaseCSV
+)
+
+// Start writing phase space samples of every body every --phaseEvery steps, including the start
+// Like the trajectory, failing to open the phase space file is not fatal - the simulation simply runs without it
+func openPhase() {
+	var err error
+	phase, err = persist.NewPhaseCSV(phasePath)
+	if err != nil {
+		slog.Warn("COULD NOT OPEN PHASE SPACE FILE", "path", phasePath, "err", err)
+		phase = nil
+		return
+	}
+	phase.Record(sim.Time, sim.Bodies())
+	sim.OnStep(func(s *simulation.Simulation) {
+		if s.Steps%phaseEvery == 0 && !replayingStep(s) {
+			phase.Record(s.Time, s.Bodies())
+		}
+	})
+}
+
+// Finish writing the phase space file, if one is being written
+// Like the diagnostics, the last step is always written, so the file ends with the run
+func closePhase() {
+	if phase == nil {
+		return
+	}
+	if sim.Steps%phaseEvery != 0 {
+		phase.Record(sim.Time, sim.Bodies())
+	}
+	if err := phase.Close(); err != nil {
+		slog.Warn("COULD NOT WRITE PHASE SPACE FILE", "path", phasePath, "err", err)
+	}
+	phase = nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"log/slog"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Without a window (the simulate command or run --headless), frames can still be drawn with the same drawing as the
+// window and written as a numbered sequence of PNG files, so videos can be made on servers without a display
+
+var (
+	// Set by the frame flags
+	framesDir   string
+	framesEvery int
+	frameTrails bool
+	// The view the frames are drawn from, set by --frameX, --frameY and --frameZoom and sized by --width and --height
+	frameView = render.Camera{Zoom: 1}
+	// What the frames are drawn into and written with, nil if frames aren't being written
+	pngCanvas *render.Canvas
+	pngFrames *render.PNGSequence
+)
+
+// Start writing a frame every --framesEvery steps, including the start
+// Like the trajectory, failing to create the directory is not fatal - the simulation simply runs without frames
+func openFrames() {
+	if framesEvery <= 0 || frameView.Zoom <= 0 {
+		fatal("--framesEvery AND --frameZoom MUST BE POSITIVE")
+	}
+	var err error
+	pngFrames, err = render.NewPNGSequence(framesDir)
+	if err != nil {
+		slog.Warn("COULD NOT CREATE FRAMES DIRECTORY", "path", framesDir, "err", err)
+		pngFrames = nil
+		return
+	}
+	frameView.Width, frameView.Height = screenWidth, screenHeight
+	pngCanvas = render.NewCanvas(screenWidth, screenHeight)
+	pngCanvas.Fill(backgroundColor)
+	writeFrame(sim)
+	sim.OnStep(func(s *simulation.Simulation) {
+		if pngFrames != nil && s.Steps%framesEvery == 0 && !replayingStep(s) {
+			writeFrame(s)
+		}
+	})
+}
+
+// Draw the bodies into a frame and write it
+// A frame that can't be written stops any more being written, rather than failing at every step
+func writeFrame(s *simulation.Simulation) {
+	if frameTrails {
+		pngCanvas.Decay(pixelDecayRate)
+	} else {
+		pngCanvas.Fill(backgroundColor)
+	}
+	for _, b := range s.Bodies() {
+		drawBodyOn(pngCanvas, &frameView, b)
+	}
+	if err := pngFrames.Present(pngCanvas); err != nil {
+		slog.Warn("COULD NOT WRITE FRAME, NO MORE WILL BE WRITTEN", "path", pngFrames.Path(pngFrames.Frames), "err", err)
+		pngFrames = nil
+	}
+}
+
+// Log how many frames were written, if any were
+func closeFrames() {
+	if pngFrames == nil {
+		return
+	}
+	slog.Info("WROTE FRAMES", "frames", pngFrames.Frames, "path", pngFrames.Dir)
+	pngFrames.Close()
+	pngFrames = nil
+}
@@ -0,0 +1,135 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"image/color"
+	"log/slog"
+	"math"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The future path of the selected body is predicted by integrating it forward from the current state, and drawn as a dashed curve
+// The prediction is recalculated every frame, so it follows edits made while paused
+
+const (
+	PREDICTIONOFF = iota
+	// Integrate only the selected body, with the other bodies held fixed where they are
+	PREDICTIONFIXED
+	// Integrate all bodies together, exactly as the simulation would
+	PREDICTIONCOUPLED
+)
+
+const (
+	// The number of timesteps to predict
+	PREDICTIONSTEPS = 1000
+	// The most pairs of bodies to find the gravity between for a coupled prediction each frame
+	// Each step finds the gravity between every pair of bodies, so with many bodies fewer steps are predicted to keep the
+	// frame rate up, but never fewer than PREDICTIONMINSTEPS so there is still a path to see
+	PREDICTIONBUDGET   = 2000000
+	PREDICTIONMINSTEPS = 20
+	// The number of steps in each dash and each gap of the predicted path
+	PREDICTIONDASH = 4
+)
+
+var (
+	predictionMode      = PREDICTIONOFF
+	predictionModeNames = []string{"off", "fixed", "coupled"}
+	predictionColor     = color.RGBA{255, 255, 0, 255}
+)
+
+// Switch to the next prediction mode
+func cyclePredictionMode() {
+	predictionMode = (predictionMode + 1) % len(predictionModeNames)
+	slog.Info("ORBIT PREDICTION", "mode", predictionModeNames[predictionMode])
+}
+
+// Predict the positions of the selected body over the coming timesteps
+// The path stops early if the body would merge into another body
+func predictPath() [][2]float64 {
+	b := selected()
+	if b == nil || b.Fixed {
+		return nil
+	}
+	if predictionMode == PREDICTIONCOUPLED {
+		return predictCoupled()
+	}
+
+	path := [][2]float64{{b.Pos.X, b.Pos.Y}}
+	p := *b
+	for i := 0; i < PREDICTIONSTEPS; i++ {
+		// Update the same way Body.Update does, moving with the old velocity then accelerating under the gravity of the other bodies
+		acc := simulation.Acceleration(&p, sim.Bodies(), sim.Params)
+		p.Pos = p.Pos.Add(p.Vel.Scale(sim.Timescale))
+		p.Vel = p.Vel.Add(acc.Scale(sim.Timescale))
+		path = append(path, [2]float64{p.Pos.X, p.Pos.Y})
+		if sim.CollisionMode == simulation.COLLISIONMERGE && touchesFixed(&p) {
+			break
+		}
+	}
+	return path
+}
+
+// Whether a predicted body touches any body other than the selected body
+func touchesFixed(p *simulation.Body) bool {
+	for i, other := range sim.Bodies() {
+		if other == nil || i == selectedBody {
+			continue
+		}
+		if simulation.DistSquared(p, other) < math.Pow(p.Radius+other.Radius, 2) {
+			return true
+		}
+	}
+	return false
+}
+
+// Predict the path of the selected body by running the whole simulation forward
+func predictCoupled() [][2]float64 {
+	actual := sim.Bodies()
+
+	steps := PREDICTIONSTEPS
+	if n := len(actual); n > 0 {
+		steps = max(min(steps, PREDICTIONBUDGET/(n*n)), PREDICTIONMINSTEPS)
+	}
+
+	b := actual[selectedBody]
+	path := [][2]float64{{b.Pos.X, b.Pos.Y}}
+	bodies := actual
+	for i := 0; i < steps; i++ {
+		next := make([]*simulation.Body, len(bodies))
+		simulation.Step(bodies, next, sim.Params, sim.Forces())
+		bodies = next
+		if bodies[selectedBody] == nil {
+			break
+		}
+		path = append(path, [2]float64{bodies[selectedBody].Pos.X, bodies[selectedBody].Pos.Y})
+	}
+	return path
+}
+
+// Draw the predicted path of the selected body as a dashed curve
+func drawPrediction() {
+	if predictionMode == PREDICTIONOFF {
+		return
+	}
+	path := predictPath()
+	for i := 1; i < len(path); i++ {
+		if (i/PREDICTIONDASH)%2 == 1 {
+			continue
+		}
+		x0, y0 := worldToScreen(path[i-1][0], path[i-1][1])
+		x1, y1 := worldToScreen(path[i][0], path[i][1])
+		// Skip segments far off the screen, e.g. after a body escapes, rather than drawing thousands of invisible pixels
+		if !onScreen(x0, y0) && !onScreen(x1, y1) {
+			continue
+		}
+		render.Line(frame, x0, y0, x1, y1, predictionColor)
+	}
+}
+
+// Whether a point is within the window
+func onScreen(x, y int32) bool {
+	return camera.OnScreen(float64(x), float64(y))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"time"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Headless runs log a line of progress every --progressEvery, so long runs aren't silent, e.g.
+//
+//	level=INFO msg=PROGRESS steps=1200000 stepsPerSecond=40000 eta=25m0s bodies=87 energyDrift=0.0012 virialRatio=0.97
+//
+// The energy drift is the change in the total energy since the start of the run, relative to the starting energy
+// The virial ratio (see virial.go) says whether the bodies are collapsing, in equilibrium or flying apart
+
+// How often headless runs log their progress, 0 to never log it
+var progressEvery time.Duration
+
+// Reports the progress of a headless run
+type progressReporter struct {
+	start       time.Time
+	last        time.Time
+	lastSteps   int
+	startEnergy float64
+}
+
+// Start reporting the progress of a run starting now
+func newProgressReporter() *progressReporter {
+	now := time.Now()
+	return &progressReporter{
+		start:       now,
+		last:        now,
+		lastSteps:   sim.Steps,
+		startEnergy: totalEnergy(),
+	}
+}
+
+// The total kinetic and potential energy of the bodies
+// This is O(n^2) like a step, so is only found when reporting
+func totalEnergy() float64 {
+	return simulation.KineticEnergy(sim.Bodies()) + simulation.PotentialEnergy(sim.Bodies(), sim.G)
+}
+
+// Log the progress of the run, with the speed since the last report
+func (p *progressReporter) report() {
+	now := time.Now()
+	stepsPerSecond := float64(sim.Steps-p.lastSteps) / now.Sub(p.last).Seconds()
+	p.last, p.lastSteps = now, sim.Steps
+
+	bodies := 0
+	for _, b := range sim.Bodies() {
+		if b != nil {
+			bodies++
+		}
+	}
+	attrs := []any{"steps", sim.Steps - startSteps, "stepsPerSecond", math.Round(stepsPerSecond)}
+	// The time left is estimated from how long the run has taken to get this far towards its nearest limit
+	elapsed := now.Sub(p.start)
+	if fraction, limited := runFraction(elapsed); limited && fraction > 0 {
+		remaining := time.Duration(float64(elapsed) * (1 - fraction) / fraction)
+		attrs = append(attrs, "eta", remaining.Round(time.Second))
+	}
+	attrs = append(attrs, "bodies", bodies, "energyDrift", (totalEnergy()-p.startEnergy)/math.Abs(p.startEnergy),
+		"virialRatio", simulation.VirialRatio(sim.Bodies(), sim.G))
+	slog.Info("PROGRESS", attrs...)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"hmcalister/gravity_simulation/persist"
+)
+
+// Every run records where it came from (its seed, the version of the program and a command to run it again with) in the
+// csv and JSON saves and csv trajectories it writes, and logs the command at startup, so any run can be reproduced later
+// The command has every flag that was set, including those set by the config file or the environment, and the seed used
+
+// Where the files written by this run came from, nil for commands that don't run a simulation (e.g. convert)
+var provenance *persist.Provenance
+
+// Record the provenance of the run once the flags are parsed and the seed is chosen, and log the command to run it again
+func setupProvenance(name string, fs *flag.FlagSet) {
+	args := []string{os.Args[0], name}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "seed" {
+			args = append(args, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	args = append(args, "--seed="+strconv.FormatInt(seed, 10))
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+	provenance = &persist.Provenance{Seed: seed, Version: codeVersion(), Command: strings.Join(args, " ")}
+	slog.Info("TO RUN THIS AGAIN", "command", provenance.Command, "codeVersion", provenance.Version)
+}
+
+// The version of this program from its build information: the commit it was built from (marked dirty if there were
+// uncommitted changes), or the module version if it was installed with go install, or unknown if it has neither
+func codeVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	revision, modified := "", false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if revision != "" {
+		if modified {
+			revision += "-dirty"
+		}
+		return revision
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "unknown"
+}
+
+// Quote an argument for a POSIX shell if it has any characters the shell would treat specially
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=+.,:/@%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
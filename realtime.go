@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// With --realtime, the run command keeps the simulation running at its full rate of one step every FRAMETIME milliseconds
+// of wall time, however slow drawing is. The physics goroutine already hands the render loop only its newest frame, so
+// slow drawing skips frames, but the render loop also holds physicsLock while handling inputs and drawing overlays,
+// which holds up steps, and a tick missed while waiting is lost, slowing the simulation down. In realtime mode the physics
+// goroutine counts the steps due since it was unpaused and takes as many as it is behind by each tick, so missed ticks are
+// made up and the steps in between are never drawn. If the steps themselves take longer than FRAMETIME, the simulation
+// can't keep up however few frames are drawn, so a backlog of more than REALTIMEMAXSTEPS is dropped rather than growing
+//
+// The HUD shows the simulated time per second the simulation is running at, against the rate it should run at, and the
+// fraction of steps that weren't drawn
+
+// The most steps the physics goroutine takes in one tick to catch up in realtime mode
+const REALTIMEMAXSTEPS = 10
+
+// How often the speed shown in the HUD is measured
+const SPEEDMEASUREEVERY = time.Second
+
+// Set by the --realtime flag
+var realtime bool
+
+// Counts the steps due in realtime mode, one every FRAMETIME milliseconds since it was started
+type realtimePacer struct {
+	// When the steps started being counted, zero while paused
+	start time.Time
+	// The steps taken since start
+	taken int
+}
+
+// The number of steps to take now to catch up with the wall clock, starting to count if not counting already
+func (p *realtimePacer) due(now time.Time) int {
+	if p.start.IsZero() {
+		p.start, p.taken = now, 0
+	}
+	due := int(now.Sub(p.start)/(FRAMETIME*time.Millisecond)) + 1 - p.taken
+	if due > REALTIMEMAXSTEPS {
+		// Too far behind to catch up, so start counting again as if only REALTIMEMAXSTEPS were due
+		p.start, p.taken = now.Add(-(REALTIMEMAXSTEPS-1)*FRAMETIME*time.Millisecond), 0
+		due = REALTIMEMAXSTEPS
+	}
+	p.taken += max(due, 0)
+	return max(due, 0)
+}
+
+// Stop counting, e.g. while paused, so the time paused isn't made up once unpaused
+func (p *realtimePacer) stop() {
+	p.start = time.Time{}
+}
+
+// Measures how fast the simulation is running from the frames the render loop draws
+type speedMeter struct {
+	// The wall time, simulated time and step at the start of the current measurement
+	since     time.Time
+	sinceTime float64
+	sinceStep int
+	// The last step drawn, and the steps skipped (not drawn) since the start of the current measurement
+	lastStep int
+	skipped  int
+	// The last measurement: the simulated time per second, that of the timescale at full rate, and the fraction skipped
+	speed       float64
+	targetSpeed float64
+	skippedRate float64
+}
+
+// The speed of the frames the render loop draws
+var drawnSpeed speedMeter
+
+// Count a frame drawn, measuring the speed once SPEEDMEASUREEVERY has passed since the last measurement
+// Frames from before the last (e.g. after stepping backward) start the measurement again
+func (m *speedMeter) drew(frame simulation.Snapshot, now time.Time) {
+	if m.since.IsZero() || frame.Steps < m.lastStep {
+		m.since, m.sinceTime, m.sinceStep, m.lastStep, m.skipped = now, frame.Time, frame.Steps, frame.Steps, 0
+		return
+	}
+	if frame.Steps > m.lastStep+1 {
+		m.skipped += frame.Steps - m.lastStep - 1
+	}
+	m.lastStep = frame.Steps
+	elapsed := now.Sub(m.since)
+	if elapsed < SPEEDMEASUREEVERY {
+		return
+	}
+	m.speed = (frame.Time - m.sinceTime) / elapsed.Seconds()
+	m.targetSpeed = frame.Timescale * 1000 / FRAMETIME
+	m.skippedRate = 0
+	if steps := frame.Steps - m.sinceStep; steps > 0 {
+		m.skippedRate = float64(m.skipped) / float64(steps)
+	}
+	m.since, m.sinceTime, m.sinceStep, m.skipped = now, frame.Time, frame.Steps, 0
+}
+
+// The lines of the HUD showing the speed in realtime mode, none otherwise
+func realtimeStatus() []string {
+	if !realtime {
+		return nil
+	}
+	if paused {
+		return []string{"SIM SPEED: PAUSED"}
+	}
+	percent := 0.0
+	if drawnSpeed.targetSpeed != 0 {
+		percent = 100 * drawnSpeed.speed / drawnSpeed.targetSpeed
+	}
+	return []string{
+		fmt.Sprintf("SIM SPEED: %.3g/S (%.0f%%)", drawnSpeed.speed, percent),
+		fmt.Sprintf("FRAMES SKIPPED: %.0f%%", 100*drawnSpeed.skippedRate),
+	}
+}
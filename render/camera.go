@@ -0,0 +1,63 @@
+package render
+
+import "math"
+
+// A Camera is a view of the simulation on a screen (or canvas), converting between positions in the simulation
+// and positions on the screen in pixels
+// The view is centered on (X, Y), scaled by Zoom (the distance in the simulation across each pixel) and rotated by Rotation
+// A positive rotation turns the view clockwise, so the simulation appears to turn anticlockwise
+type Camera struct {
+	X float64
+	Y float64
+	// The distance in the simulation across each pixel, so a larger zoom shows more of the simulation
+	Zoom float64
+	// The angle the view is rotated by, in radians
+	Rotation float64
+	// The size of the screen in pixels
+	Width  int32
+	Height int32
+}
+
+// Convert a position on the screen (in pixels) to a position in the simulation
+func (c *Camera) ScreenToWorld(x, y float64) (float64, float64) {
+	dx, dy := Rotate((x-float64(c.Width)/2)*c.Zoom, (y-float64(c.Height)/2)*c.Zoom, c.Rotation)
+	return dx + c.X, dy + c.Y
+}
+
+// Convert a position in the simulation to a position on the screen, without rounding to whole pixels
+func (c *Camera) WorldToScreen(x, y float64) (float64, float64) {
+	dx, dy := Rotate(x-c.X, y-c.Y, -c.Rotation)
+	return dx/c.Zoom + float64(c.Width)/2, dy/c.Zoom + float64(c.Height)/2
+}
+
+// Move the view by a distance on the screen (in pixels), e.g. moving up moves up the screen however the view is rotated
+func (c *Camera) Pan(x, y float64) {
+	dx, dy := Rotate(x*c.Zoom, y*c.Zoom, c.Rotation)
+	c.X += dx
+	c.Y += dy
+}
+
+// Rotate the view by an angle, about the center of the screen
+func (c *Camera) Rotate(angle float64) {
+	c.Rotation = math.Mod(c.Rotation+angle, 2*math.Pi)
+}
+
+// Multiply the zoom by factor, keeping the point at (x, y) on the screen in place, e.g. the point between pinching fingers
+func (c *Camera) ZoomAbout(factor, x, y float64) {
+	worldX, worldY := c.ScreenToWorld(x, y)
+	c.Zoom *= factor
+	newX, newY := c.ScreenToWorld(x, y)
+	c.X += worldX - newX
+	c.Y += worldY - newY
+}
+
+// Whether a position on the screen (in pixels) is on the screen
+func (c *Camera) OnScreen(x, y float64) bool {
+	return x >= 0 && x < float64(c.Width) && y >= 0 && y < float64(c.Height)
+}
+
+// Rotate a vector by an angle
+func Rotate(x, y, angle float64) (float64, float64) {
+	sin, cos := math.Sincos(angle)
+	return x*cos - y*sin, x*sin + y*cos
+}
@@ -0,0 +1,107 @@
+// Package render draws simulations into plain pixel buffers, with no window or graphics library needed
+// A Canvas can be shown in a window (e.g. as an SDL texture), written to an image file or encoded into a video
+package render
+
+import (
+	"image"
+	"image/color"
+)
+
+// An array of RGBA pixels, four bytes per pixel, row by row from the top left
+// This is the layout of an SDL ABGR8888 texture and of an image.RGBA, so the pixels can be used directly by either
+type Canvas struct {
+	Pixels []byte
+	Width  int32
+	Height int32
+}
+
+// Allocate a canvas of the given size, initially black
+// Drawing only ever sets the color channels, so every pixel is made opaque here, for images written from the canvas
+func NewCanvas(width, height int32) *Canvas {
+	c := &Canvas{
+		Pixels: make([]byte, width*height*4),
+		Width:  width,
+		Height: height,
+	}
+	for index := 3; index < len(c.Pixels); index += 4 {
+		c.Pixels[index] = 0xff
+	}
+	return c
+}
+
+// The canvas as an image, so the image and font packages can draw into it
+// The image shares the canvas's pixels, so anything drawn into it is drawn onto the canvas
+func (c *Canvas) Image() *image.RGBA {
+	return &image.RGBA{
+		Pix:    c.Pixels,
+		Stride: int(c.Width) * 4,
+		Rect:   image.Rect(0, 0, int(c.Width), int(c.Height)),
+	}
+}
+
+// set a specific pixel to a color
+// Pixels off the canvas are ignored, so callers can draw shapes that are partly off screen
+func (c *Canvas) SetPixel(x, y int32, col color.RGBA) {
+	// The conditional here is just to avoid drawing off the canvas
+	if x < 0 || y < 0 || x >= c.Width || y >= c.Height {
+		return
+	}
+
+	// This is the index into the pixels array
+	// Which is a flattened array of rgb values
+	// Hence the extra factor of width for y
+	// and multiplying by the four color channels
+	index := (y*c.Width + x) * 4
+	c.Pixels[index] = col.R
+	c.Pixels[index+1] = col.G
+	c.Pixels[index+2] = col.B
+}
+
+// set all pixels in the canvas to a specific color
+func (c *Canvas) Fill(col color.RGBA) {
+	for index := 0; index+3 < len(c.Pixels); index += 4 {
+		c.Pixels[index] = col.R
+		c.Pixels[index+1] = col.G
+		c.Pixels[index+2] = col.B
+	}
+}
+
+// Decay every pixel by subtracting rate from each RGB channel, which fades old drawings into trails
+// When the color channel is below the decay rate (i.e. the next subtraction would be negative)
+// instead we set the color channel to zero. A zero value in the color channel will remain at zero
+func (c *Canvas) Decay(rate uint8) {
+	for index := 0; index+3 < len(c.Pixels); index += 4 {
+		for i := index; i < index+3; i++ {
+			if c.Pixels[i] < rate {
+				c.Pixels[i] = 0
+				continue
+			}
+			c.Pixels[i] -= rate
+		}
+	}
+}
+
+// Draw a filled circle centered at x, y, e.g. a body
+// The center and radius are not rounded, so a body moving smoothly is drawn moving smoothly
+func (c *Canvas) FillCircle(centerX, centerY, radius float64, col color.RGBA) {
+	// If the circle is already off the canvas, don't bother doing any loops!
+	if centerX+radius < 0 || centerX-radius > float64(c.Width) ||
+		centerY+radius < 0 || centerY-radius > float64(c.Height) {
+		return
+	}
+
+	for y := -radius; y < radius; y++ {
+		if centerY+y < 0 || centerY+y >= float64(c.Height) {
+			continue
+		}
+		for x := -radius; x < radius; x++ {
+			if centerX+x < 0 || centerX+x >= float64(c.Width) {
+				continue
+			}
+
+			if x*x+y*y < radius*radius {
+				c.SetPixel(int32(centerX+x), int32(centerY+y), col)
+			}
+		}
+	}
+}
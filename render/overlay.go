@@ -0,0 +1,123 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Overlays (panels of text, highlights...) are drawn into an image, e.g. a copy of the simulation's canvas
+
+const (
+	// The space between the edge of a panel and its text, in pixels
+	PANELPADDING = 6
+	// The space between a panel and the edge of the window, in pixels
+	PANELMARGIN = 10
+)
+
+var (
+	// The font used for all text, a small fixed size bitmap font so no font files are needed
+	TextFace       = basicfont.Face7x13
+	TextLineHeight = TextFace.Metrics().Height.Ceil()
+	textLineAscent = TextFace.Metrics().Ascent.Ceil()
+	TextColor      = color.RGBA{255, 255, 255, 255}
+	PanelColor     = color.RGBA{0, 0, 0, 180}
+	HighlightColor = color.RGBA{255, 255, 255, 255}
+	PlotAxisColor  = color.RGBA{100, 100, 100, 255}
+	EscapingColor  = color.RGBA{255, 80, 80, 255}
+	WarningColor   = color.RGBA{255, 200, 0, 255}
+)
+
+// Measure the size of a panel holding the given lines of text, including the padding
+func PanelSize(lines []string) (int, int) {
+	width := 0
+	for _, line := range lines {
+		if w := font.MeasureString(TextFace, line).Ceil(); w > width {
+			width = w
+		}
+	}
+	return width + 2*PANELPADDING, len(lines)*TextLineHeight + 2*PANELPADDING
+}
+
+// Draw a panel of text with its top left corner at x, y, returning the panel's rectangle
+func Panel(dst *image.RGBA, x, y int, lines []string) image.Rectangle {
+	width, height := PanelSize(lines)
+	rect := image.Rect(x, y, x+width, y+height)
+	Box(dst, rect)
+	for i, line := range lines {
+		Text(dst, x+PANELPADDING, y+PANELPADDING+i*TextLineHeight, line, TextColor)
+	}
+	return rect
+}
+
+// Draw the translucent background of a panel over rect, for panels holding more than text (e.g. a plot)
+func Box(dst *image.RGBA, rect image.Rectangle) {
+	draw.Draw(dst, rect, image.NewUniform(PanelColor), image.Point{}, draw.Over)
+}
+
+// Draw a line of text with its top left corner at x, y
+func Text(dst *image.RGBA, x, y int, text string, c color.RGBA) {
+	d := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(c),
+		Face: TextFace,
+		Dot:  fixed.P(x, y+textLineAscent),
+	}
+	d.DrawString(text)
+}
+
+// Draw the outline of a circle centered at x, y, e.g. to highlight a body
+func Ring(dst *image.RGBA, x, y int32, radius float64, c color.RGBA) {
+	steps := int(2*math.Pi*radius) + 8
+	for i := 0; i < steps; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(steps)
+		dst.SetRGBA(int(float64(x)+radius*math.Cos(angle)), int(float64(y)+radius*math.Sin(angle)), c)
+	}
+}
+
+// Draw a straight line between two points
+func Line(dst *image.RGBA, x0, y0, x1, y1 int32, c color.RGBA) {
+	steps := int(math.Max(math.Abs(float64(x1-x0)), math.Abs(float64(y1-y0)))) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		dst.SetRGBA(int(float64(x0)+t*float64(x1-x0)), int(float64(y0)+t*float64(y1-y0)), c)
+	}
+}
+
+// Draw a line plot of each series (oldest value first) across rect, each in its own color
+// All of the series share one vertical scale fitted to their smallest and largest values, with a line at zero if it is in range
+func Plot(dst *image.RGBA, rect image.Rectangle, series [][]float64, colors []color.RGBA) {
+	low, high, samples := math.Inf(1), math.Inf(-1), 0
+	for _, s := range series {
+		for _, v := range s {
+			low, high = math.Min(low, v), math.Max(high, v)
+		}
+		samples = max(samples, len(s))
+	}
+	if samples < 2 || math.IsNaN(low) || math.IsNaN(high) {
+		return
+	}
+	if low == high {
+		low, high = low-1, high+1
+	}
+	x := func(i int) int32 {
+		return int32(rect.Min.X + i*(rect.Dx()-1)/(samples-1))
+	}
+	y := func(v float64) int32 {
+		return int32(rect.Max.Y - 1 - int(float64(rect.Dy()-1)*(v-low)/(high-low)))
+	}
+
+	if low < 0 && high > 0 {
+		Line(dst, x(0), y(0), x(samples-1), y(0), PlotAxisColor)
+	}
+	for j, s := range series {
+		for i := 1; i < len(s); i++ {
+			Line(dst, x(i-1), y(s[i-1]), x(i), y(s[i]), colors[j])
+		}
+	}
+}
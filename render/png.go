@@ -0,0 +1,51 @@
+package render
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// A Renderer that writes every frame to a numbered PNG file in a directory, e.g. frames/frame00000000.png,
+// so videos can be made without a window, e.g. with ffmpeg -framerate 60 -i frames/frame%08d.png video.mp4
+type PNGSequence struct {
+	// The directory the frames are written into
+	Dir string
+	// The number of frames written so far, which is also the number of the next frame
+	Frames int
+}
+
+// Start writing frames into dir, creating it if it doesn't exist
+func NewPNGSequence(dir string) (*PNGSequence, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &PNGSequence{Dir: dir}, nil
+}
+
+// The path of the numbered frame
+func (p *PNGSequence) Path(frame int) string {
+	return filepath.Join(p.Dir, fmt.Sprintf("frame%08d.png", frame))
+}
+
+func (p *PNGSequence) Present(c *Canvas) error {
+	f, err := os.Create(p.Path(p.Frames))
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, c.Image()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	p.Frames++
+	return nil
+}
+
+// Every frame is closed as soon as it is written
+func (p *PNGSequence) Close() error {
+	return nil
+}
@@ -0,0 +1,26 @@
+package render
+
+// A Renderer shows finished frames somewhere: in a window, in files, or nowhere at all
+// The canvas passed to Present is only lent for the call, so a Renderer that keeps frames must copy them
+type Renderer interface {
+	// Show a frame
+	Present(c *Canvas) error
+	// Release whatever the renderer holds, after the last frame
+	Close() error
+}
+
+// A Renderer that throws every frame away, only counting them
+// It needs no window or display, so the whole render loop can be run in tests and on servers
+type Null struct {
+	// The number of frames presented so far
+	Frames int
+}
+
+func (n *Null) Present(c *Canvas) error {
+	n.Frames++
+	return nil
+}
+
+func (n *Null) Close() error {
+	return nil
+}
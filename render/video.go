@@ -0,0 +1,76 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// A Renderer that encodes every frame into a video file (e.g. an MP4) with ffmpeg, which must be installed
+// The frames are piped to ffmpeg as raw pixels, so nothing is written to disk but the video itself
+type Video struct {
+	// The path of the video being written
+	Path string
+	// The number of frames encoded so far
+	Frames int
+
+	width, height int32
+	cmd           *exec.Cmd
+	stdin         io.WriteCloser
+	// What ffmpeg printed, to explain why it failed if it does
+	stderr bytes.Buffer
+}
+
+// Start encoding a video of frames of the given size into path, with ffmpeg (the path to the ffmpeg program),
+// at fps frames per second, with the given quality (ffmpeg's CRF, lower is better, 18 looks lossless)
+// The format of the video is chosen by ffmpeg from the extension of path, encoded with H.264
+func NewVideo(ffmpeg, path string, width, height int32, fps, quality int) (*Video, error) {
+	v := &Video{Path: path, width: width, height: height}
+	v.cmd = exec.Command(ffmpeg, "-y", "-loglevel", "error",
+		"-f", "rawvideo", "-pixel_format", "rgba", "-video_size", fmt.Sprintf("%vx%v", width, height),
+		"-framerate", strconv.Itoa(fps), "-i", "-",
+		// H.264 needs an even width and height, so an odd frame is padded by a pixel
+		"-vf", "pad=ceil(iw/2)*2:ceil(ih/2)*2",
+		"-c:v", "libx264", "-crf", strconv.Itoa(quality), "-pix_fmt", "yuv420p", path)
+	v.cmd.Stderr = &v.stderr
+	var err error
+	if v.stdin, err = v.cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	if err := v.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not start ffmpeg: %w", err)
+	}
+	return v, nil
+}
+
+func (v *Video) Present(c *Canvas) error {
+	if c.Width != v.width || c.Height != v.height {
+		return fmt.Errorf("the frame is %vx%v, but the video is %vx%v", c.Width, c.Height, v.width, v.height)
+	}
+	if _, err := v.stdin.Write(c.Pixels); err != nil {
+		// ffmpeg has stopped reading, so find out why
+		v.stdin.Close()
+		return v.wait(err)
+	}
+	v.Frames++
+	return nil
+}
+
+// Finish the video, waiting for ffmpeg to finish encoding it
+func (v *Video) Close() error {
+	if err := v.stdin.Close(); err != nil {
+		return err
+	}
+	return v.wait(nil)
+}
+
+// Wait for ffmpeg to exit, returning why it failed (with what it printed) if it did, or else err
+func (v *Video) wait(err error) error {
+	if waitErr := v.cmd.Wait(); waitErr != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %v", waitErr, strings.TrimSpace(v.stderr.String()))
+	}
+	return err
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"log/slog"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"hmcalister/gravity_simulation/persist"
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The help for the render command, printed with -h
+const RENDERHELP = `
+Gravity Simulation - render
+Usage:
+	./gravity_simulation render [flags] trajectory
+
+	Replays a trajectory file (as written by --trajectoryOut, in either format) with no window, drawing each frame into
+	a numbered PNG file, e.g. frames/frame00000000.png, or with --out=video.mp4 straight into a video, encoded by ffmpeg
+	(which must be installed) without writing any frames to disk, e.g.
+	./gravity_simulation render --out=video.mp4 --zoom=2 --fps=60 trajectory.csv
+	Trajectories don't record colors, so each body is given a random color which is the same every time it is rendered
+
+Flags:
+	--out : The directory to write the frames into, created if it doesn't exist, or the path of a video to write
+		if it ends in a video extension (.mp4, .mkv, .mov or .avi)
+		Defaults to frames
+	--width, --height : The size of each frame in pixels
+		Defaults to 1200x800
+	--x, --y : The position in the simulation at the center of each frame
+		Defaults to 0,0
+	--zoom : The zoomscale, the distance in the simulation across each pixel
+		Defaults to 1
+	--rotation : The angle to rotate the view by in degrees, clockwise, as with , and . in the window
+		Defaults to 0
+	--every : Only draw every this many frames of the trajectory, to shorten long runs
+		Defaults to 1 (every frame)
+	--trails : Leave fading trails behind the bodies, as with X in the window
+		Defaults to false
+	--background : The background color as red,green,blue
+		Defaults to 0,0,0
+	--fps : The frames per second of a video
+		Defaults to 60
+	--crf : The quality of a video, as ffmpeg's constant rate factor: lower is better and larger, 18 looks lossless
+		Defaults to 18
+	--ffmpeg : The path to the ffmpeg program used to encode videos
+		Defaults to ffmpeg (found on the PATH)`
+
+// The extensions of the paths given to --out that are written as videos, rather than directories of frames
+var videoExtensions = []string{".mp4", ".mkv", ".mov", ".avi"}
+
+// The render command, which replays a trajectory into PNG frames or a video
+func renderCommand(args []string) {
+	var helpFlag bool
+	var out, background, ffmpeg string
+	var width, height int32 = 1200, 800
+	var centerX, centerY, zoom, rotation float64
+	var every, fps, crf int
+	var trails bool
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	fs.StringVar(&out, "out", "frames", "The directory to write the frames into, or the path of a video to write")
+	fs.Var(int32Value{&width}, "width", "The width of each frame in pixels")
+	fs.Var(int32Value{&height}, "height", "The height of each frame in pixels")
+	fs.Float64Var(&centerX, "x", 0, "The x coordinate in the simulation at the center of each frame")
+	fs.Float64Var(&centerY, "y", 0, "The y coordinate in the simulation at the center of each frame")
+	fs.Float64Var(&zoom, "zoom", 1, "The distance in the simulation across each pixel")
+	fs.Float64Var(&rotation, "rotation", 0, "The angle to rotate the view by in degrees, clockwise")
+	fs.IntVar(&every, "every", 1, "Only draw every this many frames of the trajectory")
+	fs.BoolVar(&trails, "trails", false, "Leave fading trails behind the bodies")
+	fs.StringVar(&background, "background", "0,0,0", "The background color as red,green,blue")
+	fs.IntVar(&fps, "fps", 60, "The frames per second of a video")
+	fs.IntVar(&crf, "crf", 18, "The quality of a video, lower is better")
+	fs.StringVar(&ffmpeg, "ffmpeg", "ffmpeg", "The path to the ffmpeg program used to encode videos")
+	fs.BoolVar(&helpFlag, "h", false, "Display help on this command, then quit")
+	logFlags(fs)
+	fs.Parse(args)
+	setupLogging()
+
+	if helpFlag {
+		fmt.Println(RENDERHELP + LOGGINGHELP)
+		os.Exit(0)
+	}
+	if fs.NArg() != 1 {
+		fatal("RENDER NEEDS A TRAJECTORY FILE, SEE render -h")
+	}
+	if zoom <= 0 || every <= 0 || width <= 0 || height <= 0 || fps <= 0 {
+		fatal("--zoom, --every, --width, --height AND --fps MUST ALL BE POSITIVE")
+	}
+	backColor, err := parseColor(background)
+	if err != nil {
+		fatal("INVALID BACKGROUND COLOR", "err", err)
+	}
+	var frames render.Renderer
+	if slices.Contains(videoExtensions, strings.ToLower(filepath.Ext(out))) {
+		if frames, err = render.NewVideo(ffmpeg, out, width, height, fps, crf); err != nil {
+			fatal("COULD NOT START ENCODING VIDEO", "path", out, "err", err)
+		}
+	} else if frames, err = render.NewPNGSequence(out); err != nil {
+		fatal("COULD NOT CREATE DIRECTORY", "path", out, "err", err)
+	}
+
+	view := render.Camera{X: centerX, Y: centerY, Zoom: zoom, Rotation: rotation * math.Pi / 180, Width: width, Height: height}
+	pixels := render.NewCanvas(width, height)
+	pixels.Fill(backColor)
+	// The color of each body, by id, made up the first time the body is seen
+	colors := map[int]color.RGBA{}
+	frame, written := 0, 0
+	err = persist.ReadTrajectory(fs.Arg(0), func(time float64, bodies []*simulation.Body) error {
+		frame++
+		if (frame-1)%every != 0 {
+			return nil
+		}
+		if trails {
+			pixels.Decay(PIXELDECAYRATE)
+		} else {
+			pixels.Fill(backColor)
+		}
+		for id, b := range bodies {
+			if b == nil {
+				continue
+			}
+			col, ok := colors[id]
+			if !ok {
+				col = simulation.RandomColor(rand.New(rand.NewSource(int64(id))))
+				colors[id] = col
+			}
+			x, y := view.WorldToScreen(b.Pos.X, b.Pos.Y)
+			pixels.FillCircle(x, y, b.Radius/view.Zoom, col)
+		}
+
+		written++
+		return frames.Present(pixels)
+	})
+	if err != nil {
+		fatal("COULD NOT RENDER TRAJECTORY", "err", err)
+	}
+	// A video is only finished once ffmpeg has encoded every frame
+	if err := frames.Close(); err != nil {
+		fatal("COULD NOT FINISH RENDERING", "path", out, "err", err)
+	}
+	slog.Info("RENDERED FRAMES", "frames", written, "path", out)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"hmcalister/gravity_simulation/persist"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Load a save file in any of the formats (by its extension) into the simulation
+// Protobuf saves (.pb) include the settings, so are applied to the whole simulation, other formats only replace the bodies
+// A path of - reads a csv save from stdin instead, so the simulation can be fed by a pipeline
+func loadStateFile(path string) error {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("could not read file %v - %w", path, err)
+	}
+
+	// Compressed saves are decompressed first, then read as the format before the .gz
+	name, data, err := persist.Decompress(path, data)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(name, ".pb") {
+		if err := decodeState(data); err != nil {
+			return fmt.Errorf("%v: %w", path, err)
+		}
+		return nil
+	}
+	bodies, err := persist.ParseStateFile(name, data, sim.G, sim.Rand)
+	if err != nil {
+		return err
+	}
+	sim.SetBodies(bodies)
+	return nil
+}
+
+// Save the state of the simulation to a file, one of save.csv, save.pb, save.rebound or save.json depending on --saveFormat
+func saveState() error {
+	return saveStateAs("save")
+}
+
+// Save the current state to the given path, with the extension added for the --saveFormat (e.g. save.csv)
+// The caller decides how bad a failed save is: while running it is only a warning, but failing to save before quitting is an error
+func saveStateAs(base string) error {
+	var path string
+	switch saveFormat {
+	case "protobuf":
+		path = base + ".pb"
+	case "rebound":
+		path = base + ".rebound"
+	case "json":
+		path = base + ".json"
+	default:
+		path = base + ".csv"
+	}
+	if err := saveStateToPath(path); err != nil {
+		return fmt.Errorf("could not save to %v (check the directory exists and is writable, and the disk isn't full): %w", path, err)
+	}
+	slog.Debug("SAVED", "path", path, "time", sim.Time)
+	return nil
+}
+
+// Save the current state to the given path, in the format given by its extension: .pb for protobuf, .rebound for a REBOUND snapshot,
+// .json for a JSON save and anything else for csv. A path ending in .gz (e.g. save.csv.gz) is compressed with gzip
+func saveStateToPath(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = f
+	var compressor *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		compressor = gzip.NewWriter(f)
+		w = compressor
+	}
+	if err := writeState(w, strings.TrimSuffix(path, ".gz")); err != nil {
+		f.Close()
+		return err
+	}
+	if compressor != nil {
+		if err := compressor.Close(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	// Closing can fail too (e.g. when the disk is full), so the error isn't ignored
+	if err := f.Close(); err != nil {
+		return err
+	}
+	sim.NotifySaved()
+	if !strings.HasSuffix(strings.TrimSuffix(path, ".gz"), ".pb") && !warnedInexactResume {
+		slog.Warn("ONLY PROTOBUF SAVES RESUME EXACTLY, OTHER FORMATS HOLD ONLY THE BODIES (NOT THE SETTINGS OR RANDOM NUMBER GENERATOR)", "path", path)
+		warnedInexactResume = true
+	}
+	return nil
+}
+
+// Only protobuf saves hold the settings and the state of the random number generator, so a run resumed from any other
+// format carries on with the settings of its flags and different random numbers. This is warned about the first time
+var warnedInexactResume bool
+
+// Write the current state in the format given by the extension of name, as saveStateToPath chooses it
+func writeState(w io.Writer, name string) error {
+	switch {
+	case strings.HasSuffix(name, ".pb"):
+		data, err := encodeState()
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case strings.HasSuffix(name, ".rebound"):
+		persist.WriteReboundSnapshot(w, sim.Bodies(), sim.Time, sim.G)
+		return nil
+	case strings.HasSuffix(name, ".json"):
+		return persist.WriteJSONSave(w, sim.Bodies(), provenance)
+	default:
+		if err := simulation.WriteSaveCSV(w, sim.Bodies(), provenance.Comments()...); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(w, "\n")
+		return err
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"path"
+	"strings"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The curated scenarios shipped inside the binary, so no external files are needed to get interesting simulations
+// Scenarios are save files in the usual csv format, with a comment line after the header describing the scenario
+//
+//go:embed scenarios/*.csv
+var scenarioFS embed.FS
+
+// Print the name and description of every embedded scenario
+func listScenarios() {
+	entries, _ := scenarioFS.ReadDir("scenarios")
+	fmt.Fprintln(tableWriter, "SCENARIO\tDESCRIPTION")
+	for _, e := range entries {
+		data, _ := scenarioFS.ReadFile(path.Join("scenarios", e.Name()))
+		fmt.Fprintf(tableWriter, "%v\t%v\n", strings.TrimSuffix(e.Name(), ".csv"), scenarioDescription(data))
+	}
+	tableWriter.Flush()
+}
+
+// The description of a scenario is the first comment line that is not the csv header (or version)
+func scenarioDescription(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "#x,") && !strings.HasPrefix(line, simulation.SAVEVERSIONPREFIX) {
+			return strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		}
+	}
+	return ""
+}
+
+// Read the save data of the embedded scenario with the given name
+func readScenario(name string) ([]byte, error) {
+	return scenarioFS.ReadFile(path.Join("scenarios", name+".csv"))
+}
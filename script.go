@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log/slog"
+	"sort"
+
+	"go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Scenarios can be scripted in Starlark (a small dialect of Python, https://github.com/bazelbuild/starlark)
+// The script is run once at startup, and can generate bodies and schedule functions to run at later simulation times.
+// The following builtins are available to scripts:
+//
+//	spawn(x, y, xVel=0, yVel=0, mass=1, radius=None, color=None, name="") : add a body, returning its id
+//	remove(id)                  : remove a body from the simulation
+//	body(id)                    : a dict of the parameters of a body, or None if it no longer exists
+//	num_bodies()                : the number of body ids, including removed bodies
+//	at(time, fn)                : call fn() once the simulation time reaches time
+//	set(name, value)            : set a simulation parameter, one of "G", "timescale" or "paused"
+//	get(name)                   : get a simulation parameter, as for set
+//	time()                      : the current simulation time
+//	random()                    : a random float in [0, 1)
+//
+// as well as the Starlark math module.
+
+// A function scheduled by a script to run at a given simulation time
+type scriptEvent struct {
+	time float64
+	fn   starlark.Callable
+}
+
+var (
+	// The thread scripts (and their scheduled events) are run in
+	scriptThread *starlark.Thread
+	// Pending scheduled events, sorted by time
+	scriptEvents []scriptEvent
+	// The id of the first body the script spawned, saved so the script can be resumed
+	scriptFirstID int
+	// Set while a script is run again to restore its events, see resumeScript
+	scriptResuming bool
+	scriptResumeID int
+	// The script events of a loaded save, waiting to be restored by resumeScript
+	savedScript *scriptSave
+)
+
+// The script of a saved simulation and the times of its events that had not run yet when it was saved
+type scriptSave struct {
+	path    string
+	firstID int
+	pending []float64
+}
+
+// A float argument to a builtin that also accepts integers, so scripts can write spawn(100, 0) rather than spawn(100.0, 0.0)
+type scriptFloat float64
+
+func (f *scriptFloat) Unpack(v starlark.Value) error {
+	x, ok := starlark.AsFloat(v)
+	if !ok {
+		return fmt.Errorf("got %s, want number", v.Type())
+	}
+	*f = scriptFloat(x)
+	return nil
+}
+
+// Run the script at path, which may add bodies and schedule events
+func runScript(path string) error {
+	scriptFirstID = len(sim.Bodies())
+	scriptThread = &starlark.Thread{
+		Name:  path,
+		Print: func(_ *starlark.Thread, msg string) { slog.Info("SCRIPT", "output", msg) },
+	}
+	predeclared := starlark.StringDict{
+		"spawn":      starlark.NewBuiltin("spawn", scriptSpawn),
+		"remove":     starlark.NewBuiltin("remove", scriptRemove),
+		"body":       starlark.NewBuiltin("body", scriptBody),
+		"num_bodies": starlark.NewBuiltin("num_bodies", scriptNumBodies),
+		"at":         starlark.NewBuiltin("at", scriptAt),
+		"set":        starlark.NewBuiltin("set", scriptSet),
+		"get":        starlark.NewBuiltin("get", scriptGet),
+		"time":       starlark.NewBuiltin("time", scriptTime),
+		"random":     starlark.NewBuiltin("random", scriptRandom),
+		"math":       math.Module,
+	}
+	// Scripts are for generating scenarios, so allow top level loops and while loops which plain Starlark forbids
+	options := &syntax.FileOptions{TopLevelControl: true, While: true, GlobalReassign: true, Set: true}
+	_, err := starlark.ExecFileOptions(options, scriptThread, path, nil, predeclared)
+	return err
+}
+
+// Restore the scheduled events of a saved simulation, by running its script again
+// While resuming, the builtins that change the simulation (spawn, remove and set) do nothing, as the save already
+// holds their effects, though spawn still returns the same ids so events refer to the right bodies. The random
+// number generator is restored afterwards, and events whose time had already passed when saving are dropped.
+// Events scheduled by other events, or depending on script variables changed by events, can't be restored this way,
+// so a warning is given if the restored events do not match those saved
+func resumeScript(saved *scriptSave) error {
+	state := sim.RandomSource.State
+	scriptResuming = true
+	scriptResumeID = saved.firstID
+	err := runScript(saved.path)
+	scriptResuming = false
+	scriptFirstID = saved.firstID
+	sim.RandomSource.State = state
+	if err != nil {
+		return err
+	}
+
+	// Events are run as soon as their time is reached, so any at or before the current time have already run
+	i := sort.Search(len(scriptEvents), func(i int) bool { return scriptEvents[i].time > sim.Time })
+	scriptEvents = scriptEvents[i:]
+
+	matches := len(scriptEvents) == len(saved.pending)
+	for i := 0; matches && i < len(scriptEvents); i++ {
+		matches = scriptEvents[i].time == saved.pending[i]
+	}
+	if !matches {
+		slog.Warn("NOT ALL SCRIPTED EVENTS WERE RESTORED, THE RESUMED SIMULATION MAY DIFFER", "restored", len(scriptEvents), "pending", len(saved.pending))
+	}
+	return nil
+}
+
+// Run all scheduled events whose time has been reached
+// Errors in an event are reported but do not stop the simulation
+func runScriptEvents() {
+	for len(scriptEvents) > 0 && scriptEvents[0].time <= sim.Time {
+		event := scriptEvents[0]
+		scriptEvents = scriptEvents[1:]
+		if _, err := starlark.Call(scriptThread, event.fn, nil, nil); err != nil {
+			slog.Warn("SCRIPT ERROR", "err", err)
+		}
+	}
+}
+
+// Find the body with the given id, or nil if it does not exist (or has been removed)
+func scriptLookupBody(id int) *simulation.Body {
+	if id < 0 || id >= len(sim.Bodies()) {
+		return nil
+	}
+	return sim.Bodies()[id]
+}
+
+func scriptSpawn(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var x, y, xVel, yVel scriptFloat
+	mass := scriptFloat(1)
+	var radius, bodyColor starlark.Value = starlark.None, starlark.None
+	var name string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs,
+		"x", &x, "y", &y, "xVel?", &xVel, "yVel?", &yVel, "mass?", &mass, "radius?", &radius, "color?", &bodyColor, "name?", &name); err != nil {
+		return nil, err
+	}
+	if scriptResuming {
+		id := scriptResumeID
+		scriptResumeID++
+		return starlark.MakeInt(id), nil
+	}
+
+	body := &simulation.Body{
+		Pos:    simulation.Vec2{X: float64(x), Y: float64(y)},
+		Vel:    simulation.Vec2{X: float64(xVel), Y: float64(yVel)},
+		Mass:   float64(mass),
+		Radius: simulation.MassToRadius(float64(mass)),
+		Color:  simulation.RandomColor(sim.Rand),
+		Name:   name,
+	}
+	if radius != starlark.None {
+		r, ok := starlark.AsFloat(radius)
+		if !ok {
+			return nil, fmt.Errorf("%s: radius must be a number", b.Name())
+		}
+		body.Radius = r
+	}
+	if bodyColor != starlark.None {
+		var red, green, blue uint8
+		if err := starlark.UnpackPositionalArgs(b.Name()+" color", colorTuple(bodyColor), nil, 3, &red, &green, &blue); err != nil {
+			return nil, err
+		}
+		body.Color = color.RGBA{red, green, blue, 255}
+	}
+	return starlark.MakeInt(sim.AddBody(body)), nil
+}
+
+// Convert a color value (a list or tuple of three channels) to a tuple
+func colorTuple(v starlark.Value) starlark.Tuple {
+	iterable, ok := v.(starlark.Iterable)
+	if !ok {
+		return starlark.Tuple{v}
+	}
+	var t starlark.Tuple
+	iter := iterable.Iterate()
+	defer iter.Done()
+	var item starlark.Value
+	for iter.Next(&item) {
+		t = append(t, item)
+	}
+	return t
+}
+
+func scriptRemove(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var id int
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "id", &id); err != nil {
+		return nil, err
+	}
+	if scriptLookupBody(id) != nil && !scriptResuming {
+		sim.RemoveBody(id)
+	}
+	return starlark.None, nil
+}
+
+func scriptBody(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var id int
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "id", &id); err != nil {
+		return nil, err
+	}
+	body := scriptLookupBody(id)
+	if body == nil {
+		return starlark.None, nil
+	}
+	d := starlark.NewDict(7)
+	d.SetKey(starlark.String("name"), starlark.String(body.Name))
+	d.SetKey(starlark.String("x"), starlark.Float(body.Pos.X))
+	d.SetKey(starlark.String("y"), starlark.Float(body.Pos.Y))
+	d.SetKey(starlark.String("xVel"), starlark.Float(body.Vel.X))
+	d.SetKey(starlark.String("yVel"), starlark.Float(body.Vel.Y))
+	d.SetKey(starlark.String("mass"), starlark.Float(body.Mass))
+	d.SetKey(starlark.String("radius"), starlark.Float(body.Radius))
+	return d, nil
+}
+
+func scriptNumBodies(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+		return nil, err
+	}
+	return starlark.MakeInt(len(sim.Bodies())), nil
+}
+
+func scriptAt(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var t scriptFloat
+	var fn starlark.Callable
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "time", &t, "fn", &fn); err != nil {
+		return nil, err
+	}
+	// Keep the events sorted by time, with events at the same time run in the order they were scheduled
+	i := sort.Search(len(scriptEvents), func(i int) bool { return scriptEvents[i].time > float64(t) })
+	scriptEvents = append(scriptEvents, scriptEvent{})
+	copy(scriptEvents[i+1:], scriptEvents[i:])
+	scriptEvents[i] = scriptEvent{time: float64(t), fn: fn}
+	return starlark.None, nil
+}
+
+func scriptSet(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	var value starlark.Value
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name, "value", &value); err != nil {
+		return nil, err
+	}
+	if scriptResuming {
+		return starlark.None, nil
+	}
+	switch name {
+	case "paused":
+		paused = bool(value.Truth())
+	case "G", "timescale":
+		f, ok := starlark.AsFloat(value)
+		if !ok {
+			return nil, fmt.Errorf("%s: %v must be a number", b.Name(), name)
+		}
+		if name == "G" {
+			sim.G = f
+		} else {
+			sim.Timescale = f
+		}
+	default:
+		return nil, fmt.Errorf("%s: unknown parameter %q", b.Name(), name)
+	}
+	return starlark.None, nil
+}
+
+func scriptGet(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name); err != nil {
+		return nil, err
+	}
+	switch name {
+	case "paused":
+		return starlark.Bool(paused), nil
+	case "G":
+		return starlark.Float(sim.G), nil
+	case "timescale":
+		return starlark.Float(sim.Timescale), nil
+	}
+	return nil, fmt.Errorf("%s: unknown parameter %q", b.Name(), name)
+}
+
+func scriptTime(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+		return nil, err
+	}
+	return starlark.Float(sim.Time), nil
+}
+
+func scriptRandom(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+		return nil, err
+	}
+	return starlark.Float(sim.Rand.Float64()), nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// The simulation shuts down through a context, cancelled by an interrupt (Ctrl+C or SIGTERM) or by closing the window
+// The loops of the run and simulate commands check it between steps, so the step in progress always finishes,
+// then call shutdown to write everything out, rather than exiting mid save
+// Once shutting down, signals are no longer caught, so a second Ctrl+C still kills a stuck program
+
+var (
+	// Cancelled when the simulation should shut down
+	shutdownContext context.Context = context.Background()
+	// Cancel the shutdown context, which also stops catching signals
+	requestShutdown context.CancelFunc = func() {}
+)
+
+// Start catching interrupt signals, which cancel the shutdown context
+// The context also has the --duration deadline, so the run stops when it passes like any other run limit
+func catchInterrupts() {
+	var stopCatching context.CancelFunc
+	shutdownContext, stopCatching = signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	requestShutdown = stopCatching
+	if maxDuration > 0 {
+		var cancel context.CancelFunc
+		shutdownContext, cancel = context.WithTimeout(shutdownContext, maxDuration)
+		requestShutdown = func() {
+			cancel()
+			stopCatching()
+		}
+	}
+}
+
+// Whether the simulation has been asked to shut down
+func shuttingDown() bool {
+	return shutdownContext.Err() != nil
+}
+
+// Shut the simulation down cleanly once the loop has stopped:
+// write a final snapshot and stream snapshot (if the last step wasn't one already), save the state (unless disabled),
+// flush and close the trajectory, metrics and diagnostics files, and print the summary of the run
+// An error is returned if the state could not be saved, so the program can quit with a nonzero exit code
+func shutdown() error {
+	if errors.Is(shutdownContext.Err(), context.DeadlineExceeded) {
+		slog.Info("REACHED RUN LIMIT", "limit", "duration", "time", sim.Time, "steps", sim.Steps)
+	}
+	requestShutdown()
+	slog.Info("SHUTTING DOWN", "time", sim.Time, "steps", sim.Steps)
+
+	if snapshotEvery > 0 && sim.Steps%snapshotEvery != 0 {
+		writeSnapshot()
+	}
+	if streamEvery > 0 && sim.Steps%streamEvery != 0 {
+		streamSnapshot()
+	}
+	var err error
+	if saveOnExit {
+		slog.Info("SAVING TO FILE BEFORE QUITTING")
+		err = saveState()
+	}
+	closeTrajectory()
+	closeMetrics()
+	closeDiagnostics()
+	closePhase()
+	closeEventLog()
+	closeFrames()
+	finishSummary()
+	return err
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// The help for the simulate command, printed with -h
+func simulateHelp() string {
+	return `
+Gravity Simulation - simulate
+Usage:
+	./gravity_simulation simulate [flags]
+
+	Runs the simulation without a window, as fast as it can, until it is interrupted (e.g. with Ctrl+C)
+	or reaches a run limit (--steps, --simTime or --duration). The simulation is then saved (unless --saveOnExit=false).
+	The results are written with the usual flags, e.g. --trajectoryOut, --snapshotEvery and --streamEvery.
+	This is the same as run --headless
+	The flags are the same as for the run command, the flags only affecting the window are ignored
+
+` + simulationFlagsHelp()
+}
+
+// The simulate command, which runs the simulation with no window until it is interrupted or reaches a run limit
+func simulateCommand(args []string) {
+	setupSimulation("simulate", args, simulateHelp())
+	runHeadless()
+}
+
+// Run the simulation as fast as possible with no window, then shut down, as the simulate command and run --headless do
+func runHeadless() {
+	// Stopping finishes the current step then saves and closes files
+	catchInterrupts()
+	if maxSteps > 0 {
+		slog.Info("RUNNING WITHOUT A WINDOW", "steps", maxSteps)
+	} else {
+		slog.Info("RUNNING WITHOUT A WINDOW, INTERRUPT TO QUIT")
+	}
+
+	// Frames are only drawn without a window, the window draws its own
+	if framesDir != "" {
+		openFrames()
+	}
+
+	// There is no stepping backwards without a window, so the step history is not kept
+	start := time.Now()
+	// Checking a ticker is far cheaper than checking the clock after every step
+	progress := newProgressReporter()
+	var reportProgress <-chan time.Time
+	if progressEvery > 0 {
+		ticker := time.NewTicker(progressEvery)
+		defer ticker.Stop()
+		reportProgress = ticker.C
+	}
+	// Like the physics goroutine of the window, each step holds physicsLock so the API can pause the run and change it between steps
+	paused = false
+	for !shuttingDown() {
+		physicsLock.Lock()
+		if paused {
+			physicsLock.Unlock()
+			time.Sleep(FRAMETIME * time.Millisecond)
+			continue
+		}
+		finishEdit()
+		sim.Step()
+		physicsLock.Unlock()
+		select {
+		case <-reportProgress:
+			progress.report()
+		default:
+		}
+	}
+	slog.Info("FINISHED RUNNING", "steps", sim.Steps, "seconds", time.Since(start).Seconds())
+	if err := shutdown(); err != nil {
+		fatal("COULD NOT SAVE BEFORE QUITTING", "err", err)
+	}
+	exitIfWatchdogStopped()
+}
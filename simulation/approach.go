@@ -0,0 +1,119 @@
+package simulation
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// The next close approach of two bodies is estimated from the conic they follow around each other if they were the only
+// two bodies: the time until they reach its periapsis, and how close they are then. This ignores the pull of every other
+// body and the softening, so is only an estimate, but it is exact for two bodies alone and takes no steps to find,
+// so can be found for many pairs after every step. Bodies that will touch merge (or bounce) before reaching periapsis
+
+// A predicted close approach of two bodies
+type Approach struct {
+	// The ids of the two bodies, A < B
+	A, B int
+	// The time until the bodies are closest, in simulated time
+	Time float64
+	// How far apart the centers of the bodies will be
+	Distance float64
+}
+
+// The next closest approach of b to a if they were the only two bodies: the time until it and how close they pass,
+// and whether they approach at all, which unbound bodies moving apart never do
+// A fixed body stays where it is, so the other body moves around it alone
+func NextApproach(a, b *Body, G float64) (float64, float64, bool) {
+	offset := b.Pos.Sub(a.Pos)
+	vel := b.Vel.Sub(a.Vel)
+	distance := offset.Norm()
+	radialVel := offset.Dot(vel)
+	speedSquared := vel.Dot(vel)
+	if distance == 0 {
+		return 0, 0, true
+	}
+
+	var mu float64
+	switch {
+	case a.Fixed && b.Fixed:
+		mu = 0
+	case a.Fixed:
+		mu = G * a.Mass
+	case b.Fixed:
+		mu = G * b.Mass
+	default:
+		mu = G * (a.Mass + b.Mass)
+	}
+	// Without gravity between them they move in straight lines
+	if mu <= 0 {
+		if radialVel >= 0 || speedSquared == 0 {
+			return 0, 0, false
+		}
+		time := -radialVel / speedSquared
+		return time, offset.Add(vel.Scale(time)).Norm(), true
+	}
+
+	energy := speedSquared/2 - mu/distance
+	angularMomentum := offset.X*vel.Y - offset.Y*vel.X
+	eccentricity := math.Sqrt(math.Max(0, 1+2*energy*angularMomentum*angularMomentum/(mu*mu)))
+	periapsis := angularMomentum * angularMomentum / mu / (1 + eccentricity)
+
+	switch {
+	case energy < 0:
+		// An ellipse, with the next periapsis within one period, even if they are moving apart now
+		// A circle has no periapsis, and is always as close as it gets
+		if eccentricity < 1e-9 {
+			return 0, distance, true
+		}
+		semiMajorAxis := -mu / (2 * energy)
+		motion := math.Sqrt(mu / (semiMajorAxis * semiMajorAxis * semiMajorAxis))
+		anomaly := math.Acos(math.Max(-1, math.Min(1, (1-distance/semiMajorAxis)/eccentricity)))
+		if radialVel < 0 {
+			anomaly = 2*math.Pi - anomaly
+		}
+		mean := anomaly - eccentricity*math.Sin(anomaly)
+		return (2*math.Pi - mean) / motion, periapsis, true
+	case radialVel >= 0:
+		// A parabola or hyperbola already past periapsis never comes back
+		return 0, 0, false
+	case energy > 0:
+		semiMajorAxis := mu / (2 * energy)
+		motion := math.Sqrt(mu / (semiMajorAxis * semiMajorAxis * semiMajorAxis))
+		anomaly := math.Acosh(math.Max(1, (1+distance/semiMajorAxis)/eccentricity))
+		return (eccentricity*math.Sinh(anomaly) - anomaly) / motion, periapsis, true
+	default:
+		// A parabola, with Barker's equation
+		d := math.Sqrt(math.Max(0, distance/periapsis-1))
+		return math.Sqrt(2*periapsis*periapsis*periapsis/mu) * (d + d*d*d/3), periapsis, true
+	}
+}
+
+// The close approaches between the most massive bodies (at most massive of them) within the given time, soonest first
+// Only pairs that will pass within the given distance of each other are included
+// Finding the most massive bodies is O(n log n), then each pair of them is O(1)
+func CloseApproaches(bodies []*Body, G float64, massive int, within, horizon float64) []Approach {
+	var ids []int
+	for id, b := range bodies {
+		if b != nil {
+			ids = append(ids, id)
+		}
+	}
+	slices.SortStableFunc(ids, func(i, j int) int { return cmp.Compare(bodies[j].Mass, bodies[i].Mass) })
+	if len(ids) > massive {
+		ids = ids[:massive]
+	}
+	slices.Sort(ids)
+
+	var approaches []Approach
+	for i, a := range ids {
+		for _, b := range ids[i+1:] {
+			time, distance, ok := NextApproach(bodies[a], bodies[b], G)
+			if ok && distance <= within && time <= horizon {
+				approaches = append(approaches, Approach{A: a, B: b, Time: time, Distance: distance})
+			}
+		}
+	}
+	slices.SortStableFunc(approaches, func(x, y Approach) int { return cmp.Compare(x.Time, y.Time) })
+	return approaches
+}
@@ -0,0 +1,103 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+// A planet at the apoapsis of an orbit around a fixed star is half an orbit from periapsis, by Kepler's third law
+func TestNextApproachEllipse(t *testing.T) {
+	const (
+		G            = 100
+		starMass     = 1000
+		apoapsis     = 300.0
+		eccentricity = 0.5
+	)
+	periapsis := apoapsis * (1 - eccentricity) / (1 + eccentricity)
+	semiMajorAxis := (apoapsis + periapsis) / 2
+	speed := math.Sqrt(G * starMass * (2/apoapsis - 1/semiMajorAxis))
+	period := 2 * math.Pi * math.Sqrt(semiMajorAxis*semiMajorAxis*semiMajorAxis/(G*starMass))
+
+	star := &Body{Mass: starMass, Radius: 1, Fixed: true}
+	planet := &Body{Pos: Vec2{X: apoapsis}, Vel: Vec2{Y: speed}, Mass: 1, Radius: 1}
+	time, distance, ok := NextApproach(star, planet, G)
+	if !ok {
+		t.Fatalf("a bound planet never approaches the star")
+	}
+	if math.Abs(time-period/2) > 1e-6*period || math.Abs(distance-periapsis) > 1e-6*periapsis {
+		t.Errorf("approach in %v at %v, expected in %v at %v", time, distance, period/2, periapsis)
+	}
+
+	// Just past periapsis, the next is almost a whole period away
+	planet = &Body{Pos: Vec2{X: -periapsis, Y: -1e-3}, Vel: Vec2{Y: -math.Sqrt(G * starMass * (2/periapsis - 1/semiMajorAxis))}, Mass: 1}
+	if time, _, _ := NextApproach(star, planet, G); math.Abs(time-period) > 1e-3*period {
+		t.Errorf("just past periapsis the next approach is in %v, expected nearly %v", time, period)
+	}
+}
+
+// A flyby of two free bodies passes as close and as soon as the simulation finds by stepping them in small steps
+func TestNextApproachFlyby(t *testing.T) {
+	s := New(Params{G: 100, Timescale: 0.0005, CollisionMode: COLLISIONPASS}, 1)
+	s.AddBody(&Body{Pos: Vec2{X: -20, Y: 200}, Vel: Vec2{X: 1, Y: -20}, Mass: 50, Radius: 1})
+	s.AddBody(&Body{Pos: Vec2{X: 20, Y: -100}, Vel: Vec2{X: -1, Y: 10}, Mass: 100, Radius: 1})
+	time, distance, ok := NextApproach(s.Bodies()[0], s.Bodies()[1], s.G)
+	if !ok {
+		t.Fatalf("bodies heading towards each other never approach")
+	}
+
+	closest, closestTime := math.Inf(1), 0.0
+	for s.Time < 2*time {
+		if d := math.Sqrt(DistSquared(s.Bodies()[0], s.Bodies()[1])); d < closest {
+			closest, closestTime = d, s.Time
+		}
+		s.Step()
+	}
+	if math.Abs(closest-distance) > 0.01*distance || math.Abs(closestTime-time) > 0.01*time {
+		t.Errorf("predicted approach in %v at %v, the simulation passed in %v at %v", time, distance, closestTime, closest)
+	}
+
+	// Once past each other, they never approach again
+	if _, _, ok := NextApproach(s.Bodies()[0], s.Bodies()[1], s.G); ok {
+		t.Errorf("bodies flying apart after a flyby are predicted to approach")
+	}
+}
+
+// Without gravity, bodies pass in straight lines
+func TestNextApproachStraight(t *testing.T) {
+	a := &Body{Mass: 1}
+	b := &Body{Pos: Vec2{X: -10, Y: 3}, Vel: Vec2{X: 2}, Mass: 1}
+	time, distance, ok := NextApproach(a, b, 0)
+	if !ok || math.Abs(time-5) > 1e-12 || math.Abs(distance-3) > 1e-12 {
+		t.Errorf("approach in %v at %v (%v), expected in 5 at 3", time, distance, ok)
+	}
+}
+
+// Only pairs of the most massive bodies passing close enough soon enough are found, soonest first
+func TestCloseApproaches(t *testing.T) {
+	bodies := []*Body{
+		{Pos: Vec2{X: -10, Y: 1}, Vel: Vec2{X: 1}, Mass: 10},
+		nil,
+		{Pos: Vec2{X: 10, Y: -1}, Vel: Vec2{X: -1}, Mass: 10},
+		{Pos: Vec2{X: 0, Y: 100}, Vel: Vec2{Y: 1}, Mass: 20},
+		{Pos: Vec2{X: 0, Y: 110}, Vel: Vec2{Y: -1}, Mass: 5},
+		{Pos: Vec2{X: 1, Y: -102}, Vel: Vec2{Y: 50}, Mass: 1},
+	}
+	approaches := CloseApproaches(bodies, 0, 4, 5, 100)
+	if len(approaches) != 2 {
+		t.Fatalf("found %v, expected 2 approaches", approaches)
+	}
+	if approaches[0].A != 3 || approaches[0].B != 4 || math.Abs(approaches[0].Time-5) > 1e-12 {
+		t.Errorf("the first approach is %+v, expected bodies 3 and 4 in 5", approaches[0])
+	}
+	if approaches[1].A != 0 || approaches[1].B != 2 || math.Abs(approaches[1].Distance-2) > 1e-12 {
+		t.Errorf("the second approach is %+v, expected bodies 0 and 2 at 2", approaches[1])
+	}
+
+	// A short horizon leaves out the later approach, and the least massive body is only included with room for it
+	if approaches := CloseApproaches(bodies, 0, 4, 5, 7); len(approaches) != 1 || approaches[0].A != 3 {
+		t.Errorf("found %v within 7, expected only bodies 3 and 4", approaches)
+	}
+	if approaches := CloseApproaches(bodies, 0, 5, 5, 7); len(approaches) != 3 || approaches[0].B != 5 || approaches[1].B != 5 {
+		t.Errorf("found %v with every body, expected body 5 to pass 3 and 4 first", approaches)
+	}
+}
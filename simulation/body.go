@@ -0,0 +1,138 @@
+// Package simulation is the physics of the gravity simulation: the bodies, and how they move and collide at each step
+// It has no state of its own, so any number of simulations can be run side by side by keeping their own bodies and Params
+package simulation
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+)
+
+type Body struct {
+	// The position of this body
+	Pos Vec2
+	// The velocity of this body
+	Vel Vec2
+	// The mass of this body, directionally proportional to
+	// acceleration effect on other bodies
+	Mass float64
+	// The radius of this body - for rendering
+	Radius float64
+	// Color of this body - for rendering
+	// Note the alpha channel is unused
+	Color color.RGBA
+	// An optional name, so specific bodies can be tracked across a session
+	// When two bodies merge, the larger keeps its name
+	Name string
+	// A fixed body pulls on the other bodies but never moves itself, e.g. to pin a star in place
+	Fixed bool
+	// The ids of the bodies merged into this one, including any merged into them before, so they can be traced to this body
+	// The slice is never changed in place (see Absorb), so copies of the body can share it
+	Absorbed []int
+}
+
+// How bodies that touch behave
+const (
+	// The bodies merge into one, conserving mass and momentum
+	COLLISIONMERGE = iota
+	// The bodies bounce off each other elastically
+	COLLISIONBOUNCE
+	// The bodies pass straight through each other
+	COLLISIONPASS
+)
+
+// The names of the collision modes, e.g. as used by --collisions and shown on screen
+var CollisionModeNames = []string{"merge", "bounce", "pass"}
+
+// Find the collision mode with the given name
+func ParseCollisionMode(name string) (int, error) {
+	for i, n := range CollisionModeNames {
+		if n == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown collision mode %q, must be one of %v", name, strings.Join(CollisionModeNames, ", "))
+}
+
+// The global parameters of a simulation, which every body is updated with
+type Params struct {
+	// The gravitational constant
+	G float64
+	// The length of each timestep
+	Timescale float64
+	// The softening length, which stops the force between bodies blowing up as they get close
+	Softening float64
+	// How bodies that touch behave, one of COLLISIONMERGE, COLLISIONBOUNCE or COLLISIONPASS
+	CollisionMode int
+}
+
+// Method for converting mass to radius for consistency
+func MassToRadius(mass float64) float64 {
+	return math.Sqrt(mass)
+}
+
+// The radius of a body once it has grown to the given mass, e.g. by merging
+// The body keeps its density, so a body with the radius of its mass (see MassToRadius) keeps the radius of its new mass,
+// but a denser body (e.g. a black hole) stays as dense, rather than swelling to the radius of its mass
+// A body without a mass or radius to find its density from gets the radius of its new mass
+func GrownRadius(b *Body, mass float64) float64 {
+	if b.Mass <= 0 || b.Radius <= 0 {
+		return MassToRadius(mass)
+	}
+	return b.Radius * math.Sqrt(mass/b.Mass)
+}
+
+// Whether a is the body kept when a and b merge: a fixed body is kept over a free one, and then the more massive body
+func KeptInMerge(a, b *Body) bool {
+	return (a.Fixed && !b.Fixed) || (a.Fixed == b.Fixed && a.Mass > b.Mass)
+}
+
+// Merge other, the body with the given id, into b, conserving mass and momentum
+// A fixed b stays where it is, and b keeps its density (see GrownRadius), color and name
+// Bodies without any mass between them have no mass-weighted average, so b keeps its position and velocity
+func (b *Body) Merge(id int, other *Body) {
+	mass := b.Mass + other.Mass
+	if !b.Fixed && mass > 0 {
+		b.Pos = b.Pos.Scale(b.Mass).Add(other.Pos.Scale(other.Mass)).Scale(1 / mass)
+		b.Vel = b.Vel.Scale(b.Mass).Add(other.Vel.Scale(other.Mass)).Scale(1 / mass)
+	}
+	b.Radius = GrownRadius(b, mass)
+	b.Mass = mass
+	b.Absorb(id, other)
+}
+
+// Record that other, the body with the given id, has merged into b, along with any bodies that merged into other before
+// A new slice is made rather than appending, as copies of b (e.g. from the previous step, or in snapshots) share the old one
+func (b *Body) Absorb(id int, other *Body) {
+	absorbed := make([]int, 0, len(b.Absorbed)+1+len(other.Absorbed))
+	absorbed = append(absorbed, b.Absorbed...)
+	absorbed = append(absorbed, id)
+	b.Absorbed = append(absorbed, other.Absorbed...)
+}
+
+// The id of the body that the body with the given id is now part of: the id itself if the body is still there,
+// the id of the body it merged into (however many merges ago), or -1 if it was removed some other way
+// This keeps things following a body (e.g. the view, or the selection) attached to it through merges
+func Survivor(bodies []*Body, id int) int {
+	if id >= 0 && id < len(bodies) && bodies[id] != nil {
+		return id
+	}
+	for i, b := range bodies {
+		if b == nil {
+			continue
+		}
+		for _, absorbed := range b.Absorbed {
+			if absorbed == id {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Extracted method for finding the squared distance between the centers of two bodies
+func DistSquared(a, b *Body) float64 {
+	separation := a.Pos.Sub(b.Pos)
+	return separation.Dot(separation)
+}
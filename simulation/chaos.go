@@ -0,0 +1,89 @@
+package simulation
+
+import "math"
+
+// Chaos is measured by running a shadow copy of a simulation alongside it, started a tiny distance away in phase space
+// (the positions and velocities of every body), and watching how fast the two drift apart. In a chaotic system the
+// separation grows exponentially, as e^(λt) where λ is the largest Lyapunov exponent, until it is as large as the system itself
+//
+// To keep measuring the rate of growth rather than the size of the system, the shadow is pulled back to the starting
+// separation after every step (Benettin's method), and λ is the average of the logarithm of the growth over every step
+
+// The distance between two sets of bodies in phase space, i.e. the square root of the sum of the squared differences
+// of every position and velocity, over the bodies present in both
+func PhaseDistance(a, b []*Body) float64 {
+	total := 0.0
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == nil || b[i] == nil {
+			continue
+		}
+		dPos, dVel := a[i].Pos.Sub(b[i].Pos), a[i].Vel.Sub(b[i].Vel)
+		total += dPos.Dot(dPos) + dVel.Dot(dVel)
+	}
+	return math.Sqrt(total)
+}
+
+// Measures how fast a simulation diverges from a shadow copy of itself
+type Chaos struct {
+	shadow *Simulation
+	// The separation the shadow is started at, and pulled back to after each step
+	separation float64
+	// The sum of the logarithms of the growth of the separation over every step so far
+	growth float64
+	// The simulated time the measurement started at, and the time of the last step
+	start float64
+	time  float64
+}
+
+// Start measuring the chaos of s, with a shadow copy whose first free body is moved perturbation along the x axis
+// Returns nil if s has no free bodies to move
+func NewChaos(s *Simulation, perturbation float64) *Chaos {
+	shadow := s.Clone()
+	for _, b := range shadow.bodies {
+		if b != nil && !b.Fixed {
+			b.Pos.X += perturbation
+			return &Chaos{shadow: shadow, separation: perturbation, start: s.Time, time: s.Time}
+		}
+	}
+	return nil
+}
+
+// Step the shadow along with s, which has just been stepped, and measure how far it diverged
+// The shadow uses the current parameters of s, so changes to e.g. the timescale apply to both,
+// but any other change to the bodies of s (dragging or editing them) is treated as divergence
+func (c *Chaos) Step(s *Simulation) {
+	c.shadow.Params = s.Params
+	c.shadow.Step()
+	c.time = s.Time
+	distance := PhaseDistance(s.bodies, c.shadow.bodies)
+	if distance == 0 {
+		return
+	}
+	c.growth += math.Log(distance / c.separation)
+
+	// Pull the shadow back towards s along the direction it diverged in
+	scale := c.separation / distance
+	for i, b := range c.shadow.bodies {
+		if b == nil || i >= len(s.bodies) || s.bodies[i] == nil {
+			continue
+		}
+		ref := s.bodies[i]
+		b.Pos = ref.Pos.Add(b.Pos.Sub(ref.Pos).Scale(scale))
+		b.Vel = ref.Vel.Add(b.Vel.Sub(ref.Vel).Scale(scale))
+	}
+}
+
+// The separation the shadow would have reached by now if it had never been pulled back,
+// as long as it had stayed small compared to the system
+func (c *Chaos) Divergence() float64 {
+	return c.separation * math.Exp(c.growth)
+}
+
+// The estimate of the largest Lyapunov exponent so far, the average rate the separation grew at over the simulated time,
+// or 0 if no time has passed
+func (c *Chaos) Lyapunov() float64 {
+	if c.time <= c.start {
+		return 0
+	}
+	return c.growth / (c.time - c.start)
+}
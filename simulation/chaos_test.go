@@ -0,0 +1,40 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+// Stepping a clone must leave the simulation it was cloned from as it was
+func TestCloneIsIndependent(t *testing.T) {
+	s := twoBodyCircular()
+	c := s.Clone()
+	for i := 0; i < 10; i++ {
+		c.Step()
+	}
+	if s.Steps != 0 || s.Time != 0 || s.Bodies()[0].Pos.X != -50 {
+		t.Errorf("stepping the clone changed the original: %v steps, time %v, %+v", s.Steps, s.Time, s.Bodies()[0])
+	}
+	if c.Steps != 10 || c.Bodies()[0].Pos.X == -50 {
+		t.Errorf("the clone did not step: %v steps, %+v", c.Steps, c.Bodies()[0])
+	}
+}
+
+// Without gravity, bodies moving in straight lines keep the same separation from their shadows, so there is no chaos
+func TestChaosWithoutGravity(t *testing.T) {
+	const perturbation = 1e-6
+	s := New(Params{G: 0, Timescale: 0.1, CollisionMode: COLLISIONPASS}, 1)
+	s.AddBody(&Body{Vel: Vec2{X: 1}, Mass: 1, Radius: 1})
+	s.AddBody(&Body{Pos: Vec2{X: 100}, Vel: Vec2{Y: -1}, Mass: 1, Radius: 1})
+	c := NewChaos(s, perturbation)
+	s.OnStep(c.Step)
+	for i := 0; i < 100; i++ {
+		s.Step()
+	}
+	if math.Abs(c.Lyapunov()) > 1e-6 {
+		t.Errorf("the Lyapunov exponent is %v, want 0", c.Lyapunov())
+	}
+	if math.Abs(c.Divergence()-perturbation) > 1e-9 {
+		t.Errorf("the divergence is %v, want %v", c.Divergence(), perturbation)
+	}
+}
@@ -0,0 +1,84 @@
+package simulation
+
+import (
+	"sync"
+	"testing"
+)
+
+// The concurrency test drives a simulation from one goroutine while others take snapshots and change it,
+// as a renderer or server would. It checks the results make sense, but is mostly useful under "go test -race"
+
+const (
+	// How many steps the stepping goroutine takes, and how many snapshots and changes the others make meanwhile
+	CONCURRENTSTEPS   = 500
+	CONCURRENTREADERS = 4
+	// How many bodies each of the other goroutines adds, keeping the steps quick
+	CONCURRENTADDS = 10
+)
+
+func TestConcurrentSnapshotsAndChanges(t *testing.T) {
+	sim := New(Params{G: 100, Timescale: 0.25, CollisionMode: COLLISIONMERGE}, 1)
+	for i := 0; i < 20; i++ {
+		sim.AddBody(NewRandomBody(sim.Rand, RandomOptions{MassMin: 1, MassMax: 11, VelocityRange: 1, Width: 1200, Height: 800}))
+	}
+	steps := 0
+	sim.OnStep(func(s *Simulation) { steps++ })
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 0; i < CONCURRENTSTEPS; i++ {
+			sim.Step()
+		}
+	}()
+
+	for r := 0; r < CONCURRENTREADERS; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lastSteps, added := 0, 0
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				snapshot := sim.Snapshot()
+				if snapshot.Steps < lastSteps {
+					t.Errorf("snapshot went back from step %v to %v", lastSteps, snapshot.Steps)
+					return
+				}
+				lastSteps = snapshot.Steps
+				// Changing the copies must not change the simulation
+				for _, b := range snapshot.Bodies {
+					if b != nil {
+						b.Mass = 0
+					}
+				}
+				if added < CONCURRENTADDS {
+					sim.Do(func(s *Simulation) {
+						s.AddBody(&Body{Pos: Vec2{X: 1e6, Y: 1e6}, Mass: 1, Radius: 1})
+					})
+					added++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	snapshot := sim.Snapshot()
+	if snapshot.Steps != CONCURRENTSTEPS || steps != CONCURRENTSTEPS {
+		t.Fatalf("took %v steps (%v callbacks), want %v", snapshot.Steps, steps, CONCURRENTSTEPS)
+	}
+	if want := 20 + CONCURRENTREADERS*CONCURRENTADDS; len(snapshot.Bodies) > want {
+		t.Errorf("have %v bodies, want at most %v", len(snapshot.Bodies), want)
+	}
+	for id, b := range snapshot.Bodies {
+		if b != nil && b.Mass <= 0 {
+			t.Errorf("body %v has mass %v, changed through a snapshot", id, b.Mass)
+		}
+	}
+}
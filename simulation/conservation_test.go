@@ -0,0 +1,248 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+// The conservation tests check that the physics conserves what it should, however the bodies are ordered:
+// momentum and mass exactly (to floating point error) through gravity, bounces and merges,
+// and energy to within a bounded drift, as the integrator only approximates the true orbits
+
+const (
+	// How far the total momentum or mass may be from the start, relative to the scale of the system
+	CONSERVATIONTOLERANCE = 1e-9
+	// How long the energy drift is checked for, and how far the total energy may drift from the start, relative to the starting energy
+	// Each step moves the bodies along their velocities before updating the velocities (i.e. the explicit Euler method),
+	// so the energy drifts steadily, by a little under 0.5% every GOLDENSTEPS steps of the golden systems
+	ENERGYDRIFTSTEPS     = 10 * GOLDENSTEPS
+	ENERGYDRIFTTOLERANCE = 0.05
+)
+
+// The total mass and momentum of the bodies
+func totals(bodies []*Body) (mass, xMomentum, yMomentum float64) {
+	for _, b := range bodies {
+		if b != nil {
+			mass += b.Mass
+			xMomentum += b.Mass * b.Vel.X
+			yMomentum += b.Mass * b.Vel.Y
+		}
+	}
+	return mass, xMomentum, yMomentum
+}
+
+// The scale of the momentum of the bodies, for comparing momenta against
+func momentumScale(bodies []*Body) float64 {
+	scale := 0.0
+	for _, b := range bodies {
+		if b != nil {
+			scale += b.Mass * b.Vel.Norm()
+		}
+	}
+	return scale
+}
+
+// The total kinetic and gravitational potential energy of the bodies, with no softening
+// Bodies within the unit distance don't pull on each other, so don't add to the potential energy either
+func energy(bodies []*Body, G float64) float64 {
+	total := 0.0
+	for i, a := range bodies {
+		if a == nil {
+			continue
+		}
+		total += a.Mass * (a.Vel.X*a.Vel.X + a.Vel.Y*a.Vel.Y) / 2
+		for _, b := range bodies[i+1:] {
+			if b != nil && DistSquared(a, b) >= 1 {
+				total -= G * a.Mass * b.Mass / math.Sqrt(DistSquared(a, b))
+			}
+		}
+	}
+	return total
+}
+
+// Step the simulation, failing the test if the mass or momentum of the bodies changes at any step
+func checkConserved(t *testing.T, s *Simulation, steps int) {
+	t.Helper()
+	mass, xMomentum, yMomentum := totals(s.Bodies())
+	scale := momentumScale(s.Bodies())
+	for step := 1; step <= steps; step++ {
+		s.Step()
+		m, px, py := totals(s.Bodies())
+		if math.Abs(m-mass) > CONSERVATIONTOLERANCE*mass {
+			t.Fatalf("step %v: total mass %v, want %v", step, m, mass)
+		}
+		if math.Abs(px-xMomentum) > CONSERVATIONTOLERANCE*scale || math.Abs(py-yMomentum) > CONSERVATIONTOLERANCE*scale {
+			t.Fatalf("step %v: total momentum (%v, %v), want (%v, %v)", step, px, py, xMomentum, yMomentum)
+		}
+	}
+}
+
+// The number of bodies left in the simulation
+func remaining(s *Simulation) int {
+	n := 0
+	for _, b := range s.Bodies() {
+		if b != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// A cluster of random bodies, close enough together that many of them collide
+func cluster(mode int) *Simulation {
+	s := New(Params{G: 1, Timescale: 0.05, CollisionMode: mode}, 42)
+	for i := 0; i < 40; i++ {
+		s.AddBody(&Body{
+			Pos:  Vec2{X: s.Rand.Float64()*200 - 100, Y: s.Rand.Float64()*200 - 100},
+			Vel:  Vec2{X: s.Rand.Float64()*2 - 1, Y: s.Rand.Float64()*2 - 1},
+			Mass: 1 + s.Rand.Float64()*99,
+		})
+		s.Bodies()[i].Radius = MassToRadius(s.Bodies()[i].Mass)
+	}
+	return s
+}
+
+func TestMomentumConservedByGravity(t *testing.T) {
+	checkConserved(t, figureEight(), GOLDENSTEPS)
+	checkConserved(t, cluster(COLLISIONPASS), 1000)
+}
+
+func TestMomentumConservedByBounces(t *testing.T) {
+	checkConserved(t, cluster(COLLISIONBOUNCE), 1000)
+}
+
+func TestMassAndMomentumConservedByMerges(t *testing.T) {
+	s := cluster(COLLISIONMERGE)
+	checkConserved(t, s, 1000)
+	if remaining(s) == 40 {
+		t.Fatal("no bodies merged")
+	}
+}
+
+// Two bodies of the same mass colliding head on merge into one body, at rest where they met
+func TestEqualMassMerge(t *testing.T) {
+	s := New(Params{G: 1, Timescale: 0.05, CollisionMode: COLLISIONMERGE}, 1)
+	s.AddBody(&Body{Pos: Vec2{X: -2}, Vel: Vec2{X: 1}, Mass: 10, Radius: 3})
+	s.AddBody(&Body{Pos: Vec2{X: 2}, Vel: Vec2{X: -1}, Mass: 10, Radius: 3})
+	checkConserved(t, s, 1)
+	if remaining(s) != 1 {
+		t.Fatalf("%v bodies remaining, want 1", remaining(s))
+	}
+	b := s.Bodies()[0]
+	if b == nil || b.Mass != 20 || b.Pos.X != 0 || b.Vel.X != 0 {
+		t.Fatalf("merged body %+v, want mass 20 at rest at the origin", b)
+	}
+}
+
+// A merged body keeps the density of the most massive body, so a black hole stays small as it swallows other bodies
+func TestMergeKeepsDensity(t *testing.T) {
+	s := New(Params{G: 1, Timescale: 0.05, CollisionMode: COLLISIONMERGE}, 1)
+	s.AddBody(&Body{Mass: 5000, Radius: 2})
+	s.AddBody(&Body{Pos: Vec2{X: 3}, Mass: 16, Radius: MassToRadius(16)})
+	s.AddBody(&Body{Pos: Vec2{X: 100}, Mass: 9, Radius: MassToRadius(9)})
+	s.AddBody(&Body{Pos: Vec2{X: 103}, Mass: 16, Radius: MassToRadius(16)})
+	s.Step()
+	if b := s.Bodies()[0]; b == nil || math.Abs(b.Radius-2*math.Sqrt(5016.0/5000)) > 1e-12 {
+		t.Errorf("black hole merged into %+v, want a radius of about 2", b)
+	}
+	if b := s.Bodies()[3]; b == nil || math.Abs(b.Radius-5) > 1e-12 {
+		t.Errorf("planets merged into %+v, want the radius of their mass, 5", b)
+	}
+}
+
+// A small body touching a large body, which touches a body larger still, all merge into the largest
+// whichever order they are in
+func TestChainMerge(t *testing.T) {
+	bodies := []Body{
+		{Pos: Vec2{X: 0}, Vel: Vec2{Y: 1}, Mass: 1, Radius: 2},
+		{Pos: Vec2{X: 3}, Vel: Vec2{Y: -1}, Mass: 5, Radius: 2},
+		{Pos: Vec2{X: 6}, Vel: Vec2{Y: 2}, Mass: 9, Radius: 2},
+	}
+	orders := [][]int{{0, 1, 2}, {2, 1, 0}, {1, 0, 2}, {1, 2, 0}}
+	for _, order := range orders {
+		s := New(Params{G: 1, Timescale: 0.05, CollisionMode: COLLISIONMERGE}, 1)
+		for _, i := range order {
+			b := bodies[i]
+			s.AddBody(&b)
+		}
+		checkConserved(t, s, 1)
+		if remaining(s) != 1 {
+			t.Fatalf("order %v: %v bodies remaining, want 1", order, remaining(s))
+		}
+		// Every body is traced to the one they all merged into
+		survivor := Survivor(s.Bodies(), 0)
+		if survivor < 0 || len(s.Bodies()[survivor].Absorbed) != 2 {
+			t.Fatalf("order %v: survivor %v has not absorbed the other two bodies", order, survivor)
+		}
+		for id := range bodies {
+			if got := Survivor(s.Bodies(), id); got != survivor {
+				t.Errorf("order %v: body %v survived as %v, want %v", order, id, got, survivor)
+			}
+		}
+	}
+}
+
+// Merging chosen bodies (e.g. a selection in the window) follows the rules of a collision: a fixed body is kept and stays
+// fixed where it is, keeping its density, and bodies without any mass merge without NaN
+func TestMergeBodies(t *testing.T) {
+	s := New(Params{G: 1, Timescale: 0.05, CollisionMode: COLLISIONMERGE}, 1)
+	s.AddBody(&Body{Pos: Vec2{X: 10}, Vel: Vec2{Y: 1}, Mass: 100, Radius: 10})
+	s.AddBody(&Body{Pos: Vec2{X: 50}, Mass: 4, Radius: 1, Fixed: true, Name: "anchor"})
+	s.AddBody(&Body{Pos: Vec2{X: -10}, Mass: 20, Radius: 5})
+	s.AddBody(&Body{Pos: Vec2{Y: 30}, Mass: 1, Radius: 1})
+	kept := s.MergeBodies([]int{0, 1, 2, 7})
+	if kept != 1 {
+		t.Fatalf("merged into body %v, want the fixed body 1", kept)
+	}
+	b := s.Bodies()[kept]
+	if !b.Fixed || b.Name != "anchor" || b.Pos != (Vec2{X: 50}) || b.Vel != (Vec2{}) {
+		t.Errorf("merged into %+v, want the anchor fixed where it was", b)
+	}
+	if b.Mass != 124 || math.Abs(b.Radius-math.Sqrt(124.0/4)) > 1e-12 || len(b.Absorbed) != 2 {
+		t.Errorf("merged into %+v, want a mass of 124 with the anchor's density, absorbing two bodies", b)
+	}
+	if s.Bodies()[0] != nil || s.Bodies()[2] != nil || s.Bodies()[3] == nil {
+		t.Errorf("merging left %v, want only the merged body and the unselected body 3", s.Bodies())
+	}
+
+	// Free bodies merge into the most massive, at their center of mass
+	s.AddBody(&Body{Pos: Vec2{X: 3}, Vel: Vec2{Y: 3}, Mass: 3, Radius: 1})
+	kept = s.MergeBodies([]int{3, 4})
+	if b := s.Bodies()[kept]; kept != 4 || b.Mass != 4 || b.Pos != (Vec2{X: 2.25, Y: 7.5}) || b.Vel != (Vec2{Y: 2.25}) {
+		t.Errorf("merged into body %v: %+v, want body 4 at the center of mass", kept, b)
+	}
+
+	// Bodies without any mass have no center of mass, so the kept body stays where it is
+	s.AddBody(&Body{Pos: Vec2{X: 1}})
+	s.AddBody(&Body{Pos: Vec2{X: 2}})
+	kept = s.MergeBodies([]int{5, 6})
+	if b := s.Bodies()[kept]; math.IsNaN(b.Pos.X) || math.IsNaN(b.Vel.X) || math.IsNaN(b.Radius) {
+		t.Errorf("massless bodies merged into %+v", b)
+	}
+	if kept := s.MergeBodies([]int{0, 99}); kept != -1 {
+		t.Errorf("merging bodies that aren't there kept body %v", kept)
+	}
+}
+
+// The total energy of a bound system drifts as the integrator approximates the orbits, but the drift must stay small over a long run
+func TestEnergyDriftBounded(t *testing.T) {
+	systems := []struct {
+		name string
+		new  func() *Simulation
+	}{
+		{"two_body_circular", twoBodyCircular},
+		{"figure_eight", figureEight},
+	}
+	for _, system := range systems {
+		t.Run(system.name, func(t *testing.T) {
+			s := system.new()
+			start := energy(s.Bodies(), s.G)
+			for step := 1; step <= ENERGYDRIFTSTEPS; step++ {
+				s.Step()
+				if drift := math.Abs(energy(s.Bodies(), s.G)/start - 1); drift > ENERGYDRIFTTOLERANCE {
+					t.Fatalf("step %v: energy drifted by %.3g of the starting energy", step, drift)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package simulation
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// Totals over all of the bodies, for checking how well the simulation conserves mass, momentum and energy
+
+// The total mass of the bodies
+func TotalMass(bodies []*Body) float64 {
+	total := 0.0
+	for _, b := range bodies {
+		if b != nil {
+			total += b.Mass
+		}
+	}
+	return total
+}
+
+// The total momentum of the bodies
+func Momentum(bodies []*Body) Vec2 {
+	var total Vec2
+	for _, b := range bodies {
+		if b != nil {
+			total = total.Add(b.Vel.Scale(b.Mass))
+		}
+	}
+	return total
+}
+
+// The total kinetic energy of the bodies
+func KineticEnergy(bodies []*Body) float64 {
+	total := 0.0
+	for _, b := range bodies {
+		if b != nil {
+			total += b.Mass * b.Vel.Dot(b.Vel) / 2
+		}
+	}
+	return total
+}
+
+// The total gravitational potential energy of the bodies, ignoring any softening
+// Bodies within the unit distance don't pull on each other (see Acceleration), so don't add to the potential energy either
+// Note this is O(n^2) like the gravity itself
+func PotentialEnergy(bodies []*Body, G float64) float64 {
+	total := 0.0
+	for i, a := range bodies {
+		if a == nil {
+			continue
+		}
+		for _, b := range bodies[i+1:] {
+			if b == nil {
+				continue
+			}
+			if distSquared := DistSquared(a, b); distSquared >= 1 {
+				total -= G * a.Mass * b.Mass / math.Sqrt(distSquared)
+			}
+		}
+	}
+	return total
+}
+
+// The total angular momentum of the bodies about the origin
+// The simulation is two dimensional, so this is the component out of the plane, positive for anticlockwise motion
+func AngularMomentum(bodies []*Body) float64 {
+	total := 0.0
+	for _, b := range bodies {
+		if b != nil {
+			total += b.Mass * (b.Pos.X*b.Vel.Y - b.Pos.Y*b.Vel.X)
+		}
+	}
+	return total
+}
+
+// The position and velocity of the center of mass (the barycenter) of the bodies, or zero if they have no mass
+func Barycenter(bodies []*Body) (Vec2, Vec2) {
+	var mass float64
+	var weightedPos, momentum Vec2
+	for _, b := range bodies {
+		if b != nil {
+			mass += b.Mass
+			weightedPos = weightedPos.Add(b.Pos.Scale(b.Mass))
+			momentum = momentum.Add(b.Vel.Scale(b.Mass))
+		}
+	}
+	if mass == 0 {
+		return Vec2{}, Vec2{}
+	}
+	return weightedPos.Scale(1 / mass), momentum.Scale(1 / mass)
+}
+
+// The angular momentum of a body about a point moving with the given velocity, positive for anticlockwise motion around it
+func AngularMomentumAbout(b *Body, pos, vel Vec2) float64 {
+	offset := b.Pos.Sub(pos)
+	relative := b.Vel.Sub(vel)
+	return b.Mass * (offset.X*relative.Y - offset.Y*relative.X)
+}
+
+// The total angular momentum of the bodies about their barycenter, moving with it
+// Unlike the angular momentum about the origin, this doesn't depend on where the system is or how fast it is moving,
+// so it only changes if the bodies do. Gravity conserves it, so it only changes from the error of each step (largest during
+// close encounters) and from merges, as the angular momentum of the two bodies around each other would become the spin of
+// the merged body, which isn't simulated
+func BarycentricAngularMomentum(bodies []*Body) float64 {
+	pos, vel := Barycenter(bodies)
+	total := 0.0
+	for _, b := range bodies {
+		if b != nil {
+			total += AngularMomentumAbout(b, pos, vel)
+		}
+	}
+	return total
+}
+
+// The virial ratio of the bodies, 2T/|U|, where T is their kinetic energy moving relative to their barycenter and U their
+// potential energy. A system in equilibrium (virialized) has a ratio of 1, one with less (e.g. bodies starting at rest)
+// collapses, and one with more expands, and is unbound altogether from 2, as then its total energy is positive
+// Bodies with no potential energy between them are never bound, so have an infinite ratio if they are moving at all
+// Like the potential energy, this is O(n^2)
+func VirialRatio(bodies []*Body, G float64) float64 {
+	_, vel := Barycenter(bodies)
+	kinetic := 0.0
+	for _, b := range bodies {
+		if b != nil {
+			relative := b.Vel.Sub(vel)
+			kinetic += b.Mass * relative.Dot(relative) / 2
+		}
+	}
+	potential := PotentialEnergy(bodies, G)
+	if potential == 0 {
+		if kinetic == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return 2 * kinetic / math.Abs(potential)
+}
+
+// A checksum of the exact state of the bodies: the position, velocity, mass and radius of each, and which ids have been removed
+// Two runs from the same starting state have the same checksum after each step only if they are bit for bit the same,
+// so comparing checksums step by step finds the first step two runs that should be reproducible differ at
+func Checksum(bodies []*Body) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	write := func(x float64) {
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(x))
+		h.Write(buf[:])
+	}
+	for _, b := range bodies {
+		if b == nil {
+			// NaN is never a real position, so removed bodies can't be mistaken for any body
+			write(math.NaN())
+			continue
+		}
+		write(b.Pos.X)
+		write(b.Pos.Y)
+		write(b.Vel.X)
+		write(b.Vel.Y)
+		write(b.Mass)
+		write(b.Radius)
+	}
+	return h.Sum64()
+}
+
+// The ids of the bodies with a NaN or infinite position, velocity or mass, which no step can recover from
+// These come from the numbers blowing up (e.g. two bodies at exactly the same place with no softening), so finding them
+// straight away, before they spread to every body they pull on, points at the step and bodies that went wrong
+func NonFinite(bodies []*Body) []int {
+	var ids []int
+	for id, b := range bodies {
+		if b == nil {
+			continue
+		}
+		for _, x := range [...]float64{b.Pos.X, b.Pos.Y, b.Vel.X, b.Vel.Y, b.Mass} {
+			if math.IsNaN(x) || math.IsInf(x, 0) {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ids
+}
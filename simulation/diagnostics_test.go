@@ -0,0 +1,109 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+// Copies of a simulation stepped the same way keep the same checksum, and the smallest change to any body changes it
+func TestChecksum(t *testing.T) {
+	s := New(Params{G: 100, Timescale: 0.25, CollisionMode: COLLISIONMERGE}, 1)
+	for i := 0; i < 30; i++ {
+		s.AddBody(NewRandomBody(s.Rand, RandomOptions{MassMin: 1, MassMax: 11, VelocityRange: 1, Width: 1200, Height: 800}))
+	}
+	c := s.Clone()
+	for step := 0; step < 200; step++ {
+		if Checksum(s.Bodies()) != Checksum(c.Bodies()) {
+			t.Fatalf("step %v: copies of the simulation have different checksums", step)
+		}
+		s.Step()
+		c.Step()
+	}
+
+	before := Checksum(c.Bodies())
+	for id, b := range c.Bodies() {
+		if b == nil {
+			continue
+		}
+		x := b.Pos.X
+		b.Pos.X = math.Nextafter(x, math.Inf(1))
+		if Checksum(c.Bodies()) == before {
+			t.Errorf("moving body %v by the smallest amount left the checksum the same", id)
+		}
+		b.Pos.X = x
+	}
+	if Checksum(c.Bodies()) != before {
+		t.Errorf("putting the bodies back changed the checksum")
+	}
+}
+
+// Only the bodies with a NaN or infinite value are found, whichever of their values it is
+func TestNonFinite(t *testing.T) {
+	bodies := []*Body{
+		{Pos: Vec2{1, 2}, Vel: Vec2{3, 4}, Mass: 5},
+		nil,
+		{Pos: Vec2{math.NaN(), 0}, Mass: 1},
+		{Vel: Vec2{0, math.Inf(-1)}, Mass: 1},
+		{Mass: math.Inf(1)},
+		{Pos: Vec2{-1e300, 1e300}, Mass: 1},
+	}
+	ids := NonFinite(bodies)
+	if len(ids) != 3 || ids[0] != 2 || ids[1] != 3 || ids[2] != 4 {
+		t.Errorf("found %v, expected [2 3 4]", ids)
+	}
+	if ids := NonFinite(bodies[:2]); ids != nil {
+		t.Errorf("found %v in finite bodies", ids)
+	}
+}
+
+// The angular momentum about the barycenter is the same wherever the system is and however fast it moves, and is the
+// angular momentum about the origin once the barycenter is at the origin and at rest
+func TestBarycentricAngularMomentum(t *testing.T) {
+	s := New(Params{G: 100}, 1)
+	for i := 0; i < 20; i++ {
+		s.AddBody(NewRandomBody(s.Rand, RandomOptions{MassMin: 1, MassMax: 11, VelocityRange: 2, Width: 1200, Height: 800}))
+	}
+	before := BarycentricAngularMomentum(s.Bodies())
+
+	pos, vel := Barycenter(s.Bodies())
+	for _, b := range s.Bodies() {
+		b.Pos, b.Vel = b.Pos.Sub(pos), b.Vel.Sub(vel)
+	}
+	if l := AngularMomentum(s.Bodies()); math.Abs(l-before) > 1e-9*math.Abs(before) {
+		t.Errorf("about the origin with the barycenter there at rest it is %v, expected %v", l, before)
+	}
+
+	for _, b := range s.Bodies() {
+		b.Pos, b.Vel = b.Pos.Add(Vec2{X: 5000, Y: -300}), b.Vel.Add(Vec2{X: 40, Y: 7})
+	}
+	if l := BarycentricAngularMomentum(s.Bodies()); math.Abs(l-before) > 1e-9*math.Abs(before) {
+		t.Errorf("moving the system changed it from %v to %v", before, l)
+	}
+}
+
+// A circular binary is exactly virialized, whatever it is moving at as a whole, and bodies at rest have a ratio of 0
+func TestVirialRatio(t *testing.T) {
+	const G, mass, separation = 100.0, 10.0, 50.0
+	// Each body circles the barycenter at half the separation, pulled by the other body
+	speed := math.Sqrt(G * mass / (2 * separation))
+	bodies := []*Body{
+		{Pos: Vec2{X: -separation / 2}, Vel: Vec2{X: 3, Y: -speed + 4}, Mass: mass},
+		nil,
+		{Pos: Vec2{X: separation / 2}, Vel: Vec2{X: 3, Y: speed + 4}, Mass: mass},
+	}
+	if ratio := VirialRatio(bodies, G); math.Abs(ratio-1) > 1e-12 {
+		t.Errorf("a circular binary has a virial ratio of %v, expected 1", ratio)
+	}
+
+	for _, b := range bodies {
+		if b != nil {
+			b.Vel = Vec2{}
+		}
+	}
+	if ratio := VirialRatio(bodies, G); ratio != 0 {
+		t.Errorf("bodies at rest have a virial ratio of %v, expected 0", ratio)
+	}
+	if ratio := VirialRatio([]*Body{{Vel: Vec2{X: 1}, Mass: 1}, {Pos: Vec2{X: 10}, Mass: 1}}, 0); !math.IsInf(ratio, 1) {
+		t.Errorf("moving bodies without gravity have a virial ratio of %v, expected infinity", ratio)
+	}
+}
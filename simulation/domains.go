@@ -0,0 +1,101 @@
+package simulation
+
+import (
+	"math"
+	"sort"
+)
+
+// For very large numbers of bodies, the gravity can be found in parts (e.g. by several processes or machines) by
+// domain decomposition: the bodies are split by position into domains, and the accelerations of the bodies owned by
+// each domain are found from just the bodies that pull on them, the domain's sources
+//
+// The sources of a domain are its own bodies and ghosts, copies of the bodies of the other domains within the ghost
+// distance of it. The rest of each other domain is too far away for the detail to matter much, so pulls as one body of
+// their total mass at their center of mass. With a ghost distance of 0 every other body is a ghost, so the accelerations
+// are exactly those of Gravity, only found in parts
+
+// A part of the simulation whose accelerations can be found on their own
+type Domain struct {
+	// The ids of the bodies owned by the domain
+	Owned []int
+	// The bodies pulling on the owned bodies, with only their positions and masses: the owned bodies and ghosts in order
+	// of id, then the rest of each other domain as one body
+	Sources []*Body
+	// The index in Sources of each owned body, in the same order as Owned
+	Targets []int
+}
+
+// The accelerations of the owned bodies of the domain due to the gravity of its sources, in the same order as Owned
+func (d Domain) Accelerations(p Params) []Vec2 {
+	acc := make([]Vec2, len(d.Targets))
+	for i, target := range d.Targets {
+		acc[i] = Acceleration(d.Sources[target], d.Sources, p)
+	}
+	return acc
+}
+
+// Split the bodies into at most n domains of (nearly) equal numbers of bodies, in strips from left to right
+// There are fewer than n domains if there are fewer than n bodies, and none without any bodies
+// Bodies within ghostDistance of another domain are its ghosts, or with a ghostDistance of 0 every body is a ghost of every domain
+func Decompose(bodies []*Body, n int, ghostDistance float64) []Domain {
+	var ids []int
+	for id, b := range bodies {
+		if b != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return bodies[ids[i]].Pos.X < bodies[ids[j]].Pos.X })
+	n = min(n, len(ids))
+
+	// Each domain owns a strip of the bodies, with the box bounding them, and domain[id] is the domain owning each body
+	owned := make([][]int, n)
+	boxes := make([][2]Vec2, n)
+	domain := make([]int, len(bodies))
+	for i := range owned {
+		owned[i] = ids[i*len(ids)/n : (i+1)*len(ids)/n]
+		sort.Ints(owned[i])
+		boxes[i] = [2]Vec2{{X: math.Inf(1), Y: math.Inf(1)}, {X: math.Inf(-1), Y: math.Inf(-1)}}
+		for _, id := range owned[i] {
+			domain[id] = i
+			pos := bodies[id].Pos
+			boxes[i][0] = Vec2{X: math.Min(boxes[i][0].X, pos.X), Y: math.Min(boxes[i][0].Y, pos.Y)}
+			boxes[i][1] = Vec2{X: math.Max(boxes[i][1].X, pos.X), Y: math.Max(boxes[i][1].Y, pos.Y)}
+		}
+	}
+
+	domains := make([]Domain, n)
+	for i := range domains {
+		d := Domain{Owned: owned[i]}
+		// The bodies of each other domain too far away to be ghosts, added together
+		rest := make([]Body, n)
+		for id, b := range bodies {
+			if b == nil {
+				continue
+			}
+			if domain[id] != i && ghostDistance > 0 && boxDistance(b.Pos, boxes[i]) > ghostDistance {
+				r := &rest[domain[id]]
+				r.Pos = r.Pos.Add(b.Pos.Scale(b.Mass))
+				r.Mass += b.Mass
+				continue
+			}
+			if domain[id] == i {
+				d.Targets = append(d.Targets, len(d.Sources))
+			}
+			d.Sources = append(d.Sources, &Body{Pos: b.Pos, Mass: b.Mass})
+		}
+		for _, r := range rest {
+			if r.Mass > 0 {
+				d.Sources = append(d.Sources, &Body{Pos: r.Pos.Scale(1 / r.Mass), Mass: r.Mass})
+			}
+		}
+		domains[i] = d
+	}
+	return domains
+}
+
+// The distance from a position to the nearest point of a box (given by its corners), 0 if the position is inside it
+func boxDistance(pos Vec2, box [2]Vec2) float64 {
+	dx := math.Max(0, math.Max(box[0].X-pos.X, pos.X-box[1].X))
+	dy := math.Max(0, math.Max(box[0].Y-pos.Y, pos.Y-box[1].Y))
+	return math.Hypot(dx, dy)
+}
@@ -0,0 +1,89 @@
+package simulation
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Bodies scattered over a disk, with a few removed
+func scatteredBodies(n int) []*Body {
+	rng := rand.New(rand.NewSource(1))
+	bodies := make([]*Body, n)
+	for i := range bodies {
+		if i%7 == 3 {
+			continue
+		}
+		bodies[i] = &Body{Pos: Vec2{X: rng.Float64()*2000 - 1000, Y: rng.Float64()*2000 - 1000}, Mass: 1 + rng.Float64()*10}
+	}
+	return bodies
+}
+
+// The accelerations found from every domain, indexed by id like the bodies
+func domainAccelerations(t *testing.T, bodies []*Body, domains []Domain, p Params) []Vec2 {
+	t.Helper()
+	acc := make([]Vec2, len(bodies))
+	seen := make([]bool, len(bodies))
+	for _, d := range domains {
+		for i, a := range d.Accelerations(p) {
+			id := d.Owned[i]
+			if seen[id] {
+				t.Fatalf("body %v is owned by more than one domain", id)
+			}
+			seen[id] = true
+			acc[id] = a
+		}
+	}
+	for id, b := range bodies {
+		if b != nil && !seen[id] {
+			t.Fatalf("body %v is owned by no domain", id)
+		}
+		if b == nil && seen[id] {
+			t.Fatalf("removed body %v is owned by a domain", id)
+		}
+	}
+	return acc
+}
+
+// With every other body a ghost, the domains find exactly the same accelerations as Gravity
+func TestDecomposeWithoutApproximation(t *testing.T) {
+	bodies := scatteredBodies(200)
+	p := Params{G: 100, Softening: 1}
+	want := Gravity{G: p.G, Softening: p.Softening}.Accelerations(bodies)
+	for _, n := range []int{1, 3, 8, 1000} {
+		domains := Decompose(bodies, n, 0)
+		if len(domains) > n {
+			t.Errorf("%v domains: made %v domains", n, len(domains))
+		}
+		got := domainAccelerations(t, bodies, domains, p)
+		for id := range bodies {
+			if got[id] != want[id] {
+				t.Errorf("%v domains: body %v has acceleration %v, want %v", n, id, got[id], want[id])
+			}
+		}
+	}
+}
+
+// With a ghost distance, distant bodies pull as one body at their center of mass, which is close to their pull as separate bodies
+func TestDecomposeWithGhostDistance(t *testing.T) {
+	bodies := scatteredBodies(200)
+	p := Params{G: 100, Softening: 1}
+	want := Gravity{G: p.G, Softening: p.Softening}.Accelerations(bodies)
+	domains := Decompose(bodies, 4, 300)
+	ghosts := 0
+	for _, d := range domains {
+		ghosts += len(d.Sources) - len(d.Owned)
+	}
+	if ghosts >= 3*len(bodies) {
+		t.Errorf("every body was sent to every domain (%v ghosts), when distant domains should be added together", ghosts)
+	}
+	got := domainAccelerations(t, bodies, domains, p)
+	var errorSum, sum float64
+	for id := range bodies {
+		errorSum += got[id].Sub(want[id]).Norm()
+		sum += want[id].Norm()
+	}
+	if relative := errorSum / sum; relative > 0.05 || math.IsNaN(relative) {
+		t.Errorf("the accelerations are %.1f%% out on average, want under 5%%", 100*relative)
+	}
+}
@@ -0,0 +1,92 @@
+package simulation
+
+import "math"
+
+// Callbacks can be registered on a simulation to be told when things happen to it,
+// so features such as logging, sound or metrics can be added without changing the core loop
+// Callbacks are run in the order they were registered, as part of the call that caused the event (e.g. Step)
+
+// Two bodies touching during a step, as passed to OnCollision callbacks
+// Bodies only collide in the merge and bounce collision modes, in the pass mode they go straight through each other
+type Collision struct {
+	// The indices of the two bodies
+	A int
+	B int
+	// Whether the bodies merged, in which case B was merged into A (and removed), rather than bouncing off each other
+	Merged bool
+	// Copies of the two bodies as they were when they collided: for bounces at the start of the step,
+	// and for merges after the step's update, just before B was merged into A
+	BodyA Body
+	BodyB Body
+}
+
+// The callbacks registered on a simulation
+type hooks struct {
+	step      []func(s *Simulation)
+	collision []func(s *Simulation, c Collision)
+	removed   []func(s *Simulation, id int, b *Body)
+	save      []func(s *Simulation)
+}
+
+// Call f after every step
+func (s *Simulation) OnStep(f func(s *Simulation)) {
+	s.hooks.step = append(s.hooks.step, f)
+}
+
+// Call f whenever two bodies collide
+// Finding bouncing collisions means checking every pair of bodies, so this makes steps in the bounce mode slower
+func (s *Simulation) OnCollision(f func(s *Simulation, c Collision)) {
+	s.hooks.collision = append(s.hooks.collision, f)
+}
+
+// Call f whenever a body is removed from the simulation, either by merging into another body or with RemoveBody
+// b is the body as it was just before it was removed
+func (s *Simulation) OnBodyRemoved(f func(s *Simulation, id int, b *Body)) {
+	s.hooks.removed = append(s.hooks.removed, f)
+}
+
+// Call f whenever the simulation is saved
+func (s *Simulation) OnSave(f func(s *Simulation)) {
+	s.hooks.save = append(s.hooks.save, f)
+}
+
+// Run the OnSave callbacks for a save written without Save, e.g. in another file format
+func (s *Simulation) NotifySaved() {
+	for _, f := range s.hooks.save {
+		f(s)
+	}
+}
+
+// Run the OnBodyRemoved callbacks for a body that has been removed
+func (s *Simulation) notifyRemoved(id int, b *Body) {
+	for _, f := range s.hooks.removed {
+		f(s, id, b)
+	}
+}
+
+// Whether two bodies are touching, the same way Update decides
+func touching(a, b *Body) bool {
+	distSquared := DistSquared(a, b)
+	return distSquared >= 1 && distSquared < math.Pow(a.Radius+b.Radius, 2)
+}
+
+// Find the pairs of bodies that will bounce off each other this step, i.e. touching bodies moving together
+func findBounces(bodies []*Body) []Collision {
+	var collisions []Collision
+	for i, a := range bodies {
+		if a == nil {
+			continue
+		}
+		for j := i + 1; j < len(bodies); j++ {
+			b := bodies[j]
+			if b == nil || !touching(a, b) {
+				continue
+			}
+			closing := a.Vel.Sub(b.Vel).Dot(a.Pos.Sub(b.Pos))
+			if closing < 0 {
+				collisions = append(collisions, Collision{A: i, B: j, BodyA: *a, BodyB: *b})
+			}
+		}
+	}
+	return collisions
+}
@@ -0,0 +1,84 @@
+package simulation
+
+import "math"
+
+// A ForceProvider is one of the forces acting on the bodies of a simulation, e.g. the gravity between them, or drag
+// Any number of forces can act on a simulation, and the acceleration of each body is the sum of the accelerations from all of them
+type ForceProvider interface {
+	// The acceleration of each of the bodies (so the result is indexed the same as bodies)
+	// The accelerations of nil (removed) bodies are ignored
+	Accelerations(bodies []*Body) []Vec2
+}
+
+// A function used as a ForceProvider, so custom forces can be written without declaring a type
+type ForceFunc func(bodies []*Body) []Vec2
+
+func (f ForceFunc) Accelerations(bodies []*Body) []Vec2 {
+	return f(bodies)
+}
+
+// The pairwise gravity between all of the bodies, the force every simulation has
+type Gravity struct {
+	// The gravitational constant
+	G float64
+	// The softening length, which stops the force between bodies blowing up as they get close
+	Softening float64
+}
+
+func (g Gravity) Accelerations(bodies []*Body) []Vec2 {
+	p := Params{G: g.G, Softening: g.Softening}
+	acc := make([]Vec2, len(bodies))
+	for i, b := range bodies {
+		if b != nil {
+			acc[i] = Acceleration(b, bodies, p)
+		}
+	}
+	return acc
+}
+
+// The gravity of a fixed point mass that is not one of the bodies, e.g. to stand in for the center of a galaxy
+type CentralPotential struct {
+	// The position of the mass
+	Center Vec2
+	// The mass, in the same units as the masses of the bodies
+	Mass float64
+	// The gravitational constant
+	G float64
+	// The softening length, which stops the force blowing up as a body gets close to the center
+	Softening float64
+}
+
+func (c CentralPotential) Accelerations(bodies []*Body) []Vec2 {
+	acc := make([]Vec2, len(bodies))
+	for i, b := range bodies {
+		if b == nil {
+			continue
+		}
+		separation := b.Pos.Sub(c.Center)
+		distSquared := separation.Dot(separation)
+		if distSquared < 1 {
+			continue
+		}
+		// The same force as between two bodies, pointing towards the center
+		magnitude := -1 * c.G * c.Mass / (distSquared + c.Softening*c.Softening)
+		acc[i] = separation.Scale(magnitude / math.Sqrt(distSquared))
+	}
+	return acc
+}
+
+// A drag force opposing the motion of every body, proportional to its velocity
+// Drag slowly takes energy out of the simulation, so orbits decay and bodies settle into clumps
+type Drag struct {
+	// The acceleration of a body is -Coefficient times its velocity
+	Coefficient float64
+}
+
+func (d Drag) Accelerations(bodies []*Body) []Vec2 {
+	acc := make([]Vec2, len(bodies))
+	for i, b := range bodies {
+		if b != nil {
+			acc[i] = b.Vel.Scale(-d.Coefficient)
+		}
+	}
+	return acc
+}
@@ -0,0 +1,151 @@
+package simulation
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The golden tests run known systems for a fixed number of steps and compare the trajectories against references in testdata,
+// so any change to the physics that moves the bodies (even slightly) is caught
+// After an intended change to the physics, check the new trajectories are still right then update the references with
+//
+//	go test ./simulation -run Golden -update
+var update = flag.Bool("update", false, "Update the golden trajectories in testdata instead of comparing against them")
+
+const (
+	// The number of steps to run each system for, and how often to record the bodies
+	GOLDENSTEPS  = 4000
+	GOLDENRECORD = 100
+	// How far a position or velocity may be from the reference, allowing for floating point differences between platforms
+	GOLDENTOLERANCE = 1e-6
+)
+
+// Two equal masses in a circular orbit about their center of mass
+func twoBodyCircular() *Simulation {
+	s := New(Params{G: 1, Timescale: 0.05, CollisionMode: COLLISIONPASS}, 1)
+	const mass, separation = 100.0, 100.0
+	// Each body orbits at half the separation, pulled by the other body at the full separation
+	speed := math.Sqrt(mass / (2 * separation))
+	s.AddBody(&Body{Pos: Vec2{X: -separation / 2}, Vel: Vec2{Y: -speed}, Mass: mass, Radius: 1})
+	s.AddBody(&Body{Pos: Vec2{X: separation / 2}, Vel: Vec2{Y: speed}, Mass: mass, Radius: 1})
+	return s
+}
+
+// The figure-eight choreography of three equal masses (Chenciner and Montgomery, 2000)
+// The usual initial conditions (with G = 1 and unit masses) are scaled up 100 times in length and mass, leaving the velocities unchanged,
+// so the bodies never come within the unit distance at which the gravity between them is ignored
+func figureEight() *Simulation {
+	s := New(Params{G: 1, Timescale: 0.05, CollisionMode: COLLISIONPASS}, 1)
+	const scale = 100.0
+	x, y := 0.97000436*scale, -0.24308753*scale
+	xVel, yVel := 0.93240737, 0.86473146
+	s.AddBody(&Body{Pos: Vec2{X: x, Y: y}, Vel: Vec2{X: xVel / 2, Y: yVel / 2}, Mass: scale, Radius: 1})
+	s.AddBody(&Body{Pos: Vec2{X: -x, Y: -y}, Vel: Vec2{X: xVel / 2, Y: yVel / 2}, Mass: scale, Radius: 1})
+	s.AddBody(&Body{Vel: Vec2{X: -xVel, Y: -yVel}, Mass: scale, Radius: 1})
+	return s
+}
+
+// Run a simulation, recording every body every GOLDENRECORD steps as rows of step, id, x, y, xVel, yVel
+func runGolden(s *Simulation) [][]float64 {
+	var rows [][]float64
+	for step := 0; step <= GOLDENSTEPS; step++ {
+		if step%GOLDENRECORD == 0 {
+			for id, b := range s.Bodies() {
+				if b != nil {
+					rows = append(rows, []float64{float64(step), float64(id), b.Pos.X, b.Pos.Y, b.Vel.X, b.Vel.Y})
+				}
+			}
+		}
+		s.Step()
+	}
+	return rows
+}
+
+func writeGolden(path string, rows [][]float64) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "#step, id, x, y, xVel, yVel")
+	for _, row := range rows {
+		fields := make([]string, len(row))
+		for i, v := range row {
+			fields[i] = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		fmt.Fprintln(w, strings.Join(fields, ","))
+	}
+	return w.Flush()
+}
+
+func readGolden(path string) ([][]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var rows [][]float64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "#") {
+			continue
+		}
+		var row []float64
+		for _, field := range strings.Split(scanner.Text(), ",") {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, v)
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+func TestGolden(t *testing.T) {
+	systems := []struct {
+		name string
+		new  func() *Simulation
+	}{
+		{"two_body_circular", twoBodyCircular},
+		{"figure_eight", figureEight},
+	}
+	columns := []string{"step", "id", "x", "y", "xVel", "yVel"}
+
+	for _, system := range systems {
+		t.Run(system.name, func(t *testing.T) {
+			path := filepath.Join("testdata", system.name+".csv")
+			got := runGolden(system.new())
+			if *update {
+				if err := writeGolden(path, got); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := readGolden(path)
+			if err != nil {
+				t.Fatalf("cannot read the reference trajectory (run with -update to create it): %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("got %v rows, want %v", len(got), len(want))
+			}
+			for i := range want {
+				for j := range want[i] {
+					if math.Abs(got[i][j]-want[i][j]) > GOLDENTOLERANCE {
+						t.Fatalf("step %v, body %v: %v = %v, want %v", want[i][0], want[i][1], columns[j], got[i][j], want[i][j])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+// Pluggable forces (see ForceProvider) changed how each step is integrated: the forces are all found from the positions
+// at the start of the step, and bodies that merge keep the gravity of the step they merged in. These tests run the
+// original integration alongside and check the difference is the one intended: the original let the total momentum
+// drift, the current integration conserves it, and otherwise the trajectories only differ by the error of a step
+
+// The gravity of the original integration: each body was pulled from where it had moved to this step, but from where
+// every other body started it, and bodies that merged this step weren't pulled at all (though they still pulled the others)
+func originalGravity(s *Simulation) ForceFunc {
+	return func(bodies []*Body) []Vec2 {
+		acc := make([]Vec2, len(bodies))
+		for i, b := range bodies {
+			if b == nil || b.Fixed {
+				continue
+			}
+			merging := false
+			for j, other := range bodies {
+				if j != i && other != nil && s.CollisionMode == COLLISIONMERGE && touching(b, other) {
+					merging = true
+				}
+			}
+			if merging {
+				continue
+			}
+			moved := *b
+			moved.Pos = b.Pos.Add(b.Vel.Scale(s.Timescale))
+			acc[i] = Acceleration(&moved, bodies, s.Params)
+		}
+		return acc
+	}
+}
+
+// The largest change of the total momentum from the start over a run, relative to the scale of the momentum
+func momentumDrift(s *Simulation, steps int) float64 {
+	_, startX, startY := totals(s.Bodies())
+	scale := momentumScale(s.Bodies())
+	drift := 0.0
+	for step := 0; step < steps; step++ {
+		s.Step()
+		_, px, py := totals(s.Bodies())
+		drift = max(drift, math.Hypot(px-startX, py-startY)/scale)
+	}
+	return drift
+}
+
+// The original integration drifts the momentum of an orbiting system, the current integration doesn't
+func TestIntegrationConservesMomentumInOrbit(t *testing.T) {
+	original := figureEight()
+	original.SetGravity(originalGravity(original))
+	originalDrift := momentumDrift(original, GOLDENSTEPS)
+	current := figureEight()
+	currentDrift := momentumDrift(current, GOLDENSTEPS)
+	if currentDrift > CONSERVATIONTOLERANCE {
+		t.Errorf("the current integration drifted the momentum by %v", currentDrift)
+	}
+	if originalDrift < 1000*CONSERVATIONTOLERANCE {
+		t.Errorf("the original integration drifted the momentum by only %v, the difference isn't being tested", originalDrift)
+	}
+
+	// Apart from the drift, both follow the same orbits, to within the error of the steps
+	for i, b := range current.Bodies() {
+		if d := b.Pos.Dist(original.Bodies()[i].Pos); d > 1 {
+			t.Errorf("body %v ended %v apart in the two integrations, more than the error of the steps", i, d)
+		}
+	}
+}
+
+// Merges in the original integration left the merging bodies without the gravity of that step, while they still pulled
+// the others, so each merge changed the total momentum. The current integration keeps it through every merge
+func TestIntegrationConservesMomentumThroughMerges(t *testing.T) {
+	original := cluster(COLLISIONMERGE)
+	original.SetGravity(originalGravity(original))
+	originalDrift := momentumDrift(original, 1000)
+	current := cluster(COLLISIONMERGE)
+	currentDrift := momentumDrift(current, 1000)
+	if current.Merges == 0 {
+		t.Fatal("no bodies merged, the merges aren't being tested")
+	}
+	if currentDrift > CONSERVATIONTOLERANCE {
+		t.Errorf("the current integration drifted the momentum by %v through %v merges", currentDrift, current.Merges)
+	}
+	if originalDrift < 1000*CONSERVATIONTOLERANCE {
+		t.Errorf("the original integration drifted the momentum by only %v, the difference isn't being tested", originalDrift)
+	}
+}
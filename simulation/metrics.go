@@ -0,0 +1,54 @@
+package simulation
+
+import "time"
+
+// A simulation can emit metrics after every step to a Metrics sink, so it can be instrumented
+// (e.g. logged, scraped by Prometheus or written to a csv file, see the metrics package) without changing the physics
+// Counters only ever go up, by the delta given each step, while gauges are set to their current value
+
+// The names of the metrics emitted after each step
+const (
+	// Counters
+	METRICSTEPS      = "steps"
+	METRICCOLLISIONS = "collisions"
+	// Gauges
+	METRICTIME        = "time"
+	METRICSTEPSECONDS = "step_seconds"
+	METRICBODIES      = "bodies"
+	METRICENERGY      = "energy"
+)
+
+// Somewhere to send the metrics of a simulation
+// The methods are called from the goroutine stepping the simulation, with the simulation locked
+type Metrics interface {
+	// Add delta to the counter with the given name
+	Counter(name string, delta float64)
+	// Set the gauge with the given name to value
+	Gauge(name string, value float64)
+	// Called once all of the metrics of a step have been emitted, e.g. to write them out together
+	Flush()
+}
+
+// Emit the metrics of the simulation to m after every step, or stop emitting them if m is nil
+// The energy and the bouncing collisions are found over every pair of bodies, so this makes each step slower
+func (s *Simulation) SetMetrics(m Metrics) {
+	s.metrics = m
+}
+
+// Emit the metrics of the step just taken, which took elapsed and had the given number of collisions
+func (s *Simulation) emitMetrics(elapsed time.Duration, collisions int) {
+	m := s.metrics
+	m.Counter(METRICSTEPS, 1)
+	m.Counter(METRICCOLLISIONS, float64(collisions))
+	m.Gauge(METRICTIME, s.Time)
+	m.Gauge(METRICSTEPSECONDS, elapsed.Seconds())
+	bodies := 0
+	for _, b := range s.bodies {
+		if b != nil {
+			bodies++
+		}
+	}
+	m.Gauge(METRICBODIES, float64(bodies))
+	m.Gauge(METRICENERGY, KineticEnergy(s.bodies)+PotentialEnergy(s.bodies, s.G))
+	m.Flush()
+}
@@ -0,0 +1,160 @@
+package simulation
+
+import "math"
+
+// The orbits of the bodies around the most massive body (the primary) are measured from their apsides:
+// the closest (periapsis) and furthest (apoapsis) points from the primary, where the distance to it stops falling or rising
+// The period is the time between two periapses, and the eccentricity is found from the distances at the last two apsides
+// An orbit too close to circular for its distance to ever stop rising or falling is never measured
+
+// What has been measured of the orbit of one body around the primary
+type Orbit struct {
+	// The distances from the primary at the last periapsis and apoapsis, 0 until each has been passed
+	Periapsis float64
+	Apoapsis  float64
+	// The time between the last two periapses, 0 until a whole orbit has been seen
+	Period float64
+	// The eccentricity of the orbit, 0 for a circle and approaching 1 as the orbit gets longer, found once both apsides have been passed
+	Eccentricity float64
+	// The number of whole orbits seen, i.e. the number of periapses after the first
+	Orbits int
+
+	// The speed away from the primary at the last update, and the distance from it
+	radialSpeed float64
+	distance    float64
+	// The time of the last periapsis, and how many periapses there have been
+	lastPeriapsis float64
+	periapses     int
+}
+
+// Whether the orbit has been seen all the way around, so its period and eccentricity are known
+func (o Orbit) Measured() bool {
+	return o.Orbits > 0 && o.Apoapsis > 0
+}
+
+// Tracks the orbits of the bodies of a simulation around the primary, updated after each step
+type OrbitTracker struct {
+	// The id of the primary, or -1 if there are no bodies
+	primary int
+	orbits  map[int]*Orbit
+	time    float64
+}
+
+// Start tracking orbits, with nothing measured yet
+func NewOrbitTracker() *OrbitTracker {
+	return &OrbitTracker{primary: -1, orbits: map[int]*Orbit{}}
+}
+
+// The id of the most massive body, or the first of the most massive if there is a tie, or -1 if there are no bodies
+func Primary(bodies []*Body) int {
+	primary := -1
+	for i, b := range bodies {
+		if b != nil && (primary < 0 || b.Mass > bodies[primary].Mass) {
+			primary = i
+		}
+	}
+	return primary
+}
+
+// The distance of b from center, and the speed it is moving away from center at (negative if it is getting closer)
+// If b is at the center, it has no direction away from it, so the speed is 0
+func Radial(b, center *Body) (float64, float64) {
+	offset := b.Pos.Sub(center.Pos)
+	distance := offset.Norm()
+	if distance == 0 {
+		return 0, 0
+	}
+	return distance, b.Vel.Sub(center.Vel).Dot(offset) / distance
+}
+
+// The eccentricity of the orbit b would follow around center if they were the only two bodies (its osculating orbit),
+// and whether that orbit is bound, i.e. an ellipse that b would follow back around rather than escape along
+// Unbound orbits are parabolas and hyperbolas, with eccentricities of 1 or more
+// If b is at the center, or neither has any mass, there is no orbit, so the eccentricity is 0 and it is unbound
+func OsculatingEccentricity(b, center *Body, G float64) (float64, bool) {
+	offset := b.Pos.Sub(center.Pos)
+	vel := b.Vel.Sub(center.Vel)
+	distance := offset.Norm()
+	mu := G * (b.Mass + center.Mass)
+	if distance == 0 || mu <= 0 {
+		return 0, false
+	}
+	energy := vel.Dot(vel)/2 - mu/distance
+	angularMomentum := offset.X*vel.Y - offset.Y*vel.X
+	// Rounding can take a circular orbit just below 0
+	return math.Sqrt(math.Max(0, 1+2*energy*angularMomentum*angularMomentum/(mu*mu))), energy < 0
+}
+
+// The id of the body every orbit is measured around, or -1 if there are no bodies
+func (t *OrbitTracker) Primary() int {
+	return t.primary
+}
+
+// The orbit measured so far for a body, and whether the body is being tracked at all
+func (t *OrbitTracker) Orbit(id int) (Orbit, bool) {
+	o, ok := t.orbits[id]
+	if !ok {
+		return Orbit{}, false
+	}
+	return *o, true
+}
+
+// Update the orbits with the bodies at the given simulated time, returning the ids of the bodies that have just completed an orbit
+// If the primary changes (e.g. it was outgrown by a merge) or time goes backwards (e.g. an undo) every orbit is measured again from scratch
+func (t *OrbitTracker) Update(bodies []*Body, time float64) []int {
+	dt := time - t.time
+	t.time = time
+
+	primary := Primary(bodies)
+	if primary != t.primary || dt < 0 {
+		t.primary = primary
+		clear(t.orbits)
+	}
+	if primary < 0 {
+		return nil
+	}
+	p := bodies[primary]
+
+	var completed []int
+	// Bodies that have been removed have no orbit left to measure
+	for id := range t.orbits {
+		if id >= len(bodies) || bodies[id] == nil {
+			delete(t.orbits, id)
+		}
+	}
+	for id, b := range bodies {
+		if b == nil || id == primary {
+			continue
+		}
+		distance, radialSpeed := Radial(b, p)
+		if distance == 0 {
+			continue
+		}
+
+		o, ok := t.orbits[id]
+		if !ok {
+			t.orbits[id] = &Orbit{radialSpeed: radialSpeed, distance: distance}
+			continue
+		}
+		// The apsis was passed when the radial speed changed sign, between the last update and this one
+		// Its time is found by assuming the radial speed changed steadily, and its distance is the nearer or further of the two distances
+		if o.radialSpeed < 0 && radialSpeed >= 0 {
+			crossed := time - dt*radialSpeed/(radialSpeed-o.radialSpeed)
+			o.Periapsis = math.Min(o.distance, distance)
+			if o.periapses > 0 {
+				o.Period = crossed - o.lastPeriapsis
+				o.Orbits++
+				completed = append(completed, id)
+			}
+			o.lastPeriapsis = crossed
+			o.periapses++
+		} else if o.radialSpeed > 0 && radialSpeed <= 0 {
+			o.Apoapsis = math.Max(o.distance, distance)
+		}
+		if o.Periapsis > 0 && o.Apoapsis > 0 {
+			o.Eccentricity = (o.Apoapsis - o.Periapsis) / (o.Apoapsis + o.Periapsis)
+		}
+		o.radialSpeed, o.distance = radialSpeed, distance
+	}
+	return completed
+}
@@ -0,0 +1,93 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+// A planet started at the apoapsis of an orbit around a fixed star should have that orbit measured,
+// with the period given by Kepler's third law
+// Its first periapsis is half an orbit in, so in the time of three orbits it completes two
+func TestOrbitTracker(t *testing.T) {
+	const (
+		G            = 100
+		starMass     = 1000
+		apoapsis     = 300.0
+		eccentricity = 0.5
+	)
+	periapsis := apoapsis * (1 - eccentricity) / (1 + eccentricity)
+	semiMajorAxis := (apoapsis + periapsis) / 2
+	// The vis-viva equation gives the speed at any distance along the orbit
+	speed := math.Sqrt(G * starMass * (2/apoapsis - 1/semiMajorAxis))
+	period := 2 * math.Pi * math.Sqrt(semiMajorAxis*semiMajorAxis*semiMajorAxis/(G*starMass))
+
+	s := New(Params{G: G, Timescale: 0.001}, 1)
+	s.AddBody(&Body{Mass: starMass, Radius: 1, Fixed: true})
+	planet := s.AddBody(&Body{Pos: Vec2{X: apoapsis}, Vel: Vec2{Y: speed}, Mass: 1, Radius: 1})
+
+	tracker := NewOrbitTracker()
+	completed := 0
+	s.OnStep(func(s *Simulation) {
+		for _, id := range tracker.Update(s.Bodies(), s.Time) {
+			if id != planet {
+				t.Fatalf("body %v completed an orbit, but only the planet is orbiting", id)
+			}
+			completed++
+		}
+	})
+	for s.Time < 3*period {
+		s.Step()
+	}
+
+	if tracker.Primary() != 0 {
+		t.Errorf("the primary is body %v, want the star", tracker.Primary())
+	}
+	o, ok := tracker.Orbit(planet)
+	if !ok || !o.Measured() {
+		t.Fatalf("the orbit was not measured: %+v", o)
+	}
+	if completed != 2 || o.Orbits != 2 {
+		t.Errorf("completed %v (and counted %v) orbits, want 2", completed, o.Orbits)
+	}
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"period", o.Period, period},
+		{"eccentricity", o.Eccentricity, eccentricity},
+		{"periapsis", o.Periapsis, periapsis},
+		{"apoapsis", o.Apoapsis, apoapsis},
+	} {
+		if math.Abs(c.got-c.want) > 0.01*c.want {
+			t.Errorf("the %v is %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+// A planet at the apoapsis of an orbit has the eccentricity of that orbit, and one moving fast enough to escape is unbound
+func TestOsculatingEccentricity(t *testing.T) {
+	const (
+		G        = 100
+		starMass = 1000
+		apoapsis = 300.0
+	)
+	star := &Body{Pos: Vec2{X: 50, Y: -20}, Vel: Vec2{X: 3, Y: 1}, Mass: starMass}
+	for _, eccentricity := range []float64{0, 0.5, 0.9} {
+		semiMajorAxis := apoapsis / (1 + eccentricity)
+		speed := math.Sqrt(G * starMass * (2/apoapsis - 1/semiMajorAxis))
+		planet := &Body{Pos: star.Pos.Add(Vec2{Y: apoapsis}), Vel: star.Vel.Add(Vec2{X: speed}), Mass: 1e-9}
+		e, bound := OsculatingEccentricity(planet, star, G)
+		if !bound || math.Abs(e-eccentricity) > 1e-6 {
+			t.Errorf("an orbit of eccentricity %v was measured as %v (bound: %v)", eccentricity, e, bound)
+		}
+	}
+
+	escapeSpeed := math.Sqrt(2 * G * starMass / apoapsis)
+	planet := &Body{Pos: star.Pos.Add(Vec2{Y: apoapsis}), Vel: star.Vel.Add(Vec2{X: 1.1 * escapeSpeed}), Mass: 1e-9}
+	if e, bound := OsculatingEccentricity(planet, star, G); bound || e <= 1 {
+		t.Errorf("a planet faster than the escape speed has eccentricity %v (bound: %v), want unbound above 1", e, bound)
+	}
+	if e, bound := OsculatingEccentricity(star, star, G); bound || e != 0 {
+		t.Errorf("a body has eccentricity %v (bound: %v) around itself, want 0 and unbound", e, bound)
+	}
+}
@@ -0,0 +1,171 @@
+package simulation
+
+import "math"
+
+// Associated method to update this body, given all the bodies in the simulation (including itself)
+// and the acceleration of this body from all of the forces acting on it (see ForceProvider)
+//
+// This method handles updating a bodies x,y coordinates based on velocity, and the x,y velocities based on the acceleration
+// This simulation uses very crude particle models with simple discrete timesteps. If these timesteps are small enough the simulation is roughly accurate.
+// In the bounce collision mode, touching bodies also bounce off each other elastically
+// Merging touching bodies (the default collision mode) involves every body in the collision at once, so is done by Step instead
+//
+// To aide in memory management, two arrays of bodies are used (and swapped at each frame). Therefore, this method has to return a *body to be placed into the next array
+func (b *Body) Update(bodies []*Body, acc Vec2, p Params) *Body {
+	// If a body is nil, it has already been consumed
+	if b == nil {
+		return nil
+	}
+
+	newBody := *b
+
+	// Fixed bodies stay where they are, whatever pulls on them
+	if b.Fixed {
+		return &newBody
+	}
+	newBody.Pos = newBody.Pos.Add(newBody.Vel.Scale(p.Timescale))
+
+	if p.CollisionMode == COLLISIONBOUNCE {
+		for _, other := range bodies {
+			if other == nil || !touching(b, other) {
+				continue
+			}
+			// Bounce off each other elastically, but only if still moving together so touching bodies don't stick
+			// Each body works out its own half of the collision, which conserves momentum and energy between them
+			separation := b.Pos.Sub(other.Pos)
+			closing := b.Vel.Sub(other.Vel).Dot(separation)
+			if closing < 0 {
+				// A fixed body can't be pushed, so acts as if infinitely massive
+				share := 2 * other.Mass / (b.Mass + other.Mass)
+				if other.Fixed {
+					share = 2
+				}
+				impulse := share * closing / DistSquared(b, other)
+				newBody.Vel = newBody.Vel.Sub(separation.Scale(impulse))
+			}
+		}
+	}
+
+	newBody.Vel = newBody.Vel.Add(acc.Scale(p.Timescale))
+	return &newBody
+}
+
+// Update every body in current, putting the results in next, which must be the same length
+// The acceleration of each body is the sum of the accelerations from all of the forces
+// The forces are all found from the positions at the start of the step, before any body moves. Gravity used to pull each
+// body from where it had moved to, but from where every other body started, so the pull between two bodies wasn't quite
+// equal and opposite and momentum drifted. From the start of the step, momentum is conserved to rounding error
+// To avoid memory being allocated and collected each step, callers keep both arrays and swap them after each step
+//
+// In the merge collision mode, the bodies touching at the start of the step are merged, and the merges are returned
+// (as collisions of the body kept with each body merged into it)
+func Step(current, next []*Body, p Params, forces []ForceProvider) []Collision {
+	acc := make([]Vec2, len(current))
+	for _, f := range forces {
+		for i, a := range f.Accelerations(current) {
+			acc[i] = acc[i].Add(a)
+		}
+	}
+	for i, body := range current {
+		next[i] = body.Update(current, acc[i], p)
+	}
+	if p.CollisionMode != COLLISIONMERGE {
+		return nil
+	}
+	return mergeTouching(current, next)
+}
+
+// Merge every group of touching bodies in current into one body, replacing their updates in next
+// Bodies touching each other in a chain all merge together, so no mass or momentum is lost however many bodies collide at once
+// Collisions are modelled as inelastic - the colliding bodies have their masses added together, velocities set to the solution of the conservation of momentum equations, and coordinates placed at the center of mass
+// The merged body keeps the place (and name, color and density) of the most massive body, or the first of the most massive if there is a tie
+// Unless one of the bodies is fixed, in which case it absorbs the others without moving any differently
+func mergeTouching(current, next []*Body) []Collision {
+	// Find the groups of touching bodies, where group[i] is the index of a body in the same group as i (or i itself)
+	group := make([]int, len(current))
+	for i := range group {
+		group[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if group[i] != i {
+			group[i] = find(group[i])
+		}
+		return group[i]
+	}
+	merging := false
+	for i, a := range current {
+		if a == nil {
+			continue
+		}
+		for j := i + 1; j < len(current); j++ {
+			if current[j] != nil && touching(a, current[j]) {
+				group[find(j)] = find(i)
+				merging = true
+			}
+		}
+	}
+	if !merging {
+		return nil
+	}
+
+	// The body kept from each group
+	kept := map[int]int{}
+	for i, b := range current {
+		if b == nil {
+			continue
+		}
+		root := find(i)
+		k, ok := kept[root]
+		if !ok || KeptInMerge(b, current[k]) {
+			kept[root] = i
+		}
+	}
+
+	// The bodies are merged as they are after this step's update, so their pull on each other (and the other bodies) this step is kept
+	// Bodies used to merge without the gravity of the step they touched, but the other bodies were still pulled by them,
+	// so each merge changed the total momentum
+	var merges []Collision
+	for i := range current {
+		if current[i] == nil {
+			continue
+		}
+		k := kept[find(i)]
+		if k == i {
+			continue
+		}
+		// Larger mass gets added to
+		m, b := next[k], next[i]
+		merge := Collision{A: k, B: i, Merged: true, BodyA: *m, BodyB: *b}
+		m.Merge(i, b)
+		next[i] = nil
+		merges = append(merges, merge)
+	}
+	return merges
+}
+
+// The acceleration of a body due to the gravity of the given bodies
+// The force between bodies is softened by the softening length, so close encounters don't fling bodies apart
+// Note this is rather inefficient - it is O(n) for each body and therefore O(n^2) over all bodies
+// This could be improved by:
+//   - Noticing that the effect of a->b is the exact opposite of b->a, halving the number of calculations to be done (reducing work by a factor of 2, but still O(n^2))
+//   - Using a different method of calculating force (e.g. a quadtree) which reduces calculations for each body from O(n) to O(log(n)) roughly
+//
+// These have not been implemented because this is a proof of concept and a toy model only - but the options are open in future!
+func Acceleration(b *Body, bodies []*Body, p Params) Vec2 {
+	var total Vec2
+	for _, other := range bodies {
+		if other == nil {
+			continue
+		}
+		currDistSquared := DistSquared(b, other)
+		if currDistSquared < 1 {
+			continue
+		}
+		// The direction from the other body to this one, so a negative magnitude pulls towards the other body
+		acc_magnitude := -1 * p.G * other.Mass / (currDistSquared + p.Softening*p.Softening)
+		direction := b.Pos.Sub(other.Pos).Scale(1 / math.Sqrt(currDistSquared))
+		total = total.Add(direction.Scale(acc_magnitude))
+	}
+	return total
+}
@@ -0,0 +1,72 @@
+package simulation
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+)
+
+// A source of random numbers whose entire state is a single number (the splitmix64 generator)
+// math/rand's default source has no way to read or restore its state, so this is used instead
+// to let saved simulations continue with exactly the same random numbers as the uninterrupted run
+type RandomSource struct {
+	State uint64
+}
+
+func (s *RandomSource) Seed(seed int64) {
+	s.State = uint64(seed)
+}
+
+func (s *RandomSource) Uint64() uint64 {
+	s.State += 0x9e3779b97f4a7c15
+	z := s.State
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *RandomSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// A random color for a body
+func RandomColor(rng *rand.Rand) color.RGBA {
+	return color.RGBA{uint8(rng.Intn(255)), uint8(rng.Intn(255)), uint8(rng.Intn(255)), 255}
+}
+
+// The limits on the parameters of randomly generated bodies
+type RandomOptions struct {
+	// The range of masses
+	MassMin float64
+	MassMax float64
+	// The width of the range of each velocity component, centered on zero
+	VelocityRange float64
+	// If positive, bodies are spawned uniformly in a disk of this radius about the origin
+	SpawnRadius float64
+	// Otherwise, bodies are spawned in a rectangle of this size centered on the origin (e.g. the starting view of the screen)
+	Width  float64
+	Height float64
+}
+
+// Create a new body with totally random parameters
+// Notice some limits are placed on parameter values (e.g. a max speed and mass), set by the options
+func NewRandomBody(rng *rand.Rand, opts RandomOptions) *Body {
+	mass := opts.MassMin + rng.Float64()*(opts.MassMax-opts.MassMin)
+	b := &Body{
+		Pos:    Vec2{X: rng.Float64()*opts.Width - opts.Width/2, Y: rng.Float64()*opts.Height - opts.Height/2},
+		Vel:    Vec2{X: rng.Float64()*opts.VelocityRange - opts.VelocityRange/2, Y: rng.Float64()*opts.VelocityRange - opts.VelocityRange/2},
+		Mass:   mass,
+		Radius: MassToRadius(mass),
+		Color:  RandomColor(rng),
+	}
+
+	// If a spawn radius is given, spawn uniformly in a disk about the origin rather than over the screen
+	// The square root makes the density uniform, rather than bunched up at the center
+	if opts.SpawnRadius > 0 {
+		r := opts.SpawnRadius * math.Sqrt(rng.Float64())
+		angle := rng.Float64() * 2 * math.Pi
+		b.Pos.X = r * math.Cos(angle)
+		b.Pos.Y = r * math.Sin(angle)
+	}
+	return b
+}
@@ -0,0 +1,53 @@
+package simulation
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// The random tests check that everything random comes from the generator a simulation is given,
+// so simulations given the same generator (or seed) create exactly the same bodies
+
+// The options random bodies are created with in the tests
+var testRandomOptions = RandomOptions{MassMin: 1, MassMax: 11, VelocityRange: 1, Width: 1200, Height: 800}
+
+// Create n random bodies in sim
+func addRandomBodies(sim *Simulation, n int) {
+	for i := 0; i < n; i++ {
+		sim.AddBody(NewRandomBody(sim.Rand, testRandomOptions))
+	}
+}
+
+// Check two sets of bodies are exactly the same
+func checkSameBodies(t *testing.T, a, b []*Body) {
+	t.Helper()
+	if len(a) != len(b) {
+		t.Fatalf("have %v and %v bodies", len(a), len(b))
+	}
+	for i := range a {
+		if !reflect.DeepEqual(a[i], b[i]) {
+			t.Errorf("body %v differs: %+v and %+v", i, *a[i], *b[i])
+		}
+	}
+}
+
+func TestSameSeedSameBodies(t *testing.T) {
+	a, b := New(Params{}, 42), New(Params{}, 42)
+	addRandomBodies(a, 10)
+	// Using the global generator in between must not change anything
+	rand.Float64()
+	addRandomBodies(b, 10)
+	checkSameBodies(t, a.Bodies(), b.Bodies())
+}
+
+func TestSuppliedGenerator(t *testing.T) {
+	a := NewWithRand(Params{}, rand.New(rand.NewSource(7)))
+	b := NewWithRand(Params{}, rand.New(rand.NewSource(7)))
+	if a.RandomSource != nil {
+		t.Errorf("a supplied generator has no RandomSource to save, but got %v", a.RandomSource)
+	}
+	addRandomBodies(a, 10)
+	addRandomBodies(b, 10)
+	checkSameBodies(t, a.Bodies(), b.Bodies())
+}
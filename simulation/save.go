@@ -0,0 +1,50 @@
+package simulation
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// The csv save format, as written by Simulation.Save
+// Reading save files, including upgrading older versions of the format, is done by the persist package
+const (
+	SAVEVERSION       = 3
+	SAVEVERSIONPREFIX = "#version "
+	// The header written at the top of every save file
+	// When loading, the header is used to find each column by name, so columns may be in any order,
+	// optional columns may be left out, and extra columns are ignored
+	SAVEHEADER = "#x, y, xVel, yVel, mass, radius, red, green, blue, name, fixed"
+)
+
+// Write the bodies in the csv save format, including the version and header lines
+// Any comments (e.g. where the save came from) are written as comment lines after the header, so must not contain line breaks
+// Removed (nil) bodies are skipped
+func WriteSaveCSV(out io.Writer, bodies []*Body, comments ...string) error {
+	if _, err := fmt.Fprintf(out, "%v%v\n%v\n", SAVEVERSIONPREFIX, SAVEVERSION, SAVEHEADER); err != nil {
+		return err
+	}
+	for _, c := range comments {
+		if _, err := fmt.Fprintf(out, "#%v\n", c); err != nil {
+			return err
+		}
+	}
+	// Names may contain commas or quotes, so rows are written with the csv package to quote them correctly
+	w := csv.NewWriter(out)
+	for _, b := range bodies {
+		if b != nil {
+			w.Write(SaveRecord(b))
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// The fields of a body in the order of SAVEHEADER
+func SaveRecord(b *Body) []string {
+	return []string{
+		fmt.Sprint(b.Pos.X), fmt.Sprint(b.Pos.Y), fmt.Sprint(b.Vel.X), fmt.Sprint(b.Vel.Y), fmt.Sprint(b.Mass), fmt.Sprint(b.Radius),
+		fmt.Sprint(b.Color.R), fmt.Sprint(b.Color.G), fmt.Sprint(b.Color.B),
+		b.Name, fmt.Sprint(b.Fixed),
+	}
+}
@@ -0,0 +1,252 @@
+package simulation
+
+import (
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// A whole simulation: the bodies, the parameters they are updated with and the random numbers used to create them
+// Each Simulation is independent of any other, so several can be run side by side (or in tests)
+//
+// A simulation can be stepped in one goroutine while others (e.g. a renderer or a server) read and change it:
+// Step holds the simulation's lock for the whole step, including the callbacks, and Snapshot and Do take the same lock
+// Other goroutines must only use the simulation through Snapshot and Do, and everything else is left unlocked so that
+// callbacks and functions passed to Do can use the simulation freely, but must not call Step, Snapshot or Do themselves
+type Simulation struct {
+	// The global parameters every body is updated with, which may be changed between steps
+	Params
+	// The total simulated time, i.e. the sum of the timescale over all steps taken
+	Time float64
+	// The number of steps taken so far
+	Steps int
+	// The number of bodies merged into other bodies in collisions so far
+	Merges int
+	// The random number generator used for everything random in the simulation (random bodies, colors...)
+	// Nothing in the simulation uses the global math/rand functions, so simulations never share random numbers
+	Rand *rand.Rand
+	// The state of Rand, which can be saved and restored to continue with exactly the same random numbers
+	// This is nil if the simulation was given its own generator with NewWithRand
+	RandomSource *RandomSource
+
+	// List of bodies to store current frame and next frame
+	// This allows for consistent simulations (not changing bodies mid frame)
+	// We keep both so the garbage collector does not kill old arrays every frame
+	bodies []*Body
+	next   []*Body
+	// The forces acting on the bodies other than their gravity
+	forces []ForceProvider
+	// What finds the gravity between the bodies instead of Gravity, if anything, see SetGravity
+	gravity ForceProvider
+	// The callbacks registered with OnStep, OnCollision...
+	hooks hooks
+	// Where the metrics of each step are sent, nil if they aren't wanted
+	metrics Metrics
+	// Held while stepping, taking a snapshot or running Do
+	mu sync.Mutex
+}
+
+// A copy of the state of a simulation at one moment, as returned by Snapshot
+// The bodies are copies, so they can be read (e.g. drawn or sent over a network) while the simulation carries on stepping
+type Snapshot struct {
+	Params
+	Time  float64
+	Steps int
+	// Indexed by id like Simulation.Bodies, with removed bodies nil
+	Bodies []*Body
+}
+
+// Create an empty simulation with the given parameters, its random numbers seeded with seed
+func New(p Params, seed int64) *Simulation {
+	source := &RandomSource{}
+	s := NewWithRand(p, rand.New(source))
+	s.RandomSource = source
+	s.Rand.Seed(seed)
+	return s
+}
+
+// Create an empty simulation with the given parameters, using rng for all of its random numbers
+// This lets embedding code and tests supply their own source, e.g. a fixed sequence, or a generator per goroutine
+// The state of rng can't be read, so it isn't saved with the simulation (RandomSource is nil)
+func NewWithRand(p Params, rng *rand.Rand) *Simulation {
+	return &Simulation{Params: p, Rand: rng}
+}
+
+// Update every body by one step of the timescale
+func (s *Simulation) Step() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := time.Now()
+	var collisions []Collision
+	if (len(s.hooks.collision) > 0 || s.metrics != nil) && s.CollisionMode == COLLISIONBOUNCE {
+		collisions = findBounces(s.bodies)
+	}
+
+	merges := Step(s.bodies, s.next, s.Params, s.Forces())
+	// To avoid memory being allocated and collected each frame
+	// Simply swap the next (now calculated) array and current array
+	s.bodies, s.next = s.next, s.bodies
+
+	s.Time += s.Timescale
+	s.Steps++
+	s.Merges += len(merges)
+	if s.metrics != nil {
+		s.emitMetrics(time.Since(start), len(collisions)+len(merges))
+	}
+
+	// The bodies merged into other bodies are gone, as they were before the step
+	for _, c := range merges {
+		s.notifyRemoved(c.B, s.next[c.B])
+	}
+	collisions = append(collisions, merges...)
+	for _, c := range collisions {
+		for _, f := range s.hooks.collision {
+			f(s, c)
+		}
+	}
+	for _, f := range s.hooks.step {
+		f(s)
+	}
+}
+
+// Copy the current state of the simulation, waiting for any step in progress to finish
+func (s *Simulation) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bodies := make([]*Body, len(s.bodies))
+	for i, b := range s.bodies {
+		if b != nil {
+			copied := *b
+			bodies[i] = &copied
+		}
+	}
+	return Snapshot{Params: s.Params, Time: s.Time, Steps: s.Steps, Bodies: bodies}
+}
+
+// Make an independent copy of the simulation: its parameters, time, forces and copies of its bodies, but none of its callbacks or metrics
+// The copy continues with the same random numbers if the simulation's state can be saved (see RandomSource), and with a fixed seed otherwise
+// Like Step, this must not be called while another goroutine is stepping the simulation
+func (s *Simulation) Clone() *Simulation {
+	var c *Simulation
+	if s.RandomSource != nil {
+		source := *s.RandomSource
+		c = NewWithRand(s.Params, rand.New(&source))
+		c.RandomSource = &source
+	} else {
+		c = NewWithRand(s.Params, rand.New(rand.NewSource(1)))
+	}
+	c.Time, c.Steps, c.Merges = s.Time, s.Steps, s.Merges
+	bodies := make([]*Body, len(s.bodies))
+	for i, b := range s.bodies {
+		if b != nil {
+			copied := *b
+			bodies[i] = &copied
+		}
+	}
+	c.SetBodies(bodies)
+	c.forces = append([]ForceProvider(nil), s.forces...)
+	c.gravity = s.gravity
+	return c
+}
+
+// Run f with the simulation locked, so it can read or change the simulation (add bodies, change the timescale...)
+// from a different goroutine to the one stepping it, between steps
+func (s *Simulation) Do(f func(s *Simulation)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s)
+}
+
+// Add a force acting on the bodies, on top of their gravity and any other forces already added
+func (s *Simulation) AddForce(f ForceProvider) {
+	s.forces = append(s.forces, f)
+}
+
+// Find the gravity between the bodies with g instead of Gravity, e.g. to find it in parts on other machines
+// g should use the current parameters of the simulation, as Gravity does. With nil, Gravity is used again
+func (s *Simulation) SetGravity(g ForceProvider) {
+	s.gravity = g
+}
+
+// All of the forces acting on the bodies, starting with the gravity between them
+// The gravity is made from the current parameters, so changes to G or the softening length take effect at the next step
+func (s *Simulation) Forces() []ForceProvider {
+	gravity := s.gravity
+	if gravity == nil {
+		gravity = Gravity{G: s.G, Softening: s.Softening}
+	}
+	return append([]ForceProvider{gravity}, s.forces...)
+}
+
+// Add a new body to the simulation, returning its index
+// The index of a body never changes, so it can be used to find the body again later (until it is removed)
+func (s *Simulation) AddBody(b *Body) int {
+	s.bodies = append(s.bodies, b)
+	s.next = append(s.next, nil)
+	return len(s.bodies) - 1
+}
+
+// Remove a body from the simulation
+// The ids of the other bodies are unchanged, the removed body's place is simply left empty
+func (s *Simulation) RemoveBody(id int) {
+	if id < 0 || id >= len(s.bodies) || s.bodies[id] == nil {
+		return
+	}
+	b := s.bodies[id]
+	s.bodies[id] = nil
+	s.notifyRemoved(id, b)
+}
+
+// Merge the bodies with the given ids into one, as if they had all collided, returning the id of the merged body
+// The body kept is chosen, and the others merged into it, as in collisions (see KeptInMerge and Body.Merge)
+// Ids of bodies that aren't in the simulation are ignored, and -1 is returned if none of them are
+func (s *Simulation) MergeBodies(ids []int) int {
+	kept := -1
+	for _, id := range ids {
+		if id < 0 || id >= len(s.bodies) || s.bodies[id] == nil {
+			continue
+		}
+		if kept < 0 || KeptInMerge(s.bodies[id], s.bodies[kept]) {
+			kept = id
+		}
+	}
+	if kept < 0 {
+		return -1
+	}
+	// The kept body is merged into as a copy, so copies of it taken before (e.g. for undo) are unchanged
+	merged := *s.bodies[kept]
+	for _, id := range ids {
+		if id != kept && id >= 0 && id < len(s.bodies) && s.bodies[id] != nil {
+			merged.Merge(id, s.bodies[id])
+			s.RemoveBody(id)
+		}
+	}
+	s.bodies[kept] = &merged
+	return kept
+}
+
+// The bodies of the simulation, indexed by the ids returned by AddBody
+// Bodies that have been removed (e.g. merged into another body) are nil
+// The slice is the simulation's own, so bodies may be changed or removed (set to nil) in place, but new bodies must be added with AddBody
+// Removing bodies with RemoveBody rather than setting them to nil lets any OnBodyRemoved callbacks know
+func (s *Simulation) Bodies() []*Body {
+	return s.bodies
+}
+
+// Replace all of the bodies of the simulation, e.g. with bodies loaded from a file
+func (s *Simulation) SetBodies(bodies []*Body) {
+	s.bodies = bodies
+	s.next = make([]*Body, len(bodies))
+}
+
+// Write the bodies of the simulation in the csv save format
+func (s *Simulation) Save(w io.Writer) error {
+	if err := WriteSaveCSV(w, s.bodies); err != nil {
+		return err
+	}
+	s.NotifySaved()
+	return nil
+}
@@ -0,0 +1,62 @@
+package simulation
+
+// A body escaping the system is one with enough energy to get away from the rest of the bodies, and heading away from them
+// Its energy is its kinetic energy moving relative to the center of mass of the whole system, plus its potential energy from
+// the rest of the bodies treated as a single point mass at their center of mass. That is only exact for a body far away from
+// the rest, but finds the energy of every body in O(n) rather than O(n^2)
+
+// How tightly a body is bound to the rest of the system, as found by Bindings
+type Binding struct {
+	// The specific orbital energy of the body, i.e. its kinetic plus potential energy per unit mass
+	// The body is bound if this is negative, and unbound if it is positive
+	Energy float64
+	// The distance from the body to the center of mass of the rest of the system
+	Distance float64
+	// Whether the body is moving away from the rest of the system
+	Receding bool
+}
+
+// Whether the body is unbound and moving away, so will never come back
+func (b Binding) Escaping() bool {
+	return b.Energy > 0 && b.Receding
+}
+
+// How tightly each body is bound to the rest of the system, indexed by id like the bodies
+// Removed bodies, fixed bodies (which can't escape) and a body alone in the system have the zero Binding
+func Bindings(bodies []*Body, G float64) []Binding {
+	var mass float64
+	var weightedPos, momentum Vec2
+	for _, b := range bodies {
+		if b != nil {
+			mass += b.Mass
+			weightedPos = weightedPos.Add(b.Pos.Scale(b.Mass))
+			momentum = momentum.Add(b.Vel.Scale(b.Mass))
+		}
+	}
+
+	bindings := make([]Binding, len(bodies))
+	for i, b := range bodies {
+		if b == nil || b.Fixed {
+			continue
+		}
+		// The center of mass of the rest of the system, with the body taken out
+		rest := mass - b.Mass
+		if rest <= 0 {
+			continue
+		}
+		center := weightedPos.Sub(b.Pos.Scale(b.Mass)).Scale(1 / rest)
+
+		offset := b.Pos.Sub(center)
+		vel := b.Vel.Sub(momentum.Scale(1 / mass))
+		distance := offset.Norm()
+		if distance == 0 {
+			continue
+		}
+		bindings[i] = Binding{
+			Energy:   vel.Dot(vel)/2 - G*rest/distance,
+			Distance: distance,
+			Receding: vel.Dot(offset) > 0,
+		}
+	}
+	return bindings
+}
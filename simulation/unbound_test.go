@@ -0,0 +1,36 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+// A light body moving directly away from a heavy one escapes only if it is faster than the escape velocity,
+// and never while moving towards it
+func TestBindings(t *testing.T) {
+	const G, mass, distance = 1.0, 1000.0, 100.0
+	escapeSpeed := math.Sqrt(2 * G * mass / distance)
+	for _, c := range []struct {
+		name     string
+		speed    float64
+		escaping bool
+	}{
+		{"slower than escape velocity", 0.9 * escapeSpeed, false},
+		{"faster than escape velocity", 1.1 * escapeSpeed, true},
+		{"falling in faster than escape velocity", -1.1 * escapeSpeed, false},
+	} {
+		bodies := []*Body{
+			{Mass: mass, Radius: 1},
+			nil,
+			{Pos: Vec2{X: distance}, Vel: Vec2{X: c.speed}, Mass: 1e-6, Radius: 1},
+		}
+		bindings := Bindings(bodies, G)
+		if got := bindings[2].Escaping(); got != c.escaping {
+			t.Errorf("%v: escaping is %v, want %v (%+v)", c.name, got, c.escaping, bindings[2])
+		}
+		// The heavy body barely moves relative to the center of mass of the system, however fast the light body moves
+		if bindings[0].Escaping() || bindings[1] != (Binding{}) {
+			t.Errorf("%v: the heavy body or removed body is escaping: %+v", c.name, bindings)
+		}
+	}
+}
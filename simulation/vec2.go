@@ -0,0 +1,40 @@
+package simulation
+
+import "math"
+
+// A two dimensional vector, e.g. the position, velocity or acceleration of a body
+// The methods return new vectors rather than changing the vector, so they can be chained, e.g. b.Pos.Add(b.Vel.Scale(dt))
+type Vec2 struct {
+	X float64
+	Y float64
+}
+
+// The sum of the vectors
+func (v Vec2) Add(o Vec2) Vec2 {
+	return Vec2{v.X + o.X, v.Y + o.Y}
+}
+
+// The difference of the vectors, v - o
+func (v Vec2) Sub(o Vec2) Vec2 {
+	return Vec2{v.X - o.X, v.Y - o.Y}
+}
+
+// The vector multiplied by s
+func (v Vec2) Scale(s float64) Vec2 {
+	return Vec2{v.X * s, v.Y * s}
+}
+
+// The dot product of the vectors
+func (v Vec2) Dot(o Vec2) float64 {
+	return v.X*o.X + v.Y*o.Y
+}
+
+// The length of the vector
+func (v Vec2) Norm() float64 {
+	return math.Hypot(v.X, v.Y)
+}
+
+// The distance between the points at v and o
+func (v Vec2) Dist(o Vec2) float64 {
+	return v.Sub(o).Norm()
+}
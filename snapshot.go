@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// Snapshots are numbered save files written into a directory every --snapshotEvery steps,
+// e.g. snapshots/step00000100.csv, so a long (possibly headless) run can be animated or analysed afterwards
+// Snapshots are written in the --saveFormat format, and the step number in the name keeps them in order when sorted
+
+// Create the snapshot directory if it doesn't exist yet
+func openSnapshotDir() {
+	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
+		fatal("COULD NOT CREATE SNAPSHOT DIRECTORY", "path", snapshotDir, "err", err)
+	}
+}
+
+// Write a snapshot of the current state, named by the current step
+// A missed snapshot only leaves a gap, so the run carries on with a warning
+func writeSnapshot() {
+	if err := saveStateAs(filepath.Join(snapshotDir, fmt.Sprintf("step%08d", sim.Steps))); err != nil {
+		slog.Warn("COULD NOT WRITE SNAPSHOT", "err", err)
+	}
+}
@@ -0,0 +1,212 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"encoding/gob"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"hmcalister/gravity_simulation/render"
+)
+
+// The help for the spectate command, printed with -h
+const SPECTATEHELP = `
+Gravity Simulation - spectate
+Usage:
+	./gravity_simulation spectate [flags] address
+
+	Watches a simulation run elsewhere with --spectateAddr, e.g. ./gravity_simulation spectate teacher-pc:7000
+	The simulation can't be changed from here, but the view is your own: move it with WASD, zoom with Q and E,
+	rotate it with , and . (and / to put it back upright) and turn trails on and off with X, as in the window
+	If the connection is lost, the last frame stays on screen
+
+Flags:
+	--width, --height : The size of the window in pixels
+		Defaults to 1200x800
+	--x, --y : The position in the simulation at the center of the view
+		Defaults to 0,0
+	--zoom : The zoomscale, the distance in the simulation across each pixel
+		Defaults to 1
+	--background : The background color as red,green,blue
+		Defaults to 0,0,0`
+
+// The simulation as last sent by the server, updated by the connection and drawn by the window
+type spectatedSimulation struct {
+	lock   sync.Mutex
+	bodies map[int]spectatorBody
+	time   float64
+	steps  int
+	paused bool
+	// Set once the connection is lost
+	disconnected bool
+}
+
+// The spectate command, which shows a simulation served with --spectateAddr in a window
+func spectateCommand(args []string) {
+	var helpFlag bool
+	var background string
+	fs := flag.NewFlagSet("spectate", flag.ExitOnError)
+	fs.Var(int32Value{&screenWidth}, "width", "The width of the window in pixels")
+	fs.Var(int32Value{&screenHeight}, "height", "The height of the window in pixels")
+	fs.Float64Var(&camera.X, "x", 0, "The x coordinate in the simulation at the center of the view")
+	fs.Float64Var(&camera.Y, "y", 0, "The y coordinate in the simulation at the center of the view")
+	fs.Float64Var(&camera.Zoom, "zoom", 1, "The distance in the simulation across each pixel")
+	fs.StringVar(&background, "background", "0,0,0", "The background color as red,green,blue")
+	fs.BoolVar(&helpFlag, "h", false, "Display help on this command, then quit")
+	logFlags(fs)
+	fs.Parse(args)
+	setupLogging()
+
+	if helpFlag {
+		fmt.Println(SPECTATEHELP + LOGGINGHELP)
+		os.Exit(0)
+	}
+	if fs.NArg() != 1 {
+		fatal("SPECTATE NEEDS THE ADDRESS OF A SIMULATION, SEE spectate -h")
+	}
+	if camera.Zoom <= 0 || screenWidth <= 0 || screenHeight <= 0 {
+		fatal("--zoom, --width AND --height MUST ALL BE POSITIVE")
+	}
+	var err error
+	if backgroundColor, err = parseColor(background); err != nil {
+		fatal("INVALID BACKGROUND COLOR", "err", err)
+	}
+	addr := fs.Arg(0)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		fatal("COULD NOT CONNECT TO THE SIMULATION", "addr", addr, "err", err)
+	}
+	defer conn.Close()
+	slog.Info("SPECTATING", "addr", addr)
+
+	spectated := &spectatedSimulation{bodies: map[int]spectatorBody{}}
+	go receiveFrames(conn, spectated)
+
+	camera.Width, camera.Height = screenWidth, screenHeight
+	canvas = render.NewCanvas(screenWidth, screenHeight)
+	canvas.Fill(backgroundColor)
+	allocateFrame()
+	catchInterrupts()
+	err = withWindow("Gravity Simulation - "+addr, func(w windowRenderer) error {
+		for !shuttingDown() {
+			handleSpectatorInputs()
+			drawSpectated(spectated, addr)
+			if err := w.Present(frameCanvas); err != nil {
+				return err
+			}
+			time.Sleep(FRAMETIME * time.Millisecond)
+		}
+		return nil
+	})
+	if err != nil {
+		fatal("COULD NOT OPEN THE WINDOW", "err", err)
+	}
+}
+
+// Apply the frames sent by the server until the connection is lost
+func receiveFrames(conn net.Conn, spectated *spectatedSimulation) {
+	decoder := gob.NewDecoder(conn)
+	for {
+		var frame spectatorFrame
+		err := decoder.Decode(&frame)
+		spectated.lock.Lock()
+		if err != nil {
+			spectated.disconnected = true
+			spectated.lock.Unlock()
+			slog.Warn("LOST CONNECTION TO THE SIMULATION", "err", err)
+			return
+		}
+		frame.apply(spectated.bodies)
+		spectated.time, spectated.steps, spectated.paused = frame.Time, frame.Steps, frame.Paused
+		spectated.lock.Unlock()
+	}
+}
+
+// Handle the inputs of the spectate window, which only move the view
+func handleSpectatorInputs() {
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		switch t := event.(type) {
+		case *sdl.QuitEvent:
+			requestShutdown()
+		case *sdl.KeyboardEvent:
+			if t.State == sdl.RELEASED {
+				continue
+			}
+			switch t.Keysym.Scancode {
+			case keymap["trails"]:
+				if t.Repeat != 1 {
+					pixeldecay = !pixeldecay
+				}
+			case keymap["zoomOut"]:
+				camera.Zoom *= 1.2
+				canvas.Fill(backgroundColor)
+			case keymap["zoomIn"]:
+				camera.Zoom /= 1.2
+				canvas.Fill(backgroundColor)
+			case keymap["moveUp"]:
+				panView(0, -movescale)
+			case keymap["moveDown"]:
+				panView(0, movescale)
+			case keymap["moveLeft"]:
+				panView(-movescale, 0)
+			case keymap["moveRight"]:
+				panView(movescale, 0)
+			case keymap["rotateLeft"]:
+				rotateView(-ROTATIONSTEP)
+			case keymap["rotateRight"]:
+				rotateView(ROTATIONSTEP)
+			case keymap["resetRotation"]:
+				rotateView(-camera.Rotation)
+			}
+		}
+	}
+}
+
+// Draw the spectated simulation into the frame, with a panel saying where it is from
+// Bodies are drawn in order of id, as in the window, so overlapping bodies don't flicker
+func drawSpectated(spectated *spectatedSimulation, addr string) {
+	spectated.lock.Lock()
+	defer spectated.lock.Unlock()
+
+	if pixeldecay {
+		if !spectated.paused {
+			canvas.Decay(pixelDecayRate)
+		}
+	} else {
+		canvas.Fill(backgroundColor)
+	}
+	ids := make([]int, 0, len(spectated.bodies))
+	for id := range spectated.bodies {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		b := spectated.bodies[id]
+		x, y := camera.WorldToScreen(b.X, b.Y)
+		canvas.FillCircle(x, y, b.Radius/camera.Zoom, b.Color)
+	}
+
+	copy(frameCanvas.Pixels, canvas.Pixels)
+	lines := []string{
+		"SPECTATING: " + addr,
+		fmt.Sprintf("TIME: %.2f (%v STEPS)", spectated.time, spectated.steps),
+		fmt.Sprintf("BODIES: %v", len(spectated.bodies)),
+	}
+	if spectated.paused {
+		lines = append(lines, "PAUSED")
+	}
+	if spectated.disconnected {
+		lines = append(lines, "DISCONNECTED")
+	}
+	width, height := render.PanelSize(lines)
+	render.Panel(frame, int(screenWidth)-width-render.PANELMARGIN, int(screenHeight)-height-render.PANELMARGIN, lines)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/gob"
+	"image/color"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// With --spectateAddr, read-only spectators can watch the simulation over the network with the spectate command, e.g. on
+// the screens of a classroom, each panning and zooming their own view while this program runs the one copy of the physics
+//
+// Every FRAMETIME milliseconds the bodies are compared against those last broadcast, and each spectator is sent a
+// spectatorFrame over a gob stream with only what changed: the new positions of bodies that moved, the bodies that were
+// added or changed how they look (e.g. grew in a merge), and the ids of those removed. A spectator that can't keep up
+// isn't waited for, its unsent frame is replaced with a keyframe of every body, which it starts over from
+// Spectators never send anything, so they can't change the simulation
+
+// How long sending a frame to a spectator can take before it is disconnected
+const SPECTATORTIMEOUT = 5 * time.Second
+
+var (
+	// Set by the --spectateAddr flag
+	spectateAddr string
+	// The connected spectators, guarded by physicsLock
+	spectators = map[*spectator]bool{}
+)
+
+// A body as sent to spectators, with only what is needed to draw it
+type spectatorBody struct {
+	ID     int
+	X, Y   float64
+	Radius float64
+	Color  color.RGBA
+}
+
+// A new position of a body a spectator already has
+type spectatorMove struct {
+	ID   int
+	X, Y float64
+}
+
+// What changed in the simulation since the last frame sent to a spectator
+type spectatorFrame struct {
+	// A keyframe has every body in Bodies, replacing all the bodies the spectator had
+	Keyframe bool
+	Time     float64
+	Steps    int
+	Paused   bool
+	// Bodies that were added or look different, whole
+	Bodies []spectatorBody
+	// Bodies that only moved
+	Moved []spectatorMove
+	// The ids of the bodies that were removed
+	Removed []int
+}
+
+// Apply the changes of a frame to the bodies a spectator has, by id
+func (f spectatorFrame) apply(bodies map[int]spectatorBody) {
+	if f.Keyframe {
+		clear(bodies)
+	}
+	for _, b := range f.Bodies {
+		bodies[b.ID] = b
+	}
+	for _, m := range f.Moved {
+		if b, ok := bodies[m.ID]; ok {
+			b.X, b.Y = m.X, m.Y
+			bodies[m.ID] = b
+		}
+	}
+	for _, id := range f.Removed {
+		delete(bodies, id)
+	}
+}
+
+// A connected spectator
+type spectator struct {
+	conn net.Conn
+	// The next frame to send, only ever holding one
+	frames chan spectatorFrame
+	// Whether the spectator has missed a frame (or has just connected), so must be sent a keyframe
+	// Only used by the broadcaster
+	needsKeyframe bool
+}
+
+// Start accepting spectators on --spectateAddr, and broadcasting to them, in the background
+// Like the metrics, failing to listen for spectators is not fatal - the simulation simply runs without them
+func setupSpectators() {
+	listener, err := net.Listen("tcp", spectateAddr)
+	if err != nil {
+		slog.Warn("COULD NOT LISTEN FOR SPECTATORS", "addr", spectateAddr, "err", err)
+		return
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSpectator(conn)
+		}
+	}()
+	go broadcastToSpectators()
+	slog.Info("LISTENING FOR SPECTATORS", "addr", listener.Addr().String())
+}
+
+// Send frames to a spectator until it disconnects, falls too far behind or the simulation shuts down
+func serveSpectator(conn net.Conn) {
+	s := &spectator{conn: conn, frames: make(chan spectatorFrame, 1), needsKeyframe: true}
+	physicsLock.Lock()
+	spectators[s] = true
+	count := len(spectators)
+	physicsLock.Unlock()
+	slog.Info("SPECTATOR CONNECTED", "addr", conn.RemoteAddr().String(), "spectators", count)
+	defer func() {
+		physicsLock.Lock()
+		delete(spectators, s)
+		count := len(spectators)
+		physicsLock.Unlock()
+		conn.Close()
+		slog.Info("SPECTATOR DISCONNECTED", "addr", conn.RemoteAddr().String(), "spectators", count)
+	}()
+
+	encoder := gob.NewEncoder(conn)
+	for {
+		select {
+		case <-shutdownContext.Done():
+			return
+		case frame := <-s.frames:
+			conn.SetWriteDeadline(time.Now().Add(SPECTATORTIMEOUT))
+			if err := encoder.Encode(frame); err != nil {
+				slog.Debug("COULD NOT SEND TO SPECTATOR", "addr", conn.RemoteAddr().String(), "err", err)
+				return
+			}
+		}
+	}
+}
+
+// Every FRAMETIME milliseconds, send every spectator what changed since the last broadcast, until shutting down
+func broadcastToSpectators() {
+	ticker := time.NewTicker(FRAMETIME * time.Millisecond)
+	defer ticker.Stop()
+	// The bodies as of the last broadcast, by id
+	last := map[int]spectatorBody{}
+	for {
+		select {
+		case <-shutdownContext.Done():
+			return
+		case <-ticker.C:
+		}
+
+		physicsLock.Lock()
+		if len(spectators) > 0 {
+			last = broadcastFrame(last)
+		}
+		physicsLock.Unlock()
+	}
+}
+
+// Send every spectator the changes from the bodies last broadcast, returning the bodies as they are now
+// Only called with physicsLock held
+func broadcastFrame(last map[int]spectatorBody) map[int]spectatorBody {
+	current := make(map[int]spectatorBody, len(last))
+	delta := spectatorFrame{Time: sim.Time, Steps: sim.Steps, Paused: paused}
+	keyframe := spectatorFrame{Keyframe: true, Time: sim.Time, Steps: sim.Steps, Paused: paused}
+	for id, b := range sim.Bodies() {
+		if b == nil {
+			continue
+		}
+		body := spectatorBody{ID: id, X: b.Pos.X, Y: b.Pos.Y, Radius: b.Radius, Color: b.Color}
+		current[id] = body
+		keyframe.Bodies = append(keyframe.Bodies, body)
+		previous, ok := last[id]
+		if !ok || previous.Radius != body.Radius || previous.Color != body.Color {
+			delta.Bodies = append(delta.Bodies, body)
+		} else if previous.X != body.X || previous.Y != body.Y {
+			delta.Moved = append(delta.Moved, spectatorMove{id, body.X, body.Y})
+		}
+	}
+	for id := range last {
+		if _, ok := current[id]; !ok {
+			delta.Removed = append(delta.Removed, id)
+		}
+	}
+
+	for s := range spectators {
+		// A frame still waiting to be sent means the spectator is behind, and the delta alone would skip its changes
+		select {
+		case <-s.frames:
+			s.needsKeyframe = true
+		default:
+		}
+		if s.needsKeyframe {
+			s.frames <- keyframe
+			s.needsKeyframe = false
+		} else {
+			s.frames <- delta
+		}
+	}
+	return current
+}
@@ -0,0 +1,34 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"log/slog"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// The number keys 1 to 9 jump straight to preset timescales, from slow motion to fast forward, and 0 resets the timescale
+// The presets are multiples of the starting timescale (from --timescale or the config file)
+var speedPresets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 50}
+
+// The timescale the simulation started with, which the presets are multiples of
+var defaultTimescale float64
+
+// Handle a press of the number keys without Ctrl or Alt, returning false if it wasn't one
+// As with bookmarks, the number keys are used by position so are not rebound in the keymap
+func handleSpeedPresetKey(t *sdl.KeyboardEvent) bool {
+	if t.Repeat == 1 {
+		return false
+	}
+	switch {
+	case t.Keysym.Scancode == sdl.SCANCODE_0:
+		sim.Timescale = defaultTimescale
+	case t.Keysym.Scancode >= sdl.SCANCODE_1 && t.Keysym.Scancode <= sdl.SCANCODE_9:
+		sim.Timescale = defaultTimescale * speedPresets[t.Keysym.Scancode-sdl.SCANCODE_1]
+	default:
+		return false
+	}
+	slog.Info("TIMESCALE", "timescale", sim.Timescale, "multiple", sim.Timescale/defaultTimescale)
+	return true
+}
@@ -0,0 +1,6 @@
+// Package statepb holds the protobuf schema for the state of a simulation (bodies and settings), the gRPC service
+// for driving a running simulation, and the Go code generated from them.
+package statepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative state.proto
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative service.proto
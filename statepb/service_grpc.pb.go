@@ -0,0 +1,252 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: service.proto
+
+package statepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	SimulationService_GetState_FullMethodName  = "/gravity.SimulationService/GetState"
+	SimulationService_Step_FullMethodName      = "/gravity.SimulationService/Step"
+	SimulationService_SetPaused_FullMethodName = "/gravity.SimulationService/SetPaused"
+	SimulationService_Subscribe_FullMethodName = "/gravity.SimulationService/Subscribe"
+)
+
+// SimulationServiceClient is the client API for SimulationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SimulationServiceClient interface {
+	GetState(ctx context.Context, in *GetStateRequest, opts ...grpc.CallOption) (*Snapshot, error)
+	Step(ctx context.Context, in *StepRequest, opts ...grpc.CallOption) (*Snapshot, error)
+	SetPaused(ctx context.Context, in *SetPausedRequest, opts ...grpc.CallOption) (*Settings, error)
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (SimulationService_SubscribeClient, error)
+}
+
+type simulationServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSimulationServiceClient(cc grpc.ClientConnInterface) SimulationServiceClient {
+	return &simulationServiceClient{cc}
+}
+
+func (c *simulationServiceClient) GetState(ctx context.Context, in *GetStateRequest, opts ...grpc.CallOption) (*Snapshot, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Snapshot)
+	err := c.cc.Invoke(ctx, SimulationService_GetState_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulationServiceClient) Step(ctx context.Context, in *StepRequest, opts ...grpc.CallOption) (*Snapshot, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Snapshot)
+	err := c.cc.Invoke(ctx, SimulationService_Step_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulationServiceClient) SetPaused(ctx context.Context, in *SetPausedRequest, opts ...grpc.CallOption) (*Settings, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Settings)
+	err := c.cc.Invoke(ctx, SimulationService_SetPaused_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulationServiceClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (SimulationService_SubscribeClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SimulationService_ServiceDesc.Streams[0], SimulationService_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &simulationServiceSubscribeClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SimulationService_SubscribeClient interface {
+	Recv() (*Snapshot, error)
+	grpc.ClientStream
+}
+
+type simulationServiceSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *simulationServiceSubscribeClient) Recv() (*Snapshot, error) {
+	m := new(Snapshot)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SimulationServiceServer is the server API for SimulationService service.
+// All implementations must embed UnimplementedSimulationServiceServer
+// for forward compatibility
+type SimulationServiceServer interface {
+	GetState(context.Context, *GetStateRequest) (*Snapshot, error)
+	Step(context.Context, *StepRequest) (*Snapshot, error)
+	SetPaused(context.Context, *SetPausedRequest) (*Settings, error)
+	Subscribe(*SubscribeRequest, SimulationService_SubscribeServer) error
+	mustEmbedUnimplementedSimulationServiceServer()
+}
+
+// UnimplementedSimulationServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedSimulationServiceServer struct {
+}
+
+func (UnimplementedSimulationServiceServer) GetState(context.Context, *GetStateRequest) (*Snapshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetState not implemented")
+}
+func (UnimplementedSimulationServiceServer) Step(context.Context, *StepRequest) (*Snapshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Step not implemented")
+}
+func (UnimplementedSimulationServiceServer) SetPaused(context.Context, *SetPausedRequest) (*Settings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPaused not implemented")
+}
+func (UnimplementedSimulationServiceServer) Subscribe(*SubscribeRequest, SimulationService_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedSimulationServiceServer) mustEmbedUnimplementedSimulationServiceServer() {}
+
+// UnsafeSimulationServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SimulationServiceServer will
+// result in compilation errors.
+type UnsafeSimulationServiceServer interface {
+	mustEmbedUnimplementedSimulationServiceServer()
+}
+
+func RegisterSimulationServiceServer(s grpc.ServiceRegistrar, srv SimulationServiceServer) {
+	s.RegisterService(&SimulationService_ServiceDesc, srv)
+}
+
+func _SimulationService_GetState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulationServiceServer).GetState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SimulationService_GetState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulationServiceServer).GetState(ctx, req.(*GetStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SimulationService_Step_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StepRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulationServiceServer).Step(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SimulationService_Step_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulationServiceServer).Step(ctx, req.(*StepRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SimulationService_SetPaused_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPausedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulationServiceServer).SetPaused(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SimulationService_SetPaused_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulationServiceServer).SetPaused(ctx, req.(*SetPausedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SimulationService_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SimulationServiceServer).Subscribe(m, &simulationServiceSubscribeServer{ServerStream: stream})
+}
+
+type SimulationService_SubscribeServer interface {
+	Send(*Snapshot) error
+	grpc.ServerStream
+}
+
+type simulationServiceSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *simulationServiceSubscribeServer) Send(m *Snapshot) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// SimulationService_ServiceDesc is the grpc.ServiceDesc for SimulationService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SimulationService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gravity.SimulationService",
+	HandlerType: (*SimulationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetState",
+			Handler:    _SimulationService_GetState_Handler,
+		},
+		{
+			MethodName: "Step",
+			Handler:    _SimulationService_Step_Handler,
+		},
+		{
+			MethodName: "SetPaused",
+			Handler:    _SimulationService_SetPaused_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _SimulationService_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "service.proto",
+}
@@ -0,0 +1,555 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        (unknown)
+// source: state.proto
+
+package statepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Color struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Red   uint32 `protobuf:"varint,1,opt,name=red,proto3" json:"red,omitempty"`
+	Green uint32 `protobuf:"varint,2,opt,name=green,proto3" json:"green,omitempty"`
+	Blue  uint32 `protobuf:"varint,3,opt,name=blue,proto3" json:"blue,omitempty"`
+}
+
+func (x *Color) Reset() {
+	*x = Color{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_state_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Color) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Color) ProtoMessage() {}
+
+func (x *Color) ProtoReflect() protoreflect.Message {
+	mi := &file_state_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Color.ProtoReflect.Descriptor instead.
+func (*Color) Descriptor() ([]byte, []int) {
+	return file_state_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Color) GetRed() uint32 {
+	if x != nil {
+		return x.Red
+	}
+	return 0
+}
+
+func (x *Color) GetGreen() uint32 {
+	if x != nil {
+		return x.Green
+	}
+	return 0
+}
+
+func (x *Color) GetBlue() uint32 {
+	if x != nil {
+		return x.Blue
+	}
+	return 0
+}
+
+type Body struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id     int64   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	X      float64 `protobuf:"fixed64,2,opt,name=x,proto3" json:"x,omitempty"`
+	Y      float64 `protobuf:"fixed64,3,opt,name=y,proto3" json:"y,omitempty"`
+	XVel   float64 `protobuf:"fixed64,4,opt,name=x_vel,json=xVel,proto3" json:"x_vel,omitempty"`
+	YVel   float64 `protobuf:"fixed64,5,opt,name=y_vel,json=yVel,proto3" json:"y_vel,omitempty"`
+	Mass   float64 `protobuf:"fixed64,6,opt,name=mass,proto3" json:"mass,omitempty"`
+	Radius float64 `protobuf:"fixed64,7,opt,name=radius,proto3" json:"radius,omitempty"`
+	Color  *Color  `protobuf:"bytes,8,opt,name=color,proto3" json:"color,omitempty"`
+	Name   string  `protobuf:"bytes,9,opt,name=name,proto3" json:"name,omitempty"`
+	Fixed  bool    `protobuf:"varint,10,opt,name=fixed,proto3" json:"fixed,omitempty"`
+}
+
+func (x *Body) Reset() {
+	*x = Body{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_state_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Body) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Body) ProtoMessage() {}
+
+func (x *Body) ProtoReflect() protoreflect.Message {
+	mi := &file_state_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Body.ProtoReflect.Descriptor instead.
+func (*Body) Descriptor() ([]byte, []int) {
+	return file_state_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Body) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Body) GetX() float64 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Body) GetY() float64 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *Body) GetXVel() float64 {
+	if x != nil {
+		return x.XVel
+	}
+	return 0
+}
+
+func (x *Body) GetYVel() float64 {
+	if x != nil {
+		return x.YVel
+	}
+	return 0
+}
+
+func (x *Body) GetMass() float64 {
+	if x != nil {
+		return x.Mass
+	}
+	return 0
+}
+
+func (x *Body) GetRadius() float64 {
+	if x != nil {
+		return x.Radius
+	}
+	return 0
+}
+
+func (x *Body) GetColor() *Color {
+	if x != nil {
+		return x.Color
+	}
+	return nil
+}
+
+func (x *Body) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Body) GetFixed() bool {
+	if x != nil {
+		return x.Fixed
+	}
+	return false
+}
+
+type Settings struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	G              float64 `protobuf:"fixed64,1,opt,name=g,proto3" json:"g,omitempty"`
+	Timescale      float64 `protobuf:"fixed64,2,opt,name=timescale,proto3" json:"timescale,omitempty"`
+	SimulationTime float64 `protobuf:"fixed64,3,opt,name=simulation_time,json=simulationTime,proto3" json:"simulation_time,omitempty"`
+	Paused         bool    `protobuf:"varint,4,opt,name=paused,proto3" json:"paused,omitempty"`
+	RngState       uint64  `protobuf:"varint,5,opt,name=rng_state,json=rngState,proto3" json:"rng_state,omitempty"`
+	Softening      float64 `protobuf:"fixed64,6,opt,name=softening,proto3" json:"softening,omitempty"`
+	Steps          int64   `protobuf:"varint,7,opt,name=steps,proto3" json:"steps,omitempty"`
+	Merges         int64   `protobuf:"varint,8,opt,name=merges,proto3" json:"merges,omitempty"`
+	CollisionMode  string  `protobuf:"bytes,9,opt,name=collision_mode,json=collisionMode,proto3" json:"collision_mode,omitempty"`
+}
+
+func (x *Settings) Reset() {
+	*x = Settings{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_state_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Settings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Settings) ProtoMessage() {}
+
+func (x *Settings) ProtoReflect() protoreflect.Message {
+	mi := &file_state_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Settings.ProtoReflect.Descriptor instead.
+func (*Settings) Descriptor() ([]byte, []int) {
+	return file_state_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Settings) GetG() float64 {
+	if x != nil {
+		return x.G
+	}
+	return 0
+}
+
+func (x *Settings) GetTimescale() float64 {
+	if x != nil {
+		return x.Timescale
+	}
+	return 0
+}
+
+func (x *Settings) GetSimulationTime() float64 {
+	if x != nil {
+		return x.SimulationTime
+	}
+	return 0
+}
+
+func (x *Settings) GetPaused() bool {
+	if x != nil {
+		return x.Paused
+	}
+	return false
+}
+
+func (x *Settings) GetRngState() uint64 {
+	if x != nil {
+		return x.RngState
+	}
+	return 0
+}
+
+func (x *Settings) GetSoftening() float64 {
+	if x != nil {
+		return x.Softening
+	}
+	return 0
+}
+
+func (x *Settings) GetSteps() int64 {
+	if x != nil {
+		return x.Steps
+	}
+	return 0
+}
+
+func (x *Settings) GetMerges() int64 {
+	if x != nil {
+		return x.Merges
+	}
+	return 0
+}
+
+func (x *Settings) GetCollisionMode() string {
+	if x != nil {
+		return x.CollisionMode
+	}
+	return ""
+}
+
+type State struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Settings      *Settings `protobuf:"bytes,1,opt,name=settings,proto3" json:"settings,omitempty"`
+	Bodies        []*Body   `protobuf:"bytes,2,rep,name=bodies,proto3" json:"bodies,omitempty"`
+	Script        string    `protobuf:"bytes,3,opt,name=script,proto3" json:"script,omitempty"`
+	PendingEvents []float64 `protobuf:"fixed64,4,rep,packed,name=pending_events,json=pendingEvents,proto3" json:"pending_events,omitempty"`
+	ScriptFirstId int64     `protobuf:"varint,5,opt,name=script_first_id,json=scriptFirstId,proto3" json:"script_first_id,omitempty"`
+}
+
+func (x *State) Reset() {
+	*x = State{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_state_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *State) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*State) ProtoMessage() {}
+
+func (x *State) ProtoReflect() protoreflect.Message {
+	mi := &file_state_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use State.ProtoReflect.Descriptor instead.
+func (*State) Descriptor() ([]byte, []int) {
+	return file_state_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *State) GetSettings() *Settings {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+func (x *State) GetBodies() []*Body {
+	if x != nil {
+		return x.Bodies
+	}
+	return nil
+}
+
+func (x *State) GetScript() string {
+	if x != nil {
+		return x.Script
+	}
+	return ""
+}
+
+func (x *State) GetPendingEvents() []float64 {
+	if x != nil {
+		return x.PendingEvents
+	}
+	return nil
+}
+
+func (x *State) GetScriptFirstId() int64 {
+	if x != nil {
+		return x.ScriptFirstId
+	}
+	return 0
+}
+
+var File_state_proto protoreflect.FileDescriptor
+
+var file_state_proto_rawDesc = []byte{
+	0x0a, 0x0b, 0x73, 0x74, 0x61, 0x74, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x67,
+	0x72, 0x61, 0x76, 0x69, 0x74, 0x79, 0x22, 0x43, 0x0a, 0x05, 0x43, 0x6f, 0x6c, 0x6f, 0x72, 0x12,
+	0x10, 0x0a, 0x03, 0x72, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x72, 0x65,
+	0x64, 0x12, 0x14, 0x0a, 0x05, 0x67, 0x72, 0x65, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x05, 0x67, 0x72, 0x65, 0x65, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x62, 0x6c, 0x75, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x62, 0x6c, 0x75, 0x65, 0x22, 0xd8, 0x01, 0x0a, 0x04,
+	0x42, 0x6f, 0x64, 0x79, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x02, 0x69, 0x64, 0x12, 0x0c, 0x0a, 0x01, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x01, 0x78, 0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x01, 0x79,
+	0x12, 0x13, 0x0a, 0x05, 0x78, 0x5f, 0x76, 0x65, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x04, 0x78, 0x56, 0x65, 0x6c, 0x12, 0x13, 0x0a, 0x05, 0x79, 0x5f, 0x76, 0x65, 0x6c, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x04, 0x79, 0x56, 0x65, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x61,
+	0x73, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04, 0x6d, 0x61, 0x73, 0x73, 0x12, 0x16,
+	0x0a, 0x06, 0x72, 0x61, 0x64, 0x69, 0x75, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06,
+	0x72, 0x61, 0x64, 0x69, 0x75, 0x73, 0x12, 0x24, 0x0a, 0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x18,
+	0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x67, 0x72, 0x61, 0x76, 0x69, 0x74, 0x79, 0x2e,
+	0x43, 0x6f, 0x6c, 0x6f, 0x72, 0x52, 0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x66, 0x69, 0x78, 0x65, 0x64, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x05, 0x66, 0x69, 0x78, 0x65, 0x64, 0x22, 0x87, 0x02, 0x0a, 0x08, 0x53, 0x65, 0x74, 0x74, 0x69,
+	0x6e, 0x67, 0x73, 0x12, 0x0c, 0x0a, 0x01, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x01,
+	0x67, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x63, 0x61, 0x6c, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x63, 0x61, 0x6c, 0x65, 0x12,
+	0x27, 0x0a, 0x0f, 0x73, 0x69, 0x6d, 0x75, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x74, 0x69,
+	0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0e, 0x73, 0x69, 0x6d, 0x75, 0x6c, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x61, 0x75, 0x73,
+	0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x70, 0x61, 0x75, 0x73, 0x65, 0x64,
+	0x12, 0x1b, 0x0a, 0x09, 0x72, 0x6e, 0x67, 0x5f, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x08, 0x72, 0x6e, 0x67, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x1c, 0x0a,
+	0x09, 0x73, 0x6f, 0x66, 0x74, 0x65, 0x6e, 0x69, 0x6e, 0x67, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x09, 0x73, 0x6f, 0x66, 0x74, 0x65, 0x6e, 0x69, 0x6e, 0x67, 0x12, 0x14, 0x0a, 0x05, 0x73,
+	0x74, 0x65, 0x70, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x73, 0x74, 0x65, 0x70,
+	0x73, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x06, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x63, 0x6f, 0x6c,
+	0x6c, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x6d, 0x6f, 0x64, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0d, 0x63, 0x6f, 0x6c, 0x6c, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x4d, 0x6f, 0x64, 0x65,
+	0x22, 0xc4, 0x01, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x2d, 0x0a, 0x08, 0x73, 0x65,
+	0x74, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x67,
+	0x72, 0x61, 0x76, 0x69, 0x74, 0x79, 0x2e, 0x53, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52,
+	0x08, 0x73, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x25, 0x0a, 0x06, 0x62, 0x6f, 0x64,
+	0x69, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x67, 0x72, 0x61, 0x76,
+	0x69, 0x74, 0x79, 0x2e, 0x42, 0x6f, 0x64, 0x79, 0x52, 0x06, 0x62, 0x6f, 0x64, 0x69, 0x65, 0x73,
+	0x12, 0x16, 0x0a, 0x06, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x12, 0x25, 0x0a, 0x0e, 0x70, 0x65, 0x6e, 0x64,
+	0x69, 0x6e, 0x67, 0x5f, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x01,
+	0x52, 0x0d, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x12,
+	0x26, 0x0a, 0x0f, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x5f, 0x66, 0x69, 0x72, 0x73, 0x74, 0x5f,
+	0x69, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74,
+	0x46, 0x69, 0x72, 0x73, 0x74, 0x49, 0x64, 0x42, 0x27, 0x5a, 0x25, 0x68, 0x6d, 0x63, 0x61, 0x6c,
+	0x69, 0x73, 0x74, 0x65, 0x72, 0x2f, 0x67, 0x72, 0x61, 0x76, 0x69, 0x74, 0x79, 0x5f, 0x73, 0x69,
+	0x6d, 0x75, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2f, 0x73, 0x74, 0x61, 0x74, 0x65, 0x70, 0x62,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_state_proto_rawDescOnce sync.Once
+	file_state_proto_rawDescData = file_state_proto_rawDesc
+)
+
+func file_state_proto_rawDescGZIP() []byte {
+	file_state_proto_rawDescOnce.Do(func() {
+		file_state_proto_rawDescData = protoimpl.X.CompressGZIP(file_state_proto_rawDescData)
+	})
+	return file_state_proto_rawDescData
+}
+
+var file_state_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_state_proto_goTypes = []interface{}{
+	(*Color)(nil),    // 0: gravity.Color
+	(*Body)(nil),     // 1: gravity.Body
+	(*Settings)(nil), // 2: gravity.Settings
+	(*State)(nil),    // 3: gravity.State
+}
+var file_state_proto_depIdxs = []int32{
+	0, // 0: gravity.Body.color:type_name -> gravity.Color
+	2, // 1: gravity.State.settings:type_name -> gravity.Settings
+	1, // 2: gravity.State.bodies:type_name -> gravity.Body
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_state_proto_init() }
+func file_state_proto_init() {
+	if File_state_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_state_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Color); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_state_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Body); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_state_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Settings); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_state_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*State); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_state_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_state_proto_goTypes,
+		DependencyIndexes: file_state_proto_depIdxs,
+		MessageInfos:      file_state_proto_msgTypes,
+	}.Build()
+	File_state_proto = out.File
+	file_state_proto_rawDesc = nil
+	file_state_proto_goTypes = nil
+	file_state_proto_depIdxs = nil
+}
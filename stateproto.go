@@ -0,0 +1,88 @@
+package main
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	"hmcalister/gravity_simulation/persist"
+	"hmcalister/gravity_simulation/simulation"
+	"hmcalister/gravity_simulation/statepb"
+)
+
+// Convert the current state of the simulation (bodies and settings) to its protobuf form
+func stateToProto() *statepb.State {
+	state := &statepb.State{
+		Settings: &statepb.Settings{
+			G:              sim.G,
+			Timescale:      sim.Timescale,
+			SimulationTime: sim.Time,
+			Paused:         paused,
+			RngState:       sim.RandomSource.State,
+			Softening:      sim.Softening,
+			Steps:          int64(sim.Steps),
+			Merges:         int64(sim.Merges),
+			CollisionMode:  simulation.CollisionModeNames[sim.CollisionMode],
+		},
+	}
+	if scriptThread != nil {
+		state.Script = scriptThread.Name
+		state.ScriptFirstId = int64(scriptFirstID)
+		for _, event := range scriptEvents {
+			state.PendingEvents = append(state.PendingEvents, event.time)
+		}
+	}
+	state.Bodies = persist.BodiesToProto(sim.Bodies())
+	return state
+}
+
+// Replace the state of the simulation with the given protobuf state
+func stateFromProto(state *statepb.State) error {
+	bodies, err := persist.BodiesFromProto(state)
+	if err != nil {
+		return err
+	}
+	var collisionMode int
+	if settings := state.Settings; settings != nil && settings.CollisionMode != "" {
+		if collisionMode, err = simulation.ParseCollisionMode(settings.CollisionMode); err != nil {
+			return err
+		}
+	}
+
+	sim.SetBodies(bodies)
+	if settings := state.Settings; settings != nil {
+		sim.G = settings.G
+		sim.Timescale = settings.Timescale
+		sim.Time = settings.SimulationTime
+		sim.Softening = settings.Softening
+		sim.Steps = int(settings.Steps)
+		sim.Merges = int(settings.Merges)
+		// Older saves have no collision mode, in which case the current mode (e.g. from --collisions) is kept
+		if settings.CollisionMode != "" {
+			sim.CollisionMode = collisionMode
+		}
+		paused = settings.Paused
+		// Older saves have no random number generator state, in which case the current state is kept
+		if settings.RngState != 0 {
+			sim.RandomSource.State = settings.RngState
+		}
+	}
+	// Any scripted events are restored once the script is known, see resumeScript
+	savedScript = nil
+	if state.Script != "" {
+		savedScript = &scriptSave{state.Script, int(state.ScriptFirstId), state.PendingEvents}
+	}
+	return nil
+}
+
+// Encode the current state of the simulation as protobuf
+func encodeState() ([]byte, error) {
+	return proto.Marshal(stateToProto())
+}
+
+// Decode a protobuf encoded state and apply it to the simulation
+func decodeState(data []byte) error {
+	var state statepb.State
+	if err := proto.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	return stateFromProto(&state)
+}
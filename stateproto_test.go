@@ -0,0 +1,51 @@
+package main
+
+import (
+	"image/color"
+	"reflect"
+	"testing"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// A simulation saved as protobuf and loaded again is the same simulation, so a resumed run carries on exactly
+func TestStateProtoRoundTrip(t *testing.T) {
+	sim = simulation.New(simulation.Params{G: 3, Timescale: 0.02, Softening: 1.5, CollisionMode: simulation.COLLISIONBOUNCE}, 7)
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{X: -50, Y: 3}, Vel: simulation.Vec2{Y: 1}, Mass: 100, Radius: 10, Color: color.RGBA{200, 100, 50, 255}, Name: "a"})
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{X: 50}, Vel: simulation.Vec2{Y: -1}, Mass: 50, Radius: 7, Color: color.RGBA{1, 2, 3, 255}, Fixed: true})
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{X: 0, Y: 80}, Mass: 1, Radius: 1, Color: color.RGBA{255, 255, 255, 255}})
+	sim.RemoveBody(1)
+	for i := 0; i < 5; i++ {
+		sim.Step()
+	}
+	sim.Merges = 4
+	sim.Rand.Float64()
+	want := sim.Clone()
+
+	data, err := encodeState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim = simulation.New(simulation.Params{}, 1)
+	if err := decodeState(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if sim.Params != want.Params {
+		t.Errorf("loaded parameters %+v, want %+v", sim.Params, want.Params)
+	}
+	if sim.Time != want.Time || sim.Steps != want.Steps || sim.Merges != want.Merges {
+		t.Errorf("loaded time %v, steps %v and merges %v, want %v, %v and %v", sim.Time, sim.Steps, sim.Merges, want.Time, want.Steps, want.Merges)
+	}
+	if sim.RandomSource.State != want.RandomSource.State {
+		t.Errorf("loaded random state %v, want %v", sim.RandomSource.State, want.RandomSource.State)
+	}
+	if len(sim.Bodies()) != len(want.Bodies()) {
+		t.Fatalf("loaded %v bodies, want %v", len(sim.Bodies()), len(want.Bodies()))
+	}
+	for i, b := range sim.Bodies() {
+		if w := want.Bodies()[i]; (b == nil) != (w == nil) || b != nil && !reflect.DeepEqual(*b, *w) {
+			t.Errorf("loaded body %v as %+v, want %+v", i, b, w)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Streaming snapshots to stdout lets the simulation be composed in Unix pipelines, e.g.
+//
+//	generator | gravity_simulation --saveFile=- --streamEvery=10 | analyzer
+//
+// Each snapshot is a complete csv save (version line, header and rows), preceded by a "#t = time" comment
+// and followed by a blank line, so a reader can split the stream into snapshots on the blank lines.
+
+// The writer for the real stdout, as os.Stdout is redirected to stderr while streaming
+var streamOutput *bufio.Writer
+
+// Take over stdout for the stream, sending all other output to stderr
+func openStream() {
+	streamOutput = bufio.NewWriter(os.Stdout)
+	os.Stdout = os.Stderr
+	tableWriter = tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+}
+
+// Write a snapshot of the current state to the stream
+// The stream is flushed after every snapshot so downstream programs see it straight away
+func streamSnapshot() {
+	if streamOutput == nil {
+		return
+	}
+	fmt.Fprintf(streamOutput, "#t = %v\n", sim.Time)
+	simulation.WriteSaveCSV(streamOutput, sim.Bodies())
+	fmt.Fprintln(streamOutput)
+	streamOutput.Flush()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Every run ends with a summary of what happened, printed after the final save (unless --summary=false), e.g. how many
+// bodies merged, how the mass ended up distributed and how well energy and momentum were conserved
+// With --summaryOut the summary is also written as JSON, so batch runs can be collected and compared
+
+var (
+	// Set by the summary flags
+	printSummaryFlag bool
+	summaryPath      string
+	// The state at the start of the run, which the summary compares against
+	// The steps and merges are counted from the start, so those from before a save was loaded aren't included
+	summaryStart       frameStats
+	summaryStartSteps  int
+	summaryStartMerges int
+	summaryStartWall   time.Time
+)
+
+// The summary of a run, as written to --summaryOut
+type runSummary struct {
+	Steps       int     `json:"steps"`
+	SimTime     float64 `json:"simTime"`
+	WallSeconds float64 `json:"wallSeconds"`
+	// The number of bodies merged into other bodies in collisions
+	Mergers     int `json:"mergers"`
+	StartBodies int `json:"startBodies"`
+	Bodies      int `json:"bodies"`
+	// The total mass, and the masses of the bodies remaining at the quartiles
+	TotalMass float64    `json:"totalMass"`
+	Masses    [5]float64 `json:"masses"`
+	// The change in the total energy relative to the starting energy, and in the total momentum
+	StartEnergy   float64         `json:"startEnergy"`
+	Energy        float64         `json:"energy"`
+	EnergyDrift   float64         `json:"energyDrift"`
+	StartMomentum simulation.Vec2 `json:"startMomentum"`
+	Momentum      simulation.Vec2 `json:"momentum"`
+	MomentumDrift float64         `json:"momentumDrift"`
+	// The change in the angular momentum about the barycenter, relative to the starting angular momentum
+	StartAngularMomentum float64 `json:"startAngularMomentum"`
+	AngularMomentum      float64 `json:"angularMomentum"`
+	AngularMomentumDrift float64 `json:"angularMomentumDrift"`
+	// The largest body remaining, or nil if there are none left
+	Largest *summaryBody `json:"largest"`
+	// The estimate of the largest Lyapunov exponent, if the chaos was measured (see --chaos)
+	Lyapunov *float64 `json:"lyapunov,omitempty"`
+}
+
+// A body as described in the summary
+type summaryBody struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name"`
+	Mass   float64 `json:"mass"`
+	Radius float64 `json:"radius"`
+}
+
+// Record the state at the start of the run, once it is set up
+func startSummary() {
+	summaryStart = statsOf(sim.Time, sim.Bodies(), sim.G)
+	summaryStartSteps, summaryStartMerges = sim.Steps, sim.Merges
+	summaryStartWall = time.Now()
+}
+
+// Summarize the run so far
+func summarize() runSummary {
+	end := statsOf(sim.Time, sim.Bodies(), sim.G)
+	s := runSummary{
+		Steps:         sim.Steps - summaryStartSteps,
+		SimTime:       end.time - summaryStart.time,
+		WallSeconds:   time.Since(summaryStartWall).Seconds(),
+		Mergers:       sim.Merges - summaryStartMerges,
+		StartBodies:   summaryStart.bodies,
+		Bodies:        end.bodies,
+		TotalMass:     end.mass,
+		StartEnergy:   summaryStart.kinetic + summaryStart.potential,
+		Energy:        end.kinetic + end.potential,
+		StartMomentum: summaryStart.momentum,
+		Momentum:      end.momentum,
+		MomentumDrift: end.momentum.Dist(summaryStart.momentum),
+	}
+	s.EnergyDrift = (s.Energy - s.StartEnergy) / math.Abs(s.StartEnergy)
+	s.StartAngularMomentum, s.AngularMomentum = summaryStart.angularMomentum, end.angularMomentum
+	s.AngularMomentumDrift = angularMomentumDrift(s.StartAngularMomentum, s.AngularMomentum)
+
+	var masses []float64
+	for i, b := range sim.Bodies() {
+		if b == nil {
+			continue
+		}
+		masses = append(masses, b.Mass)
+		if s.Largest == nil || b.Mass > s.Largest.Mass {
+			s.Largest = &summaryBody{ID: i, Name: b.Name, Mass: b.Mass, Radius: b.Radius}
+		}
+	}
+	if chaos != nil {
+		lyapunov := chaos.Lyapunov()
+		s.Lyapunov = &lyapunov
+	}
+
+	sort.Float64s(masses)
+	if len(masses) > 0 {
+		for i := range s.Masses {
+			s.Masses[i] = masses[i*(len(masses)-1)/(len(s.Masses)-1)]
+		}
+	}
+	return s
+}
+
+// Print the summary of the run, and write it to --summaryOut if given
+// Like the trajectory, failing to write the summary file is not fatal
+func finishSummary() {
+	s := summarize()
+	if printSummaryFlag {
+		printSummary(s)
+	}
+	if summaryPath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err == nil {
+		err = os.WriteFile(summaryPath, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		slog.Warn("COULD NOT WRITE SUMMARY", "path", summaryPath, "err", err)
+	}
+}
+
+// Print the summary of a run with some formatting, like the analyze command
+func printSummary(s runSummary) {
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Fprintf(tableWriter, "STEPS\t%v\n", s.Steps)
+	fmt.Fprintf(tableWriter, "SIMULATED TIME\t%.6g\n", s.SimTime)
+	fmt.Fprintf(tableWriter, "WALL TIME\t%v\n", time.Duration(s.WallSeconds*float64(time.Second)).Round(time.Millisecond))
+	fmt.Fprintf(tableWriter, "MERGERS\t%v\n", s.Mergers)
+	fmt.Fprintf(tableWriter, "BODIES\t%v (from %v)\n", s.Bodies, s.StartBodies)
+	fmt.Fprintf(tableWriter, "TOTAL MASS\t%.6g\n", s.TotalMass)
+	fmt.Fprintf(tableWriter, "MASSES (MIN, QUARTILES, MAX)\t%.4g, %.4g, %.4g, %.4g, %.4g\n", s.Masses[0], s.Masses[1], s.Masses[2], s.Masses[3], s.Masses[4])
+	fmt.Fprintf(tableWriter, "ENERGY\t%.6g (from %.6g, drift %.3g%%)\n", s.Energy, s.StartEnergy, 100*s.EnergyDrift)
+	fmt.Fprintf(tableWriter, "MOMENTUM\t(%.6g, %.6g) (from (%.6g, %.6g), drift %.3g)\n", s.Momentum.X, s.Momentum.Y, s.StartMomentum.X, s.StartMomentum.Y, s.MomentumDrift)
+	fmt.Fprintf(tableWriter, "ANGULAR MOMENTUM\t%.6g (from %.6g, drift %.3g%%)\n", s.AngularMomentum, s.StartAngularMomentum, 100*s.AngularMomentumDrift)
+	if s.Largest != nil {
+		fmt.Fprintf(tableWriter, "LARGEST BODY\t%v %v (mass %.6g, radius %.4g)\n", s.Largest.ID, s.Largest.Name, s.Largest.Mass, s.Largest.Radius)
+	}
+	if s.Lyapunov != nil {
+		fmt.Fprintf(tableWriter, "LYAPUNOV EXPONENT\t%.4g\n", *s.Lyapunov)
+	}
+	tableWriter.Flush()
+}
+
+// The change in the angular momentum relative to the starting angular momentum, or 0 if it started at 0
+// e.g. bodies all starting at rest, when any change is only the error of the steps
+func angularMomentumDrift(start, end float64) float64 {
+	if start == 0 {
+		return 0
+	}
+	return (end - start) / math.Abs(start)
+}
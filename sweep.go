@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The help for the sweep command, printed with -h
+const SWEEPHELP = `
+Gravity Simulation - sweep
+Usage:
+	./gravity_simulation sweep [flags] -- [simulate flags]
+
+	Runs the simulate command once for each value of a parameter, e.g. to see how the number of mergers depends on G:
+	./gravity_simulation sweep --param=G --from=50 --to=200 --count=4 -- --numBodies=50 --steps=5000 --seed=1
+	The flags after -- are given to every run, which must include a run limit (--steps, --simTime or --duration)
+	If they don't include --seed, every run is given the same random seed, so only the parameter differs between them
+
+	Each run has its own directory in --out, named after its value (e.g. sweep/G=50), which it is run in, so any relative
+	output paths (e.g. --trajectoryOut=trajectory.csv) are written there, along with its save, its summary (summary.json)
+	and everything it logs (log.txt). Input files (--saveFile, --script, --config and --horizons) are found from here
+	Once every run has finished, a row of each run's summary is written to summary.csv in --out and printed as a table
+	An interrupt (Ctrl+C) stops every run as usual (each saves and writes its summary), and no more runs are started
+
+Flags:
+	--param : The parameter to sweep, one of G, timescale, numBodies or softening
+	--values : A comma separated list of the values to run with, e.g. 50,100,200
+	--from, --to, --count : Instead of --values, run with count values evenly spaced from from to to (inclusive)
+		Use --logSpacing to space them logarithmically instead. numBodies values are rounded to whole numbers
+	--out : The directory to put the directory of each run in
+		Defaults to sweep
+	--parallel : How many runs to run at once
+		Defaults to 1`
+
+// The parameters that can be swept, by the name of their flag
+var sweepParams = []string{"G", "timescale", "numBodies", "softening"}
+
+// The flags of the simulate command that are paths to read from, so are made absolute for runs in their own directories
+// Output paths are left as they are, so each run writes its own
+var sweepInputFlags = map[string]bool{"config": true, "saveFile": true, "script": true, "horizons": true}
+
+// The result of one run of a sweep
+type sweepRun struct {
+	value string
+	dir   string
+	// The summary the run wrote, nil if it failed before writing one
+	summary *runSummary
+	err     error
+}
+
+// The sweep command, which runs the simulate command for each value of a parameter
+func sweepCommand(args []string) {
+	var helpFlag, logSpacing bool
+	var param, valuesString, out string
+	var from, to float64
+	var count, parallel int
+	fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+	fs.StringVar(&param, "param", "", "The parameter to sweep, one of "+strings.Join(sweepParams, ", "))
+	fs.StringVar(&valuesString, "values", "", "A comma separated list of the values to run with")
+	fs.Float64Var(&from, "from", 0, "The first value to run with, if --values isn't given")
+	fs.Float64Var(&to, "to", 0, "The last value to run with, if --values isn't given")
+	fs.IntVar(&count, "count", 0, "The number of values from --from to --to to run with, if --values isn't given")
+	fs.BoolVar(&logSpacing, "logSpacing", false, "Space the values from --from to --to logarithmically instead of evenly")
+	fs.StringVar(&out, "out", "sweep", "The directory to put the directory of each run in")
+	fs.IntVar(&parallel, "parallel", 1, "How many runs to run at once")
+	fs.BoolVar(&helpFlag, "h", false, "Display help on this command, then quit")
+	logFlags(fs)
+	fs.Parse(args)
+	setupLogging()
+
+	if helpFlag {
+		fmt.Println(SWEEPHELP + LOGGINGHELP)
+		os.Exit(0)
+	}
+	if !slices.Contains(sweepParams, param) {
+		fatal("SWEEP NEEDS A --param, ONE OF "+strings.Join(sweepParams, ", "), "param", param)
+	}
+	if parallel < 1 {
+		fatal("--parallel MUST BE AT LEAST 1")
+	}
+	// Interrupts from the terminal reach the runs too, which each save and stop, so here they only stop more runs starting
+	// This is before the flags of the runs are read, so their --duration isn't a deadline for the whole sweep
+	catchInterrupts()
+	values, err := sweepValues(param, valuesString, from, to, count, logSpacing)
+	if err != nil {
+		fatal("INVALID SWEEP VALUES, SEE sweep -h", "err", err)
+	}
+	runArgs := sweepRunArgs(param, fs.Args(), true)
+
+	runs := make([]sweepRun, len(values))
+	for i, value := range values {
+		runs[i] = sweepRun{value: value, dir: filepath.Join(out, param+"="+value)}
+		if err := os.MkdirAll(runs[i].dir, 0755); err != nil {
+			fatal("COULD NOT CREATE RUN DIRECTORY", "dir", runs[i].dir, "err", err)
+		}
+	}
+
+	slog.Info("SWEEPING", "param", param, "values", strings.Join(values, ","), "out", out, "parallel", parallel)
+	runSweeps(param, runs, runArgs, parallel)
+	failed := writeSweepSummary(param, out, runs)
+	if failed > 0 {
+		fatal("SOME RUNS FAILED", "failed", failed, "runs", len(runs))
+	}
+}
+
+// The values of the parameter to run with, from --values or --from, --to and --count, formatted as they are given to each run
+func sweepValues(param, valuesString string, from, to float64, count int, logSpacing bool) ([]string, error) {
+	var values []float64
+	if valuesString != "" {
+		for _, field := range strings.Split(valuesString, ",") {
+			value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+	} else {
+		if count < 1 {
+			return nil, fmt.Errorf("either --values or --from, --to and --count (at least 1) must be given")
+		}
+		if logSpacing && (from <= 0 || to <= 0) {
+			return nil, fmt.Errorf("--from and --to must be positive to space the values logarithmically")
+		}
+		for i := 0; i < count; i++ {
+			fraction := 0.0
+			if count > 1 {
+				fraction = float64(i) / float64(count-1)
+			}
+			if logSpacing {
+				values = append(values, from*math.Pow(to/from, fraction))
+			} else {
+				values = append(values, from+(to-from)*fraction)
+			}
+		}
+	}
+
+	var formatted []string
+	for _, value := range values {
+		if param == "numBodies" {
+			value = math.Round(value)
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) || value < 0 || (value == 0 && param != "softening") {
+			return nil, fmt.Errorf("%v can't be %v", param, value)
+		}
+		s := strconv.FormatFloat(value, 'g', -1, 64)
+		if param == "numBodies" {
+			s = strconv.FormatFloat(value, 'f', 0, 64)
+		}
+		// Values can round to the same number of bodies, which would be the same run
+		if !slices.Contains(formatted, s) {
+			formatted = append(formatted, s)
+		}
+	}
+	return formatted, nil
+}
+
+// Check the flags given to every run, returning them with input paths made absolute
+// param is set for each run, so can't be given to every run. With sameSeed, a --seed is added if there isn't one
+func sweepRunArgs(param string, args []string, sameSeed bool) []string {
+	var helpFlag, listFlag bool
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	simulationFlags(fs, &helpFlag, &listFlag)
+	logFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fatal("UNEXPECTED ARGUMENTS AFTER THE SIMULATE FLAGS", "args", strings.Join(fs.Args(), " "))
+	}
+	// The run limits may come from the config file too, which each run also reads
+	if err := loadConfig(fs, configPath); err != nil {
+		fatal("COULD NOT LOAD CONFIG FILE", "err", err)
+	}
+	if maxSteps <= 0 && maxSimTime <= 0 && maxDuration <= 0 {
+		fatal("EVERY RUN NEEDS A RUN LIMIT, ADD --steps, --simTime OR --duration AFTER --")
+	}
+
+	var runArgs []string
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+		value := f.Value.String()
+		if sweepInputFlags[f.Name] && value != "" && value != "-" {
+			var paths []string
+			for _, path := range strings.Split(value, ",") {
+				absolute, err := filepath.Abs(path)
+				if err != nil {
+					fatal("COULD NOT FIND INPUT FILE", "flag", f.Name, "path", path, "err", err)
+				}
+				paths = append(paths, absolute)
+			}
+			value = strings.Join(paths, ",")
+		}
+		runArgs = append(runArgs, "--"+f.Name+"="+value)
+	})
+	if set[param] {
+		fatal("--" + param + " IS SET FOR EACH RUN, SO CAN'T BE GIVEN TO EVERY RUN")
+	}
+	if sameSeed && !set["seed"] {
+		seed := time.Now().UnixMicro()
+		slog.Info("USING THE SAME SEED FOR EVERY RUN", "seed", seed)
+		runArgs = append(runArgs, "--seed="+strconv.FormatInt(seed, 10))
+	}
+	return runArgs
+}
+
+// Run the runs, parallel at a time, until they have all finished or the sweep is interrupted
+// Runs not started before an interrupt are left with an error saying so
+func runSweeps(param string, runs []sweepRun, runArgs []string, parallel int) {
+	executable, err := os.Executable()
+	if err != nil {
+		fatal("COULD NOT FIND THIS PROGRAM TO RUN", "err", err)
+	}
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, parallel)
+	for i := range runs {
+		slots <- struct{}{}
+		if shuttingDown() {
+			runs[i].err = fmt.Errorf("not run, interrupted")
+			<-slots
+			continue
+		}
+		wg.Add(1)
+		go func(r *sweepRun) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			runSweep(executable, param, r, runArgs)
+		}(&runs[i])
+	}
+	wg.Wait()
+}
+
+// Run the simulate command in the run's directory with its value, logging to log.txt, then read the summary it wrote
+func runSweep(executable, param string, r *sweepRun, runArgs []string) {
+	logFile, err := os.Create(filepath.Join(r.dir, "log.txt"))
+	if err != nil {
+		r.err = err
+		slog.Warn("RUN FAILED", param, r.value, "err", err)
+		return
+	}
+	defer logFile.Close()
+	// A summary left by an earlier run would be mistaken for this run's
+	os.Remove(filepath.Join(r.dir, "summary.json"))
+
+	args := append([]string{"simulate"}, runArgs...)
+	args = append(args, "--"+param+"="+r.value, "--summaryOut=summary.json")
+	cmd := exec.Command(executable, args...)
+	cmd.Dir = r.dir
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	slog.Info("STARTING RUN", param, r.value, "dir", r.dir)
+	if err := cmd.Run(); err != nil {
+		r.err = fmt.Errorf("%w, see %v", err, logFile.Name())
+	}
+
+	data, err := os.ReadFile(filepath.Join(r.dir, "summary.json"))
+	if err == nil {
+		var summary runSummary
+		if err = json.Unmarshal(data, &summary); err == nil {
+			r.summary = &summary
+		}
+	}
+	if r.err == nil && err != nil {
+		r.err = fmt.Errorf("could not read the summary of the run: %w", err)
+	}
+	if r.err != nil {
+		slog.Warn("RUN FAILED", param, r.value, "err", r.err)
+		return
+	}
+	slog.Info("FINISHED RUN", param, r.value, "steps", r.summary.Steps, "seconds", r.summary.WallSeconds)
+}
+
+// Write a row of each run's summary to summary.csv in out and print them as a table, returning how many runs failed
+// Runs that failed still have a row, with their error, and with their summary if they wrote one before failing
+func writeSweepSummary(param, out string, runs []sweepRun) int {
+	path := filepath.Join(out, "summary.csv")
+	file, err := os.Create(path)
+	if err != nil {
+		fatal("COULD NOT WRITE SUMMARY", "path", path, "err", err)
+	}
+	header := []string{param, "steps", "simTime", "wallSeconds", "mergers", "startBodies", "bodies", "totalMass", "largestMass", "energyDrift", "momentumDrift", "error"}
+	fmt.Fprintln(file, "#"+strings.Join(header, ", "))
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Fprintln(tableWriter, strings.ToUpper(strings.Join(header, "\t")))
+
+	failed := 0
+	for _, r := range runs {
+		errString := ""
+		if r.err != nil {
+			failed++
+			errString = r.err.Error()
+		}
+		if r.summary == nil {
+			fmt.Fprintf(file, "%v,,,,,,,,,,,%q\n", r.value, errString)
+			fmt.Fprintf(tableWriter, "%v\t\t\t\t\t\t\t\t\t\t\t%v\n", r.value, errString)
+			continue
+		}
+		s := r.summary
+		largest := 0.0
+		if s.Largest != nil {
+			largest = s.Largest.Mass
+		}
+		fmt.Fprintf(file, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%q\n", r.value, s.Steps, s.SimTime, s.WallSeconds, s.Mergers, s.StartBodies, s.Bodies,
+			s.TotalMass, largest, s.EnergyDrift, s.MomentumDrift, errString)
+		fmt.Fprintf(tableWriter, "%v\t%v\t%.6g\t%.3g\t%v\t%v\t%v\t%.6g\t%.6g\t%.3g%%\t%.3g\t%v\n", r.value, s.Steps, s.SimTime, s.WallSeconds, s.Mergers,
+			s.StartBodies, s.Bodies, s.TotalMass, largest, 100*s.EnergyDrift, s.MomentumDrift, errString)
+	}
+	tableWriter.Flush()
+	if err := file.Close(); err != nil {
+		fatal("COULD NOT WRITE SUMMARY", "path", path, "err", err)
+	}
+	fmt.Println("SUMMARY WRITTEN TO", path)
+	return failed
+}
@@ -0,0 +1,79 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"bytes"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"hmcalister/gravity_simulation/render"
+)
+
+// A line of text being typed by the user, e.g. a new value for a field of the selected body
+// While text is being typed, keys are captured by the input rather than being used as controls
+type textInput struct {
+	// What the text is for, shown by whichever overlay draws the input
+	label string
+	text  string
+	// Called with the text when Enter is pressed
+	submit func(string)
+	// Whether the input is shown as a prompt at the bottom of the window, rather than by another overlay
+	prompt bool
+}
+
+// The input currently being typed into, or nil if there is none
+var activeInput *textInput
+
+// Start typing into a new input, replacing any input already active
+func startTextInput(label, initial string, submit func(string)) {
+	activeInput = &textInput{label, initial, submit, false}
+	sdl.StartTextInput()
+}
+
+// Start typing into a prompt at the bottom of the window
+func startPrompt(label, initial string, submit func(string)) {
+	startTextInput(label, initial, submit)
+	activeInput.prompt = true
+}
+
+// Draw the prompt being typed into, if any, at the bottom center of the window
+func drawPrompt() {
+	if activeInput == nil || !activeInput.prompt {
+		return
+	}
+	lines := []string{activeInput.label + ": " + activeInput.text + "_", "ENTER TO APPLY, ESCAPE TO CANCEL"}
+	width, height := render.PanelSize(lines)
+	render.Panel(frame, int(screenWidth)/2-width/2, int(screenHeight)-height-render.PANELMARGIN, lines)
+}
+
+// Stop typing, throwing away the text
+func cancelTextInput() {
+	activeInput = nil
+	sdl.StopTextInput()
+}
+
+// Handle a key press while typing: Enter submits the text, Escape cancels and Backspace deletes the last character
+func handleTextInputKey(t *sdl.KeyboardEvent) {
+	switch t.Keysym.Scancode {
+	case sdl.SCANCODE_RETURN, sdl.SCANCODE_KP_ENTER:
+		input := activeInput
+		cancelTextInput()
+		input.submit(input.text)
+	case sdl.SCANCODE_ESCAPE:
+		cancelTextInput()
+	case sdl.SCANCODE_BACKSPACE:
+		if text := []rune(activeInput.text); len(text) > 0 {
+			activeInput.text = string(text[:len(text)-1])
+		}
+	}
+}
+
+// Add typed text to the input
+func handleTextInputText(t *sdl.TextInputEvent) {
+	text := t.Text[:]
+	if end := bytes.IndexByte(text, 0); end >= 0 {
+		text = text[:end]
+	}
+	activeInput.text += string(text)
+}
@@ -0,0 +1,60 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"hmcalister/gravity_simulation/render"
+)
+
+// How long the mouse must rest over a body before its tooltip is shown
+const TOOLTIPDELAY = 500 * time.Millisecond
+
+var (
+	// The index of the body under the mouse, or -1 if there is none
+	hoveredBody = -1
+	// When the mouse started hovering over the body
+	hoverStart time.Time
+)
+
+// Draw a small tooltip next to the mouse for the body under it, once the mouse has rested there for a moment
+// This gives a quick look at a body without selecting it, so it is not shown for the selected body or while a button is held
+func drawTooltip() {
+	x, y, buttons := sdl.GetMouseState()
+	i := -1
+	if sdl.GetMouseFocus() != nil && buttons == 0 {
+		i = bodyAtScreen(x, y)
+	}
+	if i != hoveredBody {
+		hoveredBody = i
+		hoverStart = time.Now()
+	}
+	if i < 0 || i == selectedBody || time.Since(hoverStart) < TOOLTIPDELAY {
+		return
+	}
+
+	b := sim.Bodies()[i]
+	title := fmt.Sprintf("BODY %v", i)
+	if b.Name != "" {
+		title += " - " + b.Name
+	}
+	lines := []string{
+		title,
+		fmt.Sprintf("MASS   %.2f", b.Mass),
+		fmt.Sprintf("SPEED  %.3f", b.Vel.Norm()),
+	}
+	// Keep the tooltip inside the window, flipping it to the other side of the mouse near the edges
+	width, height := render.PanelSize(lines)
+	left, top := int(x)+render.PANELMARGIN, int(y)+render.PANELMARGIN
+	if left+width > int(screenWidth) {
+		left = int(x) - render.PANELMARGIN - width
+	}
+	if top+height > int(screenHeight) {
+		top = int(y) - render.PANELMARGIN - height
+	}
+	render.Panel(frame, left, top, lines)
+}
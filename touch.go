@@ -0,0 +1,59 @@
+//go:build sdl && !headless
+
+package main
+
+import "github.com/veandco/go-sdl2/sdl"
+
+// On touchscreens, dragging with two fingers pans the view and pinching zooms it
+// A single finger acts as the mouse (SDL turns it into mouse events), so bodies can still be selected and dragged
+
+// How quickly pinching zooms, relative to the change in distance between the fingers (as a fraction of the screen)
+const PINCHZOOMRATE = 4
+
+var (
+	// The number of fingers on the touchscreen
+	touchFingers int
+	// The center of the last gesture (as a fraction of the screen), or unset when a gesture is starting
+	gestureX      float32
+	gestureY      float32
+	gestureActive bool
+)
+
+// Whether a multi finger gesture is in progress, in which case the mouse events SDL makes from the first finger are ignored
+func touchGesture() bool {
+	return touchFingers >= 2
+}
+
+// Keep track of the fingers on the touchscreen
+// Whenever a finger is added or lifted the gesture restarts, so the view doesn't jump as the center of the fingers moves
+func handleFingerEvent(t *sdl.TouchFingerEvent) {
+	if t.Type == sdl.FINGERMOTION {
+		return
+	}
+	touchFingers = sdl.GetNumTouchFingers(t.TouchID)
+	gestureActive = false
+	// Once a second finger is down, stop whatever the first finger was doing as the mouse
+	if touchGesture() {
+		stopDrag()
+		selectingBand = false
+	}
+}
+
+// Pan and zoom the view with a two finger gesture
+func handleMultiGesture(t *sdl.MultiGestureEvent) {
+	if t.NumFingers < 2 {
+		return
+	}
+	centerX := int32(t.X * float32(screenWidth))
+	centerY := int32(t.Y * float32(screenHeight))
+	if gestureActive {
+		panView(-float64(t.X-gestureX)*float64(screenWidth), -float64(t.Y-gestureY)*float64(screenHeight))
+	}
+	// Zoom about the center of the fingers, so the point between them stays put
+	if t.DDist != 0 {
+		camera.ZoomAbout(1/(1+float64(t.DDist)*PINCHZOOMRATE), float64(centerX), float64(centerY))
+	}
+	gestureX, gestureY = t.X, t.Y
+	gestureActive = true
+	canvas.Fill(backgroundColor)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log/slog"
+
+	"hmcalister/gravity_simulation/persist"
+)
+
+// The recorder for the per-step trajectory export, nil if no trajectory is being recorded
+var trajectory persist.TrajectoryRecorder
+
+// Open the trajectory file in the given format ("csv" or "columnar")
+// Like saving, failing to open the trajectory file is not fatal - the simulation simply runs without it
+func openTrajectory(path string, format string) {
+	var err error
+	trajectory, err = persist.OpenTrajectory(path, format, provenance.Comments()...)
+	if err != nil {
+		slog.Warn("COULD NOT OPEN TRAJECTORY", "path", path, "err", err)
+		trajectory = nil
+	}
+}
+
+// Record the current frame to the trajectory, if one is being recorded
+// Like opening it, failing to write the trajectory is not fatal, the file is closed and the simulation runs on without it
+func writeTrajectory() {
+	if trajectory == nil {
+		return
+	}
+	if err := trajectory.Record(sim.Time, sim.Bodies()); err != nil {
+		slog.Warn("COULD NOT WRITE TRAJECTORY, NO LONGER RECORDING IT", "path", trajectoryPath, "err", err)
+		trajectory.Close()
+		trajectory = nil
+	}
+}
+
+// Finish writing the trajectory, if one is being recorded
+func closeTrajectory() {
+	if trajectory == nil {
+		return
+	}
+	if err := trajectory.Close(); err != nil {
+		slog.Warn("COULD NOT WRITE TRAJECTORY", "path", trajectoryPath, "err", err)
+	}
+	trajectory = nil
+}
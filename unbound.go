@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log/slog"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// After every step, the bodies escaping the system (see simulation.Bindings) are found so they can be
+// marked in the window, logged as they start escaping, and removed once far enough away
+
+var (
+	// Set by the unbound flags, see simulationFlags
+	markUnbound         bool
+	logUnbound          bool
+	cullUnboundDistance float64
+	// Whether each body was escaping after the last step, indexed by id
+	escaping []bool
+	// The number of escaping bodies after the last step
+	numEscaping int
+)
+
+// Find the escaping bodies after every step, starting from the current state
+func trackUnbound() {
+	findEscaping(sim)
+	sim.OnStep(findEscaping)
+}
+
+// Find the escaping bodies of the simulation, logging any that have just started escaping and culling any far enough away
+func findEscaping(s *simulation.Simulation) {
+	bindings := simulation.Bindings(s.Bodies(), s.G)
+	was := escaping
+	escaping = make([]bool, len(bindings))
+	numEscaping = 0
+	for id, binding := range bindings {
+		if !binding.Escaping() {
+			continue
+		}
+		if cullUnboundDistance > 0 && binding.Distance > cullUnboundDistance {
+			slog.Info("CULLED ESCAPING BODY", "id", id, "name", s.Bodies()[id].Name, "mass", s.Bodies()[id].Mass, "distance", binding.Distance, "time", s.Time)
+			s.RemoveBody(id)
+			continue
+		}
+		escaping[id] = true
+		numEscaping++
+		if logUnbound && (id >= len(was) || !was[id]) {
+			slog.Info("BODY ESCAPING", "id", id, "name", s.Bodies()[id].Name, "energy", binding.Energy, "distance", binding.Distance, "time", s.Time)
+		}
+	}
+}
+
+// Whether the body with the given id was escaping after the last step
+func isEscaping(id int) bool {
+	return id < len(escaping) && escaping[id]
+}
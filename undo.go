@@ -0,0 +1,161 @@
+package main
+
+import (
+	"log/slog"
+	"reflect"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Interactive edits (spawning, removing, dragging and editing bodies, bulk edits of selections and loading dropped files)
+// can be undone with Ctrl+Z and redone with Ctrl+Y. Before each edit the state of the simulation is noted, and when the
+// edit is finished (before the next step, edit, undo or redo) the bodies it changed are stored with their values before
+// and after. Undoing puts back only those bodies, so any time simulated since the edit is kept for the rest of the system.
+// Edits that replace the whole simulation with a protobuf save (which holds the settings and time too) are the exception,
+// undoing them returns to exactly the state before, as the replaced simulation never ran on
+
+// The most undo steps that are kept, the oldest is discarded to make room for new ones
+const MAXUNDO = 50
+
+// A body changed by an edit, nil when the body didn't exist (e.g. before a spawn or after a removal)
+type bodyChange struct {
+	id     int
+	before *simulation.Body
+	after  *simulation.Body
+}
+
+// An edit that can be undone
+type edit struct {
+	changes []bodyChange
+	// For edits that replace the whole simulation, the states before and after, restored in full instead of the changes
+	whole         bool
+	before, after checkpoint
+}
+
+var (
+	// The edits, most recent last
+	undoStack []edit
+	// The edits undone, most recent last, so they can be redone
+	redoStack []edit
+	// The state before the edit in progress, if any, and whether it replaces the whole simulation
+	pendingEdit  *checkpoint
+	pendingWhole bool
+)
+
+// Record the state of the simulation before an edit, so the edit can be undone
+// A new edit means anything undone can no longer be redone
+func recordUndo() {
+	finishEdit()
+	c := takeCheckpoint()
+	pendingEdit = &c
+	pendingWhole = false
+	redoStack = nil
+}
+
+// Record the state of the simulation before an edit that replaces all of it, settings and time included
+func recordReplaceUndo() {
+	recordUndo()
+	pendingWhole = true
+}
+
+// Store the edit in progress (if any) on the undo stack, with the bodies it changed
+// This must be called before anything other than the edit changes the bodies, e.g. a step, so only the edit is stored
+func finishEdit() {
+	if pendingEdit == nil {
+		return
+	}
+	before := *pendingEdit
+	pendingEdit = nil
+	e := edit{whole: pendingWhole}
+	if e.whole {
+		e.before, e.after = before, takeCheckpoint()
+	} else {
+		bodies := sim.Bodies()
+		for id := 0; id < max(len(before.bodies), len(bodies)); id++ {
+			var old, current *simulation.Body
+			if id < len(before.bodies) && before.alive[id] {
+				old = &before.bodies[id]
+			}
+			if id < len(bodies) && bodies[id] != nil {
+				b := *bodies[id]
+				current = &b
+			}
+			if !reflect.DeepEqual(old, current) {
+				e.changes = append(e.changes, bodyChange{id, old, current})
+			}
+		}
+		if len(e.changes) == 0 {
+			return
+		}
+	}
+	if len(undoStack) >= MAXUNDO {
+		undoStack = undoStack[len(undoStack)-MAXUNDO+1:]
+	}
+	undoStack = append(undoStack, e)
+}
+
+// Set each body changed by an edit to its value before (or after) the edit
+// Bodies the edit left in place but that have since been removed (e.g. merged into another body) are not brought back,
+// as their mass lives on in the body they merged into
+func (e edit) apply(undoing bool) {
+	if e.whole {
+		if undoing {
+			e.before.restore()
+		} else {
+			e.after.restore()
+		}
+		// What happens from here is a new experiment rather than the steps already written to the output files again
+		furthestStep = sim.Steps
+		return
+	}
+	for _, c := range e.changes {
+		from, to := c.after, c.before
+		if !undoing {
+			from, to = to, from
+		}
+		bodies := sim.Bodies()
+		if c.id >= len(bodies) {
+			sim.SetBodies(append(bodies, make([]*simulation.Body, c.id+1-len(bodies))...))
+			bodies = sim.Bodies()
+		}
+		if from != nil && bodies[c.id] == nil {
+			continue
+		}
+		if to == nil {
+			bodies[c.id] = nil
+			continue
+		}
+		b := *to
+		bodies[c.id] = &b
+	}
+}
+
+// Undo the most recent edit
+func undo() {
+	finishEdit()
+	if len(undoStack) == 0 {
+		slog.Info("NOTHING TO UNDO")
+		return
+	}
+	e := undoStack[len(undoStack)-1]
+	undoStack = undoStack[:len(undoStack)-1]
+	e.apply(true)
+	redoStack = append(redoStack, e)
+	canvas.Fill(backgroundColor)
+	slog.Info("UNDONE", "remaining", len(undoStack))
+}
+
+// Redo the most recently undone edit
+func redo() {
+	finishEdit()
+	if len(redoStack) == 0 {
+		slog.Info("NOTHING TO REDO")
+		return
+	}
+	e := redoStack[len(redoStack)-1]
+	redoStack = redoStack[:len(redoStack)-1]
+	e.apply(false)
+	undoStack = append(undoStack, e)
+	canvas.Fill(backgroundColor)
+	slog.Info("REDONE", "remaining", len(redoStack))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// Undoing an edit puts back only the bodies it changed, keeping the time simulated since for the rest of the system
+func TestUndoRevertsOnlyTheEdit(t *testing.T) {
+	sim = simulation.New(simulation.Params{G: 1, Timescale: 1, CollisionMode: simulation.COLLISIONPASS}, 1)
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{X: -100}, Mass: 10, Radius: 1})
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{X: 100}, Mass: 10, Radius: 1})
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{Y: 100}, Mass: 10, Radius: 1})
+	canvas = render.NewCanvas(screenWidth, screenHeight)
+	undoStack, redoStack, pendingEdit = nil, nil, nil
+
+	// Edit one body, remove another and spawn a new one, then simulate on
+	recordUndo()
+	edited := *sim.Bodies()[0]
+	sim.Bodies()[0].Vel.X = 1
+	sim.RemoveBody(2)
+	sim.AddBody(&simulation.Body{Pos: simulation.Vec2{Y: -100}, Mass: 10, Radius: 1})
+	for i := 0; i < 10; i++ {
+		timeStep()
+	}
+	other := *sim.Bodies()[1]
+
+	undo()
+	if sim.Steps != 10 || sim.Time != 10 {
+		t.Errorf("undoing rewound the simulation to step %v, time %v", sim.Steps, sim.Time)
+	}
+	if b := sim.Bodies()[0]; b == nil || b.Pos != edited.Pos || b.Vel != edited.Vel {
+		t.Errorf("undoing left the edited body as %+v, want %+v", b, edited)
+	}
+	if b := sim.Bodies()[1]; b == nil || b.Pos != other.Pos {
+		t.Errorf("undoing changed the body the edit didn't touch to %+v, want %+v", b, other)
+	}
+	if b := sim.Bodies()[2]; b == nil || b.Pos.Y != 100 {
+		t.Errorf("undoing didn't bring back the removed body, found %+v", b)
+	}
+	if b := sim.Bodies()[3]; b != nil {
+		t.Errorf("undoing didn't remove the spawned body %+v", b)
+	}
+
+	redo()
+	if b := sim.Bodies()[0]; b == nil || b.Vel.X != 1 || b.Pos != edited.Pos {
+		t.Errorf("redoing left the edited body as %+v", b)
+	}
+	if sim.Bodies()[2] != nil || sim.Bodies()[3] == nil {
+		t.Errorf("redoing didn't remove and spawn the bodies again: %+v, %+v", sim.Bodies()[2], sim.Bodies()[3])
+	}
+
+	undo()
+	undo()
+	if len(redoStack) != 1 {
+		t.Errorf("undoing with nothing to undo changed the redo stack to %v edits", len(redoStack))
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// With --verify, the starting bodies are run twice for a number of steps instead of being simulated as usual, and the
+// checksums of the two runs (see simulation.Checksum) are compared after every step, so anything that should be
+// reproducible can be checked to be bit for bit reproducible. Each run is a copy of the starting bodies, so of the same seed
+// --verifyThreads runs the two with different numbers of threads (GOMAXPROCS), and with --workers the second run finds the
+// gravity here rather than with the workers, checking the distributed gravity against the local gravity
+// If the runs differ, the step they first differ at and the bodies that differ are logged, and the program exits with 1
+
+// The most bodies listed as differing when two runs differ
+const VERIFYMAXBODIES = 10
+
+var (
+	// Set by the --verify flag, the number of steps to run twice, 0 to not verify
+	verifySteps int
+	// Set by the --verifyThreads flag, the numbers of threads to run the two runs with, comma separated
+	verifyThreadsString string
+)
+
+// Parse --verifyThreads into the numbers of threads of the two runs, 0 for both if it isn't set, so neither is changed
+func parseVerifyThreads(s string) ([2]int, error) {
+	var threads [2]int
+	if s == "" {
+		return threads, nil
+	}
+	fields := strings.Split(s, ",")
+	if len(fields) != 2 {
+		return threads, fmt.Errorf("%q must be two numbers of threads, e.g. 1,8", s)
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return threads, err
+		}
+		if n < 1 {
+			return threads, fmt.Errorf("%v threads must be at least 1", n)
+		}
+		threads[i] = n
+	}
+	return threads, nil
+}
+
+// Run the starting bodies twice for --verify steps, and compare them after every step
+func runVerify() {
+	threads, err := parseVerifyThreads(verifyThreadsString)
+	if err != nil {
+		fatal("INVALID --verifyThreads", "err", err)
+	}
+	local := workersString != ""
+	catchInterrupts()
+
+	slog.Info("VERIFYING", "steps", verifySteps)
+	checksums := make([]uint64, 0, verifySteps+1)
+	finished := verifyRun(threads[0], false, func(c *simulation.Simulation) bool {
+		checksums = append(checksums, simulation.Checksum(c.Bodies()))
+		return true
+	})
+	if !finished {
+		fatal("VERIFICATION INTERRUPTED")
+	}
+
+	// The second run stops at the first step that differs from the first, keeping its bodies for comparison
+	differs := -1
+	var differing *simulation.Simulation
+	finished = verifyRun(threads[1], local, func(c *simulation.Simulation) bool {
+		step := c.Steps - sim.Steps
+		if simulation.Checksum(c.Bodies()) == checksums[step] {
+			return true
+		}
+		differs, differing = step, c
+		return false
+	})
+	if !finished {
+		fatal("VERIFICATION INTERRUPTED")
+	}
+	if differs < 0 {
+		slog.Info("VERIFIED, THE RUNS ARE IDENTICAL", "steps", verifySteps, "checksum", fmt.Sprintf("%016x", checksums[verifySteps]))
+		return
+	}
+
+	// Run the first run again as far as the step that differs, to find which bodies differ
+	var first *simulation.Simulation
+	verifyRun(threads[0], false, func(c *simulation.Simulation) bool {
+		first = c
+		return c.Steps-sim.Steps < differs
+	})
+	ids := differingBodies(first.Bodies(), differing.Bodies())
+	attrs := []any{"step", differs, "time", differing.Time, "differingBodies", len(ids)}
+	if len(ids) > VERIFYMAXBODIES {
+		ids = ids[:VERIFYMAXBODIES]
+	}
+	attrs = append(attrs, "ids", ids)
+	if local {
+		attrs = append(attrs, "note", "the first run found the gravity with --workers and the second here")
+	}
+	slog.Error("VERIFICATION FAILED, THE RUNS DIFFER", attrs...)
+	os.Exit(1)
+}
+
+// Run a copy of the starting bodies for --verify steps with the given number of threads (if not 0), calling f with the copy
+// before the first step and after each step, until f returns false. With local, the copy finds the gravity here even with --workers
+// Returns whether the run wasn't interrupted
+func verifyRun(threads int, local bool, f func(c *simulation.Simulation) bool) bool {
+	if threads > 0 {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(threads))
+		slog.Info("RUNNING WITH THREADS", "threads", threads)
+	}
+	c := sim.Clone()
+	if local {
+		c.SetGravity(nil)
+	}
+	for step := 0; f(c) && step < verifySteps; step++ {
+		if shuttingDown() {
+			return false
+		}
+		c.Step()
+	}
+	return true
+}
+
+// The ids of the bodies that aren't exactly the same in a and b, including those removed from only one
+func differingBodies(a, b []*simulation.Body) []int {
+	var ids []int
+	for id := 0; id < max(len(a), len(b)); id++ {
+		var bodyA, bodyB *simulation.Body
+		if id < len(a) {
+			bodyA = a[id]
+		}
+		if id < len(b) {
+			bodyB = b[id]
+		}
+		if (bodyA == nil) != (bodyB == nil) ||
+			(bodyA != nil && simulation.Checksum([]*simulation.Body{bodyA}) != simulation.Checksum([]*simulation.Body{bodyB})) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,85 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The smallest distance (in pixels) a click can be from a body to pick it, so even tiny bodies can be clicked
+const PICKRADIUS = 4
+
+// The view is the camera, see render.Camera
+
+// How far each press of the rotation keys rotates the view, in radians
+const ROTATIONSTEP = math.Pi / 36
+
+// Convert a position on the screen (in pixels, e.g. of the mouse) to a position in the simulation
+func screenToWorld(x, y int32) (float64, float64) {
+	return camera.ScreenToWorld(float64(x), float64(y))
+}
+
+// Convert a position in the simulation to a position on the screen, rounded to whole pixels
+func worldToScreen(x, y float64) (int32, int32) {
+	screenX, screenY := camera.WorldToScreen(x, y)
+	return int32(screenX), int32(screenY)
+}
+
+// Move the view by a distance on the screen (in pixels), e.g. moving up moves up the screen however the view is rotated
+func panView(x, y float64) {
+	camera.Pan(x, y)
+	canvas.Fill(backgroundColor)
+}
+
+// Rotate the view by an angle, about the center of the screen
+func rotateView(angle float64) {
+	camera.Rotate(angle)
+	canvas.Fill(backgroundColor)
+}
+
+// Find the index of the body under a position on the screen, or -1 if there is none
+// If bodies overlap, the one drawn on top (the last in the array) is picked
+func bodyAtScreen(x, y int32) int {
+	worldX, worldY := screenToWorld(x, y)
+	for i := len(sim.Bodies()) - 1; i >= 0; i-- {
+		b := sim.Bodies()[i]
+		if b == nil || !visible(b) {
+			continue
+		}
+		pickRadius := math.Max(b.Radius, PICKRADIUS*camera.Zoom)
+		if b.Pos.Dist(simulation.Vec2{X: worldX, Y: worldY}) <= pickRadius {
+			return i
+		}
+	}
+	return -1
+}
+
+// Prompt for an exact zoomscale, as zooming with the keys multiplies it and can't return to a precise value
+func promptZoom() {
+	startPrompt("ZOOMSCALE", strconv.FormatFloat(camera.Zoom, 'g', -1, 64), func(text string) {
+		value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil || value <= 0 {
+			slog.Warn("THE ZOOMSCALE MUST BE A POSITIVE NUMBER", "value", text)
+			return
+		}
+		camera.Zoom = value
+		canvas.Fill(backgroundColor)
+	})
+}
+
+// Prompt for an exact timescale, as speeding up and slowing down with the keys multiplies it and can't return to a precise value
+func promptTimescale() {
+	startPrompt("TIMESCALE", strconv.FormatFloat(sim.Timescale, 'g', -1, 64), func(text string) {
+		value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil || value <= 0 {
+			slog.Warn("THE TIMESCALE MUST BE A POSITIVE NUMBER", "value", text)
+			return
+		}
+		sim.Timescale = value
+	})
+}
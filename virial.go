@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The virial ratio (see simulation.VirialRatio) says at a glance whether a system, e.g. a randomly generated cluster, is
+// collapsing, in equilibrium, expanding or flying apart. It is logged at the start of each run and with its progress,
+// and shown in the HUD. It takes as long as a step to find, so the HUD only finds it again every VIRIALEVERY
+
+// How often the virial ratio shown in the HUD is found again
+const VIRIALEVERY = 250 * time.Millisecond
+
+// How far from 1 the virial ratio can be for the system to count as virialized
+const VIRIALTOLERANCE = 0.1
+
+var (
+	// The virial ratio last found for the HUD, and when it was found
+	hudVirialRatio float64
+	hudVirialFound time.Time
+)
+
+// What a virial ratio means for the system
+func virialState(ratio float64) string {
+	switch {
+	case ratio >= 2:
+		return "unbound"
+	case ratio > 1+VIRIALTOLERANCE:
+		return "expanding"
+	case ratio >= 1-VIRIALTOLERANCE:
+		return "virialized"
+	default:
+		return "collapsing"
+	}
+}
+
+// Log the virial ratio of the bodies at the start of the run
+func logVirialRatio() {
+	ratio := simulation.VirialRatio(sim.Bodies(), sim.G)
+	slog.Info("VIRIAL RATIO", "ratio", ratio, "state", virialState(ratio))
+}
+
+// The line of the HUD showing the virial ratio, found again if VIRIALEVERY has passed since it was last found
+func virialStatus() string {
+	if now := time.Now(); now.Sub(hudVirialFound) >= VIRIALEVERY {
+		hudVirialRatio, hudVirialFound = simulation.VirialRatio(sim.Bodies(), sim.G), now
+	}
+	return fmt.Sprintf("VIRIAL RATIO: %.3g (%v)", hudVirialRatio, strings.ToUpper(virialState(hudVirialRatio)))
+}
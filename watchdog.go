@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+
+	"hmcalister/gravity_simulation/persist"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// After every step, the watchdog checks for bodies with a NaN or infinite position, velocity or mass (see simulation.NonFinite)
+// Such a body can never recover, and would otherwise fly off to nowhere, disappear from the window or spread NaN to every
+// body it pulls on. When one is found the simulation is paused, the bodies are logged, and the last WATCHDOGHISTORY steps
+// of every body are written to --watchdogOut as a csv trajectory, to see how it went wrong. The window shows a warning
+// Each body is only reported once, so the simulation can be carried on with (e.g. after removing the bodies)
+// Without a window or an API to carry on with, the run is stopped instead, and exits with 1 after saving as usual
+
+// The number of steps before a body goes wrong kept for --watchdogOut
+const WATCHDOGHISTORY = 20
+
+// The most bodies logged when bodies go wrong
+const WATCHDOGMAXBODIES = 10
+
+// A copy of the bodies after a step, kept by the watchdog
+type watchdogFrame struct {
+	time float64
+	// Indexed by id, with removed bodies nil, pointing into store
+	bodies []*simulation.Body
+	store  []simulation.Body
+}
+
+// What the watchdog found, for the warning in the window
+type watchdogReport struct {
+	steps int
+	time  float64
+	ids   []int
+}
+
+var (
+	// Set by the watchdog flags, see simulationFlags
+	watchdogEnabled bool
+	watchdogPath    string
+	// The last WATCHDOGHISTORY frames, oldest first once full, reused as new frames are kept
+	watchdogFrames []watchdogFrame
+	// The index of the next frame in watchdogFrames to keep a copy in
+	watchdogNext int
+	// Whether each body has been reported already, indexed by id
+	watchdogReported []bool
+	// The most recent report, nil if no bodies have gone wrong
+	watchdogLast *watchdogReport
+	// Whether the run was stopped by the watchdog, so should exit with 1
+	watchdogStopped bool
+	// Whether the window is open, so the simulation can be carried on with once paused
+	windowOpen bool
+)
+
+// Check the bodies after every step, starting from the current state
+func setupWatchdog() {
+	keepWatchdogFrame(sim)
+	sim.OnStep(checkWatchdog)
+}
+
+// Keep a copy of the bodies after the step, replacing the oldest copy once there are WATCHDOGHISTORY
+func keepWatchdogFrame(s *simulation.Simulation) {
+	if len(watchdogFrames) < WATCHDOGHISTORY {
+		watchdogFrames = append(watchdogFrames, watchdogFrame{})
+	}
+	f := &watchdogFrames[watchdogNext]
+	watchdogNext = (watchdogNext + 1) % WATCHDOGHISTORY
+	f.time = s.Time
+	f.bodies = f.bodies[:0]
+	f.store = f.store[:0]
+	for _, b := range s.Bodies() {
+		if b != nil {
+			f.store = append(f.store, *b)
+		}
+	}
+	// The store is complete before pointing into it, as appending may have moved it
+	i := 0
+	for _, b := range s.Bodies() {
+		if b == nil {
+			f.bodies = append(f.bodies, nil)
+			continue
+		}
+		f.bodies = append(f.bodies, &f.store[i])
+		i++
+	}
+}
+
+// Check the bodies after a step, reporting and pausing if any have gone wrong since the last check
+func checkWatchdog(s *simulation.Simulation) {
+	keepWatchdogFrame(s)
+	var ids []int
+	for _, id := range simulation.NonFinite(s.Bodies()) {
+		for id >= len(watchdogReported) {
+			watchdogReported = append(watchdogReported, false)
+		}
+		if !watchdogReported[id] {
+			watchdogReported[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	watchdogLast = &watchdogReport{steps: s.Steps, time: s.Time, ids: ids}
+	logged := ids
+	if len(logged) > WATCHDOGMAXBODIES {
+		logged = logged[:WATCHDOGMAXBODIES]
+	}
+	slog.Error("BODIES WENT NaN OR INFINITE", "step", s.Steps, "time", s.Time, "bodies", len(ids), "ids", logged)
+	previous := watchdogPrevious()
+	for _, id := range logged {
+		b := s.Bodies()[id]
+		attrs := []any{"id", id, "name", b.Name, "pos", b.Pos, "vel", b.Vel, "mass", b.Mass}
+		if previous != nil && id < len(previous.bodies) && previous.bodies[id] != nil {
+			p := previous.bodies[id]
+			attrs = append(attrs, "previousPos", p.Pos, "previousVel", p.Vel, "previousMass", p.Mass)
+		}
+		slog.Error("NON-FINITE BODY", attrs...)
+	}
+	writeWatchdogHistory()
+
+	paused = true
+	if !windowOpen && apiAddr == "" && grpcAddr == "" {
+		slog.Error("STOPPING THE RUN, AS NOTHING CAN CARRY IT ON")
+		watchdogStopped = true
+		requestShutdown()
+		return
+	}
+	slog.Warn("PAUSED BY THE WATCHDOG, UNPAUSE TO CARRY ON")
+}
+
+// The frame kept before the newest, the bodies before the step that went wrong, or nil if there isn't one
+func watchdogPrevious() *watchdogFrame {
+	if len(watchdogFrames) < 2 {
+		return nil
+	}
+	// watchdogNext is just after the newest
+	return &watchdogFrames[(watchdogNext-2+2*WATCHDOGHISTORY)%WATCHDOGHISTORY]
+}
+
+// Write the kept frames, oldest first, to --watchdogOut, replacing any history written before
+func writeWatchdogHistory() {
+	if watchdogPath == "" {
+		return
+	}
+	if err := os.Remove(watchdogPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("COULD NOT WRITE WATCHDOG HISTORY", "path", watchdogPath, "err", err)
+		return
+	}
+	history, err := persist.NewCSVTrajectory(watchdogPath, provenance.Comments()...)
+	if err != nil {
+		slog.Warn("COULD NOT WRITE WATCHDOG HISTORY", "path", watchdogPath, "err", err)
+		return
+	}
+	for i := 0; i < len(watchdogFrames); i++ {
+		f := &watchdogFrames[(watchdogNext+i)%len(watchdogFrames)]
+		history.Record(f.time, f.bodies)
+	}
+	history.Close()
+	slog.Info("WROTE THE LAST STEPS OF EVERY BODY", "path", watchdogPath, "steps", len(watchdogFrames))
+}
+
+// Exit with 1 once shut down if the watchdog stopped the run, so scripts running the simulation notice it went wrong
+func exitIfWatchdogStopped() {
+	if watchdogStopped {
+		fatal("THE RUN WAS STOPPED BY THE WATCHDOG, AS BODIES WENT NaN OR INFINITE")
+	}
+}
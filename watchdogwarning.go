@@ -0,0 +1,36 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"fmt"
+	"image"
+
+	"hmcalister/gravity_simulation/render"
+)
+
+// Draw a warning at the top of the window while the simulation is paused by the watchdog
+// The warning goes once the simulation is unpaused, as the bodies aren't reported again
+func drawWatchdog() {
+	if watchdogLast == nil {
+		return
+	}
+	if !paused {
+		watchdogLast = nil
+		return
+	}
+	lines := []string{
+		fmt.Sprintf("WARNING: %v BODIES WENT NaN OR INFINITE AT STEP %v (TIME %.2f)", len(watchdogLast.ids), watchdogLast.steps, watchdogLast.time),
+		fmt.Sprintf("IDS: %v", watchdogLast.ids[:min(len(watchdogLast.ids), WATCHDOGMAXBODIES)]),
+		"PAUSED. BACKSPACE STEPS BACK, OR UNPAUSE TO CARRY ON",
+	}
+	if watchdogPath != "" {
+		lines = append(lines, "THE LAST STEPS OF EVERY BODY ARE IN "+watchdogPath)
+	}
+	width, height := render.PanelSize(lines)
+	x, y := (int(screenWidth)-width)/2, render.PANELMARGIN
+	render.Box(frame, image.Rect(x, y, x+width, y+height))
+	for i, line := range lines {
+		render.Text(frame, x+render.PANELPADDING, y+render.PANELPADDING+i*render.TextLineHeight, line, render.WarningColor)
+	}
+}
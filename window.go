@@ -0,0 +1,533 @@
+//go:build sdl && !headless
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+	"unsafe"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"hmcalister/gravity_simulation/render"
+	"hmcalister/gravity_simulation/simulation"
+)
+
+// The interactive program: the window the simulation is drawn in, and the controls
+// This is only built with the sdl tag (and without the headless tag), otherwise all of this (and SDL) is left out, see headless.go
+
+// The command run when no other command is given
+const DEFAULTCOMMAND = "run"
+
+// What the run command shows the simulation on when not given --renderer
+const DEFAULTRENDERER = "window"
+
+// The help for the run command, printed with -h
+func runHelp() string {
+	return `
+Gravity Simulation
+Usage:
+	Run from source using "go run -tags sdl ." (or "go run -tags sdl . run")
+	Build from source using "go build -tags sdl ."
+	Build without the window (so without needing SDL) using "go build ."
+	Run from executable using "./gravity_simulation" (or "./gravity_simulation run")
+	Use "./gravity_simulation help" to see the other commands, e.g. simulate to run without a window
+
+` + simulationFlagsHelp() + `
+
+Controls:
+	While the simulation is running you can use the keyboard to control parts of the application. The default controls are
+	below, any of which can be rebound in the [keys] table of the config file:
+
+	W : Move view window up
+	A : Move view window left
+	S : Move view window down
+	D : Move view window right
+	Q : Zoom out
+	E : Zoom in
+	, : Rotate view window anticlockwise
+	. : Rotate view window clockwise
+	/ : Reset the rotation of the view window
+	Ctrl+1 to Ctrl+9 : Store the current view (position, zoom and rotation) in a bookmark
+	Alt+1 to Alt+9 : Move the view to a stored bookmark (this was 1 to 9 before they became the speed presets)
+	
+	ArrowKeyDown : Decrease the rate of view window movement
+	ArrowKeyUp : Increase the rate of view window movement
+	ArrowKeyLeft : Decrease the speed of the simulation
+	ArrowKeyRight : Increase the speed of the simulation
+	PageDown : Weaken gravity (decrease G)
+	PageUp : Strengthen gravity (increase G)
+	T : Type an exact timescale
+	I : Type an exact zoomscale
+	1 to 9 : Jump to a preset timescale, from 0.05x to 50x the starting timescale
+	0 : Reset the timescale to the starting timescale
+
+	Spacebar : Toggle pause/resume
+	X : Toggle particle trails
+	] : Hide light bodies from view, doubling the mass below which bodies are hidden with each press
+	[ : Show light bodies again, halving the mass below which bodies are hidden
+	N : Cycle what happens when bodies touch, between merging, bouncing and passing through each other
+	' : Show or hide a scrolling plot of the kinetic, potential and total energy
+	C : Advance a single timestep (without unpausing)
+	Backspace : Go back a single timestep, up to 100 steps
+	P : Print the current state of the simulation (all bodies + settings)
+	Grave (the key below Escape) : Open or close the console, type help in the console for a list of commands
+	O : Save the currect state of the simulation
+
+	Dropping a save file onto the window loads it into the running simulation, either replacing the current bodies or merging with them
+	K : Store a checkpoint of the current state of the simulation (in memory)
+	L : Restore the most recent checkpoint
+	J : Discard the most recent checkpoint
+	Tab : Show or hide the control panel, with sliders for G, the timescale, softening, trail decay and movescale
+	Ctrl+Z : Undo the last edit (spawning, removing, dragging or editing a body, or loading a dropped file), putting back only the bodies it changed
+	Ctrl+Y : Redo the last undone edit
+	F : Freeze the selected body in place (it still pulls on the other bodies but never moves), or unfreeze it
+	Ctrl+C : Copy the selected body to the clipboard, as a row of a csv save file
+	Shift+R : Replace the bodies with a new random configuration (using --numBodies and the generation flags), printing its seed
+	U : Cycle the orbit prediction for the selected body, between off, fixed (other bodies held in place) and coupled (all bodies move)
+	H : Spawn a black hole (a very heavy, very small body) under the mouse
+	G : Turn the kick tool on or off. While on, dragging the mouse pushes the bodies under it in the direction of the drag
+	; : Turn the measure tool on or off. While on, click two points or bodies to show the distance, relative velocity
+		and orbital period between them
+	B : Cycle through the spawn brushes (none, body, cluster, ring and stream), which spawn bodies when clicking empty space
+
+	Hovering the mouse over a body for a moment shows a tooltip with its index (and name), mass and speed
+	Left Click : Select the clicked body, showing its live parameters in the inspector panel (click empty space to deselect)
+		The mass, velocity and color of the selected body can be edited by clicking a field of the editor panel below the inspector,
+		typing a new value and pressing Enter (or Escape to cancel)
+	Left Drag : While paused, move the clicked body
+	Shift + Left Drag : While paused, set the velocity of the clicked body, shown as a line from the body
+	Left Drag (on empty space) : Select all the bodies in the dragged rectangle, which can then be removed (Delete),
+		merged into one (M), recolored (R) or kicked towards the mouse (V)
+	Right Click : Remove the clicked body from the simulation
+
+	On a touchscreen, drag with two fingers to move the view window and pinch to zoom. A single finger acts as the mouse`
+}
+
+// Set up what the window needs once the flags and config file have been read
+func setupDisplay() {
+	defaultTimescale = sim.Timescale
+	camera.Width, camera.Height = screenWidth, screenHeight
+	allocateFrame()
+}
+
+// print the configuration variables with some formatting
+func printConfiguration() {
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Fprintln(tableWriter, "PAUSED\t", paused)
+	fmt.Fprintf(tableWriter, "SIMULATION TIME\t%.2f (%v STEPS)\n", sim.Time, sim.Steps)
+	fmt.Fprintf(tableWriter, "TIMESCALE\t%.2f\n", sim.Timescale)
+	fmt.Fprintf(tableWriter, "G\t%.2f\n", sim.G)
+	fmt.Fprintln(tableWriter, "COLLISIONS\t", simulation.CollisionModeNames[sim.CollisionMode])
+	fmt.Fprintf(tableWriter, "ZOOMSCALE\t%.2f\n", camera.Zoom)
+	fmt.Fprintf(tableWriter, "MOVESCALE\t%.2f\n", movescale)
+	fmt.Fprintf(tableWriter, "SCREEN CENTER\t (%.2f, %.2f)\n", camera.X, camera.Y)
+	fmt.Fprintln(tableWriter, "SEED\t", seed)
+	fmt.Fprintf(tableWriter, "ROTATION\t%.1f DEGREES\n", camera.Rotation*180/math.Pi)
+	// The corners of the screen, which are only the limits in x and y when the view isn't rotated
+	left, top := camera.ScreenToWorld(0, 0)
+	right, bottom := camera.ScreenToWorld(float64(screenWidth), float64(screenHeight))
+	fmt.Fprintf(tableWriter, "SCREEN CORNERS\t (%.0f, %.0f) - (%.0f, %.0f)\n", left, top, right, bottom)
+	tableWriter.Flush()
+}
+
+// Handle all the inputs for the application
+// This includes quit events (alt+F4, ...), keyboard, mouse and touch events, and save files dropped onto the window
+func handleInputs() {
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		switch t := event.(type) {
+		case *sdl.QuitEvent:
+			// Closing the window shuts down the same way as an interrupt (e.g. Ctrl+C in the terminal)
+			requestShutdown()
+		case *sdl.MouseButtonEvent:
+			// During a touch gesture, the mouse events made from the first finger are ignored
+			if t.Which == sdl.TOUCH_MOUSEID && touchGesture() {
+				continue
+			}
+			// Left clicking a body selects it, showing its parameters in the inspector
+			// While paused, the body can also be dragged around
+			// Clicking anywhere else stops editing a field of the body
+			if t.Button == sdl.BUTTON_LEFT && t.State == sdl.PRESSED {
+				if activeInput != nil {
+					cancelTextInput()
+				}
+				// With a brush, clicking empty space spawns bodies rather than deselecting
+				// Otherwise, dragging across empty space selects all the bodies inside the dragged rectangle
+				if !clickControlPanel(t.X, t.Y) && !clickEditor(t.X, t.Y) && !startKick(t.X, t.Y) && !clickMeasure(t.X, t.Y) {
+					clearMultiSelection()
+					if bodyAtScreen(t.X, t.Y) >= 0 || !paintBrush(t.X, t.Y) {
+						selectBodyAtScreen(t.X, t.Y)
+						startDrag(t.X, t.Y)
+						if selectedBody < 0 {
+							startBand(t.X, t.Y)
+						}
+					}
+				}
+			}
+			if t.Button == sdl.BUTTON_LEFT && t.State == sdl.RELEASED {
+				stopDrag()
+				stopKick()
+				releaseSlider()
+				endBand()
+			}
+			// Right clicking a body removes it from the simulation
+			if t.Button == sdl.BUTTON_RIGHT && t.State == sdl.PRESSED {
+				if i := bodyAtScreen(t.X, t.Y); i >= 0 {
+					recordUndo()
+					slog.Info("REMOVED BODY", "id", i)
+					sim.RemoveBody(i)
+				}
+			}
+		case *sdl.TextInputEvent:
+			if activeInput != nil {
+				handleTextInputText(t)
+			}
+		case *sdl.MouseMotionEvent:
+			if t.Which == sdl.TOUCH_MOUSEID && touchGesture() {
+				continue
+			}
+			updateDrag(t.X, t.Y)
+			updateKick(t.X, t.Y)
+			dragSlider(t.X)
+			updateBand(t.X, t.Y)
+		case *sdl.TouchFingerEvent:
+			handleFingerEvent(t)
+		case *sdl.MultiGestureEvent:
+			// Two finger drags pan the view and pinches zoom it
+			handleMultiGesture(t)
+		case *sdl.DropEvent:
+			// Save files dropped onto the window are loaded into the running simulation
+			if t.Type == sdl.DROPFILE {
+				loadDroppedFile(t.File)
+			}
+		case *sdl.KeyboardEvent:
+			// Ignore released keys
+			if t.State == sdl.RELEASED {
+				continue
+			}
+
+			// The console is opened and closed with `
+			if t.Keysym.Scancode == keymap["console"] && t.Repeat != 1 {
+				if consoleOpen() {
+					cancelTextInput()
+				} else {
+					openConsole()
+				}
+				continue
+			}
+
+			// While text is being typed, keys go to the text rather than being controls
+			if activeInput != nil {
+				handleTextInputKey(t)
+				continue
+			}
+
+			// While bodies are selected with a selection rectangle, some keys act on the selected bodies
+			if handleMultiSelectionKey(t) {
+				continue
+			}
+
+			// The number keys store and recall camera bookmarks with Ctrl and Alt, and are speed presets on their own
+			if handleBookmarkKey(t) || handleSpeedPresetKey(t) {
+				continue
+			}
+
+			// If spacebar pressed, pause the simulation
+			if t.Keysym.Scancode == keymap["pause"] && t.Repeat != 1 {
+				paused = !paused
+			}
+
+			// X makes pixels decay
+			if t.Keysym.Scancode == keymap["trails"] && t.Repeat != 1 {
+				pixeldecay = !pixeldecay
+			}
+
+			// Pressing c steps one frame (Ctrl+C copies the selected body instead)
+			if t.Keysym.Scancode == keymap["step"] && t.Keysym.Mod&sdl.KMOD_CTRL == 0 {
+				timeStep()
+			}
+			// Pressing backspace steps one frame backward
+			if t.Keysym.Scancode == keymap["stepBack"] {
+				stepBackward()
+			}
+
+			// Pressing Q/E zooms
+			if t.Keysym.Scancode == keymap["zoomOut"] {
+				camera.Zoom *= 1.2
+				canvas.Fill(backgroundColor)
+			}
+			if t.Keysym.Scancode == keymap["zoomIn"] {
+				camera.Zoom /= 1.2
+				canvas.Fill(backgroundColor)
+			}
+
+			// Pressing W moves the view up and so on...
+			if t.Keysym.Scancode == keymap["moveUp"] {
+				panView(0, -movescale)
+			}
+			if t.Keysym.Scancode == keymap["moveDown"] {
+				panView(0, movescale)
+			}
+			if t.Keysym.Scancode == keymap["moveLeft"] {
+				panView(-movescale, 0)
+			}
+			if t.Keysym.Scancode == keymap["moveRight"] {
+				panView(movescale, 0)
+			}
+
+			// Pressing , and . rotates the view, and / puts it back upright
+			if t.Keysym.Scancode == keymap["rotateLeft"] {
+				rotateView(-ROTATIONSTEP)
+			}
+			if t.Keysym.Scancode == keymap["rotateRight"] {
+				rotateView(ROTATIONSTEP)
+			}
+			if t.Keysym.Scancode == keymap["resetRotation"] {
+				rotateView(-camera.Rotation)
+			}
+
+			// Pressing up and down scales how quickly we move through space
+			if t.Keysym.Scancode == keymap["moveFaster"] {
+				movescale += 1
+			}
+			if t.Keysym.Scancode == keymap["moveSlower"] {
+				if movescale > 0 {
+					movescale -= 1
+				}
+			}
+
+			// Pressing left slows down the simulation
+			if t.Keysym.Scancode == keymap["slowDown"] {
+				sim.Timescale /= 1.1
+			}
+			// Pressing right speeds up the simulation
+			if t.Keysym.Scancode == keymap["speedUp"] {
+				sim.Timescale *= 1.1
+			}
+			// Page Down weakens gravity and Page Up strengthens it
+			if t.Keysym.Scancode == keymap["weakenGravity"] {
+				sim.G /= 1.1
+			}
+			if t.Keysym.Scancode == keymap["strengthenGravity"] {
+				sim.G *= 1.1
+			}
+
+			// T and I prompt for an exact timescale and zoomscale
+			if t.Keysym.Scancode == keymap["setTimescale"] && t.Repeat != 1 {
+				promptTimescale()
+			}
+			if t.Keysym.Scancode == keymap["setZoom"] && t.Repeat != 1 {
+				promptZoom()
+			}
+
+			// P prints out all bodies
+			if t.Keysym.Scancode == keymap["print"] {
+				fmt.Printf("\n\n\n")
+				printBodies()
+				printConfiguration()
+			}
+
+			// O saves the current state of the simulation to a file
+			if t.Keysym.Scancode == keymap["save"] {
+				slog.Info("SAVING TO FILE")
+				if err := saveState(); err != nil {
+					slog.Warn("COULD NOT SAVE STATE", "err", err)
+				}
+			}
+
+			// K stores a checkpoint, L restores it and J throws it away
+			if t.Keysym.Scancode == keymap["storeCheckpoint"] && t.Repeat != 1 {
+				storeCheckpoint()
+			}
+			if t.Keysym.Scancode == keymap["restoreCheckpoint"] && t.Repeat != 1 {
+				restoreCheckpoint()
+			}
+			if t.Keysym.Scancode == keymap["discardCheckpoint"] && t.Repeat != 1 {
+				discardCheckpoint()
+			}
+
+			// Ctrl+Z undoes the last edit and Ctrl+Y redoes it
+			if t.Keysym.Scancode == keymap["undo"] && t.Keysym.Mod&sdl.KMOD_CTRL != 0 {
+				undo()
+			}
+			if t.Keysym.Scancode == keymap["redo"] && t.Keysym.Mod&sdl.KMOD_CTRL != 0 {
+				redo()
+			}
+
+			// Shift+R regenerates a random configuration
+			if t.Keysym.Scancode == keymap["rerandomize"] && t.Keysym.Mod&sdl.KMOD_SHIFT != 0 && t.Repeat != 1 {
+				rerandomize()
+			}
+
+			// F freezes or unfreezes the selected body
+			if t.Keysym.Scancode == keymap["freeze"] && t.Repeat != 1 {
+				freezeSelectedBody()
+			}
+
+			// Ctrl+C copies the selected body to the clipboard
+			if t.Keysym.Scancode == keymap["copyBody"] && t.Keysym.Mod&sdl.KMOD_CTRL != 0 && t.Repeat != 1 {
+				copySelectedBody()
+			}
+
+			// N cycles through the collision modes
+			if t.Keysym.Scancode == keymap["collisionMode"] && t.Repeat != 1 {
+				cycleCollisionMode()
+			}
+
+			// U cycles through the orbit prediction modes
+			if t.Keysym.Scancode == keymap["prediction"] && t.Repeat != 1 {
+				cyclePredictionMode()
+			}
+
+			// B cycles through the spawn brushes
+			if t.Keysym.Scancode == keymap["brush"] && t.Repeat != 1 {
+				cycleBrush()
+			}
+
+			// H spawns a black hole under the mouse
+			if t.Keysym.Scancode == keymap["blackHole"] && t.Repeat != 1 {
+				x, y, _ := sdl.GetMouseState()
+				spawnBlackHole(x, y)
+			}
+
+			// G turns the kick tool on or off
+			if t.Keysym.Scancode == keymap["kickTool"] && t.Repeat != 1 {
+				kickTool = !kickTool
+				stopKick()
+			}
+
+			// ; turns the measure tool on or off
+			if t.Keysym.Scancode == keymap["measure"] && t.Repeat != 1 {
+				toggleMeasureTool()
+			}
+
+			// ] hides more of the lightest bodies and [ shows them again
+			if t.Keysym.Scancode == keymap["raiseMassFilter"] && t.Repeat != 1 {
+				raiseMassFilter()
+			}
+			if t.Keysym.Scancode == keymap["lowerMassFilter"] && t.Repeat != 1 {
+				lowerMassFilter()
+			}
+
+			// ' shows or hides the energy plot
+			if t.Keysym.Scancode == keymap["energyPlot"] && t.Repeat != 1 {
+				toggleEnergyPlot()
+			}
+
+			// Tab shows or hides the control panel
+			if t.Keysym.Scancode == keymap["controlPanel"] && t.Repeat != 1 {
+				showControlPanel = !showControlPanel
+			}
+		}
+	}
+}
+
+// Replace the bodies with a fresh random configuration, generated with a new seed from the current --numBodies and generation flags
+// The new seed is printed so the configuration can be repeated with --seed
+func rerandomize() {
+	recordUndo()
+	seed = time.Now().UnixMicro()
+	sim.Rand.Seed(seed)
+	sim.SetBodies(make([]*simulation.Body, numBodies))
+	for i := 0; i < numBodies; i++ {
+		sim.Bodies()[i] = simulation.NewRandomBody(sim.Rand, randomOptions())
+	}
+	selectedBody = -1
+	clearMultiSelection()
+	canvas.Fill(backgroundColor)
+	slog.Info("RANDOMIZED", "seed", seed)
+}
+
+// The run command, which runs the simulation in a window with all of the interactive controls
+func runCommand(args []string) {
+	setupSimulation("run", args, runHelp())
+	if headlessFlag {
+		runHeadless()
+		return
+	}
+
+	// Catch interrupts so they shut down alongside the other quit events, rather than killing the program mid save
+	catchInterrupts()
+	if rendererName == "null" {
+		if err := runNullRenderer(); err != nil {
+			fatal("RENDERING FAILED", "err", err)
+		}
+	} else if err := runWindow(); err != nil {
+		fatal("COULD NOT OPEN THE WINDOW", "err", err)
+	}
+	// The window is closed by now, so the final save happens after it disappears
+	if err := shutdown(); err != nil {
+		fatal("COULD NOT SAVE BEFORE QUITTING", "err", err)
+	}
+}
+
+// Open the window and run the simulation in it until the window is closed or the program is interrupted
+func runWindow() error {
+	return withWindow("Gravity Simulation", func(w windowRenderer) error {
+		// SDL starts with text input on, which would send the key that opens a prompt as text to the prompt
+		// Text input is only turned on while typing instead
+		sdl.StopTextInput()
+		windowOpen = true
+
+		// The physics runs alongside the render loop, see frames.go
+		return renderLoop(w, handleInputs, func() *render.Canvas {
+			drawFrame()
+			return frameCanvas
+		})
+	})
+}
+
+// Open a window with the given title, the size of the screen, and run f with a renderer showing frames in it
+// The window is closed once f returns
+func withWindow(title string, f func(w windowRenderer) error) error {
+	// Start by initializing the SDL framework
+	if err := sdl.Init(sdl.INIT_VIDEO); err != nil {
+		return fmt.Errorf("could not initialize SDL: %w", err)
+	}
+	defer sdl.Quit()
+
+	window, err := sdl.CreateWindow(title, sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
+		screenWidth, screenHeight, sdl.WINDOW_SHOWN)
+	if err != nil {
+		return fmt.Errorf("could not create the window: %w", err)
+	}
+	defer window.Destroy()
+
+	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
+	if err != nil {
+		return fmt.Errorf("could not create the renderer: %w", err)
+	}
+	defer renderer.Destroy()
+
+	tex, err := renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STREAMING, screenWidth, screenHeight)
+	if err != nil {
+		return fmt.Errorf("could not create the texture: %w", err)
+	}
+	defer tex.Destroy()
+
+	return f(windowRenderer{renderer, tex})
+}
+
+// Shows frames in the window, by copying them into a streaming texture the size of the window
+type windowRenderer struct {
+	renderer *sdl.Renderer
+	tex      *sdl.Texture
+}
+
+func (w windowRenderer) Present(c *render.Canvas) error {
+	if err := w.tex.Update(nil, unsafe.Pointer(&c.Pixels[0]), int(c.Width)*4); err != nil {
+		return err
+	}
+	if err := w.renderer.Copy(w.tex, nil, nil); err != nil {
+		return err
+	}
+	w.renderer.Present()
+	return nil
+}
+
+// The texture and renderer are destroyed along with the window, see withWindow
+func (w windowRenderer) Close() error {
+	return nil
+}